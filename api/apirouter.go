@@ -47,6 +47,8 @@ func NewAPIRouter(app *appContext, userRealIP bool) apiMux {
 			rd.Get("/header", app.getBlockHeader)
 			rd.Get("/size", app.getBlockSize)
 			rd.With((middleware.Compress(1))).Get("/verbose", app.getBlockVerbose)
+			rd.Get("/raw", app.getBlockRaw)
+			rd.Get("/header/raw", app.getBlockHeaderRaw)
 			rd.Get("/pos", app.getBlockStakeInfoExtended)
 			rd.Route("/tx", func(rt chi.Router) {
 				rt.Get("/", app.getBlockTransactions)
@@ -61,6 +63,8 @@ func NewAPIRouter(app *appContext, userRealIP bool) apiMux {
 			rd.Get("/header", app.getBlockHeader)
 			rd.Get("/size", app.getBlockSize)
 			rd.With((middleware.Compress(1))).Get("/verbose", app.getBlockVerbose)
+			rd.Get("/raw", app.getBlockRaw)
+			rd.Get("/header/raw", app.getBlockHeaderRaw)
 			rd.Get("/pos", app.getBlockStakeInfoExtended)
 			rd.Route("/tx", func(rt chi.Router) {
 				rt.Get("/", app.getBlockTransactions)
@@ -75,6 +79,8 @@ func NewAPIRouter(app *appContext, userRealIP bool) apiMux {
 			rd.Get("/hash", app.getBlockHash)
 			rd.Get("/size", app.getBlockSize)
 			rd.With((middleware.Compress(1))).Get("/verbose", app.getBlockVerbose)
+			rd.Get("/raw", app.getBlockRaw)
+			rd.Get("/header/raw", app.getBlockHeaderRaw)
 			rd.Get("/pos", app.getBlockStakeInfoExtended)
 			rd.Route("/tx", func(rt chi.Router) {
 				rt.Get("/", app.getBlockTransactions)
@@ -87,6 +93,7 @@ func NewAPIRouter(app *appContext, userRealIP bool) apiMux {
 			rd.Use(middleware.Compress(1))
 			rd.Get("/", app.getBlockRangeSummary)
 			rd.Get("/size", app.getBlockRangeSize)
+			rd.Get("/raw", app.getBlockRangeRaw)
 			rd.Route("/{step}", func(rs chi.Router) {
 				rs.Use(m.BlockStepPathCtx)
 				rs.Get("/", app.getBlockRangeSteppedSummary)
@@ -101,14 +108,19 @@ func NewAPIRouter(app *appContext, userRealIP bool) apiMux {
 
 	mux.Route("/stake", func(r chi.Router) {
 		r.Route("/vote", func(rd chi.Router) {
-			rd.Use(app.StakeVersionLatestCtx)
-			rd.Get("/info", app.getVoteInfo)
+			rd.With(app.StakeVersionLatestCtx).Get("/info", app.getVoteInfo)
+			rd.Get("/participation", app.getVoteParticipation)
+			rd.Get("/invalidated", app.getInvalidatingBlocks)
+			rd.Get("/incomplete", app.getIncompleteVoteBlocks)
 		})
 		r.Route("/pool", func(rd chi.Router) {
 			rd.With(app.BlockIndexLatestCtx).Get("/", app.getTicketPoolInfo)
 			rd.With(app.BlockIndexLatestCtx).Get("/full", app.getTicketPool)
 			rd.With(m.BlockIndexPathCtx).Get("/b/{idx}", app.getTicketPoolInfo)
 			rd.With(m.BlockIndexOrHashPathCtx).Get("/b/{idxorhash}/full", app.getTicketPool)
+			rd.With(m.BlockIndexPathCtx).Get("/b/{idx}/tickets", app.getTicketPoolPage)
+			rd.With(m.BlockIndexPathCtx).Get("/b/{idx}/ages", app.getTicketPoolAges)
+			rd.With(m.TransactionHashCtx).Get("/ticket/{txid}", app.getTicketPoolHistory)
 			rd.With(m.BlockIndex0PathCtx, m.BlockIndexPathCtx).Get("/r/{idx0}/{idx}", app.getTicketPoolInfoRange)
 		})
 		r.Route("/diff", func(rd chi.Router) {
@@ -134,10 +146,14 @@ func NewAPIRouter(app *appContext, userRealIP bool) apiMux {
 					ri.With(m.TransactionIOIndexCtx).Get("/{txinoutindex}", app.getTransactionInput)
 				})
 				rd.Get("/vinfo", app.getTxVoteInfo)
+				rd.Get("/swap", app.getTxAtomicSwaps)
 			})
 		})
 		r.With(m.TransactionHashCtx).Get("/hex/{txid}", app.getTransactionHex)
+		r.With(m.RawTransactionCtx).Post("/broadcast", app.broadcastTransaction)
+		r.With(m.TrackingIDPathCtx).Get("/broadcast/{trackingid}", app.getBroadcastStatus)
 		r.With(m.TransactionHashCtx).Get("/decoded/{txid}", app.getDecodedTx)
+		r.With(m.TxClassPathCtx).Get("/class/{class}", app.getTxnsByClass)
 	})
 
 	mux.Route("/address", func(r chi.Router) {
@@ -153,6 +169,10 @@ func NewAPIRouter(app *appContext, userRealIP bool) apiMux {
 		})
 	})
 
+	mux.Route("/fees", func(r chi.Router) {
+		r.Get("/estimate", app.getFeeEstimate)
+	})
+
 	mux.Route("/mempool", func(r chi.Router) {
 		r.Get("/", http.NotFound /*app.getMempoolOverview*/)
 		// ticket purchases
@@ -163,7 +183,26 @@ func NewAPIRouter(app *appContext, userRealIP bool) apiMux {
 			rd.Get("/details", app.getSSTxDetails)
 			rd.With(m.NPathCtx).Get("/details/{N}", app.getSSTxDetails)
 		})
+		r.Get("/history", app.getMempoolHistory)
+	})
+
+	mux.Get("/treasury", app.getTreasury)
+
+	mux.Route("/richlist", func(r chi.Router) {
+		r.Get("/", app.getRichList)
+		r.Get("/distribution", app.getAddressDistribution)
+		r.Get("/activity", app.getAddressActivity)
 	})
+	mux.Get("/cdd", app.getCoinDaysDestroyed)
+
+	mux.Route("/utxoset", func(r chi.Router) {
+		r.Get("/stats", app.getUtxoSetStats)
+		r.Get("/history", app.getUtxoSetHistory)
+	})
+
+	mux.Get("/swaps", app.getAtomicSwaps)
+
+	mux.With(m.ChartPathCtx).Get("/chart/{chart}", app.getChartData)
 
 	mux.NotFound(func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, r.URL.RequestURI()+" ain't no country I've ever heard of! (404)", http.StatusNotFound)
@@ -4,21 +4,28 @@
 package api
 
 import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/decred/dcrd/chaincfg"
 	"github.com/decred/dcrd/dcrjson"
-	"github.com/decred/dcrd/rpcclient"
 	apitypes "github.com/decred/dcrdata/api/types"
 	"github.com/decred/dcrdata/db/dbtypes"
 	"github.com/decred/dcrdata/explorer"
 	m "github.com/decred/dcrdata/middleware"
 	notify "github.com/decred/dcrdata/notification"
+	"github.com/decred/dcrdata/rpcutils"
+	"github.com/decred/dcrdata/txhelpers"
 )
 
 // APIDataSource implements an interface for collecting data for the api
@@ -27,10 +34,13 @@ type APIDataSource interface {
 	GetBestBlockHash() (string, error)
 	GetBlockHash(idx int64) (string, error)
 	GetBlockHeight(hash string) (int64, error)
+	GetChainParams() *chaincfg.Params
 	//Get(idx int) *blockdata.BlockData
 	GetHeader(idx int) *dcrjson.GetBlockHeaderVerboseResult
 	GetBlockVerbose(idx int, verboseTx bool) *dcrjson.GetBlockVerboseResult
 	GetBlockVerboseByHash(hash string, verboseTx bool) *dcrjson.GetBlockVerboseResult
+	GetRawBlock(idx int64) ([]byte, error)
+	GetRawBlockHeader(idx int64) ([]byte, error)
 	GetRawTransaction(txid string) *apitypes.Tx
 	GetTransactionHex(txid string) string
 	GetTrimmedTransaction(txid string) *apitypes.TrimmedTx
@@ -48,6 +58,7 @@ type APIDataSource interface {
 	GetStakeInfoExtended(idx int) *apitypes.StakeInfoExtended
 	//needs db update: GetStakeInfoExtendedByHash(hash string) *apitypes.StakeInfoExtended
 	GetStakeDiffEstimates() *apitypes.StakeDiff
+	GetFeeEstimate(blocks int) *apitypes.FeeEstimate
 	//GetBestBlock() *blockdata.BlockData
 	GetSummary(idx int) *apitypes.BlockDataBasic
 	GetSummaryByHash(hash string) *apitypes.BlockDataBasic
@@ -60,11 +71,16 @@ type APIDataSource interface {
 	GetPool(idx int64) ([]string, error)
 	GetPoolByHash(hash string) ([]string, error)
 	GetPoolValAndSizeRange(idx0, idx1 int) ([]float64, []float64)
+	GetTicketPoolHistory(txid string) *apitypes.TicketPoolHistory
+	GetPoolPage(idx int64, offset, count int) *apitypes.TicketPoolPage
+	GetPoolAges(idx int64, binSize int64) *apitypes.TicketPoolAges
 	GetSDiff(idx int) float64
 	GetSDiffRange(idx0, idx1 int) []float64
 	GetMempoolSSTxSummary() *apitypes.MempoolTicketFeeInfo
 	GetMempoolSSTxFeeRates(N int) *apitypes.MempoolTicketFees
 	GetMempoolSSTxDetails(N int) *apitypes.MempoolTicketDetails
+	GetMempoolHistory(from, to int64) *apitypes.MempoolHistory
+	GetChartData(chart string, from, to int64) *apitypes.ChartData
 	GetAddressTransactions(addr string, count int) *apitypes.Address
 	GetAddressTransactionsRaw(addr string, count int) []*apitypes.AddressTxRaw
 	SendRawTransaction(txhex string) (string, error)
@@ -78,18 +94,84 @@ type explorerDataSource interface {
 	FillAddressTransactions(addrInfo *explorer.AddressInfo) error
 }
 
+// voteSummarySource provides the per-block vote summaries, which are only
+// stored by the PostgreSQL database.
+type voteSummarySource interface {
+	VoteParticipation(interval string, from, to int64) ([]apitypes.VoteParticipation, error)
+	InvalidatingBlocks(N, offset int64) ([]*dbtypes.VoteSummary, error)
+	IncompleteVoteBlocks(N, offset int64) ([]*dbtypes.VoteSummary, error)
+}
+
+// treasurySource provides the development subsidy ledger, which is only
+// stored by the PostgreSQL database.
+type treasurySource interface {
+	TreasurySummary(N int) (*dbtypes.TreasurySummary, error)
+}
+
+// addressStatsSource provides the address balance summaries and coin-days
+// destroyed, which are only stored by the PostgreSQL database.
+type addressStatsSource interface {
+	RichList(N int) (*apitypes.RichList, error)
+	AddressDistribution() (*apitypes.AddressDistribution, error)
+	AddressActivity() (*apitypes.AddressActivity, error)
+	CoinDaysDestroyed(from, to int64) ([]apitypes.BlockCoinDays, error)
+}
+
+// txClassSource provides the stored transaction classes, which are only
+// stored by the PostgreSQL database.
+type txClassSource interface {
+	TxClass(txid string) (txhelpers.TxClass, error)
+	TxnsByClass(class txhelpers.TxClass, N, offset int64) ([]apitypes.ClassifiedTx, error)
+}
+
+// utxoSetSource provides the UTXO set statistics and snapshots, which are only
+// stored by the PostgreSQL database.
+type utxoSetSource interface {
+	UtxoSetStats() (*apitypes.UtxoSetStats, error)
+	UtxoSetHistory(from, to int64) (*apitypes.UtxoSetHistory, error)
+}
+
+// atomicSwapSource provides the resolved atomic swaps, which are only stored by
+// the PostgreSQL database.
+type atomicSwapSource interface {
+	AtomicSwaps(N, offset int64) ([]apitypes.AtomicSwap, error)
+	TxAtomicSwaps(txid string) ([]apitypes.AtomicSwap, error)
+}
+
+// chartSource provides the charts of the transaction counts, fees and coin
+// supply, which are only stored by the PostgreSQL database.
+type chartSource interface {
+	ChartData(chart string, from, to int64) (*apitypes.ChartData, error)
+}
+
+// spendingTxSource finds the transactions spending outputs. The PostgreSQL DB
+// finds any spending transaction, while the SQLite DB requires the node's
+// address index for outputs other than tickets.
+type spendingTxSource interface {
+	SpendingTransaction(fundingTx string, vout uint32) (string, uint32, int8, error)
+}
+
 // dcrdata application context used by all route handlers
 type appContext struct {
-	nodeClient     *rpcclient.Client
+	nodeClient     rpcutils.NodeClient
 	BlockData      APIDataSource
 	ExplorerSource explorerDataSource
+	VoteSource     voteSummarySource
+	TreasurySource treasurySource
+	AddressStats   addressStatsSource
+	UtxoSetSource  utxoSetSource
+	TxClassSource  txClassSource
+	SpendingSource spendingTxSource
+	SwapSource     atomicSwapSource
+	ChartSource    chartSource
 	Status         apitypes.Status
 	statusMtx      sync.RWMutex
 	JSONIndent     string
+	broadcasts     *broadcastTracker
 }
 
 // Constructor for appContext
-func NewContext(client *rpcclient.Client, blockData APIDataSource, JSONIndent string) *appContext {
+func NewContext(client rpcutils.NodeClient, blockData APIDataSource, JSONIndent string) *appContext {
 	conns, _ := client.GetConnectionCount()
 	nodeHeight, _ := client.GetBlockCount()
 
@@ -103,6 +185,7 @@ func NewContext(client *rpcclient.Client, blockData APIDataSource, JSONIndent st
 			DcrdataVersion:  ver.String(),
 		},
 		JSONIndent: JSONIndent,
+		broadcasts: newBroadcastTracker(),
 	}
 }
 
@@ -205,6 +288,65 @@ func (c *appContext) getIndentQuery(r *http.Request) (indent string) {
 	return
 }
 
+// Encodings of raw block data, which may be requested with the "encoding" URL
+// query parameter or the Accept header.
+const (
+	rawEncodingBinary = "binary"
+	rawEncodingHex    = "hex"
+	rawEncodingBase64 = "base64"
+)
+
+// getRawEncodingQuery gets the encoding of raw block data requested by the
+// "encoding" URL query parameter, or else by the media types of the Accept
+// header: application/octet-stream for binary, text/plain for hex, and
+// application/base64 for base64. The default is binary.
+func getRawEncodingQuery(r *http.Request) (string, error) {
+	switch encoding := r.URL.Query().Get("encoding"); encoding {
+	case rawEncodingBinary, rawEncodingHex, rawEncodingBase64:
+		return encoding, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown encoding %q", encoding)
+	}
+
+	for _, mediaRange := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(mediaRange, ";", 2)[0])
+		switch mediaType {
+		case "application/octet-stream":
+			return rawEncodingBinary, nil
+		case "text/plain":
+			return rawEncodingHex, nil
+		case "application/base64":
+			return rawEncodingBase64, nil
+		}
+	}
+	return rawEncodingBinary, nil
+}
+
+// setRawContentType sets the Content-Type header for raw data in the given
+// encoding.
+func setRawContentType(w http.ResponseWriter, encoding string) {
+	if encoding == rawEncodingBinary {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+}
+
+// writeRaw writes the data in the given encoding.
+func writeRaw(w io.Writer, data []byte, encoding string) error {
+	var err error
+	switch encoding {
+	case rawEncodingHex:
+		_, err = io.WriteString(w, hex.EncodeToString(data))
+	case rawEncodingBase64:
+		_, err = io.WriteString(w, base64.StdEncoding.EncodeToString(data))
+	default:
+		_, err = w.Write(data)
+	}
+	return err
+}
+
 func getVoteVersionQuery(r *http.Request) (int32, string, error) {
 	verLatest := int64(m.GetLatestVoteVersionCtx(r))
 	voteVersion := r.URL.Query().Get("version")
@@ -223,6 +365,65 @@ func getVoteVersionQuery(r *http.Request) (int32, string, error) {
 	return int32(ver), voteVersion, nil
 }
 
+// getFeeEstimateBlocksQuery gets the target number of blocks for a fee estimate
+// from the "blocks" URL query parameter, 1 by default.
+func getFeeEstimateBlocksQuery(r *http.Request) (int, error) {
+	blocks := r.URL.Query().Get("blocks")
+	if blocks == "" {
+		return 1, nil
+	}
+	return strconv.Atoi(blocks)
+}
+
+// getTimeRangeQuery parses the "from" and "to" URL queries, times in UNIX
+// seconds. The default range is the span seconds ending now.
+func getTimeRangeQuery(r *http.Request, span int64) (int64, int64, error) {
+	to := time.Now().Unix()
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		var err error
+		if to, err = strconv.ParseInt(toStr, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid to time")
+		}
+	}
+	from := to - span
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		var err error
+		if from, err = strconv.ParseInt(fromStr, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid from time")
+		}
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("from must not be after to")
+	}
+	return from, to, nil
+}
+
+// maxPageCount is the largest count of a page requested with the "count" URL
+// query.
+const maxPageCount = 1000
+
+// getCountOffsetQuery parses the "count" and "offset" URL queries, which
+// default to 20 and 0. The count may be at most maxPageCount.
+func getCountOffsetQuery(r *http.Request) (int64, int64, error) {
+	count, offset := int64(20), int64(0)
+	if countStr := r.URL.Query().Get("count"); countStr != "" {
+		var err error
+		if count, err = strconv.ParseInt(countStr, 10, 64); err != nil || count < 1 {
+			return 0, 0, fmt.Errorf("invalid count")
+		}
+		if count > maxPageCount {
+			return 0, 0, fmt.Errorf("count must be at most %d", maxPageCount)
+		}
+	}
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		var err error
+		if offset, err = strconv.ParseInt(offsetStr, 10, 64); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset")
+		}
+	}
+	return count, offset, nil
+}
+
 func (c *appContext) status(w http.ResponseWriter, r *http.Request) {
 	c.statusMtx.RLock()
 	defer c.statusMtx.RUnlock()
@@ -352,6 +553,87 @@ func (c *appContext) getBlockVerbose(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, blockVerbose, c.getIndentQuery(r))
 }
 
+func (c *appContext) getBlockRaw(w http.ResponseWriter, r *http.Request) {
+	c.writeRawBlockData(w, r, c.BlockData.GetRawBlock)
+}
+
+func (c *appContext) getBlockHeaderRaw(w http.ResponseWriter, r *http.Request) {
+	c.writeRawBlockData(w, r, c.BlockData.GetRawBlockHeader)
+}
+
+// writeRawBlockData writes the serialized block data returned by fetch for the
+// block in the request context, in the encoding requested.
+func (c *appContext) writeRawBlockData(w http.ResponseWriter, r *http.Request,
+	fetch func(idx int64) ([]byte, error)) {
+	encoding, err := getRawEncodingQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), 422)
+		return
+	}
+
+	idx := c.getBlockHeightCtx(r)
+	if idx < 0 {
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	data, err := fetch(idx)
+	if err != nil {
+		apiLog.Errorf("Unable to get raw data for block %d: %v", idx, err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	setRawContentType(w, encoding)
+	if err = writeRaw(w, data, encoding); err != nil {
+		apiLog.Debugf("Unable to write raw data for block %d: %v", idx, err)
+	}
+}
+
+// getBlockRangeRaw streams the serialized blocks on [idx0, idx]. In binary,
+// the blocks are concatenated, and in hex or base64 there is one block per
+// line.
+func (c *appContext) getBlockRangeRaw(w http.ResponseWriter, r *http.Request) {
+	encoding, err := getRawEncodingQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), 422)
+		return
+	}
+
+	idx0 := m.GetBlockIndex0Ctx(r)
+	if idx0 < 0 {
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	idx := m.GetBlockIndexCtx(r)
+	if idx < 0 || idx < idx0 || idx > c.BlockData.GetHeight() {
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	setRawContentType(w, encoding)
+	flusher, _ := w.(http.Flusher)
+	for i := idx0; i <= idx; i++ {
+		// Once streaming has begun, an error can only end the response early.
+		data, err := c.BlockData.GetRawBlock(int64(i))
+		if err != nil {
+			apiLog.Errorf("Unable to get raw block %d: %v", i, err)
+			return
+		}
+		if err = writeRaw(w, data, encoding); err != nil {
+			apiLog.Debugf("Unable to write raw block %d: %v", i, err)
+			return
+		}
+		if encoding != rawEncodingBinary {
+			io.WriteString(w, "\n")
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
 func (c *appContext) getVoteInfo(w http.ResponseWriter, r *http.Request) {
 	ver, verStr, err := getVoteVersionQuery(r)
 	if err != nil || ver < 0 {
@@ -368,6 +650,381 @@ func (c *appContext) getVoteInfo(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, voteVersionInfo, c.getIndentQuery(r))
 }
 
+// voteParticipationIntervals are the time intervals by which vote
+// participation may be aggregated.
+var voteParticipationIntervals = map[string]bool{
+	"day":   true,
+	"week":  true,
+	"month": true,
+}
+
+// voteSourceAvailable checks that the vote summaries may be queried, and
+// responds with an error if they may not.
+func (c *appContext) voteSourceAvailable(w http.ResponseWriter) bool {
+	if c.VoteSource == nil {
+		http.Error(w, "vote summaries are not available in lite mode",
+			http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+func (c *appContext) getVoteParticipation(w http.ResponseWriter, r *http.Request) {
+	if !c.voteSourceAvailable(w) {
+		return
+	}
+
+	interval := r.URL.Query().Get("interval")
+	if interval == "" {
+		interval = "day"
+	}
+	if !voteParticipationIntervals[interval] {
+		http.Error(w, "interval must be day, week, or month", 422)
+		return
+	}
+	// The default range is the last 30 days.
+	from, to, err := getTimeRangeQuery(r, 30*86400)
+	if err != nil {
+		http.Error(w, err.Error(), 422)
+		return
+	}
+
+	intervals, err := c.VoteSource.VoteParticipation(interval, from, to)
+	if err != nil {
+		apiLog.Errorf("Unable to get vote participation: %v", err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	writeJSON(w, &apitypes.VoteParticipationHistory{
+		Interval:  interval,
+		From:      from,
+		To:        to,
+		Intervals: intervals,
+	}, c.getIndentQuery(r))
+}
+
+func (c *appContext) getInvalidatingBlocks(w http.ResponseWriter, r *http.Request) {
+	if !c.voteSourceAvailable(w) {
+		return
+	}
+	c.writeVoteSummaries(w, r, c.VoteSource.InvalidatingBlocks)
+}
+
+func (c *appContext) getIncompleteVoteBlocks(w http.ResponseWriter, r *http.Request) {
+	if !c.voteSourceAvailable(w) {
+		return
+	}
+	c.writeVoteSummaries(w, r, c.VoteSource.IncompleteVoteBlocks)
+}
+
+// writeVoteSummaries writes the page of block vote summaries given by the
+// count and offset URL queries, as retrieved by fetch.
+func (c *appContext) writeVoteSummaries(w http.ResponseWriter, r *http.Request,
+	fetch func(N, offset int64) ([]*dbtypes.VoteSummary, error)) {
+	count, offset, err := getCountOffsetQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), 422)
+		return
+	}
+
+	summaries, err := fetch(count, offset)
+	if err != nil {
+		apiLog.Errorf("Unable to get block vote summaries: %v", err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+	if summaries == nil {
+		summaries = []*dbtypes.VoteSummary{}
+	}
+
+	writeJSON(w, summaries, c.getIndentQuery(r))
+}
+
+func (c *appContext) getTreasury(w http.ResponseWriter, r *http.Request) {
+	if c.TreasurySource == nil {
+		http.Error(w, "the treasury is not available in lite mode",
+			http.StatusServiceUnavailable)
+		return
+	}
+
+	// The number of largest spends to include, 10 by default.
+	N := 10
+	if spendsStr := r.URL.Query().Get("spends"); spendsStr != "" {
+		var err error
+		if N, err = strconv.Atoi(spendsStr); err != nil || N < 0 {
+			http.Error(w, "invalid spends", 422)
+			return
+		}
+	}
+
+	summary, err := c.TreasurySource.TreasurySummary(N)
+	if err != nil {
+		apiLog.Errorf("Unable to get treasury summary: %v", err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	writeJSON(w, summary, c.getIndentQuery(r))
+}
+
+// maxRichListLimit is the largest number of addresses in a rich list.
+const maxRichListLimit = 1000
+
+// addressStatsAvailable checks that the address statistics may be queried, and
+// responds with an error if they may not.
+func (c *appContext) addressStatsAvailable(w http.ResponseWriter) bool {
+	if c.AddressStats == nil {
+		http.Error(w, "address statistics are not available in lite mode",
+			http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+func (c *appContext) getRichList(w http.ResponseWriter, r *http.Request) {
+	if !c.addressStatsAvailable(w) {
+		return
+	}
+
+	limit := 100
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxRichListLimit {
+			http.Error(w, fmt.Sprintf("limit must be from 1 to %d",
+				maxRichListLimit), 422)
+			return
+		}
+	}
+
+	richList, err := c.AddressStats.RichList(limit)
+	if err != nil {
+		apiLog.Errorf("Unable to get rich list: %v", err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+	if richList.Addresses == nil {
+		richList.Addresses = []apitypes.RichListEntry{}
+	}
+
+	writeJSON(w, richList, c.getIndentQuery(r))
+}
+
+func (c *appContext) getAddressDistribution(w http.ResponseWriter, r *http.Request) {
+	if !c.addressStatsAvailable(w) {
+		return
+	}
+
+	distribution, err := c.AddressStats.AddressDistribution()
+	if err != nil {
+		apiLog.Errorf("Unable to get address distribution: %v", err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	writeJSON(w, distribution, c.getIndentQuery(r))
+}
+
+func (c *appContext) getAddressActivity(w http.ResponseWriter, r *http.Request) {
+	if !c.addressStatsAvailable(w) {
+		return
+	}
+
+	activity, err := c.AddressStats.AddressActivity()
+	if err != nil {
+		apiLog.Errorf("Unable to get address activity: %v", err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	writeJSON(w, activity, c.getIndentQuery(r))
+}
+
+func (c *appContext) getCoinDaysDestroyed(w http.ResponseWriter, r *http.Request) {
+	if !c.addressStatsAvailable(w) {
+		return
+	}
+
+	// The default range is the last day.
+	from, to, err := getTimeRangeQuery(r, 86400)
+	if err != nil {
+		http.Error(w, err.Error(), 422)
+		return
+	}
+
+	blocks, err := c.AddressStats.CoinDaysDestroyed(from, to)
+	if err != nil {
+		apiLog.Errorf("Unable to get coin days destroyed: %v", err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+	if blocks == nil {
+		blocks = []apitypes.BlockCoinDays{}
+	}
+
+	writeJSON(w, &apitypes.CoinDaysDestroyedHistory{
+		From:   from,
+		To:     to,
+		Blocks: blocks,
+	}, c.getIndentQuery(r))
+}
+
+// utxoSetAvailable checks that the UTXO set statistics may be queried, and
+// responds with an error if they may not.
+func (c *appContext) utxoSetAvailable(w http.ResponseWriter) bool {
+	if c.UtxoSetSource == nil {
+		http.Error(w, "UTXO set statistics are not available in lite mode",
+			http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+func (c *appContext) getAtomicSwaps(w http.ResponseWriter, r *http.Request) {
+	if !c.atomicSwapsAvailable(w) {
+		return
+	}
+
+	N, offset, err := getCountOffsetQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), 422)
+		return
+	}
+
+	swaps, err := c.SwapSource.AtomicSwaps(N, offset)
+	if err != nil {
+		apiLog.Errorf("Unable to get atomic swaps: %v", err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+	if swaps == nil {
+		swaps = []apitypes.AtomicSwap{}
+	}
+
+	writeJSON(w, swaps, c.getIndentQuery(r))
+}
+
+func (c *appContext) getTxAtomicSwaps(w http.ResponseWriter, r *http.Request) {
+	if !c.atomicSwapsAvailable(w) {
+		return
+	}
+
+	txid := m.GetTxIDCtx(r)
+	if txid == "" {
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	swaps, err := c.SwapSource.TxAtomicSwaps(txid)
+	if err != nil {
+		apiLog.Errorf("Unable to get atomic swaps of transaction %s: %v", txid, err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+	if swaps == nil {
+		swaps = []apitypes.AtomicSwap{}
+	}
+
+	writeJSON(w, swaps, c.getIndentQuery(r))
+}
+
+func (c *appContext) atomicSwapsAvailable(w http.ResponseWriter) bool {
+	if c.SwapSource == nil {
+		http.Error(w, "atomic swaps are not available in lite mode",
+			http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+func (c *appContext) getChartData(w http.ResponseWriter, r *http.Request) {
+	chart := m.GetChartCtx(r)
+	isSummaryChart := chartIn(chart, apitypes.SummaryCharts)
+	if !isSummaryChart && !chartIn(chart, apitypes.ChainCharts) {
+		http.Error(w, fmt.Sprintf("unknown chart %q", chart), 422)
+		return
+	}
+
+	// The default range is the last 30 days.
+	from, to, err := getTimeRangeQuery(r, 30*86400)
+	if err != nil {
+		http.Error(w, err.Error(), 422)
+		return
+	}
+
+	var data *apitypes.ChartData
+	if isSummaryChart {
+		data = c.BlockData.GetChartData(chart, from, to)
+		if data == nil {
+			apiLog.Errorf("Unable to get %s chart", chart)
+			http.Error(w, http.StatusText(422), 422)
+			return
+		}
+	} else {
+		if c.ChartSource == nil {
+			http.Error(w, fmt.Sprintf("the %s chart is not available in lite mode", chart),
+				http.StatusServiceUnavailable)
+			return
+		}
+		data, err = c.ChartSource.ChartData(chart, from, to)
+		if err != nil {
+			apiLog.Errorf("Unable to get %s chart: %v", chart, err)
+			http.Error(w, http.StatusText(422), 422)
+			return
+		}
+	}
+
+	writeJSON(w, data, c.getIndentQuery(r))
+}
+
+// chartIn checks if the chart is one of the charts.
+func chartIn(chart string, charts []string) bool {
+	for _, c := range charts {
+		if c == chart {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *appContext) getUtxoSetStats(w http.ResponseWriter, r *http.Request) {
+	if !c.utxoSetAvailable(w) {
+		return
+	}
+
+	stats, err := c.UtxoSetSource.UtxoSetStats()
+	if err != nil {
+		apiLog.Errorf("Unable to get UTXO set stats: %v", err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	writeJSON(w, stats, c.getIndentQuery(r))
+}
+
+func (c *appContext) getUtxoSetHistory(w http.ResponseWriter, r *http.Request) {
+	if !c.utxoSetAvailable(w) {
+		return
+	}
+
+	// The default range is the last 30 days.
+	from, to, err := getTimeRangeQuery(r, 30*86400)
+	if err != nil {
+		http.Error(w, err.Error(), 422)
+		return
+	}
+
+	history, err := c.UtxoSetSource.UtxoSetHistory(from, to)
+	if err != nil {
+		apiLog.Errorf("Unable to get UTXO set history: %v", err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	writeJSON(w, history, c.getIndentQuery(r))
+}
+
 func (c *appContext) getTransaction(w http.ResponseWriter, r *http.Request) {
 	txid := m.GetTxIDCtx(r)
 	if txid == "" {
@@ -382,9 +1039,89 @@ func (c *appContext) getTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The stored class also identifies split ticket funding transactions.
+	if c.TxClassSource != nil && tx.Confirmations > 0 {
+		class, err := c.TxClassSource.TxClass(txid)
+		if err == nil {
+			tx.Class = class.Labels()
+		} else if err != sql.ErrNoRows {
+			apiLog.Errorf("Unable to get class of transaction %s: %v", txid, err)
+		}
+	}
+
+	c.setSpentRedeemScripts(tx)
+
 	writeJSON(w, tx, c.getIndentQuery(r))
 }
 
+// setSpentRedeemScripts sets the redeem scripts of the spent P2SH outputs of a
+// transaction from the signature scripts of the inputs spending them.
+func (c *appContext) setSpentRedeemScripts(tx *apitypes.Tx) {
+	if c.SpendingSource == nil || tx.Confirmations == 0 {
+		return
+	}
+	params := c.BlockData.GetChainParams()
+	for i := range tx.Vout {
+		vout := &tx.Vout[i]
+		spk := &vout.ScriptPubKeyDecoded
+		if len(spk.Addresses) != 1 || !txhelpers.IsScriptHashAddress(spk.Addresses[0]) {
+			continue
+		}
+		spendingTx, vin, _, err := c.SpendingSource.SpendingTransaction(tx.TxID, vout.N)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				apiLog.Errorf("Unable to get spending transaction of %s:%d: %v",
+					tx.TxID, vout.N, err)
+			}
+			continue
+		}
+		msgTx, err := txhelpers.MsgTxFromHex(c.BlockData.GetTransactionHex(spendingTx))
+		if err != nil {
+			apiLog.Errorf("Unable to decode spending transaction %s: %v",
+				spendingTx, err)
+			continue
+		}
+		vout.RedeemScript = txhelpers.SpentRedeemScript(msgTx, vin,
+			spk.Addresses[0], params)
+	}
+}
+
+func (c *appContext) getTxnsByClass(w http.ResponseWriter, r *http.Request) {
+	if c.TxClassSource == nil {
+		http.Error(w, "transaction classes are not available in lite mode",
+			http.StatusServiceUnavailable)
+		return
+	}
+
+	label := m.GetTxClassCtx(r)
+	class, ok := txhelpers.TxClassFromLabel(label)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown transaction class %q", label), 422)
+		return
+	}
+
+	N, offset, err := getCountOffsetQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), 422)
+		return
+	}
+
+	txns, err := c.TxClassSource.TxnsByClass(class, N, offset)
+	if err != nil {
+		apiLog.Errorf("Unable to get %s transactions: %v", label, err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+	if txns == nil {
+		txns = []apitypes.ClassifiedTx{}
+	}
+
+	writeJSON(w, &apitypes.ClassifiedTxns{
+		Class: label,
+		Txns:  txns,
+	}, c.getIndentQuery(r))
+}
+
 func (c *appContext) getTransactionHex(w http.ResponseWriter, r *http.Request) {
 	txid := m.GetTxIDCtx(r)
 	if txid == "" {
@@ -602,6 +1339,28 @@ func (c *appContext) getStakeDiffEstimates(w http.ResponseWriter, r *http.Reques
 	writeJSON(w, stakeDiff.Estimates, c.getIndentQuery(r))
 }
 
+func (c *appContext) getFeeEstimate(w http.ResponseWriter, r *http.Request) {
+	blocks, err := getFeeEstimateBlocksQuery(r)
+	if err != nil || blocks < 1 {
+		http.Error(w, "Invalid number of blocks", 422)
+		return
+	}
+	if blocks > apitypes.FeeEstimateMaxBlocks {
+		http.Error(w, fmt.Sprintf("Number of blocks must be at most %d",
+			apitypes.FeeEstimateMaxBlocks), 422)
+		return
+	}
+
+	feeEstimate := c.BlockData.GetFeeEstimate(blocks)
+	if feeEstimate == nil {
+		apiLog.Errorf("Unable to estimate fee rates for %d blocks", blocks)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	writeJSON(w, feeEstimate, c.getIndentQuery(r))
+}
+
 func (c *appContext) getSSTxSummary(w http.ResponseWriter, r *http.Request) {
 	sstxSummary := c.BlockData.GetMempoolSSTxSummary()
 	if sstxSummary == nil {
@@ -637,6 +1396,24 @@ func (c *appContext) getSSTxDetails(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, sstxDetails, c.getIndentQuery(r))
 }
 
+func (c *appContext) getMempoolHistory(w http.ResponseWriter, r *http.Request) {
+	// The default range is the last day.
+	from, to, err := getTimeRangeQuery(r, 86400)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	history := c.BlockData.GetMempoolHistory(from, to)
+	if history == nil {
+		apiLog.Errorf("Unable to get mempool history")
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	writeJSON(w, history, c.getIndentQuery(r))
+}
+
 func (c *appContext) getBlockSize(w http.ResponseWriter, r *http.Request) {
 	idx := c.getBlockHeightCtx(r)
 	if idx < 0 {
@@ -852,6 +1629,91 @@ func (c *appContext) getTicketPool(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, tp, c.getIndentQuery(r))
 }
 
+func (c *appContext) getTicketPoolHistory(w http.ResponseWriter, r *http.Request) {
+	txid := m.GetTxIDCtx(r)
+	if txid == "" {
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	history := c.BlockData.GetTicketPoolHistory(txid)
+	if history == nil {
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	writeJSON(w, history, c.getIndentQuery(r))
+}
+
+// Limits on the tickets per page of the live ticket pool (?count=N).
+const (
+	defaultPoolPageCount = 1000
+	maxPoolPageCount     = 10000
+)
+
+func (c *appContext) getTicketPoolPage(w http.ResponseWriter, r *http.Request) {
+	idx := c.getBlockHeightCtx(r)
+	if idx < 0 {
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	offset, count := 0, defaultPoolPageCount
+	var err error
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if offset, err = strconv.Atoi(offsetStr); err != nil || offset < 0 {
+			http.Error(w, "invalid offset", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+	if countStr := r.URL.Query().Get("count"); countStr != "" {
+		if count, err = strconv.Atoi(countStr); err != nil || count < 0 {
+			http.Error(w, "invalid count", http.StatusUnprocessableEntity)
+			return
+		}
+		if count > maxPoolPageCount {
+			count = maxPoolPageCount
+		}
+	}
+
+	page := c.BlockData.GetPoolPage(idx, offset, count)
+	if page == nil {
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	writeJSON(w, page, c.getIndentQuery(r))
+}
+
+// defaultPoolAgeBinSize is the default width, in blocks, of the bins of the
+// live ticket pool age distribution (?binsize=N).
+const defaultPoolAgeBinSize = 1024
+
+func (c *appContext) getTicketPoolAges(w http.ResponseWriter, r *http.Request) {
+	idx := c.getBlockHeightCtx(r)
+	if idx < 0 {
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	binSize := int64(defaultPoolAgeBinSize)
+	if binStr := r.URL.Query().Get("binsize"); binStr != "" {
+		var err error
+		if binSize, err = strconv.ParseInt(binStr, 10, 64); err != nil || binSize <= 0 {
+			http.Error(w, "invalid binsize", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	ages := c.BlockData.GetPoolAges(idx, binSize)
+	if ages == nil {
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	writeJSON(w, ages, c.getIndentQuery(r))
+}
+
 func (c *appContext) getTicketPoolInfo(w http.ResponseWriter, r *http.Request) {
 	idx := c.getBlockHeightCtx(r)
 	if idx < 0 {
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	m "github.com/decred/dcrdata/middleware"
+	"github.com/go-chi/chi"
+)
+
+func TestGetRawEncodingQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		accept   string
+		encoding string
+		err      bool
+	}{
+		{"", "", rawEncodingBinary, false},
+		{"", "*/*", rawEncodingBinary, false},
+		{"", "application/octet-stream", rawEncodingBinary, false},
+		{"", "text/plain", rawEncodingHex, false},
+		{"", "application/base64", rawEncodingBase64, false},
+		// The first supported media type is used, ignoring parameters.
+		{"", "text/html, text/plain;q=0.9, application/base64", rawEncodingHex, false},
+		{"", "application/base64; q=0.5", rawEncodingBase64, false},
+		// The query parameter takes precedence over the Accept header.
+		{"hex", "application/octet-stream", rawEncodingHex, false},
+		{"base64", "text/plain", rawEncodingBase64, false},
+		{"binary", "text/plain", rawEncodingBinary, false},
+		{"base58", "", "", true},
+		{"HEX", "text/plain", "", true},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/raw?encoding="+test.query, nil)
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		encoding, err := getRawEncodingQuery(r)
+		if (err != nil) != test.err || encoding != test.encoding {
+			t.Errorf("getRawEncodingQuery(%q, %q) = %q, %v, expected %q "+
+				"(error: %v)", test.query, test.accept, encoding, err,
+				test.encoding, test.err)
+		}
+	}
+}
+
+func TestWriteRaw(t *testing.T) {
+	data := []byte{0x00, 0x01, 0xfe, 0xff}
+	tests := []struct {
+		encoding string
+		output   string
+	}{
+		{rawEncodingBinary, "\x00\x01\xfe\xff"},
+		{rawEncodingHex, "0001feff"},
+		{rawEncodingBase64, "AAH+/w=="},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := writeRaw(&buf, data, test.encoding); err != nil {
+			t.Fatalf("writeRaw failed: %v", err)
+		}
+		if buf.String() != test.output {
+			t.Errorf("writeRaw(%s) = %q, expected %q", test.encoding,
+				buf.String(), test.output)
+		}
+	}
+}
+
+// rawBlockSource is an APIDataSource serving only the height and raw blocks.
+type rawBlockSource struct {
+	APIDataSource
+	blocks [][]byte
+}
+
+func (s *rawBlockSource) GetHeight() int {
+	return len(s.blocks) - 1
+}
+
+func (s *rawBlockSource) GetRawBlock(idx int64) ([]byte, error) {
+	if idx < 0 || idx >= int64(len(s.blocks)) {
+		return nil, errors.New("no block")
+	}
+	return s.blocks[idx], nil
+}
+
+func TestGetBlockRangeRaw(t *testing.T) {
+	c := &appContext{BlockData: &rawBlockSource{
+		blocks: [][]byte{{0x00}, {0x01, 0x02}, {0xff}},
+	}}
+	router := chi.NewRouter()
+	router.With(m.BlockIndex0PathCtx, m.BlockIndexPathCtx).
+		Get("/range/{idx0}/{idx}/raw", c.getBlockRangeRaw)
+
+	tests := []struct {
+		path        string
+		code        int
+		contentType string
+		body        string
+	}{
+		{"/range/0/2/raw", 200, "application/octet-stream", "\x00\x01\x02\xff"},
+		{"/range/1/1/raw", 200, "application/octet-stream", "\x01\x02"},
+		// Encoded blocks are on separate lines.
+		{"/range/0/2/raw?encoding=hex", 200, "text/plain; charset=utf-8",
+			"00\n0102\nff\n"},
+		{"/range/1/2/raw?encoding=base64", 200, "text/plain; charset=utf-8",
+			"AQI=\n/w==\n"},
+		{"/range/0/2/raw?encoding=base58", 422, "", ""},
+		{"/range/2/1/raw", 422, "", ""},
+		{"/range/0/3/raw", 422, "", ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.code {
+			t.Errorf("%s: status %d, expected %d", test.path, w.Code, test.code)
+			continue
+		}
+		if test.code != http.StatusOK {
+			continue
+		}
+		if ct := w.Header().Get("Content-Type"); ct != test.contentType {
+			t.Errorf("%s: Content-Type %q, expected %q", test.path, ct,
+				test.contentType)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("%s: body %q, expected %q", test.path, w.Body.String(),
+				test.body)
+		}
+	}
+}
+
+func TestGetCountOffsetQuery(t *testing.T) {
+	tests := []struct {
+		query         string
+		count, offset int64
+		err           bool
+	}{
+		{"", 20, 0, false},
+		{"count=5&offset=10", 5, 10, false},
+		{"count=1000", 1000, 0, false},
+		{"count=1001", 0, 0, true},
+		{"count=0", 0, 0, true},
+		{"count=x", 0, 0, true},
+		{"offset=-1", 0, 0, true},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/swaps?"+test.query, nil)
+		count, offset, err := getCountOffsetQuery(r)
+		if (err != nil) != test.err || count != test.count || offset != test.offset {
+			t.Errorf("getCountOffsetQuery(%q) = %d, %d, %v, expected %d, %d "+
+				"(error: %v)", test.query, count, offset, err, test.count,
+				test.offset, test.err)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/blockchain"
+	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+	apitypes "github.com/decred/dcrdata/api/types"
+	m "github.com/decred/dcrdata/middleware"
+	"github.com/decred/dcrdata/rpcutils"
+	"github.com/decred/dcrdata/txhelpers"
+	"github.com/decred/dcrwallet/wallet/txrules"
+)
+
+// Codes of the reasons a transaction submitted to the broadcast endpoint is
+// not relayed, as returned in apitypes.TxBroadcastError.
+const (
+	broadcastErrDecode   = "decode_failed"
+	broadcastErrInvalid  = "invalid"
+	broadcastErrExpired  = "expired"
+	broadcastErrKnown    = "already_known"
+	broadcastErrInputs   = "spent_or_missing_input"
+	broadcastErrFee      = "insufficient_fee"
+	broadcastErrHighFee  = "high_fee"
+	broadcastErrDust     = "dust_output"
+	broadcastErrRejected = "rejected"
+)
+
+// broadcastTrackingTime is how long a broadcast transaction may be tracked.
+const broadcastTrackingTime = 24 * time.Hour
+
+// broadcastRecord is a transaction relayed by the broadcast endpoint, with the
+// times it was submitted and accepted to the node's mempool.
+type broadcastRecord struct {
+	txHash    chainhash.Hash
+	submitted time.Time
+	firstSeen time.Time
+}
+
+// broadcastTracker records the transactions relayed by the broadcast endpoint
+// by tracking ID.
+type broadcastTracker struct {
+	mtx     sync.Mutex
+	records map[string]broadcastRecord
+}
+
+func newBroadcastTracker() *broadcastTracker {
+	return &broadcastTracker{
+		records: make(map[string]broadcastRecord),
+	}
+}
+
+// track records a relayed transaction, and returns its new tracking ID.
+// Records older than broadcastTrackingTime are forgotten.
+func (bt *broadcastTracker) track(rec broadcastRecord) (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	trackingID := hex.EncodeToString(id[:])
+
+	bt.mtx.Lock()
+	defer bt.mtx.Unlock()
+	for id, r := range bt.records {
+		if time.Since(r.submitted) > broadcastTrackingTime {
+			delete(bt.records, id)
+		}
+	}
+	bt.records[trackingID] = rec
+	return trackingID, nil
+}
+
+// get returns the record for the tracking ID, if it is tracked.
+func (bt *broadcastTracker) get(trackingID string) (broadcastRecord, bool) {
+	bt.mtx.Lock()
+	defer bt.mtx.Unlock()
+	rec, ok := bt.records[trackingID]
+	if ok && time.Since(rec.submitted) > broadcastTrackingTime {
+		return rec, false
+	}
+	return rec, ok
+}
+
+// checkBroadcastTx checks a transaction before it is relayed. It must be sane,
+// unexpired, unknown to the node, and spend only unspent outputs, including
+// those spent in mempool. Regular transactions must also pay the default
+// minimum relay fee, without paying an absurdly high fee, and have no dust
+// outputs. The transaction's fee is returned if the checks pass. An error is
+// returned if the node could not be queried.
+func checkBroadcastTx(client rpcutils.NodeClient, msgTx *wire.MsgTx,
+	params *chaincfg.Params, height int64) (dcrutil.Amount, *apitypes.TxBroadcastError, error) {
+	if err := blockchain.CheckTransactionSanity(msgTx, params); err != nil {
+		return 0, &apitypes.TxBroadcastError{Code: broadcastErrInvalid, Message: err.Error()}, nil
+	}
+
+	// The next block is the first that may include the transaction.
+	if msgTx.Expiry != wire.NoExpiryValue && int64(msgTx.Expiry) <= height+1 {
+		return 0, &apitypes.TxBroadcastError{
+			Code:    broadcastErrExpired,
+			Message: fmt.Sprintf("transaction expires at height %d", msgTx.Expiry),
+		}, nil
+	}
+
+	txHash := msgTx.TxHash()
+	if _, err := client.GetRawTransactionVerbose(&txHash); err == nil {
+		return 0, &apitypes.TxBroadcastError{
+			Code:    broadcastErrKnown,
+			Message: fmt.Sprintf("transaction %v is already in mempool or a block", txHash),
+		}, nil
+	}
+
+	txType := stake.DetermineTxType(msgTx)
+	var totalIn int64
+	for i, txIn := range msgTx.TxIn {
+		// The stakebase input of a vote spends no previous output.
+		if i == 0 && txType == stake.TxTypeSSGen {
+			totalIn += txIn.ValueIn
+			continue
+		}
+		prevOut := &txIn.PreviousOutPoint
+		txOut, err := client.GetTxOut(&prevOut.Hash, prevOut.Index, true)
+		if err != nil {
+			return 0, nil, fmt.Errorf("GetTxOut(%v) failed: %v", prevOut, err)
+		}
+		if txOut == nil {
+			return 0, &apitypes.TxBroadcastError{
+				Code:    broadcastErrInputs,
+				Message: fmt.Sprintf("input %d spends %v, which is spent or does not exist", i, prevOut),
+			}, nil
+		}
+		value, err := dcrutil.NewAmount(txOut.Value)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid value of %v: %v", prevOut, err)
+		}
+		totalIn += int64(value)
+	}
+
+	fee := dcrutil.Amount(totalIn) - txhelpers.TotalOutFromMsgTx(msgTx)
+	if fee < 0 {
+		return 0, &apitypes.TxBroadcastError{
+			Code:    broadcastErrFee,
+			Message: fmt.Sprintf("outputs exceed inputs by %v", -fee),
+		}, nil
+	}
+	if txType != stake.TxTypeRegular {
+		return fee, nil, nil
+	}
+
+	minFee := txrules.FeeForSerializeSize(txrules.DefaultRelayFeePerKb, msgTx.SerializeSize())
+	if fee < minFee {
+		return 0, &apitypes.TxBroadcastError{
+			Code:    broadcastErrFee,
+			Message: fmt.Sprintf("fee %v is less than the minimum relay fee %v", fee, minFee),
+		}, nil
+	}
+	if txrules.PaysHighFees(dcrutil.Amount(totalIn), msgTx) {
+		return 0, &apitypes.TxBroadcastError{
+			Code:    broadcastErrHighFee,
+			Message: fmt.Sprintf("fee %v is absurdly high", fee),
+		}, nil
+	}
+	for i, txOut := range msgTx.TxOut {
+		if txrules.IsDustOutput(txOut, txrules.DefaultRelayFeePerKb) {
+			return 0, &apitypes.TxBroadcastError{
+				Code:    broadcastErrDust,
+				Message: fmt.Sprintf("output %d of %v is dust", i, dcrutil.Amount(txOut.Value)),
+			}, nil
+		}
+	}
+	return fee, nil, nil
+}
+
+// mempoolAcceptTime returns the time the node accepted the transaction to its
+// mempool. The node accepts a transaction before SendRawTransaction returns, so
+// if the transaction is no longer in mempool, the current time is returned.
+func mempoolAcceptTime(client rpcutils.NodeClient, txHash *chainhash.Hash) time.Time {
+	mempool, err := client.GetRawMempoolVerbose(dcrjson.GRMAll)
+	if err != nil {
+		apiLog.Warnf("GetRawMempoolVerbose failed: %v", err)
+		return time.Now()
+	}
+	if tx, ok := mempool[txHash.String()]; ok {
+		return time.Unix(tx.Time, 0)
+	}
+	return time.Now()
+}
+
+func writeBroadcastError(w http.ResponseWriter, txErr *apitypes.TxBroadcastError, indent string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(422)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(txErr); err != nil {
+		apiLog.Infof("JSON encode error: %v", err)
+	}
+}
+
+// broadcastTransaction checks and relays the transaction in the "rawtx" form
+// value, and responds with its ID and a tracking ID for getBroadcastStatus. If
+// the transaction is not relayed, the reason is given by an
+// apitypes.TxBroadcastError.
+func (c *appContext) broadcastTransaction(w http.ResponseWriter, r *http.Request) {
+	submitted := time.Now()
+	rawHexTx := m.GetRawHexTx(r)
+	if rawHexTx == "" {
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	indent := c.getIndentQuery(r)
+	msgTx, err := txhelpers.MsgTxFromHex(rawHexTx)
+	if err != nil {
+		writeBroadcastError(w, &apitypes.TxBroadcastError{
+			Code:    broadcastErrDecode,
+			Message: err.Error(),
+		}, indent)
+		return
+	}
+
+	fee, txErr, err := checkBroadcastTx(c.nodeClient, msgTx,
+		c.BlockData.GetChainParams(), int64(c.BlockData.GetHeight()))
+	if err != nil {
+		apiLog.Errorf("Unable to check transaction %v: %v", msgTx.TxHash(), err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError),
+			http.StatusInternalServerError)
+		return
+	}
+	if txErr != nil {
+		writeBroadcastError(w, txErr, indent)
+		return
+	}
+
+	txHash, err := c.nodeClient.SendRawTransaction(msgTx, false)
+	if err != nil {
+		writeBroadcastError(w, &apitypes.TxBroadcastError{
+			Code:    broadcastErrRejected,
+			Message: err.Error(),
+		}, indent)
+		return
+	}
+
+	trackingID, err := c.broadcasts.track(broadcastRecord{
+		txHash:    *txHash,
+		submitted: submitted,
+		firstSeen: mempoolAcceptTime(c.nodeClient, txHash),
+	})
+	if err != nil {
+		// The transaction was relayed, so respond without a tracking ID.
+		apiLog.Errorf("Unable to track transaction %v: %v", txHash, err)
+	}
+
+	size := int64(msgTx.SerializeSize())
+	writeJSON(w, &apitypes.TxBroadcast{
+		TxID:       txHash.String(),
+		TrackingID: trackingID,
+		Fee:        fee.ToCoin(),
+		FeeRate:    dcrutil.Amount(1000 * int64(fee) / size).ToCoin(),
+	}, indent)
+}
+
+// getBroadcastStatus responds with the mempool and confirmation status of the
+// transaction relayed with the tracking ID in the request context. A
+// transaction that is neither in mempool nor confirmed was dropped by the node.
+func (c *appContext) getBroadcastStatus(w http.ResponseWriter, r *http.Request) {
+	trackingID := m.GetTrackingIDCtx(r)
+	rec, ok := c.broadcasts.get(trackingID)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	status := &apitypes.TxBroadcastStatus{
+		TxID:       rec.txHash.String(),
+		TrackingID: trackingID,
+		Submitted:  rec.submitted.Unix(),
+		FirstSeen:  rec.firstSeen.Unix(),
+	}
+	if txRaw, err := c.nodeClient.GetRawTransactionVerbose(&rec.txHash); err == nil {
+		status.InMempool = txRaw.Confirmations == 0
+		status.Confirmations = txRaw.Confirmations
+		if txRaw.Confirmations > 0 {
+			status.BlockHash = txRaw.BlockHash
+			status.BlockHeight = txRaw.BlockHeight
+		}
+	}
+
+	writeJSON(w, status, c.getIndentQuery(r))
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/rpcclient"
+	"github.com/decred/dcrd/txscript"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrdata/rpcutils"
+	"github.com/decred/dcrdata/rpcutils/mocknode"
+)
+
+// p2pkhScript is a pay-to-pubkey-hash output script for the zero hash.
+var p2pkhScript, _ = txscript.NewScriptBuilder().AddOp(txscript.OP_DUP).
+	AddOp(txscript.OP_HASH160).AddData(make([]byte, 20)).
+	AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG).Script()
+
+// spendTx creates a transaction spending the outpoint with an output of each
+// of the values.
+func spendTx(prevHash chainhash.Hash, index uint32, values ...int64) *wire.MsgTx {
+	tx := wire.NewMsgTx()
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, index, wire.TxTreeRegular), nil))
+	for _, v := range values {
+		tx.AddTxOut(wire.NewTxOut(v, p2pkhScript))
+	}
+	return tx
+}
+
+func startBroadcastNode(t *testing.T) (*mocknode.Server, *rpcclient.Client) {
+	chain, err := mocknode.GenerateChain(&chaincfg.SimNetParams, 10, nil)
+	if err != nil {
+		t.Fatalf("GenerateChain failed: %v", err)
+	}
+	s := mocknode.NewServer(chain, "user", "pass")
+	if err = s.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	client, _, err := rpcutils.ConnectNodeRPC(s.Addr(), "user", "pass", "", true)
+	if err != nil {
+		s.Stop()
+		t.Fatalf("ConnectNodeRPC failed: %v", err)
+	}
+	return s, client
+}
+
+// fundingTx mines a transaction with outputs of the values, and returns its
+// hash. Generated coinbases differ only in their signature scripts, so they
+// have the same hash and cannot be spent individually.
+func fundingTx(t *testing.T, s *mocknode.Server, values ...int64) chainhash.Hash {
+	b, _ := s.Chain().BlockByHeight(1)
+	tx := spendTx(b.Transactions[0].TxHash(), 0, values...)
+	if err := s.AcceptTx(tx); err != nil {
+		t.Fatalf("AcceptTx failed: %v", err)
+	}
+	if _, err := s.MineBlock(); err != nil {
+		t.Fatalf("MineBlock failed: %v", err)
+	}
+	return tx.TxHash()
+}
+
+func TestCheckBroadcastTx(t *testing.T) {
+	s, client := startBroadcastNode(t)
+	defer s.Stop()
+	defer client.Shutdown()
+
+	const amt = 1e9
+	funding := fundingTx(t, s, amt, amt)
+	params := s.Chain().Params()
+	_, height := s.Chain().Best()
+
+	// The first funding output is spent by a mempool transaction.
+	if err := s.AcceptTx(spendTx(funding, 0, amt-1e5)); err != nil {
+		t.Fatalf("AcceptTx failed: %v", err)
+	}
+
+	expired := spendTx(funding, 1, amt-1e5)
+	expired.Expiry = uint32(height + 1)
+	unexpired := spendTx(funding, 1, amt-1e5)
+	unexpired.Expiry = uint32(height + 2)
+	known, _, _, _ := s.Chain().Transaction(funding)
+
+	tests := []struct {
+		name    string
+		tx      *wire.MsgTx
+		errCode string
+		fee     dcrutil.Amount
+	}{
+		{"no inputs", wire.NewMsgTx(), broadcastErrInvalid, 0},
+		{"expired", expired, broadcastErrExpired, 0},
+		{"known", known, broadcastErrKnown, 0},
+		{"missing input", spendTx(chainhash.Hash{1}, 0, 1e8), broadcastErrInputs, 0},
+		{"missing output index", spendTx(funding, 2, 1e8), broadcastErrInputs, 0},
+		{"spent in mempool", spendTx(funding, 0, amt-2e5), broadcastErrInputs, 0},
+		{"outputs exceed inputs", spendTx(funding, 1, amt+1), broadcastErrFee, 0},
+		{"no fee", spendTx(funding, 1, amt), broadcastErrFee, 0},
+		{"high fee", spendTx(funding, 1, amt-1e8), broadcastErrHighFee, 0},
+		{"dust output", spendTx(funding, 1, amt-1e5-1, 1), broadcastErrDust, 0},
+		{"ok", spendTx(funding, 1, amt-1e5), "", 1e5},
+		{"ok unexpired", unexpired, "", 1e5},
+	}
+	for _, test := range tests {
+		fee, txErr, err := checkBroadcastTx(client, test.tx, params, height)
+		if err != nil {
+			t.Errorf("%s: checkBroadcastTx failed: %v", test.name, err)
+			continue
+		}
+		var code string
+		if txErr != nil {
+			code = txErr.Code
+		}
+		if code != test.errCode {
+			t.Errorf("%s: error code %q (%v), expected %q", test.name, code,
+				txErr, test.errCode)
+		}
+		if fee != test.fee {
+			t.Errorf("%s: fee %v, expected %v", test.name, fee, test.fee)
+		}
+	}
+}
+
+func TestMempoolAcceptTime(t *testing.T) {
+	s, client := startBroadcastNode(t)
+	defer s.Stop()
+	defer client.Shutdown()
+
+	tx := spendTx(fundingTx(t, s, 1e9), 0, 1e9-1e5)
+	if err := s.AcceptTx(tx); err != nil {
+		t.Fatalf("AcceptTx failed: %v", err)
+	}
+	accepted, _ := s.Chain().MempoolTime(tx.TxHash())
+
+	// The time is reported by the node, which is not the time of the lookup.
+	time.Sleep(1100 * time.Millisecond)
+	txHash := tx.TxHash()
+	if got := mempoolAcceptTime(client, &txHash); got.Unix() != accepted.Unix() {
+		t.Errorf("accept time %v, expected %v", got, accepted)
+	}
+}
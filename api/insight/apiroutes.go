@@ -4,6 +4,7 @@
 package insight
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -12,17 +13,18 @@ import (
 	"sync"
 	"time"
 
+	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/dcrjson"
-	"github.com/decred/dcrd/rpcclient"
 	apitypes "github.com/decred/dcrdata/api/types"
 	"github.com/decred/dcrdata/db/dbtypes"
 	"github.com/decred/dcrdata/db/dcrpg"
 	m "github.com/decred/dcrdata/middleware"
+	"github.com/decred/dcrdata/rpcutils"
 	"github.com/decred/dcrdata/semver"
 )
 
 type insightApiContext struct {
-	nodeClient *rpcclient.Client
+	nodeClient rpcutils.NodeClient
 	BlockData  *dcrpg.ChainDBRPC
 	Status     apitypes.Status
 	statusMtx  sync.RWMutex
@@ -30,7 +32,7 @@ type insightApiContext struct {
 }
 
 // NewInsightContext Constructor for insightApiContext
-func NewInsightContext(client *rpcclient.Client, blockData *dcrpg.ChainDBRPC, JSONIndent string) *insightApiContext {
+func NewInsightContext(client rpcutils.NodeClient, blockData *dcrpg.ChainDBRPC, JSONIndent string) *insightApiContext {
 	conns, _ := client.GetConnectionCount()
 	nodeHeight, _ := client.GetBlockCount()
 	version := semver.NewSemver(1, 0, 0)
@@ -128,7 +130,28 @@ func (c *insightApiContext) getBlockHash(w http.ResponseWriter, r *http.Request)
 }
 
 func (c *insightApiContext) getRawBlock(w http.ResponseWriter, r *http.Request) {
-	writeText(w, "Implementation pending")
+	hash := c.getBlockHashCtx(r)
+	blockHash, err := chainhash.NewHashFromStr(hash)
+	if err != nil {
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	msgBlock, err := c.nodeClient.GetBlock(blockHash)
+	if err != nil {
+		apiLog.Errorf("Unable to get block %s: %v", hash, err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+	blockBytes, err := msgBlock.Bytes()
+	if err != nil {
+		apiLog.Errorf("Unable to serialize block %s: %v", hash, err)
+		http.Error(w, http.StatusText(422), 422)
+		return
+	}
+
+	rawBlock := apitypes.InsightRawBlock{Rawblock: hex.EncodeToString(blockBytes)}
+	writeJSON(w, rawBlock, c.getIndentQuery(r))
 }
 
 func (c *insightApiContext) broadcastTransactionRaw(w http.ResponseWriter, r *http.Request) {
@@ -30,6 +30,7 @@ type Tx struct {
 	TxShort
 	Confirmations int64    `json:"confirmations"`
 	Block         *BlockID `json:"block,omitempty"`
+	Class         []string `json:"class,omitempty"`
 }
 
 // TxShort models info about transaction TxID
@@ -93,6 +94,9 @@ type Vout struct {
 	N                   uint32       `json:"n"`
 	Version             uint16       `json:"version"`
 	ScriptPubKeyDecoded ScriptPubKey `json:"scriptPubKey"`
+	// RedeemScript is the redeem script revealed by the input spending a
+	// P2SH output.
+	RedeemScript *txhelpers.RedeemScript `json:"redeemScript,omitempty"`
 }
 
 // VoutHexScript models the hex script for a transaction output
@@ -252,6 +256,252 @@ type TicketPoolValsAndSizes struct {
 	Size        []float64 `json:"size"`
 }
 
+// TicketPoolHistory models the heights of the blocks in which a ticket entered
+// and left the live ticket pool. LeftHeight is -1 while the ticket is live, and
+// both are -1 if the ticket has not entered the pool.
+type TicketPoolHistory struct {
+	Ticket        string `json:"ticket"`
+	EnteredHeight int64  `json:"entered_height"`
+	LeftHeight    int64  `json:"left_height"`
+}
+
+// TicketPoolPage models a page of the tickets in the live pool at block height
+// Height, sorted by hash. Total is the size of the whole pool.
+type TicketPoolPage struct {
+	Height  int64    `json:"height"`
+	Total   int      `json:"total"`
+	Offset  int      `json:"offset"`
+	Tickets []string `json:"tickets"`
+}
+
+// TicketPoolAges models the age distribution, in blocks, of the tickets in the
+// live pool at block height Height. Counts[i] is the number of tickets with an
+// age in [i*BinSize, (i+1)*BinSize).
+type TicketPoolAges struct {
+	Height  int64   `json:"height"`
+	Total   int     `json:"total"`
+	MeanAge float64 `json:"mean_age"`
+	BinSize int64   `json:"bin_size"`
+	Counts  []int   `json:"counts"`
+}
+
+// VoteParticipation models the vote participation in the blocks mined in the
+// time interval beginning at Start. Participation is the fraction of the
+// tickets called to vote that voted, and Invalidated is the number of blocks
+// that invalidated the regular tree of the previous block. BlocksNVotes is the
+// number of blocks with N votes.
+type VoteParticipation struct {
+	Start         int64   `json:"start"`
+	Blocks        int64   `json:"blocks"`
+	Votes         int64   `json:"votes"`
+	Misses        int64   `json:"misses"`
+	Participation float64 `json:"participation"`
+	Invalidated   int64   `json:"invalidated"`
+	Blocks3Votes  int64   `json:"blocks_3_votes"`
+	Blocks4Votes  int64   `json:"blocks_4_votes"`
+	Blocks5Votes  int64   `json:"blocks_5_votes"`
+}
+
+// VoteParticipationHistory models the vote participation by time interval
+// (day, week, or month) for blocks mined between the UNIX times From and To.
+type VoteParticipationHistory struct {
+	Interval  string              `json:"interval"`
+	From      int64               `json:"from"`
+	To        int64               `json:"to"`
+	Intervals []VoteParticipation `json:"intervals"`
+}
+
+// RichListEntry models an address ranked by balance. LastActiveHeight and
+// LastActiveTime are of the last block funding or spending its outputs.
+type RichListEntry struct {
+	Rank             int    `json:"rank"`
+	Address          string `json:"address"`
+	Balance          int64  `json:"balance"`
+	TotalReceived    int64  `json:"total_received"`
+	NumUnspent       int64  `json:"num_unspent"`
+	LastActiveHeight int64  `json:"last_active_height"`
+	LastActiveTime   int64  `json:"last_active_time"`
+}
+
+// RichList models the addresses with the largest balances at block Height.
+type RichList struct {
+	Height    int64           `json:"height"`
+	Addresses []RichListEntry `json:"addresses"`
+}
+
+// BalanceBucket models the number and total balance of the addresses with
+// balances of at least Min and less than Max DCR.
+type BalanceBucket struct {
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Count   int64   `json:"count"`
+	Balance int64   `json:"balance"`
+}
+
+// AddressDistribution models the distribution of address balances at block
+// Height.
+type AddressDistribution struct {
+	Height  int64           `json:"height"`
+	Buckets []BalanceBucket `json:"buckets"`
+}
+
+// AddressActivity models the numbers of addresses at block Height: in total,
+// with a balance, active within the last day, week, and 30 days, and with a
+// balance but inactive for a year.
+type AddressActivity struct {
+	Height      int64 `json:"height"`
+	Total       int64 `json:"total"`
+	Funded      int64 `json:"funded"`
+	ActiveDay   int64 `json:"active_day"`
+	ActiveWeek  int64 `json:"active_week"`
+	ActiveMonth int64 `json:"active_month"`
+	DormantYear int64 `json:"dormant_year"`
+}
+
+// BlockCoinDays models the coin-days destroyed by a block: the sum over the
+// outputs it spends of their value in DCR times their age in days.
+type BlockCoinDays struct {
+	Height   int64   `json:"height"`
+	Hash     string  `json:"hash"`
+	Time     int64   `json:"time"`
+	CoinDays float64 `json:"coin_days"`
+}
+
+// CoinDaysDestroyedHistory models the coin-days destroyed by the blocks mined
+// between the UNIX times From and To.
+type CoinDaysDestroyedHistory struct {
+	From   int64           `json:"from"`
+	To     int64           `json:"to"`
+	Blocks []BlockCoinDays `json:"blocks"`
+}
+
+// ClassifiedTx models a mined transaction with the class labels in Class.
+type ClassifiedTx struct {
+	TxID        string   `json:"txid"`
+	BlockHash   string   `json:"block_hash"`
+	BlockHeight int64    `json:"block_height"`
+	Time        int64    `json:"time"`
+	Class       []string `json:"class"`
+	NumVin      uint32   `json:"num_vin"`
+	NumVout     uint32   `json:"num_vout"`
+	Sent        int64    `json:"sent"`
+	Fees        int64    `json:"fees"`
+}
+
+// ClassifiedTxns models the most recent transactions with the class Class.
+type ClassifiedTxns struct {
+	Class string         `json:"class"`
+	Txns  []ClassifiedTx `json:"txns"`
+}
+
+// Utxo models an unspent transaction output paying to an address.
+type Utxo struct {
+	TxHash  string `json:"txid"`
+	Vout    uint32 `json:"vout"`
+	Address string `json:"address"`
+	Value   int64  `json:"value"`
+}
+
+// UtxoAgeBucket models the number and total value of the unspent outputs
+// mined from MinDays up to MaxDays days ago. MaxDays is zero for the oldest
+// bucket.
+type UtxoAgeBucket struct {
+	MinDays int64 `json:"min_days"`
+	MaxDays int64 `json:"max_days,omitempty"`
+	Count   int64 `json:"count"`
+	Value   int64 `json:"value"`
+}
+
+// UtxoSetStats models the UTXO set as of the block at height Height. Values are
+// in atoms.
+type UtxoSetStats struct {
+	Height   int64           `json:"height"`
+	Hash     string          `json:"hash"`
+	Time     int64           `json:"time"`
+	NumUtxos int64           `json:"num_utxos"`
+	Value    int64           `json:"value"`
+	Ages     []UtxoAgeBucket `json:"ages"`
+	Largest  []Utxo          `json:"largest,omitempty"`
+}
+
+// UtxoSetHistory models the UTXO set snapshots, taken every Interval blocks,
+// of the blocks mined between the UNIX times From and To.
+type UtxoSetHistory struct {
+	From      int64          `json:"from"`
+	To        int64          `json:"to"`
+	Interval  int64          `json:"interval"`
+	Snapshots []UtxoSetStats `json:"snapshots"`
+}
+
+// Resolutions of an atomic swap contract.
+const (
+	SwapRedeemed = "redeemed"
+	SwapRefunded = "refunded"
+)
+
+// AtomicSwap models an atomic swap contract output, and the input of the
+// transaction in block BlockHash resolving it. Status is SwapRedeemed or
+// SwapRefunded, and Secret is revealed by a redemption.
+type AtomicSwap struct {
+	ContractTxID     string `json:"contract_txid"`
+	ContractVout     uint32 `json:"contract_vout"`
+	ContractAddress  string `json:"contract_address"`
+	Contract         string `json:"contract"`
+	Value            int64  `json:"value"`
+	RecipientAddress string `json:"recipient_address"`
+	RefundAddress    string `json:"refund_address"`
+	SecretHash       string `json:"secret_hash"`
+	SecretSize       int64  `json:"secret_size"`
+	LockTime         int64  `json:"lock_time"`
+	Status           string `json:"status"`
+	Secret           string `json:"secret,omitempty"`
+	SpendTxID        string `json:"spend_txid"`
+	SpendVin         uint32 `json:"spend_vin"`
+	BlockHash        string `json:"block_hash"`
+	BlockHeight      int64  `json:"block_height"`
+	Time             int64  `json:"time"`
+}
+
+// Names of the charts of historical chain data.
+const (
+	ChartTicketPrice = "ticket-price"
+	ChartPoolSize    = "pool-size"
+	ChartPoolValue   = "pool-value"
+	ChartBlockSize   = "block-size"
+	ChartDifficulty  = "difficulty"
+	ChartTxCount     = "tx-count"
+	ChartFees        = "fees"
+	ChartSupply      = "supply"
+)
+
+// SummaryCharts are the charts made from the block summaries, which are stored
+// by both databases.
+var SummaryCharts = []string{ChartTicketPrice, ChartPoolSize, ChartPoolValue,
+	ChartBlockSize, ChartDifficulty}
+
+// ChainCharts are the charts made from the blocks and transactions, which are
+// only stored by the PostgreSQL database.
+var ChainCharts = []string{ChartTxCount, ChartFees, ChartSupply}
+
+// MaxChartPoints is the maximum number of points of a chart. Longer ranges of
+// blocks are binned, each point summarizing Bin consecutive blocks.
+const MaxChartPoints = 1000
+
+// ChartData models a chart of the blocks mined between the UNIX times From and
+// To. Each point is at the height and time of the last block of its bin, with
+// the average value of the blocks in the bin, or the coin supply after the
+// last block for the supply chart. Values are in DCR for the ticket price,
+// pool value, fees and supply charts.
+type ChartData struct {
+	Chart   string    `json:"chart"`
+	From    int64     `json:"from"`
+	To      int64     `json:"to"`
+	Bin     int64     `json:"bin"`
+	Heights []int64   `json:"heights"`
+	Times   []int64   `json:"times"`
+	Values  []float64 `json:"values"`
+}
+
 // BlockDataBasic models primary information about block at height Height
 type BlockDataBasic struct {
 	Height     uint32  `json:"height,omitemtpy"`
@@ -353,3 +603,81 @@ type MempoolTicketDetails struct {
 // TicketsDetails is an array of pointers of TicketDetails used in
 // MempoolTicketDetails
 type TicketsDetails []*TicketDetails
+
+// MempoolSnapshot models the ticket data from one collection of mempool data,
+// as stored in the mempool history
+type MempoolSnapshot struct {
+	Height     uint32 `json:"height"`
+	Time       int64  `json:"time"`
+	NumVotes   uint32 `json:"num_votes"`
+	NewTickets uint32 `json:"new_tickets"`
+	dcrjson.FeeInfoMempool
+	LowestMineable float64        `json:"lowest_mineable"`
+	Tickets        TicketsDetails `json:"tickets"`
+}
+
+// MempoolHistory models the mempool snapshots collected between times From and
+// To. Truncated indicates that there are more snapshots in the range than
+// returned, in which case the next may be requested from the time of the last.
+type MempoolHistory struct {
+	From      int64              `json:"from"`
+	To        int64              `json:"to"`
+	Truncated bool               `json:"truncated"`
+	Snapshots []*MempoolSnapshot `json:"snapshots"`
+}
+
+// TxBroadcast models the result of relaying a transaction submitted to the
+// broadcast endpoint. TrackingID identifies the broadcast for status queries.
+type TxBroadcast struct {
+	TxID       string  `json:"txid"`
+	TrackingID string  `json:"tracking_id"`
+	Fee        float64 `json:"fee"`
+	FeeRate    float64 `json:"fee_rate"`
+}
+
+// TxBroadcastError models the reason a transaction submitted to the broadcast
+// endpoint was not relayed.
+type TxBroadcastError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// TxBroadcastStatus models the status of a broadcast transaction. FirstSeen is
+// the time the transaction was accepted to the node's mempool, and Submitted
+// the time it was received by the broadcast endpoint.
+type TxBroadcastStatus struct {
+	TxID          string `json:"txid"`
+	TrackingID    string `json:"tracking_id"`
+	Submitted     int64  `json:"submitted"`
+	FirstSeen     int64  `json:"first_seen_mempool"`
+	InMempool     bool   `json:"in_mempool"`
+	Confirmations int64  `json:"confirmations"`
+	BlockHash     string `json:"block_hash,omitempty"`
+	BlockHeight   int64  `json:"block_height,omitempty"`
+}
+
+// FeeRateEstimate models an estimated fee rate in DCR/kB, and the confidence
+// that a transaction paying it is included within the target number of blocks.
+type FeeRateEstimate struct {
+	FeeRate    float64 `json:"fee_rate"`
+	Confidence float64 `json:"confidence"`
+}
+
+// FeeEstimateMaxBlocks is the largest target number of blocks for a
+// FeeEstimate.
+const FeeEstimateMaxBlocks = 32
+
+// FeeEstimate models the low, medium, and high fee rate estimates for a regular
+// transaction to be included within Blocks blocks after Height. The estimates
+// are based on the fee rates included in NumBlocks recent blocks, and on the
+// regular transactions in mempool.
+type FeeEstimate struct {
+	Blocks      int             `json:"blocks"`
+	Height      int64           `json:"height"`
+	NumBlocks   int             `json:"num_blocks"`
+	MempoolTxns int             `json:"mempool_txns"`
+	MempoolSize int64           `json:"mempool_size"`
+	Low         FeeRateEstimate `json:"low"`
+	Medium      FeeRateEstimate `json:"medium"`
+	High        FeeRateEstimate `json:"high"`
+}
@@ -40,6 +40,11 @@ type InsightRawTx struct {
 	Rawtx string `json:"rawtx"`
 }
 
+// InsightRawBlock contains the raw block string
+type InsightRawBlock struct {
+	Rawblock string `json:"rawblock"`
+}
+
 // InsightPagination models basic pagination output
 // for a result
 type InsightPagination struct {
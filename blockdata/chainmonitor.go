@@ -115,12 +115,19 @@ out:
 				}
 
 				// Reorg is complete, do nothing lol
+				sideChain := p.sideChain[:len(p.sideChain)-1]
 				p.sideChain = nil
 				p.reorgLock.Lock()
 				p.reorganizing = false
 				p.reorgLock.Unlock()
 				log.Infof("Reorganization to block %v (height %d) complete in blockdata",
 					p.reorgData.NewChainHead, p.reorgData.NewChainHeight)
+
+				// The savers recording the reorg get each block of the new
+				// chain, in order, before the new head.
+				for i := range sideChain {
+					p.storeSideChainBlock(&sideChain[i])
+				}
 				// dcrsqlite's chainmonitor handles the reorg, but we keep going
 				// to update the web UI with the new best block.
 			}
@@ -199,6 +206,22 @@ out:
 
 }
 
+// storeSideChainBlock collects the data of a block of the new chain in a
+// reorganization, below the new head, and stores it with the reorg savers that
+// record reorganizations.
+func (p *chainMonitor) storeSideChainBlock(hash *chainhash.Hash) {
+	blockData, msgBlock, err := p.collector.CollectHash(hash)
+	if err != nil {
+		log.Errorf("blockdata.CollectHash(%v) failed: %v", hash, err)
+		return
+	}
+	for _, s := range p.reorgDataSavers {
+		if _, ok := s.(ReorgDataSaver); ok {
+			s.Store(blockData, msgBlock)
+		}
+	}
+}
+
 // ReorgHandler receives notification of a chain reorganization
 func (p *chainMonitor) ReorgHandler() {
 	defer p.wg.Done()
@@ -233,6 +256,16 @@ out:
 			log.Infof("Reorganize started in blockdata. OLD head block %v at height %d.",
 				oldHash, oldHeight)
 
+			// Mark the reorg for savers that record it. The blocks of the new
+			// chain then go to the same reorg savers as they are connected.
+			for _, s := range p.reorgDataSavers {
+				if rs, ok := s.(ReorgDataSaver); ok {
+					if err := rs.StoreReorg(reorgData); err != nil {
+						log.Errorf("Failed to store reorg data: %v", err)
+					}
+				}
+			}
+
 		case _, ok := <-p.quit:
 			if !ok {
 				log.Debugf("Got quit signal. Exiting reorg notification handler.")
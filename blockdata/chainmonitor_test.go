@@ -0,0 +1,135 @@
+package blockdata
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrdata/rpcutils"
+	"github.com/decred/dcrdata/rpcutils/mocknode"
+	"github.com/decred/dcrdata/stakedb"
+)
+
+// reorgRecorder is a BlockDataSaver and ReorgDataSaver recording the blocks and
+// reorgs stored.
+type reorgRecorder struct {
+	mtx    sync.Mutex
+	events []string
+}
+
+func (r *reorgRecorder) Store(data *BlockData, _ *wire.MsgBlock) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.events = append(r.events, fmt.Sprintf("block %d %s",
+		data.Header.Height, data.Header.Hash))
+	return nil
+}
+
+func (r *reorgRecorder) StoreReorg(reorg *ReorgData) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.events = append(r.events, fmt.Sprintf("reorg %d %v",
+		reorg.NewChainHeight, reorg.NewChainHead))
+	return nil
+}
+
+func (r *reorgRecorder) Events() []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func TestChainMonitorReorgStoresNewChain(t *testing.T) {
+	params := &chaincfg.SimNetParams
+	chain, err := mocknode.GenerateChain(params, 10, nil)
+	if err != nil {
+		t.Fatalf("GenerateChain failed: %v", err)
+	}
+	s := mocknode.NewServer(chain, "user", "pass")
+	if err = s.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer s.Stop()
+	client, _, err := rpcutils.ConnectNodeRPC(s.Addr(), "user", "pass", "", true)
+	if err != nil {
+		t.Fatalf("ConnectNodeRPC failed: %v", err)
+	}
+	defer client.Shutdown()
+
+	dir, err := ioutil.TempDir("", "blockdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stakeDB, err := stakedb.NewStakeDatabase(client, params, dir)
+	if err != nil {
+		t.Fatalf("NewStakeDatabase failed: %v", err)
+	}
+	defer stakeDB.Close()
+
+	recorder := new(reorgRecorder)
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	blockChan := make(chan *chainhash.Hash)
+	reorgChan := make(chan *ReorgData)
+	monitor := NewChainMonitor(NewCollector(client, params, stakeDB), nil,
+		[]BlockDataSaver{recorder}, quit, &wg, nil, blockChan, nil, reorgChan)
+	wg.Add(2)
+	go monitor.BlockConnectedHandler()
+	go monitor.ReorgHandler()
+	defer func() {
+		close(quit)
+		wg.Wait()
+	}()
+
+	// Replace blocks 8 and 9 with a longer chain of 3 blocks.
+	oldHead, oldHeight := s.Chain().Best()
+	side, err := s.Chain().SideChain(7, 3, nil, 1)
+	if err != nil {
+		t.Fatalf("SideChain failed: %v", err)
+	}
+	if err = s.Reorg(7, side); err != nil {
+		t.Fatalf("Reorg failed: %v", err)
+	}
+	newHead := side[len(side)-1].BlockHash()
+	reorgChan <- &ReorgData{
+		OldChainHead:   oldHead,
+		OldChainHeight: int32(oldHeight),
+		NewChainHead:   newHead,
+		NewChainHeight: 10,
+	}
+
+	// The reorg is recorded once the monitor is reorganizing.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(recorder.Events()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("reorg not stored")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, b := range side {
+		hash := b.BlockHash()
+		monitor.BlockConnectedSync(&hash)
+	}
+
+	expected := []string{fmt.Sprintf("reorg 10 %v", newHead)}
+	for i, b := range side {
+		expected = append(expected, fmt.Sprintf("block %d %v", 8+i, b.BlockHash()))
+	}
+	events := recorder.Events()
+	if len(events) != len(expected) {
+		t.Fatalf("stored %v, expected %v", events, expected)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("event %d is %q, expected %q", i, events[i], expected[i])
+		}
+	}
+}
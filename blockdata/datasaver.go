@@ -23,6 +23,13 @@ type BlockDataSaver interface {
 	Store(*BlockData, *wire.MsgBlock) error
 }
 
+// ReorgDataSaver may be implemented by a BlockDataSaver that also records the
+// start of a chain reorganization, such as a data feed whose consumers must
+// replace the blocks of the old chain with those that follow.
+type ReorgDataSaver interface {
+	StoreReorg(*ReorgData) error
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // The rest of the file contains some simple savers.  MAKE YOUR OWN to satisfy
 // BlockDataSaver.
@@ -63,6 +63,8 @@ type config struct {
 	DBName                 string `long:"dbname" description:"DB name"`
 	DuplicateEntryRecovery bool   `short:"r" long:"recoverfromdups" description:"Remove duplicate entries from all tables which would be prevented by the unique indexes. May be necessary to recover from an ill-timed crash."`
 	DropDBTables           bool   `short:"D" long:"droptables" description:"Drop/delete DB tables."`
+	UpgradeDBTables        bool   `short:"u" long:"upgrade" description:"Upgrade DB tables in place to the required versions, and exit."`
+	DryRun                 bool   `long:"dryrun" description:"With --upgrade, list the table upgrades without running them."`
 	ForceReindex           bool   `long:"reindex" short:"R" description:"Drop indexes prior to sync and recreate after sync, with insertion conflict checks disabled in absence of constraints."`
 	AddrSpendInfoOnline    bool   `short:"a" long:"addrspends-no-batch" description:"Continually update the address table spending transaction info during rebuild (instead of full table update at end).  SLOW if doing full rebuild!"`
 	TicketSpendInfoBatch   bool   `short:"T" long:"ticketspends-batch" description:"Batch update the tickets table spending transaction info after rebuild (instead of during the rebuild)."`
@@ -136,6 +136,24 @@ func mainCore() error {
 		return nil
 	}
 
+	if cfg.UpgradeDBTables {
+		steps, err := db.UpgradeTables(cfg.DryRun)
+		if err != nil {
+			return err
+		}
+		if len(steps) == 0 {
+			log.Info("All tables are at the required versions.")
+		}
+		for _, step := range steps {
+			if cfg.DryRun {
+				log.Infof("Upgrade required: %v", step)
+			} else {
+				log.Infof("Upgraded: %v", step)
+			}
+		}
+		return nil
+	}
+
 	// Create/load stake database (which includes the separate ticket pool DB).
 	stakeDB, err := stakedb.NewStakeDatabase(client, activeChain, "rebuild_data")
 	if err != nil {
@@ -386,6 +404,13 @@ func mainCore() error {
 		if err = db.IndexAddressTable(); err != nil {
 			log.Errorf("IndexAddressTable FAILED: %v", err)
 		}
+		// Summarize the address table now that it has spending info
+		if err = db.RebuildAddressStats(); err != nil {
+			log.Errorf("RebuildAddressStats FAILED: %v", err)
+		}
+		if err = db.RebuildUtxoSet(); err != nil {
+			log.Errorf("RebuildUtxoSet FAILED: %v", err)
+		}
 	}
 
 	if cfg.TicketSpendInfoBatch {
@@ -52,9 +52,15 @@ var (
 	defaultMempoolMinInterval = 2
 	defaultMempoolMaxInterval = 120
 	defaultMPTriggerTickets   = 1
+	defaultMPHistoryDays      = 30
+
+	defaultNodeCheckInterval       = 30
+	defaultNodeMaxLag        int64 = 2
 
 	defaultDBFileName = "dcrdata.sqlt.db"
 
+	defaultFeedNDJSONMaxSize int64 = 100
+
 	defaultPGHost   = "127.0.0.1:5432"
 	defaultPGUser   = "dcrdata"
 	defaultPGPass   = ""
@@ -90,7 +96,15 @@ type config struct {
 	MempoolMaxInterval int    `long:"mp-max-interval" description:"The maximum time in seconds between mempool reports (within a couple seconds), regarless of number of new tickets seen."`
 	MPTriggerTickets   int    `long:"mp-ticket-trigger" description:"The number minimum number of new tickets that must be seen to trigger a new mempool report."`
 	DumpAllMPTix       bool   `long:"dumpallmptix" description:"Dump to file the fees of all the tickets in mempool."`
+	MPHistoryDays      int    `long:"mp-history-days" description:"Number of days of mempool snapshots to keep in the mempool history. 0 keeps all snapshots."`
 	DBFileName         string `long:"dbfile" description:"SQLite DB file name (default is dcrdata.sqlt.db)."`
+	LiteAddrIndex      bool   `long:"lite-addrindex" description:"Maintain an address index in the SQLite DB, and use it for address queries instead of dcrd's searchrawtransactions, so dcrd need not run with --addrindex."`
+
+	// Data feeds
+	FeedNDJSONFile    string `long:"feed-ndjson" description:"Append block, mempool and reorg data as newline-delimited JSON to this file, rotating it when it grows too large."`
+	FeedNDJSONMaxSize int64  `long:"feed-ndjson-maxsize" description:"Size in MB at which the NDJSON feed file is rotated."`
+	FeedSocket        string `long:"feed-socket" description:"Stream block, mempool and reorg data as length-prefixed JSON messages to clients of a UNIX domain socket at this path."`
+	FeedBusDir        string `long:"feed-busdir" description:"Publish block, mempool and reorg data to per-topic append-only logs in this directory."`
 
 	FullMode bool   `long:"pg" description:"Run in \"Full Mode\" mode,  enables postgresql support"`
 	PGDBName string `long:"pgdbname" description:"PostgreSQL DB name."`
@@ -111,6 +125,11 @@ type config struct {
 	DcrdServ         string `long:"dcrdserv" description:"Hostname/IP and port of dcrd RPC server to connect to (default localhost:9109, testnet: localhost:19109, simnet: localhost:19556)"`
 	DcrdCert         string `long:"dcrdcert" description:"File containing the dcrd certificate file"`
 	DisableDaemonTLS bool   `long:"nodaemontls" description:"Disable TLS for the daemon RPC client -- NOTE: This is only allowed if the RPC client is connecting to localhost"`
+
+	// Additional dcrd nodes for failover
+	DcrdBackups       []string `long:"dcrdbackup" description:"Hostname/IP and port of a backup dcrd RPC server to fail over to, using the same credentials and certificate as dcrdserv. May be specified multiple times, in order of preference."`
+	NodeCheckInterval int      `long:"nodecheckinterval" description:"Time in seconds between dcrd node health checks when backup nodes are configured."`
+	NodeMaxLag        int64    `long:"nodemaxlag" description:"Number of blocks the active dcrd node may fall behind the best of the other nodes before failing over."`
 }
 
 var (
@@ -131,10 +150,14 @@ var (
 		MempoolMinInterval: defaultMempoolMinInterval,
 		MempoolMaxInterval: defaultMempoolMaxInterval,
 		MPTriggerTickets:   defaultMPTriggerTickets,
+		MPHistoryDays:      defaultMPHistoryDays,
 		PGDBName:           defaultPGDBName,
 		PGUser:             defaultPGUser,
 		PGPass:             defaultPGPass,
 		PGHost:             defaultPGHost,
+		NodeCheckInterval:  defaultNodeCheckInterval,
+		NodeMaxLag:         defaultNodeMaxLag,
+		FeedNDJSONMaxSize:  defaultFeedNDJSONMaxSize,
 	}
 )
 
@@ -418,10 +441,34 @@ func loadConfig() (*config, error) {
 		cfg.DcrdServ = defaultHost + ":" + activeNet.JSONRPCClientPort
 	}
 
+	if cfg.NodeCheckInterval <= 0 {
+		return loadConfigError(fmt.Errorf("nodecheckinterval must be positive"))
+	}
+	if cfg.NodeMaxLag < 0 {
+		return loadConfigError(fmt.Errorf("nodemaxlag must not be negative"))
+	}
+	if cfg.MPHistoryDays < 0 {
+		return loadConfigError(fmt.Errorf("mp-history-days must not be negative"))
+	}
+
 	// Output folder
 	cfg.OutFolder = cleanAndExpandPath(cfg.OutFolder)
 	cfg.OutFolder = filepath.Join(cfg.OutFolder, activeNet.Name)
 
+	// Data feed outputs
+	if cfg.FeedNDJSONFile != "" {
+		cfg.FeedNDJSONFile = cleanAndExpandPath(cfg.FeedNDJSONFile)
+	}
+	if cfg.FeedNDJSONMaxSize <= 0 {
+		return loadConfigError(fmt.Errorf("feed-ndjson-maxsize must be positive"))
+	}
+	if cfg.FeedSocket != "" {
+		cfg.FeedSocket = cleanAndExpandPath(cfg.FeedSocket)
+	}
+	if cfg.FeedBusDir != "" {
+		cfg.FeedBusDir = cleanAndExpandPath(cfg.FeedBusDir)
+	}
+
 	// Special show command to list supported subsystems and exit.
 	if cfg.DebugLevel == "show" {
 		fmt.Println("Supported subsystems", supportedSubsystems())
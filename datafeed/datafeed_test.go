@@ -0,0 +1,185 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package datafeed
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNDJSONFileRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datafeed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "feed.ndjson")
+	f, err := NewNDJSONFile(path, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []string{`{"n":1}`, `{"n":2}`, `{"n":3}`}
+	for _, m := range msgs {
+		if err = f.Publish(TopicBlock, []byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two 8-byte lines fit in 20 bytes, so the third starts a new file.
+	current, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current) != msgs[2]+"\n" {
+		t.Errorf("current file is %q", current)
+	}
+	rotated, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 1 {
+		t.Fatalf("expected 1 rotated file, found %d", len(rotated))
+	}
+	old, err := ioutil.ReadFile(rotated[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(old) != msgs[0]+"\n"+msgs[1]+"\n" {
+		t.Errorf("rotated file is %q", old)
+	}
+}
+
+func TestUnixSocketFrames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datafeed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewUnixSocket(filepath.Join(dir, "feed.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	conn, err := net.Dial("unix", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Wait for the client to be accepted.
+	for i := 0; ; i++ {
+		s.mtx.Lock()
+		n := len(s.clients)
+		s.mtx.Unlock()
+		if n == 1 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("client not accepted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	msgs := []string{`{"type":"block"}`, `{"type":"reorg"}`}
+	for _, m := range msgs {
+		if err = s.Publish("", []byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := bufio.NewReader(conn)
+	for _, m := range msgs {
+		payload, err := ReadFrame(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(payload) != m {
+			t.Errorf("got %q, expected %q", payload, m)
+		}
+	}
+}
+
+func TestFileBusOffsets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datafeed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bus, err := NewFileBus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []string{"a", "b", "c"} {
+		if err = bus.Publish(TopicBlock, []byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = bus.Publish(TopicReorg, []byte("r")); err != nil {
+		t.Fatal(err)
+	}
+	if err = bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a crash in the middle of writing a record.
+	logFile, err := os.OpenFile(filepath.Join(dir, TopicBlock+".log"),
+		os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logFile.Write([]byte{0, 0, 0, 0, 0, 0, 0, 3, 0, 0})
+	logFile.Close()
+
+	// Reopen, and continue from the last complete record.
+	bus, err = NewFileBus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+	next, err := bus.NextOffset(TopicBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != 3 {
+		t.Fatalf("next offset %d, expected 3", next)
+	}
+	if err = bus.Publish(TopicBlock, []byte("d")); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	err = bus.Read(TopicBlock, 1, func(offset uint64, payload []byte) error {
+		if offset != uint64(len(got)+1) {
+			t.Errorf("offset %d out of order", offset)
+		}
+		got = append(got, string(payload))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, "") != "bcd" {
+		t.Errorf("read %v", got)
+	}
+
+	next, err = bus.NextOffset(TopicReorg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != 1 {
+		t.Errorf("next reorg offset %d, expected 1", next)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package datafeed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileBus is a Publisher that implements a local, file-backed message bus in
+// the manner of a Kafka topic log. Each topic is an append-only file in the
+// bus directory, and each message in a topic is assigned the next offset,
+// starting from zero. A record is the 8-byte big-endian offset followed by the
+// length-prefixed payload. Consumers read a topic from any offset with Read.
+type FileBus struct {
+	mtx    sync.Mutex
+	dir    string
+	topics map[string]*topicLog
+}
+
+type topicLog struct {
+	file       *os.File
+	nextOffset uint64
+}
+
+// NewFileBus creates a FileBus that keeps its topic logs in dir.
+func NewFileBus(dir string) (*FileBus, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileBus{
+		dir:    dir,
+		topics: make(map[string]*topicLog),
+	}, nil
+}
+
+func (b *FileBus) topicPath(topic string) string {
+	return filepath.Join(b.dir, topic+".log")
+}
+
+// openTopic opens the log for topic, finding the next offset and discarding a
+// partially written record at the end of the file.
+func (b *FileBus) openTopic(topic string) (*topicLog, error) {
+	if tl, ok := b.topics[topic]; ok {
+		return tl, nil
+	}
+
+	file, err := os.OpenFile(b.topicPath(topic), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	var end int64
+	var next uint64
+	err = readRecords(file, 0, func(offset uint64, payload []byte) error {
+		end += 12 + int64(len(payload))
+		next = offset + 1
+		return nil
+	})
+	if err == nil {
+		err = file.Truncate(end)
+	}
+	if err == nil {
+		_, err = file.Seek(end, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("unable to recover topic log %s: %v", topic, err)
+	}
+
+	tl := &topicLog{file: file, nextOffset: next}
+	b.topics[topic] = tl
+	return tl, nil
+}
+
+// Publish appends payload to the log for topic.
+func (b *FileBus) Publish(topic string, payload []byte) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	tl, err := b.openTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	var record bytes.Buffer
+	record.Grow(12 + len(payload))
+	binary.Write(&record, binary.BigEndian, tl.nextOffset)
+	if err = writeFrame(&record, payload); err != nil {
+		return err
+	}
+	if _, err = tl.file.Write(record.Bytes()); err != nil {
+		return err
+	}
+	tl.nextOffset++
+	return nil
+}
+
+// NextOffset returns the offset that the next message published to topic will
+// be assigned.
+func (b *FileBus) NextOffset(topic string) (uint64, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	tl, err := b.openTopic(topic)
+	if err != nil {
+		return 0, err
+	}
+	return tl.nextOffset, nil
+}
+
+// Read calls fn for each message in the log for topic, in order, starting at
+// offset from. Reading stops at the end of the log, or when fn returns an
+// error, which is returned.
+func (b *FileBus) Read(topic string, from uint64, fn func(offset uint64, payload []byte) error) error {
+	file, err := os.Open(b.topicPath(topic))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	return readRecords(file, from, fn)
+}
+
+// Close closes all of the topic logs.
+func (b *FileBus) Close() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	var err error
+	for topic, tl := range b.topics {
+		if errClose := tl.file.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+		delete(b.topics, topic)
+	}
+	return err
+}
+
+// readRecords reads complete records from r, calling fn for those with offsets
+// of at least from. A truncated record at the end is ignored.
+func readRecords(r io.Reader, from uint64, fn func(uint64, []byte) error) error {
+	br := bufio.NewReader(r)
+	var offsetBytes [8]byte
+	for {
+		if _, err := io.ReadFull(br, offsetBytes[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+		payload, err := ReadFrame(br)
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+		offset := binary.BigEndian.Uint64(offsetBytes[:])
+		if offset < from {
+			continue
+		}
+		if err = fn(offset, payload); err != nil {
+			return err
+		}
+	}
+}
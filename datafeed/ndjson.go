@@ -0,0 +1,116 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package datafeed
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultNDJSONMaxSize is the default size in bytes at which an NDJSONFile is
+// rotated.
+const DefaultNDJSONMaxSize = 100 * 1024 * 1024
+
+// NDJSONFile is a Publisher that appends each message as a line of JSON to a
+// file. When the file reaches its maximum size, it is renamed with a timestamp
+// suffix and a new file is started, so the older files are never modified.
+type NDJSONFile struct {
+	mtx     sync.Mutex
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+}
+
+// NewNDJSONFile opens, or creates, the NDJSON file at path for appending. The
+// file is rotated when it would grow beyond maxSize bytes. If maxSize is not
+// positive, DefaultNDJSONMaxSize is used.
+func NewNDJSONFile(path string, maxSize int64) (*NDJSONFile, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultNDJSONMaxSize
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f := &NDJSONFile{
+		path:    path,
+		maxSize: maxSize,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *NDJSONFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, fi.Size()
+	return nil
+}
+
+// rotate closes the current file, moves it aside, and opens a new one.
+func (f *NDJSONFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	rotated := fmt.Sprintf("%s.%s", f.path,
+		time.Now().UTC().Format("20060102T150405.000000000"))
+	if err := os.Rename(f.path, rotated); err != nil {
+		// Keep appending to the current file.
+		if errOpen := f.open(); errOpen != nil {
+			log.Errorf("Unable to reopen NDJSON feed file: %v", errOpen)
+		}
+		return err
+	}
+	log.Debugf("Rotated NDJSON feed file to %s.", rotated)
+	return f.open()
+}
+
+// Publish appends payload and a newline to the file. The topic is not written
+// since it is the type of the message.
+func (f *NDJSONFile) Publish(_ string, payload []byte) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+
+	line := make([]byte, len(payload)+1)
+	copy(line, payload)
+	line[len(payload)] = '\n'
+
+	if f.size > 0 && f.size+int64(len(line)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := f.file.Write(line)
+	f.size += int64(n)
+	return err
+}
+
+// Close closes the file.
+func (f *NDJSONFile) Close() error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
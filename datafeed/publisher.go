@@ -0,0 +1,72 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+// Package datafeed provides block and mempool data savers that publish a
+// continuous feed of JSON messages, including chain reorganization markers,
+// to outputs such as rotating NDJSON files, UNIX domain sockets, and message
+// buses.
+package datafeed
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// Message topics, which are also the Type of each Message.
+const (
+	TopicBlock   = "block"
+	TopicMempool = "mempool"
+	TopicReorg   = "reorg"
+)
+
+// Topics lists all of the message topics published by a Saver.
+var Topics = []string{TopicBlock, TopicMempool, TopicReorg}
+
+// Publisher is the interface for a message bus or other output that accepts
+// the encoded messages of a data feed. Publish may be called from multiple
+// goroutines.
+type Publisher interface {
+	Publish(topic string, payload []byte) error
+	Close() error
+}
+
+// Message is the envelope for each item in a data feed. Data is one of
+// BlockMessage, apitypes.MempoolSnapshot, or ReorgMessage, according to Type.
+type Message struct {
+	Type   string      `json:"type"`
+	Time   int64       `json:"time"`
+	Height int64       `json:"height"`
+	Data   interface{} `json:"data"`
+}
+
+// ErrFrameTooLarge is returned when a payload cannot be length-prefixed.
+var ErrFrameTooLarge = errors.New("payload too large for frame")
+
+// writeFrame writes payload to w prefixed by its length as a 4-byte big-endian
+// unsigned integer.
+func writeFrame(w io.Writer, payload []byte) error {
+	if uint64(len(payload)) > math.MaxUint32 {
+		return ErrFrameTooLarge
+	}
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[4:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadFrame reads a length-prefixed payload, as written by the UnixSocket
+// publisher, from r.
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(length[:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package datafeed
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/decred/dcrd/wire"
+	apitypes "github.com/decred/dcrdata/api/types"
+	"github.com/decred/dcrdata/blockdata"
+	"github.com/decred/dcrdata/mempool"
+)
+
+// BlockMessage is the Data of a block Message.
+type BlockMessage struct {
+	Summary   apitypes.BlockDataBasic    `json:"summary"`
+	StakeInfo apitypes.StakeInfoExtended `json:"stake_info"`
+}
+
+// ReorgMessage is the Data of a reorg Message. It marks the start of a chain
+// reorganization. The blocks of the new chain, from the fork point up to
+// NewChainHead, follow in block messages, and replace any blocks of the old
+// chain at the same heights.
+type ReorgMessage struct {
+	OldChainHead   string `json:"old_chain_head"`
+	OldChainHeight int32  `json:"old_chain_height"`
+	NewChainHead   string `json:"new_chain_head"`
+	NewChainHeight int32  `json:"new_chain_height"`
+}
+
+// Saver publishes block data, mempool data, and reorg markers as JSON messages
+// to a Publisher. It satisfies blockdata.BlockDataSaver,
+// blockdata.ReorgDataSaver, and mempool.MempoolDataSaver.
+type Saver struct {
+	pub Publisher
+}
+
+// NewSaver creates a new Saver that publishes to pub.
+func NewSaver(pub Publisher) *Saver {
+	return &Saver{pub: pub}
+}
+
+// Close closes the Saver's Publisher.
+func (s *Saver) Close() error {
+	return s.pub.Close()
+}
+
+func (s *Saver) publish(topic string, height int64, data interface{}) error {
+	payload, err := json.Marshal(&Message{
+		Type:   topic,
+		Time:   time.Now().Unix(),
+		Height: height,
+		Data:   data,
+	})
+	if err != nil {
+		return err
+	}
+	if err = s.pub.Publish(topic, payload); err != nil {
+		log.Errorf("Failed to publish %s message at height %d: %v",
+			topic, height, err)
+	}
+	return err
+}
+
+// Store publishes a block message. It satisfies blockdata.BlockDataSaver.
+func (s *Saver) Store(data *blockdata.BlockData, _ *wire.MsgBlock) error {
+	return s.publish(TopicBlock, int64(data.Header.Height), &BlockMessage{
+		Summary:   data.ToBlockSummary(),
+		StakeInfo: data.ToStakeInfoExtended(),
+	})
+}
+
+// StoreReorg publishes a reorg marker. It satisfies blockdata.ReorgDataSaver.
+func (s *Saver) StoreReorg(reorg *blockdata.ReorgData) error {
+	return s.publish(TopicReorg, int64(reorg.NewChainHeight), &ReorgMessage{
+		OldChainHead:   reorg.OldChainHead.String(),
+		OldChainHeight: reorg.OldChainHeight,
+		NewChainHead:   reorg.NewChainHead.String(),
+		NewChainHeight: reorg.NewChainHeight,
+	})
+}
+
+// StoreMPData publishes a mempool message. It satisfies
+// mempool.MempoolDataSaver.
+func (s *Saver) StoreMPData(data *mempool.MempoolData, timestamp time.Time) error {
+	snapshot := &apitypes.MempoolSnapshot{
+		Height:         data.Height,
+		Time:           timestamp.Unix(),
+		NumVotes:       data.NumVotes,
+		NewTickets:     data.NewTickets,
+		LowestMineable: data.GetLowestMineableFee(),
+		Tickets:        apitypes.TicketsDetails(data.AllTicketsDetails),
+	}
+	if data.Ticketfees != nil {
+		snapshot.FeeInfoMempool = data.Ticketfees.FeeInfoMempool
+	}
+	return s.publish(TopicMempool, int64(data.Height), snapshot)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package datafeed
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// socketWriteTimeout limits how long a slow client may hold up a Publish.
+const socketWriteTimeout = 5 * time.Second
+
+// UnixSocket is a Publisher that listens on a UNIX domain socket and streams
+// each message to every connected client as a length-prefixed frame (see
+// ReadFrame). Clients receive only messages published after they connect. A
+// client that cannot keep up is disconnected.
+type UnixSocket struct {
+	mtx      sync.Mutex
+	listener net.Listener
+	clients  map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewUnixSocket listens on the UNIX domain socket at path, replacing any stale
+// socket file, and starts accepting clients.
+func NewUnixSocket(path string) (*UnixSocket, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if err = os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	s := &UnixSocket{
+		listener: listener,
+		clients:  make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// Addr returns the address of the socket.
+func (s *UnixSocket) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *UnixSocket) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			// The listener is closed.
+			return
+		}
+		log.Debugf("Data feed client connected to %v.", s.listener.Addr())
+		s.mtx.Lock()
+		s.clients[conn] = struct{}{}
+		s.mtx.Unlock()
+	}
+}
+
+// Publish writes the length-prefixed payload to each connected client,
+// dropping clients for which the write fails. The topic is not written since
+// it is the type of the message.
+func (s *UnixSocket) Publish(_ string, payload []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for conn := range s.clients {
+		conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+		if err := writeFrame(conn, payload); err != nil {
+			log.Debugf("Dropping data feed client: %v", err)
+			conn.Close()
+			delete(s.clients, conn)
+		}
+	}
+	return nil
+}
+
+// Close stops listening, which removes the socket file, and disconnects all
+// clients.
+func (s *UnixSocket) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for conn := range s.clients {
+		conn.Close()
+		delete(s.clients, conn)
+	}
+	return err
+}
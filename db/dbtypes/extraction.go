@@ -98,6 +98,7 @@ func processTransactions(msgBlock *wire.MsgBlock, tree int8,
 			Fees:        fees,
 			NumVin:      uint32(len(tx.TxIn)),
 			NumVout:     uint32(len(tx.TxOut)),
+			Class:       txhelpers.ClassifyTx(tx),
 		}
 
 		//dbTx.Vins = make([]VinTxProperty, 0, dbTx.NumVin)
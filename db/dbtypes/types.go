@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"github.com/decred/dcrdata/db/dbtypes/internal"
+	"github.com/decred/dcrdata/txhelpers"
 )
 
 // Tickets have 6 states, 5 possible fates:
@@ -286,6 +287,7 @@ type Tx struct {
 	VoutDbIds []uint64 `json:"voutdbids"`
 	// NOTE: VoutDbIds may not be needed if there is a vout table since each
 	// vout will have a tx_dbid
+	Class txhelpers.TxClass `json:"class"`
 }
 
 // Block models a Decred block.
@@ -328,3 +330,147 @@ type BlockDataBasic struct {
 	Time       int64   `json:"time,omitemtpy"`
 	NumTx      uint32  `json:"txlength,omitempty"`
 }
+
+// VoteSummary summarizes the votes in a block. The votes are on the validity of
+// the regular transaction tree of the previous block, the candidate block.
+// Misses are the tickets called to vote on the candidate block that did not
+// vote, and CandidateValid is the outcome recorded in the block header.
+type VoteSummary struct {
+	Height         uint32 `json:"height"`
+	BlockHash      string `json:"hash"`
+	BlockTime      int64  `json:"time"`
+	CandidateHash  string `json:"candidate_hash"`
+	Votes          uint16 `json:"votes"`
+	Approvals      uint16 `json:"approvals"`
+	Disapprovals   uint16 `json:"disapprovals"`
+	Misses         uint16 `json:"misses"`
+	CandidateValid bool   `json:"candidate_valid"`
+}
+
+// TreasuryTxType is the kind of a transaction paying to or spending from the
+// development subsidy address.
+type TreasuryTxType int16
+
+const (
+	TreasurySubsidy TreasuryTxType = iota
+	TreasuryReceipt
+	TreasurySpend
+)
+
+func (p TreasuryTxType) String() string {
+	switch p {
+	case TreasurySubsidy:
+		return "subsidy"
+	case TreasuryReceipt:
+		return "receipt"
+	case TreasurySpend:
+		return "spend"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalJSON encodes the transaction type as its string.
+func (p TreasuryTxType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// TreasuryTx is a transaction paying to or spending from the development
+// subsidy address. Received and Sent are the amounts in atoms paid to and
+// spent from the address, and Balance is the address balance after the
+// transaction. Destinations and DestinationValues are the other outputs of a
+// spending transaction.
+type TreasuryTx struct {
+	TxHash            string         `json:"txid"`
+	BlockHash         string         `json:"block_hash"`
+	BlockHeight       int64          `json:"block_height"`
+	BlockTime         int64          `json:"time"`
+	Type              TreasuryTxType `json:"type"`
+	Received          int64          `json:"received"`
+	Sent              int64          `json:"sent"`
+	Balance           int64          `json:"balance"`
+	Destinations      []string       `json:"destinations,omitempty"`
+	DestinationValues []int64        `json:"destination_values,omitempty"`
+
+	// FundingVouts and SpentOutpoints (hash:index) identify the outputs of the
+	// address created and spent by the transaction.
+	FundingVouts   []int32  `json:"-"`
+	SpentOutpoints []string `json:"-"`
+}
+
+// TreasuryMonth models the development subsidy address's inflows and outflows
+// in the month beginning at the UNIX time Month. Spent is the net amount spent
+// by NumSpends spending transactions, and Balance is the balance at the end of
+// the month. Amounts are in atoms.
+type TreasuryMonth struct {
+	Month     int64 `json:"month"`
+	Subsidy   int64 `json:"subsidy"`
+	Received  int64 `json:"received"`
+	Spent     int64 `json:"spent"`
+	NumSpends int64 `json:"num_spends"`
+	Balance   int64 `json:"balance"`
+}
+
+// TreasurySummary models the balance of the development subsidy address, its
+// total and monthly inflows and outflows, and its largest spends.
+type TreasurySummary struct {
+	Address       string          `json:"address"`
+	Height        int64           `json:"height"`
+	Balance       int64           `json:"balance"`
+	TotalSubsidy  int64           `json:"total_subsidy"`
+	TotalReceived int64           `json:"total_received"`
+	TotalSpent    int64           `json:"total_spent"`
+	Months        []TreasuryMonth `json:"months"`
+	LargestSpends []*TreasuryTx   `json:"largest_spends"`
+}
+
+// TicketMaturityBin models the immature tickets that join the live ticket pool
+// at block Height.
+type TicketMaturityBin struct {
+	Height int64 `json:"height"`
+	Count  int64 `json:"count"`
+}
+
+// TicketHeightBin models the live tickets purchased in the bin of blocks
+// starting at Height. Value is the total price of the tickets in DCR.
+type TicketHeightBin struct {
+	Height int64   `json:"height"`
+	Count  int64   `json:"count"`
+	Value  float64 `json:"value"`
+}
+
+// TicketPriceBin models the live tickets purchased at a price in DCR from
+// MinPrice up to but excluding MaxPrice.
+type TicketPriceBin struct {
+	MinPrice float64 `json:"min_price"`
+	MaxPrice float64 `json:"max_price"`
+	Count    int64   `json:"count"`
+}
+
+// TicketPoolStats models the ticket pool after the block at Height, with the
+// size and value in DCR of the live pool, the immature tickets by the height
+// at which they mature, and the live tickets by purchase height and price.
+type TicketPoolStats struct {
+	Height          int64               `json:"height"`
+	Size            uint32              `json:"size"`
+	Value           float64             `json:"value"`
+	Immature        int64               `json:"immature"`
+	Maturing        []TicketMaturityBin `json:"maturing"`
+	HeightBinSize   int64               `json:"height_bin_size"`
+	PurchaseHeights []TicketHeightBin   `json:"purchase_heights"`
+	Prices          []TicketPriceBin    `json:"prices"`
+}
+
+// BlocksInterval models the blocks mined in the time interval (e.g. a day)
+// beginning at the UNIX time Start, with heights from MinHeight to MaxHeight.
+// Fees is the total of the fees in atoms collected by the blocks, and
+// FreshStake the number of tickets purchased.
+type BlocksInterval struct {
+	Start        int64 `json:"start"`
+	Blocks       int64 `json:"blocks"`
+	Transactions int64 `json:"transactions"`
+	Fees         int64 `json:"fees"`
+	FreshStake   int64 `json:"fresh_stake"`
+	MinHeight    int64 `json:"min_height"`
+	MaxHeight    int64 `json:"max_height"`
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2018, The dcrdata developers
+// See LICENSE for details.
+
+package dcrpg
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/decred/dcrd/blockchain"
+	"github.com/decred/dcrd/chaincfg"
+	apitypes "github.com/decred/dcrdata/api/types"
+	"github.com/decred/dcrdata/db/dcrpg/internal"
+)
+
+// chainChartQueries are the statements selecting the points of the charts made
+// from the blocks and transactions. The supply chart is computed from the
+// subsidy of each block instead.
+var chainChartQueries = map[string]string{
+	apitypes.ChartTxCount: internal.SelectTxCountChart,
+	apitypes.ChartFees:    internal.SelectFeesChart,
+	apitypes.ChartSupply:  internal.SelectChartHeights,
+}
+
+// supplyCache holds the coin supply after each stored block, by height, and
+// the hash of the last block.
+type supplyCache struct {
+	sync.Mutex
+	subsidies *blockchain.SubsidyCache
+	supply    []int64
+	hash      string
+}
+
+// newSupplyCache creates an empty supplyCache.
+func newSupplyCache(params *chaincfg.Params) *supplyCache {
+	return &supplyCache{
+		subsidies: blockchain.NewSubsidyCache(0, params),
+	}
+}
+
+// blockSubsidy computes the subsidy paid by the block at the given height. The
+// work and treasury subsidies of a block are not paid if its regular
+// transactions were disapproved by the votes of the next block.
+func blockSubsidy(subsidies *blockchain.SubsidyCache, height int64, voters uint16,
+	isValid bool, params *chaincfg.Params) int64 {
+	if height == 0 {
+		return 0
+	}
+	subsidy := int64(voters) * blockchain.CalcStakeVoteSubsidy(subsidies, height, params)
+	if !isValid {
+		return subsidy
+	}
+	// Block one pays the initial distribution of coins instead.
+	if height == 1 {
+		return subsidy + params.BlockOneSubsidy()
+	}
+	return subsidy + blockchain.CalcBlockWorkSubsidy(subsidies, height, voters, params) +
+		blockchain.CalcBlockTaxSubsidy(subsidies, height, voters, params)
+}
+
+// update extends the coin supply to the best stored block. The supply after
+// the last block is recomputed since its regular transactions may have since
+// been disapproved, and the supply is recomputed entirely after a reorg.
+func (sc *supplyCache) update(db *sql.DB, params *chaincfg.Params) error {
+	var supply []int64
+	if len(sc.supply) > 0 {
+		supply = sc.supply[:len(sc.supply)-1]
+	}
+	start := int64(len(supply))
+
+	rows, err := db.Query(internal.SelectBlockSubsidyInfo, start)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	var hash string
+	reorged := len(sc.supply) > 0
+	for rows.Next() {
+		var height int64
+		var voters uint16
+		var isValid bool
+		if err = rows.Scan(&height, &hash, &voters, &isValid); err != nil {
+			return err
+		}
+		if height == start && hash == sc.hash {
+			reorged = false
+		}
+		if reorged {
+			break
+		}
+		if height != int64(len(supply)) {
+			return fmt.Errorf("no block stored at height %d", len(supply))
+		}
+
+		subsidy := blockSubsidy(sc.subsidies, height, voters, isValid, params)
+		if height > 0 {
+			subsidy += supply[height-1]
+		}
+		supply = append(supply, subsidy)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if reorged {
+		log.Debugf("Recomputing the coin supply after a reorg.")
+		sc.supply, sc.hash = nil, ""
+		return sc.update(db, params)
+	}
+	sc.supply, sc.hash = supply, hash
+	return nil
+}
+
+// RetrieveChartData retrieves the chart of the blocks mined between the times
+// from and to (UNIX seconds, inclusive), with up to apitypes.MaxChartPoints
+// points. The supply chart requires the coin supply to be computed by
+// supplyCache.update.
+func RetrieveChartData(db *sql.DB, chart string, from, to int64, supply []int64) (*apitypes.ChartData, error) {
+	query, ok := chainChartQueries[chart]
+	if !ok {
+		return nil, fmt.Errorf("unknown chart %s", chart)
+	}
+
+	var numBlocks int64
+	err := db.QueryRow(internal.CountBlocksInTimeRange, from, to).Scan(&numBlocks)
+	if err != nil {
+		return nil, err
+	}
+	bin := (numBlocks + apitypes.MaxChartPoints - 1) / apitypes.MaxChartPoints
+	if bin < 1 {
+		bin = 1
+	}
+
+	rows, err := db.Query(query, from, to, bin)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	data := &apitypes.ChartData{
+		Chart: chart,
+		From:  from,
+		To:    to,
+		Bin:   bin,
+	}
+	for rows.Next() {
+		var height, t int64
+		var value float64
+		if chart == apitypes.ChartSupply {
+			if err = rows.Scan(&height, &t); err != nil {
+				return nil, err
+			}
+			if height >= int64(len(supply)) {
+				break
+			}
+			value = float64(supply[height]) / 1e8
+		} else if err = rows.Scan(&height, &t, &value); err != nil {
+			return nil, err
+		}
+		data.Heights = append(data.Heights, height)
+		data.Times = append(data.Times, t)
+		data.Values = append(data.Values, value)
+	}
+	return data, rows.Err()
+}
+
+// ChartData retrieves the chart of the blocks mined between the times from and
+// to.
+func (pgb *ChainDB) ChartData(chart string, from, to int64) (*apitypes.ChartData, error) {
+	var supply []int64
+	if chart == apitypes.ChartSupply {
+		pgb.supply.Lock()
+		defer pgb.supply.Unlock()
+		if err := pgb.supply.update(pgb.db, pgb.chainParams); err != nil {
+			return nil, err
+		}
+		supply = pgb.supply.supply
+	}
+	return RetrieveChartData(pgb.db, chart, from, to, supply)
+}
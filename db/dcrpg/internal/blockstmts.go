@@ -75,6 +75,10 @@ const (
 		ON blocks(hash);`
 	DeindexBlockTableOnHash = `DROP INDEX uix_block_hash;`
 
+	IndexBlockTableOnTime = `CREATE INDEX uix_block_time
+		ON blocks(time);`
+	DeindexBlockTableOnTime = `DROP INDEX uix_block_time;`
+
 	RetrieveBestBlock       = `SELECT * FROM blocks ORDER BY height DESC LIMIT 0, 1;`
 	RetrieveBestBlockHeight = `SELECT id, hash, height FROM blocks ORDER BY height DESC LIMIT 1;`
 
@@ -92,10 +96,25 @@ const (
 	VALUES ($1, $2, $3, $4)
 	ON CONFLICT (this_hash) DO NOTHING;`
 
-	SelectBlockHashByHeight = `select hash from blocks where height = $1`
-	SelectBlockHeightByHash = `select height from blocks where hash = $1`
+	SelectBlockHashByHeight     = `select hash from blocks where height = $1`
+	SelectBlockHeightByHash     = `select height from blocks where hash = $1`
+	SelectBlockHashTimeByHeight = `select hash, time from blocks where height = $1`
 
 	UpdateBlockNext = `UPDATE block_chain set next_hash = $2 WHERE block_db_id = $1;`
+
+	// SelectBlocksByInterval aggregates the blocks with times in [$2, $3] by
+	// the time interval $1 (e.g. 'day', 'week', or 'month'), in UTC, newest
+	// first. The fees collected by a block are the negative fees of its
+	// coinbase transaction.
+	SelectBlocksByInterval = `SELECT
+			EXTRACT(EPOCH FROM date_trunc($1, to_timestamp(b.time) AT TIME ZONE 'UTC'))::INT8 AS start,
+			COUNT(*), SUM(b.numtx), COALESCE(SUM(-t.fees), 0)::INT8,
+			SUM(b.fresh_stake), MIN(b.height), MAX(b.height)
+		FROM blocks b
+		LEFT JOIN transactions t ON t.id = b.txDbIDs[1]
+		WHERE b.time >= $2 AND b.time <= $3
+		GROUP BY start
+		ORDER BY start DESC;`
 )
 
 func MakeBlockInsertStatement(block *dbtypes.Block, checked bool) string {
@@ -0,0 +1,42 @@
+package internal
+
+const (
+	// CountBlocksInTimeRange counts the blocks mined between the times $1 and
+	// $2.
+	CountBlocksInTimeRange = `SELECT COUNT(*) FROM blocks
+		WHERE time BETWEEN $1 AND $2;`
+
+	// The chart statements select the points for the blocks mined between the
+	// times $1 and $2, each point at the last block of a bin of $3 blocks.
+
+	// SelectTxCountChart selects the average number of transactions per block.
+	SelectTxCountChart = `SELECT MAX(height), MAX(time), AVG(numtx)::FLOAT8
+		FROM blocks
+		WHERE time BETWEEN $1 AND $2
+		GROUP BY height / $3
+		ORDER BY 1;`
+
+	// SelectFeesChart selects the average fees per block in DCR. The fees
+	// collected by a block are the negative fees of its coinbase transaction,
+	// since the coinbase input is only the block subsidy.
+	SelectFeesChart = `SELECT MAX(b.height), MAX(b.time), AVG(-t.fees)::FLOAT8 / 1e8
+		FROM blocks b
+		JOIN transactions t ON t.id = b.txDbIDs[1]
+		WHERE b.time BETWEEN $1 AND $2
+		GROUP BY b.height / $3
+		ORDER BY 1;`
+
+	// SelectChartHeights selects the points without a value.
+	SelectChartHeights = `SELECT MAX(height), MAX(time)
+		FROM blocks
+		WHERE time BETWEEN $1 AND $2
+		GROUP BY height / $3
+		ORDER BY 1;`
+
+	// SelectBlockSubsidyInfo selects the blocks from height $1 with the data
+	// determining the subsidy they paid.
+	SelectBlockSubsidyInfo = `SELECT height, hash, voters, is_valid
+		FROM blocks
+		WHERE height >= $1
+		ORDER BY height;`
+)
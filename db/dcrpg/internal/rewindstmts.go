@@ -0,0 +1,65 @@
+package internal
+
+// Statements used to remove all data for blocks above a given height ($1),
+// undoing the updates they made to rows of earlier blocks. They are intended to
+// be executed in order, in a single DB transaction.
+const (
+	// Outputs spent by transactions being removed become unspent.
+	RewindAddressesSpending = `UPDATE addresses
+		SET spending_tx_row_id = NULL, spending_tx_hash = NULL,
+			spending_tx_vin_index = NULL, vin_row_id = NULL
+		WHERE spending_tx_row_id IN
+			(SELECT id FROM transactions WHERE block_height > $1);`
+
+	// Tickets spent in removed blocks are unspent and live ($2, $3).
+	RewindTicketsSpending = `UPDATE tickets
+		SET spend_type = $2, pool_status = $3,
+			spend_height = NULL, spend_tx_db_id = NULL
+		WHERE spend_height > $1;`
+
+	// Tickets that missed votes in removed blocks, or expired after the new
+	// tip, are live again. $2, $3 and $4 are the live, missed and expired pool
+	// statuses, and $5 is the number of blocks from purchase to expiration.
+	RewindTicketsPoolStatus = `UPDATE tickets
+		SET pool_status = $2
+		WHERE spend_height IS NULL AND (
+			(pool_status = $3 AND tx_hash IN
+				(SELECT ticket_hash FROM misses WHERE height > $1))
+			OR (pool_status = $4 AND block_height + $5 > $1)
+		);`
+
+	DeleteAddressesAboveHeight = `DELETE FROM addresses
+		WHERE funding_tx_row_id IN
+			(SELECT id FROM transactions WHERE block_height > $1);`
+	DeleteVinsAboveHeight = `DELETE FROM vins
+		WHERE id IN
+			(SELECT unnest(vin_db_ids) FROM transactions WHERE block_height > $1);`
+	DeleteVoutsAboveHeight = `DELETE FROM vouts
+		WHERE id IN
+			(SELECT unnest(vout_db_ids) FROM transactions WHERE block_height > $1);`
+	DeleteTicketsAboveHeight           = `DELETE FROM tickets WHERE block_height > $1;`
+	DeleteVotesAboveHeight             = `DELETE FROM votes WHERE height > $1;`
+	DeleteMissesAboveHeight            = `DELETE FROM misses WHERE height > $1;`
+	DeleteVoteSummaryAboveHeight       = `DELETE FROM vote_summary WHERE height > $1;`
+	DeleteTreasuryAboveHeight          = `DELETE FROM treasury WHERE block_height > $1;`
+	DeleteCoinDaysDestroyedAboveHeight = `DELETE FROM coin_days_destroyed WHERE height > $1;`
+	DeleteUtxoHeightsAboveHeight       = `DELETE FROM utxo_heights WHERE height > $1;`
+	DeleteUtxoSnapshotsAboveHeight     = `DELETE FROM utxo_snapshots WHERE height > $1;`
+	DeleteAtomicSwapsAboveHeight       = `DELETE FROM swaps WHERE block_height > $1;`
+	DeleteTransactionsAboveHeight      = `DELETE FROM transactions WHERE block_height > $1;`
+	DeleteBlockChainAboveHeight        = `DELETE FROM block_chain
+		WHERE block_db_id IN (SELECT id FROM blocks WHERE height > $1);`
+	DeleteBlocksAboveHeight = `DELETE FROM blocks WHERE height > $1;`
+
+	// The new tip has no next block, and is valid until voted otherwise.
+	RewindBlockChainTip = `UPDATE block_chain SET next_hash = ''
+		WHERE block_db_id IN (SELECT id FROM blocks WHERE height = $1);`
+	RewindBlockValidity = `UPDATE blocks SET is_valid = TRUE WHERE height = $1;`
+	// The treasury rows of the new tip's regular transactions are valid again,
+	// so their net amount is restored to the balances of its other rows first.
+	RewindTreasuryBalances = `UPDATE treasury SET balance = balance +
+			(SELECT COALESCE(SUM(received - sent), 0) FROM treasury
+			WHERE block_height = $1 AND NOT is_valid)
+		WHERE block_height = $1 AND is_valid;`
+	RewindTreasuryValidity = `UPDATE treasury SET is_valid = TRUE WHERE block_height = $1;`
+)
@@ -0,0 +1,201 @@
+package internal
+
+const (
+	// Address balances
+
+	// CreateAddressBalancesTable creates the summary of each address in the
+	// addresses table: its unspent balance, the total value paid to it, and
+	// the height and time of the last block funding or spending its outputs.
+	// The table is kept current as blocks are connected, so the primary key is
+	// not dropped with the other indexes during a bulk import.
+	CreateAddressBalancesTable = `CREATE TABLE IF NOT EXISTS address_balances (
+		address TEXT PRIMARY KEY,
+		balance INT8,
+		total_received INT8,
+		num_unspent INT8,
+		last_active_height INT4,
+		last_active_time INT8
+	);`
+
+	// addressBalancesSelect summarizes the address rows selected by the WHERE
+	// clause appended to it.
+	addressBalancesSelect = `SELECT a.address,
+			COALESCE(SUM(a.value) FILTER (WHERE a.spending_tx_row_id IS NULL), 0),
+			SUM(a.value),
+			COUNT(*) FILTER (WHERE a.spending_tx_row_id IS NULL),
+			MAX(GREATEST(ft.block_height, st.block_height)),
+			MAX(GREATEST(ft.block_time, st.block_time))
+		FROM addresses a
+		JOIN transactions ft ON ft.id = a.funding_tx_row_id
+		LEFT JOIN transactions st ON st.id = a.spending_tx_row_id `
+	insertAddressBalances0 = `INSERT INTO address_balances (address, balance,
+		total_received, num_unspent, last_active_height, last_active_time) `
+
+	// InsertAllAddressBalances builds the table from the entire addresses
+	// table, which must have complete spending info.
+	InsertAllAddressBalances = insertAddressBalances0 + addressBalancesSelect +
+		`GROUP BY a.address;`
+	DeleteAddressBalances = `DELETE FROM address_balances;`
+
+	// UpsertAddressBalancesFor recomputes the summaries of the addresses in
+	// the array $1.
+	UpsertAddressBalancesFor = insertAddressBalances0 + addressBalancesSelect +
+		`WHERE a.address = ANY($1)
+		GROUP BY a.address
+		ON CONFLICT (address) DO UPDATE
+		SET balance = EXCLUDED.balance, total_received = EXCLUDED.total_received,
+			num_unspent = EXCLUDED.num_unspent,
+			last_active_height = EXCLUDED.last_active_height,
+			last_active_time = EXCLUDED.last_active_time;`
+	// DeleteAddressBalancesWithoutRows deletes the summaries of the addresses
+	// in the array $1 that no longer have rows in the addresses table.
+	DeleteAddressBalancesWithoutRows = `DELETE FROM address_balances
+		WHERE address = ANY($1)
+			AND NOT EXISTS (SELECT 1 FROM addresses
+				WHERE addresses.address = address_balances.address);`
+
+	// UpdateAddressBalancesForBlock applies to the summaries the outputs
+	// funded and spent by the transactions of the block with hash $1, at
+	// height $2 and time $3. Transactions mined in an earlier block, such as
+	// those of a block disapproved by stakeholders, were already applied.
+	UpdateAddressBalancesForBlock = `WITH block_txns AS (` + blockFirstTxns + `),
+		funded AS (
+			SELECT a.address, SUM(a.value) AS received, COUNT(*) AS num_funded
+			FROM block_txns t
+			JOIN addresses a ON a.funding_tx_hash = t.tx_hash
+			GROUP BY a.address),
+		spent AS (
+			SELECT a.address, SUM(a.value) AS sent, COUNT(*) AS num_spent
+			FROM block_txns t
+			JOIN vins vi ON vi.tx_hash = t.tx_hash AND vi.tx_tree = t.tree
+			JOIN addresses a ON a.funding_tx_hash = vi.prev_tx_hash
+				AND a.funding_tx_vout_index = vi.prev_tx_index
+			GROUP BY a.address)
+		` + insertAddressBalances0 + `
+		SELECT COALESCE(f.address, s.address),
+			COALESCE(f.received, 0) - COALESCE(s.sent, 0),
+			COALESCE(f.received, 0),
+			COALESCE(f.num_funded, 0) - COALESCE(s.num_spent, 0),
+			$2::INT4, $3::INT8
+		FROM funded f FULL OUTER JOIN spent s ON f.address = s.address
+		ON CONFLICT (address) DO UPDATE
+		SET balance = address_balances.balance + EXCLUDED.balance,
+			total_received = address_balances.total_received + EXCLUDED.total_received,
+			num_unspent = address_balances.num_unspent + EXCLUDED.num_unspent,
+			last_active_height = EXCLUDED.last_active_height,
+			last_active_time = EXCLUDED.last_active_time;`
+
+	// SelectAddressesAboveHeight selects the addresses with rows funded or
+	// spent by the transaction rows of blocks above height $1, which are
+	// removed or reset when rewinding to that height.
+	SelectAddressesAboveHeight = `WITH txns AS (
+			SELECT id FROM transactions WHERE block_height > $1)
+		SELECT a.address FROM txns t
+		JOIN addresses a ON a.funding_tx_row_id = t.id
+		UNION
+		SELECT a.address FROM txns t
+		JOIN addresses a ON a.spending_tx_row_id = t.id;`
+
+	// SelectRichList selects the $1 addresses with the largest balances.
+	SelectRichList = `SELECT address, balance, total_received, num_unspent,
+			last_active_height, last_active_time
+		FROM address_balances
+		ORDER BY balance DESC
+		LIMIT $1;`
+
+	// SelectBalanceDistribution counts the addresses and sums their balances
+	// by order of magnitude of the balance in DCR. Bucket 0 holds balances
+	// under 1 DCR, and bucket k > 0 those from 10^(k-1) up to 10^k DCR.
+	SelectBalanceDistribution = `SELECT
+			GREATEST(FLOOR(LOG(balance::NUMERIC / 100000000))::INT4 + 1, 0) AS bucket,
+			COUNT(*), SUM(balance)
+		FROM address_balances
+		WHERE balance > 0
+		GROUP BY bucket
+		ORDER BY bucket;`
+
+	// SelectAddressActivity counts all of the addresses, those with a balance,
+	// those active since each of the times $1, $2, and $3, and those with a
+	// balance that have not been active since the time $4.
+	SelectAddressActivity = `SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE balance > 0),
+			COUNT(*) FILTER (WHERE last_active_time >= $1),
+			COUNT(*) FILTER (WHERE last_active_time >= $2),
+			COUNT(*) FILTER (WHERE last_active_time >= $3),
+			COUNT(*) FILTER (WHERE balance > 0 AND last_active_time < $4)
+		FROM address_balances;`
+
+	IndexAddressBalancesTableOnBalance = `CREATE INDEX uix_address_balances_balance
+		ON address_balances(balance DESC);`
+	DeindexAddressBalancesTableOnBalance = `DROP INDEX uix_address_balances_balance;`
+
+	// Coin days destroyed
+
+	// CreateCoinDaysDestroyedTable creates the table of the coin-days
+	// destroyed by each block: the sum over the outputs spent by the block's
+	// transactions of the value in DCR times the days since it was mined.
+	CreateCoinDaysDestroyedTable = `CREATE TABLE IF NOT EXISTS coin_days_destroyed (
+		id SERIAL PRIMARY KEY,
+		height INT4,
+		block_hash TEXT NOT NULL,
+		block_time INT8,
+		coin_days FLOAT8
+	);`
+
+	// coinDaysDestroyedSelect computes the coin-days destroyed by the blocks
+	// selected by the WHERE clause appended to it.
+	coinDaysDestroyedSelect = `SELECT b.height, b.hash, b.time,
+			COALESCE(SUM(vo.value::FLOAT8 * (b.time - ft.block_time)), 0) / 1e8 / 86400
+		FROM blocks b
+		JOIN transactions st ON st.block_hash = b.hash
+		LEFT JOIN vins vi ON vi.tx_hash = st.tx_hash AND vi.tx_tree = st.tree
+		LEFT JOIN vouts vo ON vo.tx_hash = vi.prev_tx_hash
+			AND vo.tx_index = vi.prev_tx_index AND vo.tx_tree = vi.prev_tx_tree
+		LEFT JOIN LATERAL (
+			SELECT MIN(block_time) AS block_time FROM transactions
+			WHERE tx_hash = vo.tx_hash) ft ON TRUE `
+	insertCoinDaysDestroyed0 = `INSERT INTO coin_days_destroyed (height,
+		block_hash, block_time, coin_days) `
+
+	// Insert the coin-days destroyed by the block with hash $1.
+	insertCoinDaysDestroyedRow0 = insertCoinDaysDestroyed0 + coinDaysDestroyedSelect +
+		`WHERE b.hash = $1
+		GROUP BY b.height, b.hash, b.time `
+	insertCoinDaysDestroyedRow = insertCoinDaysDestroyedRow0 + `;`
+	upsertCoinDaysDestroyedRow = insertCoinDaysDestroyedRow0 + `ON CONFLICT (block_hash) DO UPDATE
+		SET coin_days = EXCLUDED.coin_days;`
+
+	// InsertAllCoinDaysDestroyed builds the table for all stored blocks.
+	InsertAllCoinDaysDestroyed = insertCoinDaysDestroyed0 + coinDaysDestroyedSelect +
+		`GROUP BY b.height, b.hash, b.time;`
+	DeleteCoinDaysDestroyed = `DELETE FROM coin_days_destroyed;`
+
+	// SelectCoinDaysDestroyed selects the coin-days destroyed by the blocks
+	// mined between the times $1 and $2.
+	SelectCoinDaysDestroyed = `SELECT height, block_hash, block_time, coin_days
+		FROM coin_days_destroyed
+		WHERE block_time BETWEEN $1 AND $2
+		ORDER BY height;`
+
+	IndexCoinDaysDestroyedTableOnHash = `CREATE UNIQUE INDEX uix_coin_days_destroyed_block_hash
+		ON coin_days_destroyed(block_hash);`
+	DeindexCoinDaysDestroyedTableOnHash = `DROP INDEX uix_coin_days_destroyed_block_hash;`
+
+	IndexCoinDaysDestroyedTableOnTime = `CREATE INDEX uix_coin_days_destroyed_block_time
+		ON coin_days_destroyed(block_time);`
+	DeindexCoinDaysDestroyedTableOnTime = `DROP INDEX uix_coin_days_destroyed_block_time;`
+
+	DeleteCoinDaysDestroyedDuplicateRows = `DELETE FROM coin_days_destroyed
+		WHERE id IN (SELECT id FROM (
+				SELECT id, ROW_NUMBER()
+				OVER (partition BY block_hash ORDER BY id) AS rnum
+				FROM coin_days_destroyed) t
+			WHERE t.rnum > 1);`
+)
+
+func MakeCoinDaysDestroyedInsertStatement(checked bool) string {
+	if checked {
+		return upsertCoinDaysDestroyedRow
+	}
+	return insertCoinDaysDestroyedRow
+}
@@ -56,6 +56,25 @@ const (
 	SelectTicketStatusByHash     = `SELECT id, spend_type, pool_status FROM tickets WHERE tx_hash = $1;`
 	SelectUnspentTickets         = `SELECT id, tx_hash FROM tickets WHERE spend_type = 0 OR spend_type = -1;`
 
+	// SelectImmatureTicketCounts selects the number of tickets purchased in
+	// each block above height $1.
+	SelectImmatureTicketCounts = `SELECT block_height, COUNT(*) FROM tickets
+		WHERE block_height > $1
+		GROUP BY block_height;`
+	// SelectLiveTicketHeightBins selects the number and total price of the
+	// live tickets purchased up to height $1, in bins of $2 blocks.
+	SelectLiveTicketHeightBins = `SELECT block_height / $2 * $2 AS bin, COUNT(*), SUM(price)
+		FROM tickets
+		WHERE pool_status = 0 AND block_height <= $1
+		GROUP BY bin
+		ORDER BY bin;`
+	// SelectLiveTicketPriceCounts selects the number of live tickets purchased
+	// up to height $1 at each price.
+	SelectLiveTicketPriceCounts = `SELECT price, COUNT(*) FROM tickets
+		WHERE pool_status = 0 AND block_height <= $1
+		GROUP BY price
+		ORDER BY price;`
+
 	// Update
 	SetTicketSpendingInfoForHash = `UPDATE tickets
 		SET spend_type = $5, spend_height = $3, spend_tx_db_id = $4, pool_status = $6
@@ -197,6 +216,108 @@ const (
 				FROM misses) t
 			WHERE t.rnum > 1);`
 
+	// Vote summaries
+
+	// CreateVoteSummaryTable creates the table of per-block vote summaries.
+	// approvals and disapprovals count the votes on the validity of the
+	// regular tree of the candidate (previous) block, and candidate_valid is
+	// the outcome recorded in the block header's vote bits.
+	CreateVoteSummaryTable = `CREATE TABLE IF NOT EXISTS vote_summary (
+		id SERIAL PRIMARY KEY,
+		height INT4,
+		block_hash TEXT NOT NULL,
+		block_time INT8,
+		candidate_block_hash TEXT NOT NULL,
+		votes INT2,
+		approvals INT2,
+		disapprovals INT2,
+		misses INT2,
+		candidate_valid BOOLEAN
+	);`
+
+	// Insert
+	insertVoteSummaryRow0 = `INSERT INTO vote_summary (
+		height, block_hash, block_time, candidate_block_hash,
+		votes, approvals, disapprovals, misses, candidate_valid)
+	VALUES (
+		$1, $2, $3, $4,
+		$5, $6, $7, $8, $9) `
+	insertVoteSummaryRow = insertVoteSummaryRow0 + `;`
+	upsertVoteSummaryRow = insertVoteSummaryRow0 + `ON CONFLICT (block_hash) DO UPDATE
+		SET votes = $5, approvals = $6, disapprovals = $7, misses = $8,
+			candidate_valid = $9;`
+
+	// InsertMissingVoteSummaries adds the vote summaries of stored blocks
+	// without one from the votes and misses tables.
+	InsertMissingVoteSummaries = `INSERT INTO vote_summary (
+		height, block_hash, block_time, candidate_block_hash,
+		votes, approvals, disapprovals, misses, candidate_valid)
+	SELECT b.height, b.hash, b.time, b.previous_hash,
+		COALESCE(v.votes, 0), COALESCE(v.approvals, 0),
+		COALESCE(v.disapprovals, 0), COALESCE(m.misses, 0),
+		(b.vote_bits & 1) <> 0
+	FROM blocks b
+	LEFT JOIN (
+		SELECT block_hash, COUNT(*) AS votes,
+			COUNT(*) FILTER (WHERE block_valid) AS approvals,
+			COUNT(*) FILTER (WHERE NOT block_valid) AS disapprovals
+		FROM votes GROUP BY block_hash) v ON v.block_hash = b.hash
+	LEFT JOIN (
+		SELECT block_hash, COUNT(*) AS misses
+		FROM misses GROUP BY block_hash) m ON m.block_hash = b.hash
+	WHERE NOT EXISTS (
+		SELECT 1 FROM vote_summary s WHERE s.block_hash = b.hash);`
+
+	// SelectVoteParticipation aggregates the vote summaries of the blocks at
+	// or above height $4 with times in [$2, $3] by the time interval $1 (e.g.
+	// 'day', 'week', or 'month'), in UTC.
+	SelectVoteParticipation = `SELECT
+			EXTRACT(EPOCH FROM date_trunc($1, to_timestamp(block_time) AT TIME ZONE 'UTC'))::INT8 AS start,
+			COUNT(*), SUM(votes), SUM(misses),
+			COUNT(*) FILTER (WHERE NOT candidate_valid),
+			COUNT(*) FILTER (WHERE votes = 3),
+			COUNT(*) FILTER (WHERE votes = 4),
+			COUNT(*) FILTER (WHERE votes = 5)
+		FROM vote_summary
+		WHERE block_time >= $2 AND block_time <= $3 AND height >= $4
+		GROUP BY start
+		ORDER BY start;`
+
+	selectVoteSummaryColumns = `SELECT height, block_hash, block_time,
+		candidate_block_hash, votes, approvals, disapprovals, misses,
+		candidate_valid
+		FROM vote_summary `
+
+	// SelectVoteSummariesInvalidating selects the vote summaries of the blocks
+	// that invalidated the regular tree of their candidate block, newest first.
+	SelectVoteSummariesInvalidating = selectVoteSummaryColumns +
+		`WHERE NOT candidate_valid
+		ORDER BY height DESC
+		LIMIT $1 OFFSET $2;`
+
+	// SelectVoteSummariesFewVotes selects the vote summaries of the blocks at
+	// or above height $1 with fewer than $2 votes, newest first.
+	SelectVoteSummariesFewVotes = selectVoteSummaryColumns +
+		`WHERE height >= $1 AND votes < $2
+		ORDER BY height DESC
+		LIMIT $3 OFFSET $4;`
+
+	// Index
+	IndexVoteSummaryTableOnHash = `CREATE UNIQUE INDEX uix_vote_summary_block_hash
+		ON vote_summary(block_hash);`
+	DeindexVoteSummaryTableOnHash = `DROP INDEX uix_vote_summary_block_hash;`
+
+	IndexVoteSummaryTableOnTime = `CREATE INDEX uix_vote_summary_block_time
+		ON vote_summary(block_time);`
+	DeindexVoteSummaryTableOnTime = `DROP INDEX uix_vote_summary_block_time;`
+
+	DeleteVoteSummaryDuplicateRows = `DELETE FROM vote_summary
+		WHERE id IN (SELECT id FROM (
+				SELECT id, ROW_NUMBER()
+				OVER (partition BY block_hash ORDER BY id) AS rnum
+				FROM vote_summary) t
+			WHERE t.rnum > 1);`
+
 	// Revokes?
 )
 
@@ -220,3 +341,10 @@ func MakeMissInsertStatement(checked bool) string {
 	}
 	return insertMissRow
 }
+
+func MakeVoteSummaryInsertStatement(checked bool) string {
+	if checked {
+		return upsertVoteSummaryRow
+	}
+	return insertVoteSummaryRow
+}
@@ -0,0 +1,91 @@
+package internal
+
+const (
+	// Atomic swaps
+
+	// CreateAtomicSwapsTable creates the table of the atomic swap contract
+	// outputs and the inputs resolving them. A contract is only revealed when
+	// its output is spent, so unresolved contracts are not in the table.
+	// secret is null for a refund.
+	CreateAtomicSwapsTable = `CREATE TABLE IF NOT EXISTS swaps (
+		id SERIAL PRIMARY KEY,
+		contract_tx_hash TEXT NOT NULL,
+		contract_vout INT4,
+		contract_address TEXT,
+		contract_script BYTEA,
+		value INT8,
+		recipient_address TEXT,
+		refund_address TEXT,
+		secret_hash TEXT,
+		secret_size INT4,
+		lock_time INT8,
+		secret TEXT,
+		spend_tx_hash TEXT NOT NULL,
+		spend_vin INT4,
+		block_hash TEXT NOT NULL,
+		block_height INT4,
+		block_time INT8
+	);`
+
+	// Insert
+	insertAtomicSwapRow0 = `INSERT INTO swaps (
+		contract_tx_hash, contract_vout, contract_address, contract_script,
+		value, recipient_address, refund_address, secret_hash, secret_size,
+		lock_time, secret, spend_tx_hash, spend_vin, block_hash, block_height,
+		block_time)
+	VALUES (
+		$1, $2, $3, $4,
+		$5, $6, $7, $8, $9,
+		$10, $11, $12, $13, $14, $15,
+		$16) `
+	insertAtomicSwapRow = insertAtomicSwapRow0 + `;`
+	upsertAtomicSwapRow = insertAtomicSwapRow0 + `ON CONFLICT (spend_tx_hash, spend_vin) DO UPDATE
+		SET block_hash = $14, block_height = $15, block_time = $16;`
+
+	// Select
+
+	selectAtomicSwapColumns = `SELECT contract_tx_hash, contract_vout,
+		contract_address, contract_script, value, recipient_address,
+		refund_address, secret_hash, secret_size, lock_time, secret,
+		spend_tx_hash, spend_vin, block_hash, block_height, block_time
+		FROM swaps `
+
+	// SelectAtomicSwaps selects the $1 most recently resolved swaps, skipping
+	// the first $2.
+	SelectAtomicSwaps = selectAtomicSwapColumns +
+		`ORDER BY block_height DESC, id DESC
+		LIMIT $1 OFFSET $2;`
+
+	// SelectAtomicSwapsByTxHash selects the swaps funded or resolved by the
+	// transaction with hash $1.
+	SelectAtomicSwapsByTxHash = selectAtomicSwapColumns +
+		`WHERE contract_tx_hash = $1 OR spend_tx_hash = $1
+		ORDER BY block_height, contract_vout, spend_vin;`
+
+	// Index
+	IndexAtomicSwapsTableOnSpend = `CREATE UNIQUE INDEX uix_swaps_spend
+		ON swaps(spend_tx_hash, spend_vin);`
+	DeindexAtomicSwapsTableOnSpend = `DROP INDEX uix_swaps_spend;`
+
+	IndexAtomicSwapsTableOnContract = `CREATE INDEX uix_swaps_contract
+		ON swaps(contract_tx_hash, contract_vout);`
+	DeindexAtomicSwapsTableOnContract = `DROP INDEX uix_swaps_contract;`
+
+	IndexAtomicSwapsTableOnHeight = `CREATE INDEX uix_swaps_block_height
+		ON swaps(block_height);`
+	DeindexAtomicSwapsTableOnHeight = `DROP INDEX uix_swaps_block_height;`
+
+	DeleteAtomicSwapsDuplicateRows = `DELETE FROM swaps
+		WHERE id IN (SELECT id FROM (
+				SELECT id, ROW_NUMBER()
+				OVER (partition BY spend_tx_hash, spend_vin ORDER BY id) AS rnum
+				FROM swaps) t
+			WHERE t.rnum > 1);`
+)
+
+func MakeAtomicSwapInsertStatement(checked bool) string {
+	if checked {
+		return upsertAtomicSwapRow
+	}
+	return insertAtomicSwapRow
+}
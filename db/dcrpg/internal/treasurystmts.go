@@ -0,0 +1,191 @@
+package internal
+
+const (
+	// Treasury
+
+	// CreateTreasuryTable creates the ledger of the transactions paying to or
+	// spending from the development subsidy address. received and sent are the
+	// amounts paid to and spent from the address by the transaction, and
+	// balance is the address balance after it. funding_vouts are the indexes
+	// of the outputs paying to the address, and spent_outpoints the spent
+	// outpoints (hash:index) of the address. destinations and
+	// destination_values are the other outputs of spending transactions.
+	// is_valid is false for the regular transactions of a block that were
+	// disapproved by the votes of the next block.
+	CreateTreasuryTable = `CREATE TABLE IF NOT EXISTS treasury (
+		id SERIAL PRIMARY KEY,
+		tx_hash TEXT NOT NULL,
+		block_hash TEXT NOT NULL,
+		block_height INT4,
+		block_time INT8,
+		tx_type INT2,
+		received INT8,
+		sent INT8,
+		balance INT8,
+		funding_vouts INT4[],
+		spent_outpoints TEXT[],
+		destinations TEXT[],
+		destination_values INT8[],
+		is_valid BOOLEAN NOT NULL DEFAULT TRUE
+	);`
+
+	// AddTreasuryValidColumn adds the is_valid column to a treasury table
+	// created without it.
+	AddTreasuryValidColumn = `ALTER TABLE treasury
+		ADD COLUMN IF NOT EXISTS is_valid BOOLEAN NOT NULL DEFAULT TRUE;`
+
+	// Insert
+	insertTreasuryRow0 = `INSERT INTO treasury (
+		tx_hash, block_hash, block_height, block_time, tx_type,
+		received, sent, balance, funding_vouts, spent_outpoints,
+		destinations, destination_values)
+	VALUES (
+		$1, $2, $3, $4, $5,
+		$6, $7, $8, $9, $10,
+		$11, $12) `
+	insertTreasuryRow = insertTreasuryRow0 + `;`
+	upsertTreasuryRow = insertTreasuryRow0 + `ON CONFLICT (tx_hash, block_hash) DO UPDATE
+		SET tx_type = $5, received = $6, sent = $7, balance = $8,
+			funding_vouts = $9, spent_outpoints = $10,
+			destinations = $11, destination_values = $12;`
+
+	// InsertTreasuryFromAddresses builds the ledger of the development subsidy
+	// address ($1) from the addresses, transactions, and vouts tables. $2, $3,
+	// and $4 are the subsidy, receipt, and spend transaction types. The regular
+	// transactions of disapproved blocks are skipped.
+	InsertTreasuryFromAddresses = `WITH dev AS (
+			SELECT funding_tx_hash, funding_tx_vout_index, value, spending_tx_hash
+			FROM addresses WHERE address = $1),
+		recv AS (
+			SELECT funding_tx_hash AS tx_hash, SUM(value) AS received,
+				array_agg(funding_tx_vout_index::INT4 ORDER BY funding_tx_vout_index) AS vouts
+			FROM dev GROUP BY funding_tx_hash),
+		spent AS (
+			SELECT spending_tx_hash AS tx_hash, SUM(value) AS sent,
+				array_agg(funding_tx_hash || ':' || funding_tx_vout_index) AS outpoints
+			FROM dev WHERE spending_tx_hash IS NOT NULL GROUP BY spending_tx_hash),
+		flows AS (
+			SELECT COALESCE(recv.tx_hash, spent.tx_hash) AS tx_hash,
+				COALESCE(recv.received, 0) AS received,
+				COALESCE(spent.sent, 0) AS sent,
+				COALESCE(recv.vouts, '{}') AS vouts,
+				COALESCE(spent.outpoints, '{}') AS outpoints
+			FROM recv FULL OUTER JOIN spent ON recv.tx_hash = spent.tx_hash),
+		txns AS (
+			SELECT DISTINCT ON (t.tx_hash) t.tx_hash, t.block_hash,
+				t.block_height, t.block_time, t.tree, t.block_index
+			FROM transactions t
+			JOIN blocks b ON b.hash = t.block_hash
+			WHERE t.tx_hash IN (SELECT tx_hash FROM flows)
+				AND (t.tree <> 0 OR b.is_valid)
+			ORDER BY t.tx_hash, t.block_height)
+	INSERT INTO treasury (
+		tx_hash, block_hash, block_height, block_time, tx_type,
+		received, sent, balance, funding_vouts, spent_outpoints,
+		destinations, destination_values)
+	SELECT f.tx_hash, t.block_hash, t.block_height, t.block_time,
+		CASE
+			WHEN f.sent > 0 THEN $4::INT2
+			WHEN t.tree = 0 AND t.block_index = 0 THEN $2::INT2
+			ELSE $3::INT2
+		END,
+		f.received, f.sent,
+		SUM(f.received - f.sent) OVER (
+			ORDER BY t.block_height, t.tree, t.block_index),
+		f.vouts, f.outpoints,
+		COALESCE((SELECT array_agg(COALESCE(v.script_addresses[1], '') ORDER BY v.tx_index)
+			FROM vouts v
+			WHERE f.sent > 0 AND v.tx_hash = f.tx_hash
+				AND NOT COALESCE($1 = ANY(v.script_addresses), FALSE)), '{}'),
+		COALESCE((SELECT array_agg(v.value ORDER BY v.tx_index)
+			FROM vouts v
+			WHERE f.sent > 0 AND v.tx_hash = f.tx_hash
+				AND NOT COALESCE($1 = ANY(v.script_addresses), FALSE)), '{}')
+	FROM flows f
+	JOIN txns t ON t.tx_hash = f.tx_hash
+	ORDER BY t.block_height, t.tree, t.block_index;`
+
+	DeleteTreasuryRows = `DELETE FROM treasury;`
+
+	// InvalidateTreasuryRegularTxns flags the rows of the regular transactions
+	// of the block ($1) as invalid, returning their hashes, net amounts
+	// received, funding vouts, and spent outpoints.
+	InvalidateTreasuryRegularTxns = `UPDATE treasury SET is_valid = FALSE
+		WHERE block_hash = $1 AND is_valid
+			AND tx_hash IN (SELECT tx_hash FROM transactions
+				WHERE block_hash = $1 AND tree = 0)
+		RETURNING tx_hash, received - sent, funding_vouts, spent_outpoints;`
+
+	// AdjustTreasuryBlockBalances adds $2 to the balances of the valid rows of
+	// the block ($1).
+	AdjustTreasuryBlockBalances = `UPDATE treasury SET balance = balance + $2
+		WHERE block_hash = $1 AND is_valid;`
+
+	// Select, from the valid rows of the ledger only
+
+	SelectTreasuryBalance = `SELECT balance FROM treasury WHERE is_valid
+		ORDER BY id DESC LIMIT 1;`
+
+	// SelectTreasuryUnspent selects the outpoints (hash:index) paying to the
+	// address that are not yet spent.
+	SelectTreasuryUnspent = `SELECT tx_hash || ':' || unnest(funding_vouts) FROM treasury
+		WHERE is_valid
+		EXCEPT
+		SELECT unnest(spent_outpoints) FROM treasury WHERE is_valid;`
+
+	// SelectTreasuryTotals selects the total amounts received from the
+	// subsidy ($1) and otherwise, and the total net amount spent by spending
+	// transactions ($2).
+	SelectTreasuryTotals = `SELECT
+			COALESCE(SUM(received) FILTER (WHERE tx_type = $1), 0),
+			COALESCE(SUM(received - sent) FILTER (WHERE tx_type <> $1 AND tx_type <> $2), 0),
+			COALESCE(SUM(sent - received) FILTER (WHERE tx_type = $2), 0)
+		FROM treasury
+		WHERE is_valid;`
+
+	// SelectTreasuryMonths aggregates the ledger by month (UTC), with the
+	// subsidy ($1), other receipts, and the net amounts spent by spending
+	// transactions ($2), and the balance at the end of the month.
+	SelectTreasuryMonths = `SELECT
+			EXTRACT(EPOCH FROM date_trunc('month', to_timestamp(block_time) AT TIME ZONE 'UTC'))::INT8 AS month,
+			COALESCE(SUM(received) FILTER (WHERE tx_type = $1), 0),
+			COALESCE(SUM(received - sent) FILTER (WHERE tx_type <> $1 AND tx_type <> $2), 0),
+			COALESCE(SUM(sent - received) FILTER (WHERE tx_type = $2), 0),
+			COUNT(*) FILTER (WHERE tx_type = $2),
+			(array_agg(balance ORDER BY id DESC))[1]
+		FROM treasury
+		WHERE is_valid
+		GROUP BY month
+		ORDER BY month;`
+
+	selectTreasuryColumns = `SELECT tx_hash, block_hash, block_height,
+		block_time, tx_type, received, sent, balance, destinations,
+		destination_values
+		FROM treasury `
+
+	// SelectTreasuryLargestSpends selects the spending transactions ($1) with
+	// the largest net amounts spent.
+	SelectTreasuryLargestSpends = selectTreasuryColumns +
+		`WHERE tx_type = $1 AND is_valid
+		ORDER BY sent - received DESC
+		LIMIT $2;`
+
+	// Index
+	IndexTreasuryTableOnHashes = `CREATE UNIQUE INDEX uix_treasury_hashes_index
+		ON treasury(tx_hash, block_hash);`
+	DeindexTreasuryTableOnHashes = `DROP INDEX uix_treasury_hashes_index;`
+
+	DeleteTreasuryDuplicateRows = `DELETE FROM treasury
+		WHERE id IN (SELECT id FROM (
+				SELECT id, ROW_NUMBER()
+				OVER (partition BY tx_hash, block_hash ORDER BY id) AS rnum
+				FROM treasury) t
+			WHERE t.rnum > 1);`
+)
+
+func MakeTreasuryInsertStatement(checked bool) string {
+	if checked {
+		return upsertTreasuryRow
+	}
+	return insertTreasuryRow
+}
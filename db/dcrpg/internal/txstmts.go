@@ -12,12 +12,12 @@ const (
 		block_hash, block_height, block_time, time,
 		tx_type, version, tree, tx_hash, block_index, 
 		lock_time, expiry, size, spent, sent, fees, 
-		num_vin, vin_db_ids, num_vout, vout_db_ids)
+		num_vin, vin_db_ids, num_vout, vout_db_ids, tx_class)
 	VALUES (
 		$1, $2, $3, $4, 
 		$5, $6, $7, $8, $9,
 		$10, $11, $12, $13, $14, $15,
-		$16, $17, $18, $19) `
+		$16, $17, $18, $19, $20) `
 	insertTxRow = insertTxRow0 + `RETURNING id;`
 	//insertTxRowChecked = insertTxRow0 + `ON CONFLICT (tx_hash, block_hash) DO NOTHING RETURNING id;`
 	upsertTxRow = insertTxRow0 + `ON CONFLICT (tx_hash, block_hash) DO UPDATE 
@@ -55,7 +55,8 @@ const (
 		num_vin INT4,
 		vin_db_ids INT8[],
 		num_vout INT4,
-		vout_db_ids INT8[]
+		vout_db_ids INT8[],
+		tx_class INT4 DEFAULT 0
 	);`
 
 	SelectTxByHash       = `SELECT id, block_hash, block_index, tree FROM transactions WHERE tx_hash = $1;`
@@ -102,6 +103,60 @@ const (
 			FROM transactions) t
 		WHERE t.rnum > 1);`
 
+	// Transaction classes, as a bit set of txhelpers.TxClass.
+
+	// AddTxClassColumn adds the tx_class column to a transactions table
+	// created before it.
+	AddTxClassColumn = `ALTER TABLE transactions ADD COLUMN tx_class INT4 DEFAULT 0;`
+
+	// SelectRegularTxStructure selects the ID, number of inputs, and output
+	// values of each non-coinbase regular transaction, for classification.
+	SelectRegularTxStructure = `SELECT t.id, t.num_vin,
+			ARRAY(SELECT vo.value FROM vouts vo
+				WHERE vo.id = ANY(t.vout_db_ids) ORDER BY vo.tx_index)
+		FROM transactions t
+		WHERE t.tree = 0 AND t.block_index > 0;`
+
+	// SetTxClasses sets the classes of the transactions with the IDs in the
+	// array $1 to those in the array $2.
+	SetTxClasses = `UPDATE transactions SET tx_class = c.tx_class
+		FROM unnest($1::INT8[], $2::INT4[]) AS c(id, tx_class)
+		WHERE transactions.id = c.id;`
+
+	// AddTxClassByHash adds the class $2 to the regular transactions with
+	// hashes in the array $1.
+	AddTxClassByHash = `UPDATE transactions SET tx_class = tx_class | $2
+		WHERE tree = 0 AND tx_hash = ANY($1);`
+
+	// AddTxClassSplitTicketFunding adds the class $1 to the regular
+	// transactions funding the stored split tickets.
+	AddTxClassSplitTicketFunding = `UPDATE transactions SET tx_class = tx_class | $1
+		WHERE tree = 0 AND tx_hash IN (
+			SELECT vi.prev_tx_hash FROM tickets tk
+			JOIN vins vi ON vi.tx_hash = tk.tx_hash AND vi.tx_tree = 1
+			WHERE tk.is_split);`
+
+	SelectTxClassByHash = `SELECT tx_class FROM transactions
+		WHERE tx_hash = $1 ORDER BY block_height DESC LIMIT 1;`
+
+	SelectBlockTxClasses = `SELECT tx_hash, tx_class FROM transactions
+		WHERE block_hash = $1 AND tx_class <> 0;`
+
+	// SelectTxnsByClass selects the $2 most recent transactions with any of
+	// the classes $1, skipping the first $3.
+	SelectTxnsByClass = `SELECT tx_hash, block_hash, block_height, block_time,
+			tx_class, num_vin, num_vout, sent, fees
+		FROM transactions
+		WHERE tx_class <> 0 AND tx_class & $1 <> 0
+		ORDER BY block_height DESC, block_index
+		LIMIT $2 OFFSET $3;`
+
+	// IndexTransactionTableOnClass creates a partial index of the classified
+	// transactions, which are a small fraction of them.
+	IndexTransactionTableOnClass = `CREATE INDEX uix_tx_class
+		ON transactions(block_height DESC, block_index) WHERE tx_class <> 0;`
+	DeindexTransactionTableOnClass = `DROP INDEX uix_tx_class;`
+
 	RetrieveVoutDbIDs = `SELECT unnest(vout_db_ids) FROM transactions WHERE id = $1;`
 	RetrieveVoutDbID  = `SELECT vout_db_ids[$2] FROM transactions WHERE id = $1;`
 )
@@ -0,0 +1,186 @@
+package internal
+
+const (
+	// UTXO set
+
+	// CreateUtxoHeightsTable creates the table of the number and total value
+	// of the unspent outputs funded at each block height, from which the UTXO
+	// set statistics are computed. Null data outputs are excluded since they
+	// cannot be spent. The table is kept current as blocks are connected, so
+	// the primary key is not dropped with the other indexes.
+	CreateUtxoHeightsTable = `CREATE TABLE IF NOT EXISTS utxo_heights (
+		height INT4 PRIMARY KEY,
+		num_utxos INT8,
+		value INT8
+	);`
+
+	// utxoFundingHeight is the lateral join giving the height of the block
+	// first mining the transaction of the output vo.
+	utxoFundingHeight = `JOIN LATERAL (
+			SELECT MIN(block_height) AS block_height FROM transactions
+			WHERE tx_hash = vo.tx_hash) ft ON TRUE `
+
+	// blockFirstTxns selects the transactions of the block with hash $1 that
+	// were not mined in an earlier block.
+	blockFirstTxns = `SELECT tx_hash, tree FROM transactions t
+		WHERE t.block_hash = $1
+			AND NOT EXISTS (SELECT 1 FROM transactions t2
+				WHERE t2.tx_hash = t.tx_hash AND t2.block_height < t.block_height)`
+
+	// InsertUtxoHeight inserts the outputs funded by the block with hash $1 at
+	// height $2.
+	InsertUtxoHeight = `WITH block_txns AS (` + blockFirstTxns + `)
+		INSERT INTO utxo_heights (height, num_utxos, value)
+		SELECT $2::INT4, COUNT(vo.id), COALESCE(SUM(vo.value), 0)
+		FROM block_txns t
+		JOIN vouts vo ON vo.tx_hash = t.tx_hash AND vo.tx_tree = t.tree
+		WHERE vo.script_type <> 'nulldata'
+		ON CONFLICT (height) DO UPDATE
+		SET num_utxos = EXCLUDED.num_utxos, value = EXCLUDED.value;`
+
+	// UpdateUtxoHeightsSpent removes the outputs spent by the block with hash
+	// $1 from the heights at which they were funded.
+	UpdateUtxoHeightsSpent = `WITH block_txns AS (` + blockFirstTxns + `),
+		spent AS (
+			SELECT ft.block_height AS height, COUNT(*) AS num_utxos,
+				SUM(vo.value) AS value
+			FROM block_txns t
+			JOIN vins vi ON vi.tx_hash = t.tx_hash AND vi.tx_tree = t.tree
+			JOIN vouts vo ON vo.tx_hash = vi.prev_tx_hash
+				AND vo.tx_index = vi.prev_tx_index AND vo.tx_tree = vi.prev_tx_tree
+			` + utxoFundingHeight + `
+			GROUP BY ft.block_height)
+		UPDATE utxo_heights u
+		SET num_utxos = u.num_utxos - spent.num_utxos,
+			value = u.value - spent.value
+		FROM spent
+		WHERE u.height = spent.height;`
+
+	// RewindUtxoHeightsSpent restores the outputs spent by the transactions of
+	// blocks above height $1 to the remaining heights at which they were
+	// funded.
+	RewindUtxoHeightsSpent = `WITH spent AS (
+			SELECT ft.block_height AS height, COUNT(*) AS num_utxos,
+				SUM(vo.value) AS value
+			FROM transactions t
+			JOIN vins vi ON vi.tx_hash = t.tx_hash AND vi.tx_tree = t.tree
+			JOIN vouts vo ON vo.tx_hash = vi.prev_tx_hash
+				AND vo.tx_index = vi.prev_tx_index AND vo.tx_tree = vi.prev_tx_tree
+			` + utxoFundingHeight + `
+			WHERE t.block_height > $1 AND ft.block_height <= $1
+				AND NOT EXISTS (SELECT 1 FROM transactions t2
+					WHERE t2.tx_hash = t.tx_hash AND t2.block_height < t.block_height)
+			GROUP BY ft.block_height)
+		UPDATE utxo_heights u
+		SET num_utxos = u.num_utxos + spent.num_utxos,
+			value = u.value + spent.value
+		FROM spent
+		WHERE u.height = spent.height;`
+
+	// InsertAllUtxoHeights builds the table from the vouts and vins tables.
+	InsertAllUtxoHeights = `INSERT INTO utxo_heights (height, num_utxos, value)
+		SELECT ft.block_height, COUNT(*), SUM(vo.value)
+		FROM vouts vo
+		` + utxoFundingHeight + `
+		WHERE vo.script_type <> 'nulldata'
+			AND NOT EXISTS (SELECT 1 FROM vins vi
+				WHERE vi.prev_tx_hash = vo.tx_hash
+					AND vi.prev_tx_index = vo.tx_index
+					AND vi.prev_tx_tree = vo.tx_tree)
+		GROUP BY ft.block_height;`
+	DeleteUtxoHeights = `DELETE FROM utxo_heights;`
+
+	// SelectUtxoSetTotals selects the number and total value of the unspent
+	// outputs.
+	SelectUtxoSetTotals = `SELECT COALESCE(SUM(num_utxos), 0), COALESCE(SUM(value), 0)
+		FROM utxo_heights;`
+
+	// SelectUtxoAgeDistribution counts the unspent outputs and sums their
+	// value by age in blocks at height $1. $2 is the array of the ages
+	// starting each bucket after the first, as for width_bucket.
+	SelectUtxoAgeDistribution = `SELECT width_bucket($1 - height, $2::INT4[]) AS bucket,
+			SUM(num_utxos), SUM(value)
+		FROM utxo_heights
+		GROUP BY bucket
+		ORDER BY bucket;`
+
+	// SelectLargestUtxos selects the $1 largest unspent outputs paying to an
+	// address.
+	SelectLargestUtxos = `SELECT funding_tx_hash, funding_tx_vout_index,
+			address, value
+		FROM addresses
+		WHERE spending_tx_row_id IS NULL
+		ORDER BY value DESC
+		LIMIT $1;`
+
+	// IndexAddressTableOnUnspentValue creates a partial index of the unspent
+	// outputs in the addresses table for selecting the largest.
+	IndexAddressTableOnUnspentValue = `CREATE INDEX uix_addresses_unspent_value
+		ON addresses(value DESC) WHERE spending_tx_row_id IS NULL;`
+	DeindexAddressTableOnUnspentValue = `DROP INDEX uix_addresses_unspent_value;`
+
+	// UTXO set snapshots
+
+	// CreateUtxoSnapshotsTable creates the table of the UTXO set statistics
+	// at every few blocks. age_counts and age_values are by age bucket, and
+	// the largest_ columns describe the largest unspent outputs.
+	CreateUtxoSnapshotsTable = `CREATE TABLE IF NOT EXISTS utxo_snapshots (
+		id SERIAL PRIMARY KEY,
+		height INT4,
+		block_hash TEXT NOT NULL,
+		block_time INT8,
+		num_utxos INT8,
+		value INT8,
+		age_counts INT8[],
+		age_values INT8[],
+		largest_tx_hashes TEXT[],
+		largest_vouts INT4[],
+		largest_addresses TEXT[],
+		largest_values INT8[]
+	);`
+
+	// Insert
+	insertUtxoSnapshotRow0 = `INSERT INTO utxo_snapshots (
+		height, block_hash, block_time, num_utxos, value, age_counts,
+		age_values, largest_tx_hashes, largest_vouts, largest_addresses,
+		largest_values)
+	VALUES (
+		$1, $2, $3, $4, $5, $6,
+		$7, $8, $9, $10,
+		$11) `
+	insertUtxoSnapshotRow = insertUtxoSnapshotRow0 + `;`
+	upsertUtxoSnapshotRow = insertUtxoSnapshotRow0 + `ON CONFLICT (block_hash) DO UPDATE
+		SET num_utxos = $4, value = $5, age_counts = $6, age_values = $7,
+			largest_tx_hashes = $8, largest_vouts = $9,
+			largest_addresses = $10, largest_values = $11;`
+
+	// SelectUtxoSnapshots selects the snapshots of the blocks mined between
+	// the times $1 and $2.
+	SelectUtxoSnapshots = `SELECT height, block_hash, block_time, num_utxos,
+			value, age_counts, age_values
+		FROM utxo_snapshots
+		WHERE block_time BETWEEN $1 AND $2
+		ORDER BY height;`
+
+	IndexUtxoSnapshotsTableOnHash = `CREATE UNIQUE INDEX uix_utxo_snapshots_block_hash
+		ON utxo_snapshots(block_hash);`
+	DeindexUtxoSnapshotsTableOnHash = `DROP INDEX uix_utxo_snapshots_block_hash;`
+
+	IndexUtxoSnapshotsTableOnTime = `CREATE INDEX uix_utxo_snapshots_block_time
+		ON utxo_snapshots(block_time);`
+	DeindexUtxoSnapshotsTableOnTime = `DROP INDEX uix_utxo_snapshots_block_time;`
+
+	DeleteUtxoSnapshotsDuplicateRows = `DELETE FROM utxo_snapshots
+		WHERE id IN (SELECT id FROM (
+				SELECT id, ROW_NUMBER()
+				OVER (partition BY block_hash ORDER BY id) AS rnum
+				FROM utxo_snapshots) t
+			WHERE t.rnum > 1);`
+)
+
+func MakeUtxoSnapshotInsertStatement(checked bool) string {
+	if checked {
+		return upsertUtxoSnapshotRow
+	}
+	return insertUtxoSnapshotRow
+}
@@ -6,7 +6,6 @@ package dcrpg
 import (
 	"bytes"
 	"database/sql"
-	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -16,12 +15,14 @@ import (
 	"github.com/decred/dcrd/chaincfg"
 	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/dcrutil"
-	"github.com/decred/dcrd/rpcclient"
 	"github.com/decred/dcrd/wire"
+	apitypes "github.com/decred/dcrdata/api/types"
 	"github.com/decred/dcrdata/blockdata"
 	"github.com/decred/dcrdata/db/dbtypes"
 	"github.com/decred/dcrdata/explorer"
+	"github.com/decred/dcrdata/rpcutils"
 	"github.com/decred/dcrdata/stakedb"
+	"github.com/decred/dcrdata/txhelpers"
 	humanize "github.com/dustin/go-humanize"
 )
 
@@ -42,13 +43,18 @@ type ChainDB struct {
 	addressCounts      *addressCounter
 	stakeDB            *stakedb.StakeDatabase
 	unspentTicketCache *TicketTxnIDGetter
+	treasury           *treasuryLedger
+	addressStats       *addressStatsCache
+	utxoStats          *utxoStatsCache
+	ticketPoolStats    *ticketPoolStatsCache
+	supply             *supplyCache
 }
 
 // ChainDBRC provides an interface for storing and manipulating extracted
 // and includes the RPC Client blockchain data in a PostgreSQL database.
 type ChainDBRPC struct {
 	ChainDB *ChainDB
-	Client  *rpcclient.Client
+	Client  rpcutils.NodeClient
 }
 
 type addressCounter struct {
@@ -167,7 +173,7 @@ func NewChainDB(dbi *DBInfo, params *chaincfg.Params, stakeDB *stakedb.StakeData
 		unspentTicketCache.SetN(unspentTicketHashes, unspentTicketDbIDs)
 	}
 
-	return &ChainDB{
+	pgb := &ChainDB{
 		db:                 db,
 		chainParams:        params,
 		devAddress:         devSubsidyAddress,
@@ -177,13 +183,24 @@ func NewChainDB(dbi *DBInfo, params *chaincfg.Params, stakeDB *stakedb.StakeData
 		addressCounts:      makeAddressCounter(),
 		stakeDB:            stakeDB,
 		unspentTicketCache: unspentTicketCache,
-	}, nil
+		addressStats:       new(addressStatsCache),
+		utxoStats:          new(utxoStatsCache),
+		ticketPoolStats:    new(ticketPoolStatsCache),
+		supply:             newSupplyCache(params),
+	}
+	if err = pgb.rememberBestBlock(); err != nil {
+		return nil, err
+	}
+	if err = pgb.reloadTreasury(); err != nil {
+		return nil, err
+	}
+	return pgb, nil
 }
 
 // NewChainDBRPC contains ChainDB and RPC client
 // parameters. By default, duplicate row checks on insertion are enabled.
 // also enables rpc client
-func NewChainDBRPC(chaindb *ChainDB, cl *rpcclient.Client) (*ChainDBRPC, error) {
+func NewChainDBRPC(chaindb *ChainDB, cl rpcutils.NodeClient) (*ChainDBRPC, error) {
 	// Connect to the PostgreSQL daemon and return the *sql.DB
 	return &ChainDBRPC{
 		chaindb,
@@ -224,8 +241,8 @@ func setupTables(db *sql.DB) error {
 }
 
 // VersionCheck checks the current version of all known tables and notifies when
-// an upgrade is required. Since there is presently no automatic upgrade, an
-// error is returned when any table is not of the correct version.
+// an upgrade is required. An error is returned when any table is not of the
+// correct version, so UpgradeTables should be run first.
 func (pgb *ChainDB) VersionCheck() error {
 	vers := TableVersions(pgb.db)
 	for tab, ver := range vers {
@@ -256,6 +273,67 @@ func (pgb *ChainDB) Height() uint64 {
 	return uint64(pgb.bestBlock)
 }
 
+// Checkpoint returns the height and hash of the best block in the DB. Since
+// each block is stored in a single DB transaction, all data up to and including
+// this block is present. The height is -1 if the DB has no blocks.
+func (pgb *ChainDB) Checkpoint() (int64, string, error) {
+	height, hash, _, err := RetrieveBestBlockHeight(pgb.db)
+	if err == sql.ErrNoRows {
+		return -1, "", nil
+	}
+	return int64(height), hash, err
+}
+
+// rememberBestBlock records the DB row ID of the best block so that its next
+// block hash may be set when the next block is stored.
+func (pgb *ChainDB) rememberBestBlock() error {
+	_, hashStr, blockDbID, err := RetrieveBestBlockHeight(pgb.db)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	hash, err := chainhash.NewHashFromStr(hashStr)
+	if err != nil {
+		return err
+	}
+	pgb.lastBlock[*hash] = blockDbID
+	return nil
+}
+
+// RewindToHeight removes the data for all blocks above the specified height,
+// undoing their effect on the spending info of earlier outputs and tickets.
+func (pgb *ChainDB) RewindToHeight(height int64) error {
+	ticketExpiryBlocks := int64(pgb.chainParams.TicketMaturity) +
+		int64(pgb.chainParams.TicketExpiry)
+	numBlocks, err := DeleteBlocksAboveHeight(pgb.db, height, ticketExpiryBlocks)
+	if err != nil {
+		return err
+	}
+	log.Infof("Removed %d blocks above height %d.", numBlocks, height)
+
+	// Reset the in-memory state that depended on the removed blocks.
+	pgb.bestBlock = height
+	pgb.lastBlock = make(map[chainhash.Hash]uint64)
+	if err = pgb.rememberBestBlock(); err != nil {
+		return err
+	}
+	pgb.addressCounts.Lock()
+	pgb.addressCounts.validHeight = height
+	pgb.addressCounts.balance = map[string]explorer.AddressBalance{}
+	pgb.addressCounts.Unlock()
+
+	unspentTicketCache := NewTicketTxnIDGetter(pgb.db)
+	unspentTicketDbIDs, unspentTicketHashes, err := RetrieveUnspentTickets(pgb.db)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	unspentTicketCache.SetN(unspentTicketHashes, unspentTicketDbIDs)
+	pgb.unspentTicketCache = unspentTicketCache
+	return pgb.reloadTreasury()
+}
+
 // SpendingTransactions retrieves all transactions spending outpoints from the
 // specified funding transaction. The spending transaction hashes, the spending
 // tx input indexes, and the corresponding funding tx output indexes, and an
@@ -287,6 +365,35 @@ func (pgb *ChainDB) BlockMissedVotes(blockHash string) ([]string, error) {
 	return RetrieveMissedVotesInBlock(pgb.db, blockHash)
 }
 
+// BlocksByInterval retrieves the aggregate data of the blocks mined between
+// the UNIX times from and to, by time interval ("day", "week", or "month"),
+// newest first.
+func (pgb *ChainDB) BlocksByInterval(interval string, from, to int64) ([]dbtypes.BlocksInterval, error) {
+	return RetrieveBlocksByInterval(pgb.db, interval, from, to)
+}
+
+// VoteParticipation retrieves the vote participation in the blocks mined
+// between the UNIX times from and to, aggregated by the time interval ("day",
+// "week", or "month"). Blocks before stake validation height are excluded.
+func (pgb *ChainDB) VoteParticipation(interval string, from, to int64) ([]apitypes.VoteParticipation, error) {
+	return RetrieveVoteParticipation(pgb.db, interval, from, to,
+		pgb.chainParams.StakeValidationHeight)
+}
+
+// InvalidatingBlocks retrieves the vote summaries of the blocks in which the
+// votes invalidated the regular tree of the previous block, newest first.
+func (pgb *ChainDB) InvalidatingBlocks(N, offset int64) ([]*dbtypes.VoteSummary, error) {
+	return RetrieveVoteSummariesInvalidating(pgb.db, N, offset)
+}
+
+// IncompleteVoteBlocks retrieves the vote summaries of the blocks with fewer
+// than the maximum number of votes per block, newest first.
+func (pgb *ChainDB) IncompleteVoteBlocks(N, offset int64) ([]*dbtypes.VoteSummary, error) {
+	return RetrieveVoteSummariesFewVotes(pgb.db,
+		pgb.chainParams.StakeValidationHeight, pgb.chainParams.TicketsPerBlock,
+		N, offset)
+}
+
 // PoolStatusForTicket retrieves the specified ticket's spend status and ticket
 // pool status, and an error value.
 func (pgb *ChainDB) PoolStatusForTicket(txid string) (dbtypes.TicketSpendType, dbtypes.TicketPoolStatus, error) {
@@ -541,6 +648,41 @@ func (pgb *ChainDB) DeleteDuplicatesRecovery() error {
 	}
 	log.Infof("Removed %d duplicate misses entries.", numTxnsRemoved)
 
+	// Remove duplicate vote summaries
+	log.Info("Finding and removing duplicate vote_summary entries before indexing...")
+	if numTxnsRemoved, err = pgb.DeleteDuplicateVoteSummaries(); err != nil {
+		return fmt.Errorf("dcrpg.DeleteDuplicateVoteSummaries failed: %v", err)
+	}
+	log.Infof("Removed %d duplicate vote_summary entries.", numTxnsRemoved)
+
+	// Remove duplicate treasury transactions
+	log.Info("Finding and removing duplicate treasury entries before indexing...")
+	if numTxnsRemoved, err = pgb.DeleteDuplicateTreasuryTxns(); err != nil {
+		return fmt.Errorf("dcrpg.DeleteDuplicateTreasuryTxns failed: %v", err)
+	}
+	log.Infof("Removed %d duplicate treasury entries.", numTxnsRemoved)
+
+	// Remove duplicate coin days destroyed
+	log.Info("Finding and removing duplicate coin_days_destroyed entries before indexing...")
+	if numTxnsRemoved, err = pgb.DeleteDuplicateCoinDaysDestroyed(); err != nil {
+		return fmt.Errorf("dcrpg.DeleteDuplicateCoinDaysDestroyed failed: %v", err)
+	}
+	log.Infof("Removed %d duplicate coin_days_destroyed entries.", numTxnsRemoved)
+
+	// Remove duplicate UTXO set snapshots
+	log.Info("Finding and removing duplicate utxo_snapshots entries before indexing...")
+	if numTxnsRemoved, err = pgb.DeleteDuplicateUtxoSnapshots(); err != nil {
+		return fmt.Errorf("dcrpg.DeleteDuplicateUtxoSnapshots failed: %v", err)
+	}
+	log.Infof("Removed %d duplicate utxo_snapshots entries.", numTxnsRemoved)
+
+	// Remove duplicate atomic swaps
+	log.Info("Finding and removing duplicate swaps entries before indexing...")
+	if numTxnsRemoved, err = pgb.DeleteDuplicateAtomicSwaps(); err != nil {
+		return fmt.Errorf("dcrpg.DeleteDuplicateAtomicSwaps failed: %v", err)
+	}
+	log.Infof("Removed %d duplicate swaps entries.", numTxnsRemoved)
+
 	return err
 }
 
@@ -568,6 +710,26 @@ func (pgb *ChainDB) DeleteDuplicateMisses() (int64, error) {
 	return DeleteDuplicateMisses(pgb.db)
 }
 
+func (pgb *ChainDB) DeleteDuplicateVoteSummaries() (int64, error) {
+	return DeleteDuplicateVoteSummaries(pgb.db)
+}
+
+func (pgb *ChainDB) DeleteDuplicateTreasuryTxns() (int64, error) {
+	return DeleteDuplicateTreasuryTxns(pgb.db)
+}
+
+func (pgb *ChainDB) DeleteDuplicateCoinDaysDestroyed() (int64, error) {
+	return DeleteDuplicateCoinDaysDestroyed(pgb.db)
+}
+
+func (pgb *ChainDB) DeleteDuplicateUtxoSnapshots() (int64, error) {
+	return DeleteDuplicateUtxoSnapshots(pgb.db)
+}
+
+func (pgb *ChainDB) DeleteDuplicateAtomicSwaps() (int64, error) {
+	return DeleteDuplicateAtomicSwaps(pgb.db)
+}
+
 // DeindexAll drops all of the indexes in all tables
 func (pgb *ChainDB) DeindexAll() error {
 	var err, errAny error
@@ -575,6 +737,10 @@ func (pgb *ChainDB) DeindexAll() error {
 		warnUnlessNotExists(err)
 		errAny = err
 	}
+	if err = DeindexBlockTableOnTime(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
 	if err = DeindexTransactionTableOnHashes(pgb.db); err != nil {
 		warnUnlessNotExists(err)
 		errAny = err
@@ -583,6 +749,10 @@ func (pgb *ChainDB) DeindexAll() error {
 		warnUnlessNotExists(err)
 		errAny = err
 	}
+	if err = DeindexTransactionTableOnClass(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
 	if err = DeindexVinTableOnVins(pgb.db); err != nil {
 		warnUnlessNotExists(err)
 		errAny = err
@@ -631,6 +801,50 @@ func (pgb *ChainDB) DeindexAll() error {
 		warnUnlessNotExists(err)
 		errAny = err
 	}
+	if err = DeindexVoteSummaryTableOnHash(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
+	if err = DeindexVoteSummaryTableOnTime(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
+	if err = DeindexTreasuryTableOnHashes(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
+	if err = DeindexAddressBalancesTableOnBalance(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
+	if err = DeindexCoinDaysDestroyedTableOnHash(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
+	if err = DeindexCoinDaysDestroyedTableOnTime(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
+	if err = DeindexUtxoSnapshotsTableOnHash(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
+	if err = DeindexUtxoSnapshotsTableOnTime(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
+	if err = DeindexAtomicSwapsTableOnSpend(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
+	if err = DeindexAtomicSwapsTableOnContract(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
+	if err = DeindexAtomicSwapsTableOnHeight(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
 	return errAny
 }
 
@@ -640,6 +854,10 @@ func (pgb *ChainDB) IndexAll() error {
 	if err := IndexBlockTableOnHash(pgb.db); err != nil {
 		return err
 	}
+	log.Infof("Indexing blocks table on time...")
+	if err := IndexBlockTableOnTime(pgb.db); err != nil {
+		return err
+	}
 	log.Infof("Indexing transactions table on tx/block hashes...")
 	if err := IndexTransactionTableOnHashes(pgb.db); err != nil {
 		return err
@@ -648,6 +866,10 @@ func (pgb *ChainDB) IndexAll() error {
 	if err := IndexTransactionTableOnBlockIn(pgb.db); err != nil {
 		return err
 	}
+	log.Infof("Indexing transactions table on class...")
+	if err := IndexTransactionTableOnClass(pgb.db); err != nil {
+		return err
+	}
 	log.Infof("Indexing vins table on txin...")
 	if err := IndexVinTableOnVins(pgb.db); err != nil {
 		return err
@@ -680,6 +902,50 @@ func (pgb *ChainDB) IndexAll() error {
 	if err := IndexMissesTableOnHashes(pgb.db); err != nil {
 		return err
 	}
+	log.Infof("Indexing vote_summary table on block hash...")
+	if err := IndexVoteSummaryTableOnHash(pgb.db); err != nil {
+		return err
+	}
+	log.Infof("Indexing vote_summary table on block time...")
+	if err := IndexVoteSummaryTableOnTime(pgb.db); err != nil {
+		return err
+	}
+	log.Infof("Indexing treasury table on tx/block hashes...")
+	if err := IndexTreasuryTableOnHashes(pgb.db); err != nil {
+		return err
+	}
+	log.Infof("Indexing address_balances table on balance...")
+	if err := IndexAddressBalancesTableOnBalance(pgb.db); err != nil {
+		return err
+	}
+	log.Infof("Indexing coin_days_destroyed table on block hash...")
+	if err := IndexCoinDaysDestroyedTableOnHash(pgb.db); err != nil {
+		return err
+	}
+	log.Infof("Indexing coin_days_destroyed table on block time...")
+	if err := IndexCoinDaysDestroyedTableOnTime(pgb.db); err != nil {
+		return err
+	}
+	log.Infof("Indexing utxo_snapshots table on block hash...")
+	if err := IndexUtxoSnapshotsTableOnHash(pgb.db); err != nil {
+		return err
+	}
+	log.Infof("Indexing utxo_snapshots table on block time...")
+	if err := IndexUtxoSnapshotsTableOnTime(pgb.db); err != nil {
+		return err
+	}
+	log.Infof("Indexing swaps table on spending input...")
+	if err := IndexAtomicSwapsTableOnSpend(pgb.db); err != nil {
+		return err
+	}
+	log.Infof("Indexing swaps table on contract output...")
+	if err := IndexAtomicSwapsTableOnContract(pgb.db); err != nil {
+		return err
+	}
+	log.Infof("Indexing swaps table on block height...")
+	if err := IndexAtomicSwapsTableOnHeight(pgb.db); err != nil {
+		return err
+	}
 	// Not indexing the address table on vout ID or address here. See
 	// IndexAddressTable to create those indexes.
 	log.Infof("Indexing addresses table on funding tx hash...")
@@ -719,18 +985,22 @@ func warnUnlessNotExists(err error) {
 }
 
 // IndexAddressTable creates the indexes on the address table on the vout ID and
-// address columns, separately.
+// address columns, separately, and on the value of the unspent outputs.
 func (pgb *ChainDB) IndexAddressTable() error {
 	log.Infof("Indexing addresses table on address...")
 	if err := IndexAddressTableOnAddress(pgb.db); err != nil {
 		return err
 	}
 	log.Infof("Indexing addresses table on vout Db ID...")
-	return IndexAddressTableOnVoutID(pgb.db)
+	if err := IndexAddressTableOnVoutID(pgb.db); err != nil {
+		return err
+	}
+	log.Infof("Indexing addresses table on unspent value...")
+	return IndexAddressTableOnUnspentValue(pgb.db)
 }
 
-// DeindexAddressTable drops the vin ID and address column indexes for the
-// address table.
+// DeindexAddressTable drops the vin ID, address, and unspent value indexes for
+// the address table.
 func (pgb *ChainDB) DeindexAddressTable() error {
 	var errAny error
 	if err := DeindexAddressTableOnAddress(pgb.db); err != nil {
@@ -741,6 +1011,10 @@ func (pgb *ChainDB) DeindexAddressTable() error {
 		warnUnlessNotExists(err)
 		errAny = err
 	}
+	if err := DeindexAddressTableOnUnspentValue(pgb.db); err != nil {
+		warnUnlessNotExists(err)
+		errAny = err
+	}
 	return errAny
 }
 
@@ -782,88 +1056,167 @@ func (pgb *ChainDB) StoreBlock(msgBlock *wire.MsgBlock, winningTickets []string,
 		Validators:     winners,
 	}
 
+	// All of the block's data is stored in a single DB transaction so that an
+	// interrupted or failed StoreBlock leaves no partial block in the tables.
+	dbtx, err := pgb.db.Begin()
+	if err != nil {
+		err = fmt.Errorf("unable to begin database transaction: %v", err)
+		return
+	}
+	rollback := func() {
+		if errRoll := dbtx.Rollback(); errRoll != nil {
+			log.Errorf("Rollback failed: %v", errRoll)
+		}
+	}
+
 	// Extract transactions and their vouts, and insert vouts into their pg table,
 	// returning their DB PKs, which are stored in the corresponding transaction
 	// data struct. Insert each transaction once they are updated with their
 	// vouts' IDs, returning the transaction PK ID, which are stored in the
-	// containing block data struct.
+	// containing block data struct. The regular and stake trees share the DB
+	// transaction, so they are stored in turn.
 
 	// regular transactions
-	resChanReg := make(chan storeTxnsResult)
-	go func() {
-		resChanReg <- pgb.storeTxns(MsgBlockPG, wire.TxTreeRegular,
-			pgb.chainParams, &dbBlock.TxDbIDs, updateAddressesSpendingInfo,
-			updateTicketsSpendingInfo)
-	}()
+	resReg := pgb.storeTxns(dbtx, MsgBlockPG, wire.TxTreeRegular,
+		pgb.chainParams, &dbBlock.TxDbIDs, updateAddressesSpendingInfo,
+		updateTicketsSpendingInfo)
+	if resReg.err != nil {
+		rollback()
+		err = resReg.err
+		return
+	}
 
 	// stake transactions
-	resChanStake := make(chan storeTxnsResult)
-	go func() {
-		resChanStake <- pgb.storeTxns(MsgBlockPG, wire.TxTreeStake,
-			pgb.chainParams, &dbBlock.STxDbIDs, updateAddressesSpendingInfo,
-			updateTicketsSpendingInfo)
-	}()
-
-	errReg := <-resChanReg
-	errStk := <-resChanStake
-	if errStk.err != nil {
-		if errReg.err == nil {
-			err = errStk.err
-			numVins = errReg.numVins
-			numVouts = errReg.numVouts
-			return
-		}
-		err = errors.New(errReg.Error() + ", " + errStk.Error())
-		return
-	} else if errReg.err != nil {
-		err = errReg.err
-		numVins = errStk.numVins
-		numVouts = errStk.numVouts
+	resStk := pgb.storeTxns(dbtx, MsgBlockPG, wire.TxTreeStake,
+		pgb.chainParams, &dbBlock.STxDbIDs, updateAddressesSpendingInfo,
+		updateTicketsSpendingInfo)
+	if resStk.err != nil {
+		rollback()
+		err = resStk.err
 		return
 	}
 
-	numVins = errStk.numVins + errReg.numVins
-	numVouts = errStk.numVouts + errReg.numVouts
-
 	// Store the block now that it has all it's transaction PK IDs
 	var blockDbID uint64
-	blockDbID, err = InsertBlock(pgb.db, dbBlock, isValid, pgb.dupChecks)
+	blockDbID, err = InsertBlock(dbtx, dbBlock, isValid, pgb.dupChecks)
 	if err != nil {
 		log.Error("InsertBlock:", err)
+		rollback()
 		return
 	}
-	pgb.lastBlock[msgBlock.BlockHash()] = blockDbID
-
-	pgb.bestBlock = int64(dbBlock.Height)
 
-	err = InsertBlockPrevNext(pgb.db, blockDbID, dbBlock.Hash,
+	err = InsertBlockPrevNext(dbtx, blockDbID, dbBlock.Hash,
 		dbBlock.PreviousHash, "")
 	if err != nil && err != sql.ErrNoRows {
 		log.Error("InsertBlockPrevNext:", err)
+		rollback()
+		return
+	}
+
+	var voteSummary *dbtypes.VoteSummary
+	if voteSummary, err = MsgBlockPG.VoteSummary(); err != nil {
+		rollback()
+		return
+	}
+	if err = InsertVoteSummary(dbtx, voteSummary, pgb.dupChecks); err != nil {
+		log.Error("InsertVoteSummary:", err)
+		rollback()
+		return
+	}
+
+	// The address summaries, coin-days destroyed, and UTXO set statistics
+	// require the spending info of the addresses table. Without it, they are
+	// rebuilt after the spending info is set in bulk.
+	if updateAddressesSpendingInfo {
+		err = UpdateAddressBalancesForBlock(dbtx, dbBlock.Hash,
+			int64(dbBlock.Height), int64(dbBlock.Time))
+		if err != nil {
+			log.Error("UpdateAddressBalancesForBlock:", err)
+			rollback()
+			return
+		}
+		if err = InsertCoinDaysDestroyed(dbtx, dbBlock.Hash, pgb.dupChecks); err != nil {
+			log.Error("InsertCoinDaysDestroyed:", err)
+			rollback()
+			return
+		}
+		err = UpdateUtxoHeights(dbtx, dbBlock.Hash, int64(dbBlock.Height))
+		if err != nil {
+			log.Error("UpdateUtxoHeights:", err)
+			rollback()
+			return
+		}
+		if dbBlock.Height%UtxoSnapshotInterval == 0 {
+			err = pgb.storeUtxoSnapshot(dbtx, int64(dbBlock.Height),
+				dbBlock.Hash, int64(dbBlock.Time))
+			if err != nil {
+				log.Error("storeUtxoSnapshot:", err)
+				rollback()
+				return
+			}
+		}
+	}
+
+	swaps := blockAtomicSwaps(msgBlock, dbBlock, pgb.chainParams)
+	if err = InsertAtomicSwaps(dbtx, swaps, pgb.dupChecks); err != nil {
+		log.Error("InsertAtomicSwaps:", err)
+		rollback()
 		return
 	}
 
 	// Update last block in db with this block's hash as it's next. Also update
-	// isValid flag in last block if votes in this block invalidated it.
+	// isValid flag in last block if votes in this block invalidated it, and
+	// invalidate its regular transactions in the treasury ledger.
 	lastBlockHash := msgBlock.Header.PrevBlock
 	lastBlockDbID, ok := pgb.lastBlock[lastBlockHash]
+	var treasuryInvalidated *treasuryInvalidation
 	if ok {
 		lastIsValid := dbBlock.VoteBits&1 != 0
 		if !lastIsValid {
 			log.Infof("Setting last block %s as INVALID", lastBlockHash)
-			err = UpdateLastBlock(pgb.db, lastBlockDbID, lastIsValid)
+			err = UpdateLastBlock(dbtx, lastBlockDbID, lastIsValid)
 			if err != nil {
 				log.Error("UpdateLastBlock:", err)
+				rollback()
+				return
+			}
+			treasuryInvalidated, err = invalidateTreasuryTxns(dbtx,
+				lastBlockHash.String())
+			if err != nil {
+				log.Error("invalidateTreasuryTxns:", err)
+				rollback()
 				return
 			}
 		}
-		err = UpdateBlockNext(pgb.db, lastBlockDbID, dbBlock.Hash)
+		err = UpdateBlockNext(dbtx, lastBlockDbID, dbBlock.Hash)
 		if err != nil {
 			log.Error("UpdateBlockNext:", err)
+			rollback()
 			return
 		}
 	}
 
+	treasuryChanges := pgb.treasury.processBlock(msgBlock, treasuryInvalidated,
+		pgb.chainParams)
+	if err = InsertTreasuryTxns(dbtx, treasuryChanges.txns, pgb.dupChecks); err != nil {
+		log.Error("InsertTreasuryTxns:", err)
+		rollback()
+		return
+	}
+
+	if err = dbtx.Commit(); err != nil {
+		err = fmt.Errorf("failed to commit block %s: %v", dbBlock.Hash, err)
+		return
+	}
+
+	numVins = resStk.numVins + resReg.numVins
+	numVouts = resStk.numVouts + resReg.numVouts
+
+	pgb.lastBlock[msgBlock.BlockHash()] = blockDbID
+	pgb.bestBlock = int64(dbBlock.Height)
+	pgb.treasury.apply(treasuryChanges)
+	pgb.unspentTicketCache.SetN(resStk.newTicketHashes, resStk.newTicketDbIDs)
+
 	pgb.addressCounts.Lock()
 	pgb.addressCounts.validHeight = int64(msgBlock.Header.Height)
 	pgb.addressCounts.balance = map[string]explorer.AddressBalance{}
@@ -876,7 +1229,11 @@ func (pgb *ChainDB) StoreBlock(msgBlock *wire.MsgBlock, winningTickets []string,
 // storeTxns in StoreBlock.
 type storeTxnsResult struct {
 	numVins, numVouts, numAddresses int64
-	err                             error
+	// newTicketHashes and newTicketDbIDs are the tickets to add to the unspent
+	// ticket cache once the block is committed.
+	newTicketHashes []string
+	newTicketDbIDs  []uint64
+	err             error
 }
 
 func (r *storeTxnsResult) Error() string {
@@ -892,7 +1249,47 @@ type MsgBlockPG struct {
 	Validators     []string
 }
 
-func (pgb *ChainDB) storeTxns(msgBlock *MsgBlockPG, txTree int8,
+// VoteSummary counts the votes in the block, their approvals and disapprovals
+// of the previous block's regular tree, and the Validators that did not vote.
+func (msgBlock *MsgBlockPG) VoteSummary() (*dbtypes.VoteSummary, error) {
+	vs := &dbtypes.VoteSummary{
+		Height:         msgBlock.Header.Height,
+		BlockHash:      msgBlock.BlockHash().String(),
+		BlockTime:      msgBlock.Header.Timestamp.Unix(),
+		CandidateHash:  msgBlock.Header.PrevBlock.String(),
+		CandidateValid: msgBlock.Header.VoteBits&1 != 0,
+	}
+
+	voted := make(map[string]struct{}, len(msgBlock.Validators))
+	for _, stx := range msgBlock.STransactions {
+		if !stake.IsSSGen(stx) {
+			continue
+		}
+		validBlock, _, err := txhelpers.SSGenVoteBlockValid(stx)
+		if err != nil {
+			return nil, err
+		}
+		vs.Votes++
+		if validBlock.Validity {
+			vs.Approvals++
+		} else {
+			vs.Disapprovals++
+		}
+		voted[stx.TxIn[1].PreviousOutPoint.Hash.String()] = struct{}{}
+	}
+
+	for _, ticket := range msgBlock.Validators {
+		if ticket == "" {
+			continue
+		}
+		if _, ok := voted[ticket]; !ok {
+			vs.Misses++
+		}
+	}
+	return vs, nil
+}
+
+func (pgb *ChainDB) storeTxns(sqlTx *sql.Tx, msgBlock *MsgBlockPG, txTree int8,
 	chainParams *chaincfg.Params, TxDbIDs *[]uint64,
 	updateAddressesSpendingInfo, updateTicketsSpendingInfo bool) storeTxnsResult {
 	// For the given block, transaction tree, and network, extract the
@@ -912,7 +1309,7 @@ func (pgb *ChainDB) storeTxns(msgBlock *MsgBlockPG, txTree int8,
 	var err error
 	for it, dbtx := range dbTransactions {
 		// Insert vouts, and collect rows to add to address table
-		dbtx.VoutDbIds, dbAddressRows[it], err = InsertVouts(pgb.db, dbTxVouts[it], pgb.dupChecks)
+		dbtx.VoutDbIds, dbAddressRows[it], err = insertVouts(sqlTx, dbTxVouts[it], pgb.dupChecks)
 		if err != nil && err != sql.ErrNoRows {
 			log.Error("InsertVouts:", err)
 			txRes.err = err
@@ -925,7 +1322,7 @@ func (pgb *ChainDB) storeTxns(msgBlock *MsgBlockPG, txTree int8,
 		}
 
 		// Insert vins
-		dbtx.VinDbIds, err = InsertVins(pgb.db, dbTxVins[it])
+		dbtx.VinDbIds, err = insertVins(sqlTx, dbTxVins[it])
 		if err != nil && err != sql.ErrNoRows {
 			log.Error("InsertVins:", err)
 			txRes.err = err
@@ -940,7 +1337,7 @@ func (pgb *ChainDB) storeTxns(msgBlock *MsgBlockPG, txTree int8,
 	}
 
 	// Get the tx PK IDs for storage in the blocks, tickets, and votes table
-	*TxDbIDs, err = InsertTxns(pgb.db, dbTransactions, pgb.dupChecks)
+	*TxDbIDs, err = insertTxns(sqlTx, dbTransactions, pgb.dupChecks)
 	if err != nil && err != sql.ErrNoRows {
 		log.Error("InsertTxns:", err)
 		txRes.err = err
@@ -950,19 +1347,29 @@ func (pgb *ChainDB) storeTxns(msgBlock *MsgBlockPG, txTree int8,
 	// If processing stake tree, insert tickets, votes, misses
 	if txTree == wire.TxTreeStake {
 		// Tickets
-		newTicketDbIDs, newTicketTx, err := InsertTickets(pgb.db, dbTransactions, *TxDbIDs, pgb.dupChecks)
+		newTicketDbIDs, newTicketTx, err := insertTickets(sqlTx, dbTransactions, *TxDbIDs, pgb.dupChecks)
 		if err != nil && err != sql.ErrNoRows {
 			log.Error("InsertTickets:", err)
 			txRes.err = err
 			return txRes
 		}
 
+		// Label the transactions funding split tickets.
+		if err = AddSplitTicketFundingClass(sqlTx, msgBlock); err != nil {
+			log.Error("AddSplitTicketFundingClass:", err)
+			txRes.err = err
+			return txRes
+		}
+
 		// Get tickets table row IDs for newly spent tickets, if we are updating
 		// them as we go as opposed to batch mode at the end of a sync.
+		// The new tickets are added to the cache only after the DB
+		// transaction is committed, as their row IDs do not exist otherwise.
 		var unspentTicketCache *TicketTxnIDGetter
 		if updateTicketsSpendingInfo {
 			for it, tdbid := range newTicketDbIDs {
-				pgb.unspentTicketCache.Set(newTicketTx[it].TxID, tdbid)
+				txRes.newTicketHashes = append(txRes.newTicketHashes, newTicketTx[it].TxID)
+				txRes.newTicketDbIDs = append(txRes.newTicketDbIDs, tdbid)
 			}
 			unspentTicketCache = pgb.unspentTicketCache
 		}
@@ -980,7 +1387,7 @@ func (pgb *ChainDB) storeTxns(msgBlock *MsgBlockPG, txTree int8,
 		// Votes
 		// voteDbIDs, voteTxns, spentTicketHashes, ticketDbIDs, missDbIDs, err := ...
 		var missesHashIDs map[string]uint64
-		_, _, _, _, missesHashIDs, err = InsertVotes(pgb.db,
+		_, _, _, _, missesHashIDs, err = insertVotes(sqlTx,
 			dbTransactions, *TxDbIDs, unspentTicketCache, msgBlock, pgb.dupChecks)
 		if err != nil && err != sql.ErrNoRows {
 			log.Error("InsertVotes:", err)
@@ -1022,10 +1429,13 @@ func (pgb *ChainDB) storeTxns(msgBlock *MsgBlockPG, txTree int8,
 
 			// Update tickets table with spending info from new votes
 			// _, err = SetSpendingForTickets(pgb.db, ticketDbIDs, voteDbIDs, blockHeights, spendTypes)
-			_, err = SetSpendingForTickets(pgb.db, ticketDbIDs, spendingTxDbIDs,
+			_, err = setSpendingForTickets(sqlTx, ticketDbIDs, spendingTxDbIDs,
 				blockHeights, spendTypes, poolStatuses)
 			if err != nil {
-				log.Warn("SetSpendingForTickets:", err)
+				pgb.stakeDB.UnlockStakeNode()
+				log.Error("SetSpendingForTickets:", err)
+				txRes.err = err
+				return txRes
 			}
 
 			// Missed but not revoked
@@ -1066,9 +1476,11 @@ func (pgb *ChainDB) storeTxns(msgBlock *MsgBlockPG, txTree int8,
 			// Release the stake node
 			pgb.stakeDB.UnlockStakeNode()
 
-			numUnrevokedMisses, err := SetPoolStatusForTicketsByHash(pgb.db, unspentEnM, missStatuses)
+			numUnrevokedMisses, err := setPoolStatusForTicketsByHash(sqlTx, unspentEnM, missStatuses)
 			if err != nil {
-				log.Warn("SetPoolStatusForTickets", err)
+				log.Error("SetPoolStatusForTickets", err)
+				txRes.err = err
+				return txRes
 			} else if numUnrevokedMisses > 0 {
 				log.Tracef("Noted %d unrevoked newly-missed tickets.", numUnrevokedMisses)
 			}
@@ -1090,7 +1502,7 @@ func (pgb *ChainDB) storeTxns(msgBlock *MsgBlockPG, txTree int8,
 	}
 
 	// Insert each new AddressRow, absent spending fields
-	_, err = InsertAddressOuts(pgb.db, dbAddressRowsFlat, pgb.dupChecks)
+	_, err = insertAddressOuts(sqlTx, dbAddressRowsFlat, pgb.dupChecks)
 	if err != nil {
 		log.Error("InsertAddressOuts:", err)
 		txRes.err = err
@@ -1134,11 +1546,13 @@ func (pgb *ChainDB) storeTxns(msgBlock *MsgBlockPG, txTree int8,
 			}
 
 			var numAddressRowsSet int64
-			numAddressRowsSet, err = SetSpendingForFundingOP(pgb.db,
+			numAddressRowsSet, err = SetSpendingForFundingOP(sqlTx,
 				vin.PrevTxHash, vin.PrevTxIndex, // funding
 				txDbID, vin.TxID, vin.TxIndex, vinDbID) // spending
 			if err != nil {
 				log.Errorf("SetSpendingForFundingOP: %v", err)
+				txRes.err = err
+				return txRes
 			}
 			txRes.numAddresses += numAddressRowsSet
 
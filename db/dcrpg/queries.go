@@ -264,6 +264,8 @@ func SetPoolStatusForTickets(db *sql.DB, ticketDbIDs []uint64,
 	return totalTicketsUpdated, dbtx.Commit()
 }
 
+// SetPoolStatusForTicketsByHash sets the pool status of the tickets specified
+// by hash in a single database transaction.
 func SetPoolStatusForTicketsByHash(db *sql.DB, tickets []string,
 	poolStatuses []dbtypes.TicketPoolStatus) (int64, error) {
 	if len(tickets) == 0 {
@@ -274,11 +276,19 @@ func SetPoolStatusForTicketsByHash(db *sql.DB, tickets []string,
 		return 0, fmt.Errorf(`unable to begin database transaction: %v`, err)
 	}
 
-	var stmt *sql.Stmt
-	stmt, err = dbtx.Prepare(internal.SetTicketPoolStatusForHash)
+	totalTicketsUpdated, err := setPoolStatusForTicketsByHash(dbtx, tickets, poolStatuses)
 	if err != nil {
-		// Already up a creek. Just return error from Prepare.
 		_ = dbtx.Rollback()
+		return 0, err
+	}
+
+	return totalTicketsUpdated, dbtx.Commit()
+}
+
+func setPoolStatusForTicketsByHash(dbtx *sql.Tx, tickets []string,
+	poolStatuses []dbtypes.TicketPoolStatus) (int64, error) {
+	stmt, err := dbtx.Prepare(internal.SetTicketPoolStatusForHash)
+	if err != nil {
 		return 0, fmt.Errorf("tickets SELECT prepare failed: %v", err)
 	}
 
@@ -289,7 +299,7 @@ func SetPoolStatusForTicketsByHash(db *sql.DB, tickets []string,
 			ticket, poolStatuses[i])
 		if err != nil {
 			_ = stmt.Close()
-			return 0, dbtx.Rollback()
+			return 0, err
 		}
 		totalTicketsUpdated += rowsAffected[i]
 		if rowsAffected[i] != 1 {
@@ -298,11 +308,11 @@ func SetPoolStatusForTicketsByHash(db *sql.DB, tickets []string,
 		}
 	}
 
-	_ = stmt.Close()
-
-	return totalTicketsUpdated, dbtx.Commit()
+	return totalTicketsUpdated, stmt.Close()
 }
 
+// SetSpendingForTickets sets the spending info for the tickets specified by
+// their DB row IDs in a single database transaction.
 func SetSpendingForTickets(db *sql.DB, ticketDbIDs, spendDbIDs []uint64,
 	blockHeights []int64, spendTypes []dbtypes.TicketSpendType,
 	poolStatuses []dbtypes.TicketPoolStatus) (int64, error) {
@@ -311,58 +321,41 @@ func SetSpendingForTickets(db *sql.DB, ticketDbIDs, spendDbIDs []uint64,
 		return 0, fmt.Errorf(`unable to begin database transaction: %v`, err)
 	}
 
-	var stmt *sql.Stmt
-	stmt, err = dbtx.Prepare(internal.SetTicketSpendingInfoForTicketDbID)
+	totalTicketsUpdated, err := setSpendingForTickets(dbtx, ticketDbIDs,
+		spendDbIDs, blockHeights, spendTypes, poolStatuses)
 	if err != nil {
-		// Already up a creek. Just return error from Prepare.
 		_ = dbtx.Rollback()
-		return 0, fmt.Errorf("tickets SELECT prepare failed: %v", err)
-	}
-
-	var totalTicketsUpdated int64
-	rowsAffected := make([]int64, len(ticketDbIDs))
-	for i, ticketDbID := range ticketDbIDs {
-		rowsAffected[i], err = sqlExecStmt(stmt, "failed to set ticket spending info: ",
-			ticketDbID, blockHeights[i], spendDbIDs[i], spendTypes[i], poolStatuses[i])
-		if err != nil {
-			_ = stmt.Close()
-			return 0, dbtx.Rollback()
-		}
-		totalTicketsUpdated += rowsAffected[i]
-		if rowsAffected[i] != 1 {
-			log.Warnf("Updated spending info for %d tickets, expecting just 1!",
-				rowsAffected[i])
-		}
+		return 0, err
 	}
 
-	_ = stmt.Close()
-
 	return totalTicketsUpdated, dbtx.Commit()
 }
 
 func setSpendingForTickets(dbtx *sql.Tx, ticketDbIDs, spendDbIDs []uint64,
 	blockHeights []int64, spendTypes []dbtypes.TicketSpendType,
-	poolStatuses []dbtypes.TicketPoolStatus) error {
+	poolStatuses []dbtypes.TicketPoolStatus) (int64, error) {
 	stmt, err := dbtx.Prepare(internal.SetTicketSpendingInfoForTicketDbID)
 	if err != nil {
-		return fmt.Errorf("tickets SELECT prepare failed: %v", err)
+		return 0, fmt.Errorf("tickets SELECT prepare failed: %v", err)
 	}
 
+	var totalTicketsUpdated int64
 	rowsAffected := make([]int64, len(ticketDbIDs))
 	for i, ticketDbID := range ticketDbIDs {
 		rowsAffected[i], err = sqlExecStmt(stmt, "failed to set ticket spending info: ",
 			ticketDbID, blockHeights[i], spendDbIDs[i], spendTypes[i], poolStatuses[i])
 		if err != nil {
 			_ = stmt.Close()
-			return err
+			return 0, err
 		}
+		totalTicketsUpdated += rowsAffected[i]
 		if rowsAffected[i] != 1 {
 			log.Warnf("Updated spending info for %d tickets, expecting just 1!",
 				rowsAffected[i])
 		}
 	}
 
-	return stmt.Close()
+	return totalTicketsUpdated, stmt.Close()
 }
 
 func SetSpendingForVinDbIDs(db *sql.DB, vinDbIDs []uint64) ([]int64, int64, error) {
@@ -492,7 +485,7 @@ func SetSpendingForVinDbID(db *sql.DB, vinDbID uint64) (int64, error) {
 	return N, dbtx.Commit()
 }
 
-func SetSpendingForFundingOP(db *sql.DB,
+func SetSpendingForFundingOP(db SqlExecutor,
 	fundingTxHash string, fundingTxVoutIndex uint32,
 	spendingTxDbID uint64, spendingTxHash string, spendingTxVinIndex uint32,
 	vinDbID uint64) (int64, error) {
@@ -651,7 +644,75 @@ func DeleteDuplicateMisses(db *sql.DB) (int64, error) {
 	return sqlExec(db, internal.DeleteMissesDuplicateRows, execErrPrefix)
 }
 
-func sqlExec(db *sql.DB, stmt, execErrPrefix string, args ...interface{}) (int64, error) {
+// DeleteDuplicateVoteSummaries deletes rows in vote_summary with duplicate
+// block hashes, leaving the one row with the lowest id.
+func DeleteDuplicateVoteSummaries(db *sql.DB) (int64, error) {
+	if isuniq, err := IsUniqueIndex(db, "uix_vote_summary_block_hash"); err != nil && err != sql.ErrNoRows {
+		return 0, err
+	} else if isuniq {
+		return 0, nil
+	}
+	execErrPrefix := "failed to delete duplicate vote summaries: "
+	return sqlExec(db, internal.DeleteVoteSummaryDuplicateRows, execErrPrefix)
+}
+
+// DeleteDuplicateTreasuryTxns deletes rows in treasury with duplicate tx-block
+// hashes, leaving the one row with the lowest id.
+func DeleteDuplicateTreasuryTxns(db *sql.DB) (int64, error) {
+	if isuniq, err := IsUniqueIndex(db, "uix_treasury_hashes_index"); err != nil && err != sql.ErrNoRows {
+		return 0, err
+	} else if isuniq {
+		return 0, nil
+	}
+	execErrPrefix := "failed to delete duplicate treasury transactions: "
+	return sqlExec(db, internal.DeleteTreasuryDuplicateRows, execErrPrefix)
+}
+
+// DeleteDuplicateCoinDaysDestroyed deletes rows in coin_days_destroyed with
+// duplicate block hashes, leaving the one row with the lowest id.
+func DeleteDuplicateCoinDaysDestroyed(db *sql.DB) (int64, error) {
+	if isuniq, err := IsUniqueIndex(db, "uix_coin_days_destroyed_block_hash"); err != nil && err != sql.ErrNoRows {
+		return 0, err
+	} else if isuniq {
+		return 0, nil
+	}
+	execErrPrefix := "failed to delete duplicate coin days destroyed: "
+	return sqlExec(db, internal.DeleteCoinDaysDestroyedDuplicateRows, execErrPrefix)
+}
+
+// DeleteDuplicateUtxoSnapshots deletes rows in utxo_snapshots with duplicate
+// block hashes, leaving the one row with the lowest id.
+func DeleteDuplicateUtxoSnapshots(db *sql.DB) (int64, error) {
+	if isuniq, err := IsUniqueIndex(db, "uix_utxo_snapshots_block_hash"); err != nil && err != sql.ErrNoRows {
+		return 0, err
+	} else if isuniq {
+		return 0, nil
+	}
+	execErrPrefix := "failed to delete duplicate UTXO set snapshots: "
+	return sqlExec(db, internal.DeleteUtxoSnapshotsDuplicateRows, execErrPrefix)
+}
+
+// DeleteDuplicateAtomicSwaps deletes rows in swaps with duplicate spending
+// inputs, leaving the one row with the lowest id.
+func DeleteDuplicateAtomicSwaps(db *sql.DB) (int64, error) {
+	if isuniq, err := IsUniqueIndex(db, "uix_swaps_spend"); err != nil && err != sql.ErrNoRows {
+		return 0, err
+	} else if isuniq {
+		return 0, nil
+	}
+	execErrPrefix := "failed to delete duplicate atomic swaps: "
+	return sqlExec(db, internal.DeleteAtomicSwapsDuplicateRows, execErrPrefix)
+}
+
+// SqlExecutor is satisfied by both *sql.DB and *sql.Tx, allowing single
+// statement queries to be run alone or as part of a larger DB transaction.
+type SqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func sqlExec(db SqlExecutor, stmt, execErrPrefix string, args ...interface{}) (int64, error) {
 	res, err := db.Exec(stmt, args...)
 	if err != nil {
 		return 0, fmt.Errorf(execErrPrefix + err.Error())
@@ -1122,7 +1183,33 @@ func RetrieveBlockSummaryByTimeRange(db *sql.DB, minTime, maxTime int64, limit i
 	return blocks, nil
 }
 
-func InsertBlock(db *sql.DB, dbBlock *dbtypes.Block, isValid, checked bool) (uint64, error) {
+// RetrieveBlocksByInterval aggregates the blocks with times in [from, to] by
+// the time interval ("day", "week", or "month"), newest first.
+func RetrieveBlocksByInterval(db *sql.DB, interval string, from, to int64) ([]dbtypes.BlocksInterval, error) {
+	rows, err := db.Query(internal.SelectBlocksByInterval, interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	var intervals []dbtypes.BlocksInterval
+	for rows.Next() {
+		var bi dbtypes.BlocksInterval
+		err = rows.Scan(&bi.Start, &bi.Blocks, &bi.Transactions, &bi.Fees,
+			&bi.FreshStake, &bi.MinHeight, &bi.MaxHeight)
+		if err != nil {
+			return nil, err
+		}
+		intervals = append(intervals, bi)
+	}
+	return intervals, rows.Err()
+}
+
+func InsertBlock(db SqlExecutor, dbBlock *dbtypes.Block, isValid, checked bool) (uint64, error) {
 	insertStatement := internal.MakeBlockInsertStatement(dbBlock, checked)
 	var id uint64
 	err := db.QueryRow(insertStatement,
@@ -1137,9 +1224,91 @@ func InsertBlock(db *sql.DB, dbBlock *dbtypes.Block, isValid, checked bool) (uin
 	return id, err
 }
 
+// InsertVoteSummary inserts the vote summary of a block into the vote_summary
+// table. With checked, an existing summary for the block is replaced.
+func InsertVoteSummary(db SqlExecutor, vs *dbtypes.VoteSummary, checked bool) error {
+	_, err := db.Exec(internal.MakeVoteSummaryInsertStatement(checked),
+		vs.Height, vs.BlockHash, vs.BlockTime, vs.CandidateHash,
+		vs.Votes, vs.Approvals, vs.Disapprovals, vs.Misses, vs.CandidateValid)
+	return err
+}
+
+// RetrieveVoteParticipation aggregates the vote summaries of the blocks at or
+// above minHeight, with times in [from, to], by the time interval ("day",
+// "week", or "month").
+func RetrieveVoteParticipation(db *sql.DB, interval string, from, to, minHeight int64) ([]apitypes.VoteParticipation, error) {
+	rows, err := db.Query(internal.SelectVoteParticipation, interval, from, to, minHeight)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	var participation []apitypes.VoteParticipation
+	for rows.Next() {
+		var vp apitypes.VoteParticipation
+		err = rows.Scan(&vp.Start, &vp.Blocks, &vp.Votes, &vp.Misses,
+			&vp.Invalidated, &vp.Blocks3Votes, &vp.Blocks4Votes, &vp.Blocks5Votes)
+		if err != nil {
+			return nil, err
+		}
+		if called := vp.Votes + vp.Misses; called > 0 {
+			vp.Participation = float64(vp.Votes) / float64(called)
+		}
+		participation = append(participation, vp)
+	}
+	return participation, rows.Err()
+}
+
+// RetrieveVoteSummariesInvalidating retrieves the vote summaries of the blocks
+// that invalidated the regular tree of the previous block, newest first.
+func RetrieveVoteSummariesInvalidating(db *sql.DB, N, offset int64) ([]*dbtypes.VoteSummary, error) {
+	rows, err := db.Query(internal.SelectVoteSummariesInvalidating, N, offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanVoteSummaryRows(rows)
+}
+
+// RetrieveVoteSummariesFewVotes retrieves the vote summaries of the blocks at
+// or above minHeight with fewer than maxVotes votes, newest first.
+func RetrieveVoteSummariesFewVotes(db *sql.DB, minHeight int64, maxVotes uint16,
+	N, offset int64) ([]*dbtypes.VoteSummary, error) {
+	rows, err := db.Query(internal.SelectVoteSummariesFewVotes, minHeight,
+		maxVotes, N, offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanVoteSummaryRows(rows)
+}
+
+func scanVoteSummaryRows(rows *sql.Rows) ([]*dbtypes.VoteSummary, error) {
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	var summaries []*dbtypes.VoteSummary
+	for rows.Next() {
+		vs := new(dbtypes.VoteSummary)
+		err := rows.Scan(&vs.Height, &vs.BlockHash, &vs.BlockTime,
+			&vs.CandidateHash, &vs.Votes, &vs.Approvals, &vs.Disapprovals,
+			&vs.Misses, &vs.CandidateValid)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, vs)
+	}
+	return summaries, rows.Err()
+}
+
 // UpdateLastBlock updates the is_valid column of the block specified by the row
 // id for the blocks table.
-func UpdateLastBlock(db *sql.DB, blockDbID uint64, isValid bool) error {
+func UpdateLastBlock(db SqlExecutor, blockDbID uint64, isValid bool) error {
 	numRows, err := sqlExec(db, internal.UpdateLastBlockValid,
 		"failed to update last block validity: ", blockDbID, isValid)
 	if err != nil {
@@ -1190,7 +1359,7 @@ func RetrieveVoutValues(db *sql.DB, txHash string) (values []uint64, txInds []ui
 	return
 }
 
-func InsertBlockPrevNext(db *sql.DB, blockDbID uint64,
+func InsertBlockPrevNext(db SqlExecutor, blockDbID uint64,
 	hash, prev, next string) error {
 	rows, err := db.Query(internal.InsertBlockPrevNext, blockDbID, prev, hash, next)
 	if err == nil {
@@ -1199,7 +1368,7 @@ func InsertBlockPrevNext(db *sql.DB, blockDbID uint64,
 	return err
 }
 
-func UpdateBlockNext(db *sql.DB, blockDbID uint64, next string) error {
+func UpdateBlockNext(db SqlExecutor, blockDbID uint64, next string) error {
 	res, err := db.Exec(internal.UpdateBlockNext, blockDbID, next)
 	if err != nil {
 		return err
@@ -1214,6 +1383,112 @@ func UpdateBlockNext(db *sql.DB, blockDbID uint64, next string) error {
 	return nil
 }
 
+// DeleteBlocksAboveHeight removes all data for blocks above the specified
+// height in a single database transaction, and undoes the changes those blocks
+// made to the spending and pool status of earlier outputs and tickets.
+// ticketExpiryBlocks is the number of blocks from a ticket's purchase until it
+// expires (ticket maturity + expiry).
+func DeleteBlocksAboveHeight(db *sql.DB, height int64, ticketExpiryBlocks int64) (int64, error) {
+	dbtx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("unable to begin database transaction: %v", err)
+	}
+
+	numBlocks, err := deleteBlocksAboveHeight(dbtx, height, ticketExpiryBlocks)
+	if err != nil {
+		if errRoll := dbtx.Rollback(); errRoll != nil {
+			log.Errorf("Rollback failed: %v", errRoll)
+		}
+		return 0, err
+	}
+
+	return numBlocks, dbtx.Commit()
+}
+
+func deleteBlocksAboveHeight(dbtx *sql.Tx, height int64, ticketExpiryBlocks int64) (int64, error) {
+	// Addresses used by the removed blocks, to be summarized again once the
+	// blocks' rows are removed.
+	addresses, err := retrieveAddressesAboveHeight(dbtx, height)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve addresses to rewind: %v", err)
+	}
+
+	// Undo updates to rows of blocks that are staying.
+	if _, err := sqlExec(dbtx, internal.RewindUtxoHeightsSpent,
+		"failed to rewind UTXO set by height: ", height); err != nil {
+		return 0, err
+	}
+	if _, err := sqlExec(dbtx, internal.RewindAddressesSpending,
+		"failed to reset address spending info: ", height); err != nil {
+		return 0, err
+	}
+	if _, err := sqlExec(dbtx, internal.RewindTicketsSpending,
+		"failed to reset ticket spending info: ", height,
+		dbtypes.TicketUnspent, dbtypes.PoolStatusLive); err != nil {
+		return 0, err
+	}
+	if _, err := sqlExec(dbtx, internal.RewindTicketsPoolStatus,
+		"failed to reset ticket pool status: ", height, dbtypes.PoolStatusLive,
+		dbtypes.PoolStatusMissed, dbtypes.PoolStatusExpired,
+		ticketExpiryBlocks); err != nil {
+		return 0, err
+	}
+
+	// Delete rows for the removed blocks. Rows that are located via the
+	// transactions table go first.
+	deletes := []struct {
+		stmt, table string
+	}{
+		{internal.DeleteAddressesAboveHeight, "addresses"},
+		{internal.DeleteVinsAboveHeight, "vins"},
+		{internal.DeleteVoutsAboveHeight, "vouts"},
+		{internal.DeleteTicketsAboveHeight, "tickets"},
+		{internal.DeleteVotesAboveHeight, "votes"},
+		{internal.DeleteMissesAboveHeight, "misses"},
+		{internal.DeleteVoteSummaryAboveHeight, "vote_summary"},
+		{internal.DeleteTreasuryAboveHeight, "treasury"},
+		{internal.DeleteCoinDaysDestroyedAboveHeight, "coin_days_destroyed"},
+		{internal.DeleteUtxoHeightsAboveHeight, "utxo_heights"},
+		{internal.DeleteUtxoSnapshotsAboveHeight, "utxo_snapshots"},
+		{internal.DeleteAtomicSwapsAboveHeight, "swaps"},
+		{internal.DeleteTransactionsAboveHeight, "transactions"},
+		{internal.DeleteBlockChainAboveHeight, "block_chain"},
+	}
+	for _, d := range deletes {
+		N, err := sqlExec(dbtx, d.stmt, "failed to delete from "+d.table+": ", height)
+		if err != nil {
+			return 0, err
+		}
+		log.Debugf("Deleted %d rows from %s.", N, d.table)
+	}
+	numBlocks, err := sqlExec(dbtx, internal.DeleteBlocksAboveHeight,
+		"failed to delete from blocks: ", height)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = recomputeAddressBalances(dbtx, addresses); err != nil {
+		return 0, fmt.Errorf("failed to rewind address balances: %v", err)
+	}
+
+	// The new best block has no next block.
+	if _, err = sqlExec(dbtx, internal.RewindBlockChainTip,
+		"failed to reset next block: ", height); err != nil {
+		return 0, err
+	}
+	if _, err = sqlExec(dbtx, internal.RewindBlockValidity,
+		"failed to reset block validity: ", height); err != nil {
+		return 0, err
+	}
+	if _, err = sqlExec(dbtx, internal.RewindTreasuryBalances,
+		"failed to reset treasury balances: ", height); err != nil {
+		return 0, err
+	}
+	_, err = sqlExec(dbtx, internal.RewindTreasuryValidity,
+		"failed to reset treasury validity: ", height)
+	return numBlocks, err
+}
+
 func InsertVin(db *sql.DB, dbVin dbtypes.VinTxProperty) (id uint64, err error) {
 	err = db.QueryRow(internal.InsertVinRow,
 		dbVin.TxID, dbVin.TxIndex, dbVin.TxTree,
@@ -1221,16 +1496,29 @@ func InsertVin(db *sql.DB, dbVin dbtypes.VinTxProperty) (id uint64, err error) {
 	return
 }
 
+// InsertVins inserts the vins in a single database transaction, returning
+// their DB row IDs.
 func InsertVins(db *sql.DB, dbVins dbtypes.VinTxPropertyARRAY) ([]uint64, error) {
 	dbtx, err := db.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("unable to begin database transaction: %v", err)
 	}
 
+	ids, err := insertVins(dbtx, dbVins)
+	if err != nil {
+		if errRoll := dbtx.Rollback(); errRoll != nil {
+			log.Errorf("Rollback failed: %v", errRoll)
+		}
+		return ids, err
+	}
+
+	return ids, dbtx.Commit()
+}
+
+func insertVins(dbtx *sql.Tx, dbVins dbtypes.VinTxPropertyARRAY) ([]uint64, error) {
 	stmt, err := dbtx.Prepare(internal.InsertVinRow)
 	if err != nil {
 		log.Errorf("Vin INSERT prepare: %v", err)
-		_ = dbtx.Rollback() // try, but we want the Prepare error back
 		return nil, err
 	}
 
@@ -1243,18 +1531,12 @@ func InsertVins(db *sql.DB, dbVins dbtypes.VinTxPropertyARRAY) ([]uint64, error)
 			vin.PrevTxHash, vin.PrevTxIndex, vin.PrevTxTree).Scan(&id)
 		if err != nil {
 			_ = stmt.Close() // try, but we want the QueryRow error back
-			if errRoll := dbtx.Rollback(); errRoll != nil {
-				log.Errorf("Rollback failed: %v", errRoll)
-			}
 			return ids, fmt.Errorf("InsertVins INSERT exec failed: %v", err)
 		}
 		ids = append(ids, id)
 	}
 
-	// Close prepared statement. Ignore errors as we'll Commit regardless.
-	_ = stmt.Close()
-
-	return ids, dbtx.Commit()
+	return ids, stmt.Close()
 }
 
 func InsertVout(db *sql.DB, dbVout *dbtypes.Vout, checked bool) (uint64, error) {
@@ -1269,17 +1551,31 @@ func InsertVout(db *sql.DB, dbVout *dbtypes.Vout, checked bool) (uint64, error)
 	return id, err
 }
 
+// InsertVouts inserts the vouts in a single database transaction, returning
+// their DB row IDs and the address rows paid by the vouts.
 func InsertVouts(db *sql.DB, dbVouts []*dbtypes.Vout, checked bool) ([]uint64, []dbtypes.AddressRow, error) {
-	addressRows := make([]dbtypes.AddressRow, 0, len(dbVouts)*2)
 	dbtx, err := db.Begin()
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to begin database transaction: %v", err)
 	}
 
+	ids, addressRows, err := insertVouts(dbtx, dbVouts, checked)
+	if err != nil {
+		if errRoll := dbtx.Rollback(); errRoll != nil {
+			log.Errorf("Rollback failed: %v", errRoll)
+		}
+		return ids, addressRows, err
+	}
+
+	return ids, addressRows, dbtx.Commit()
+}
+
+func insertVouts(dbtx *sql.Tx, dbVouts []*dbtypes.Vout, checked bool) ([]uint64, []dbtypes.AddressRow, error) {
+	addressRows := make([]dbtypes.AddressRow, 0, len(dbVouts)*2)
+
 	stmt, err := dbtx.Prepare(internal.MakeVoutInsertStatement(checked))
 	if err != nil {
 		log.Errorf("Vout INSERT prepare: %v", err)
-		_ = dbtx.Rollback() // try, but we want the Prepare error back
 		return nil, nil, err
 	}
 
@@ -1296,9 +1592,6 @@ func InsertVouts(db *sql.DB, dbVouts []*dbtypes.Vout, checked bool) ([]uint64, [
 				continue
 			}
 			_ = stmt.Close() // try, but we want the QueryRow error back
-			if errRoll := dbtx.Rollback(); errRoll != nil {
-				log.Errorf("Rollback failed: %v", errRoll)
-			}
 			return nil, nil, err
 		}
 		for _, addr := range vout.ScriptPubKeyData.Addresses {
@@ -1313,10 +1606,7 @@ func InsertVouts(db *sql.DB, dbVouts []*dbtypes.Vout, checked bool) ([]uint64, [
 		ids = append(ids, id)
 	}
 
-	// Close prepared statement. Ignore errors as we'll Commit regardless.
-	_ = stmt.Close()
-
-	return ids, addressRows, dbtx.Commit()
+	return ids, addressRows, stmt.Close()
 }
 
 func InsertAddressOut(db *sql.DB, dbA *dbtypes.AddressRow, dupCheck bool) (uint64, error) {
@@ -1345,6 +1635,18 @@ func InsertAddressOuts(db *sql.DB, dbAs []*dbtypes.AddressRow, dupCheck bool) ([
 		return nil, fmt.Errorf("unable to begin database transaction: %v", err)
 	}
 
+	ids, err := insertAddressOuts(dbtx, dbAs, dupCheck)
+	if err != nil {
+		if errRoll := dbtx.Rollback(); errRoll != nil {
+			log.Errorf("Rollback failed: %v", errRoll)
+		}
+		return ids, err
+	}
+
+	return ids, dbtx.Commit()
+}
+
+func insertAddressOuts(dbtx *sql.Tx, dbAs []*dbtypes.AddressRow, dupCheck bool) ([]uint64, error) {
 	sqlStmt := internal.InsertAddressRow
 	if dupCheck {
 		sqlStmt = internal.UpsertAddressRow
@@ -1353,7 +1655,6 @@ func InsertAddressOuts(db *sql.DB, dbAs []*dbtypes.AddressRow, dupCheck bool) ([
 	stmt, err := dbtx.Prepare(sqlStmt)
 	if err != nil {
 		log.Errorf("AddressRow INSERT prepare: %v", err)
-		_ = dbtx.Rollback() // try, but we want the Prepare error back
 		return nil, err
 	}
 
@@ -1367,18 +1668,12 @@ func InsertAddressOuts(db *sql.DB, dbAs []*dbtypes.AddressRow, dupCheck bool) ([
 				continue
 			}
 			_ = stmt.Close() // try, but we want the QueryRow error back
-			if errRoll := dbtx.Rollback(); errRoll != nil {
-				log.Errorf("Rollback failed: %v", errRoll)
-			}
 			return nil, err
 		}
 		ids = append(ids, id)
 	}
 
-	// Close prepared statement. Ignore errors as we'll Commit regardless.
-	_ = stmt.Close()
-
-	return ids, dbtx.Commit()
+	return ids, stmt.Close()
 }
 
 // InsertTickets takes a slice of *dbtypes.Tx and corresponding DB row IDs for
@@ -1391,10 +1686,21 @@ func InsertTickets(db *sql.DB, dbTxns []*dbtypes.Tx, txDbIDs []uint64, checked b
 		return nil, nil, fmt.Errorf("unable to begin database transaction: %v", err)
 	}
 
+	ids, ticketTx, err := insertTickets(dbtx, dbTxns, txDbIDs, checked)
+	if err != nil {
+		if errRoll := dbtx.Rollback(); errRoll != nil {
+			log.Errorf("Rollback failed: %v", errRoll)
+		}
+		return ids, ticketTx, err
+	}
+
+	return ids, ticketTx, dbtx.Commit()
+}
+
+func insertTickets(dbtx *sql.Tx, dbTxns []*dbtypes.Tx, txDbIDs []uint64, checked bool) ([]uint64, []*dbtypes.Tx, error) {
 	stmt, err := dbtx.Prepare(internal.MakeTicketInsertStatement(checked))
 	if err != nil {
 		log.Errorf("Ticket INSERT prepare: %v", err)
-		_ = dbtx.Rollback() // try, but we want the Prepare error back
 		return nil, nil, err
 	}
 
@@ -1438,18 +1744,12 @@ func InsertTickets(db *sql.DB, dbTxns []*dbtypes.Tx, txDbIDs []uint64, checked b
 				continue
 			}
 			_ = stmt.Close() // try, but we want the QueryRow error back
-			if errRoll := dbtx.Rollback(); errRoll != nil {
-				log.Errorf("Rollback failed: %v", errRoll)
-			}
 			return nil, nil, err
 		}
 		ids = append(ids, id)
 	}
 
-	// Close prepared statement. Ignore errors as we'll Commit regardless.
-	_ = stmt.Close()
-
-	return ids, ticketTx, dbtx.Commit()
+	return ids, ticketTx, stmt.Close()
 
 }
 
@@ -1464,7 +1764,27 @@ func InsertTickets(db *sql.DB, dbTxns []*dbtypes.Tx, txDbIDs []uint64, checked b
 // in the next block).
 //
 // Outputs are slices of DB row IDs for the votes and misses, and an error.
-func InsertVotes(db *sql.DB, dbTxns []*dbtypes.Tx, _ /*txDbIDs*/ []uint64,
+func InsertVotes(db *sql.DB, dbTxns []*dbtypes.Tx, txDbIDs []uint64,
+	fTx *TicketTxnIDGetter, msgBlock *MsgBlockPG, checked bool) ([]uint64,
+	[]*dbtypes.Tx, []string, []uint64, map[string]uint64, error) {
+	dbtx, err := db.Begin()
+	if err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("unable to begin database transaction: %v", err)
+	}
+
+	ids, voteTxs, spentTicketHashes, spentTicketDbIDs, missHashMap, err :=
+		insertVotes(dbtx, dbTxns, txDbIDs, fTx, msgBlock, checked)
+	if err != nil {
+		if errRoll := dbtx.Rollback(); errRoll != nil {
+			log.Errorf("Rollback failed: %v", errRoll)
+		}
+		return nil, nil, nil, nil, nil, err
+	}
+
+	return ids, voteTxs, spentTicketHashes, spentTicketDbIDs, missHashMap, dbtx.Commit()
+}
+
+func insertVotes(dbtx *sql.Tx, dbTxns []*dbtypes.Tx, _ /*txDbIDs*/ []uint64,
 	fTx *TicketTxnIDGetter, msgBlock *MsgBlockPG, checked bool) ([]uint64,
 	[]*dbtypes.Tx, []string, []uint64, map[string]uint64, error) {
 	// Choose only SSGen txns
@@ -1487,16 +1807,10 @@ func InsertVotes(db *sql.DB, dbTxns []*dbtypes.Tx, _ /*txDbIDs*/ []uint64,
 		return nil, nil, nil, nil, nil, nil
 	}
 
-	// Start DB transaction and prepare vote insert statement
-	dbtx, err := db.Begin()
-	if err != nil {
-		return nil, nil, nil, nil, nil, fmt.Errorf("unable to begin database transaction: %v", err)
-	}
-
+	// Prepare vote insert statement
 	stmt, err := dbtx.Prepare(internal.MakeVoteInsertStatement(checked))
 	if err != nil {
 		log.Errorf("Votes INSERT prepare: %v", err)
-		_ = dbtx.Rollback() // try, but we want the Prepare error back
 		return nil, nil, nil, nil, nil, err
 	}
 
@@ -1513,6 +1827,7 @@ func InsertVotes(db *sql.DB, dbTxns []*dbtypes.Tx, _ /*txDbIDs*/ []uint64,
 		voteVersion := stake.SSGenVersion(msgTx)
 		validBlock, voteBits, err := txhelpers.SSGenVoteBlockValid(msgTx)
 		if err != nil {
+			_ = stmt.Close()
 			return nil, nil, nil, nil, nil, err
 		}
 
@@ -1525,9 +1840,6 @@ func InsertVotes(db *sql.DB, dbTxns []*dbtypes.Tx, _ /*txDbIDs*/ []uint64,
 			t, err := fTx.TxnDbID(stakeSubmissionTxHash, true)
 			if err != nil {
 				_ = stmt.Close() // try, but we want the QueryRow error back
-				if errRoll := dbtx.Rollback(); errRoll != nil {
-					log.Errorf("Rollback failed: %v", errRoll)
-				}
 				return nil, nil, nil, nil, nil, err
 			}
 			ticketTxDbID.Int64 = int64(t)
@@ -1555,9 +1867,6 @@ func InsertVotes(db *sql.DB, dbTxns []*dbtypes.Tx, _ /*txDbIDs*/ []uint64,
 				continue
 			}
 			_ = stmt.Close() // try, but we want the QueryRow error back
-			if errRoll := dbtx.Rollback(); errRoll != nil {
-				log.Errorf("Rollback failed: %v", errRoll)
-			}
 			return nil, nil, nil, nil, nil, err
 		}
 		ids = append(ids, id)
@@ -1569,7 +1878,6 @@ func InsertVotes(db *sql.DB, dbTxns []*dbtypes.Tx, _ /*txDbIDs*/ []uint64,
 	if len(ids)+len(misses) != 5 {
 		fmt.Println(misses)
 		fmt.Println(voteTxs)
-		_ = dbtx.Rollback()
 		panic(fmt.Sprintf("votes (%d) + misses (%d) != 5", len(ids), len(misses)))
 	}
 
@@ -1579,7 +1887,6 @@ func InsertVotes(db *sql.DB, dbTxns []*dbtypes.Tx, _ /*txDbIDs*/ []uint64,
 		stmtMissed, err := dbtx.Prepare(internal.MakeMissInsertStatement(checked))
 		if err != nil {
 			log.Errorf("Miss INSERT prepare: %v", err)
-			_ = dbtx.Rollback() // try, but we want the Prepare error back
 			return nil, nil, nil, nil, nil, err
 		}
 
@@ -1594,9 +1901,6 @@ func InsertVotes(db *sql.DB, dbTxns []*dbtypes.Tx, _ /*txDbIDs*/ []uint64,
 					continue
 				}
 				_ = stmtMissed.Close() // try, but we want the QueryRow error back
-				if errRoll := dbtx.Rollback(); errRoll != nil {
-					log.Errorf("Rollback failed: %v", errRoll)
-				}
 				return nil, nil, nil, nil, nil, err
 			}
 			missHashMap[misses[i]] = id
@@ -1604,7 +1908,7 @@ func InsertVotes(db *sql.DB, dbTxns []*dbtypes.Tx, _ /*txDbIDs*/ []uint64,
 		_ = stmtMissed.Close()
 	}
 
-	return ids, voteTxs, spentTicketHashes, spentTicketDbIDs, missHashMap, dbtx.Commit()
+	return ids, voteTxs, spentTicketHashes, spentTicketDbIDs, missHashMap, nil
 }
 
 func InsertTx(db *sql.DB, dbTx *dbtypes.Tx, checked bool) (uint64, error) {
@@ -1615,20 +1919,33 @@ func InsertTx(db *sql.DB, dbTx *dbtypes.Tx, checked bool) (uint64, error) {
 		dbTx.TxType, dbTx.Version, dbTx.Tree, dbTx.TxID, dbTx.BlockIndex,
 		dbTx.Locktime, dbTx.Expiry, dbTx.Size, dbTx.Spent, dbTx.Sent, dbTx.Fees,
 		dbTx.NumVin, dbtypes.UInt64Array(dbTx.VinDbIds),
-		dbTx.NumVout, dbtypes.UInt64Array(dbTx.VoutDbIds)).Scan(&id)
+		dbTx.NumVout, dbtypes.UInt64Array(dbTx.VoutDbIds), dbTx.Class).Scan(&id)
 	return id, err
 }
 
+// InsertTxns inserts the transactions in a single database transaction,
+// returning their DB row IDs.
 func InsertTxns(db *sql.DB, dbTxns []*dbtypes.Tx, checked bool) ([]uint64, error) {
 	dbtx, err := db.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("unable to begin database transaction: %v", err)
 	}
 
+	ids, err := insertTxns(dbtx, dbTxns, checked)
+	if err != nil {
+		if errRoll := dbtx.Rollback(); errRoll != nil {
+			log.Errorf("Rollback failed: %v", errRoll)
+		}
+		return ids, err
+	}
+
+	return ids, dbtx.Commit()
+}
+
+func insertTxns(dbtx *sql.Tx, dbTxns []*dbtypes.Tx, checked bool) ([]uint64, error) {
 	stmt, err := dbtx.Prepare(internal.MakeTxInsertStatement(checked))
 	if err != nil {
 		log.Errorf("Transaction INSERT prepare: %v", err)
-		_ = dbtx.Rollback() // try, but we want the Prepare error back
 		return nil, err
 	}
 
@@ -1640,22 +1957,16 @@ func InsertTxns(db *sql.DB, dbTxns []*dbtypes.Tx, checked bool) ([]uint64, error
 			tx.TxType, tx.Version, tx.Tree, tx.TxID, tx.BlockIndex,
 			tx.Locktime, tx.Expiry, tx.Size, tx.Spent, tx.Sent, tx.Fees,
 			tx.NumVin, dbtypes.UInt64Array(tx.VinDbIds),
-			tx.NumVout, dbtypes.UInt64Array(tx.VoutDbIds)).Scan(&id)
+			tx.NumVout, dbtypes.UInt64Array(tx.VoutDbIds), tx.Class).Scan(&id)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				continue
 			}
 			_ = stmt.Close() // try, but we want the QueryRow error back
-			if errRoll := dbtx.Rollback(); errRoll != nil {
-				log.Errorf("Rollback failed: %v", errRoll)
-			}
 			return nil, err
 		}
 		ids = append(ids, id)
 	}
 
-	// Close prepared statement. Ignore errors as we'll Commit regardless.
-	_ = stmt.Close()
-
-	return ids, dbtx.Commit()
+	return ids, stmt.Close()
 }
@@ -0,0 +1,287 @@
+// Copyright (c) 2018, The dcrdata developers
+// See LICENSE for details.
+
+package dcrpg
+
+import (
+	"database/sql"
+	"math"
+	"sync"
+	"time"
+
+	apitypes "github.com/decred/dcrdata/api/types"
+	"github.com/decred/dcrdata/db/dcrpg/internal"
+	"github.com/lib/pq"
+)
+
+// The address_balances table summarizes the addresses table by address, and
+// the coin_days_destroyed table holds the coin-days destroyed by each block.
+// Both are updated as each block is stored with its address spending info.
+// When the spending info is instead set in bulk after an import, the tables
+// are rebuilt with RebuildAddressStats. A rewind recomputes the summaries of
+// the addresses used by the removed blocks.
+
+// Address activity windows.
+const (
+	activeDayWindow   = 24 * time.Hour
+	activeWeekWindow  = 7 * 24 * time.Hour
+	activeMonthWindow = 30 * 24 * time.Hour
+	dormantWindow     = 365 * 24 * time.Hour
+)
+
+// addressStatsCache holds the address balance distribution and activity
+// counts as of the best block at the time they were computed.
+type addressStatsCache struct {
+	sync.Mutex
+	height       int64
+	distribution *apitypes.AddressDistribution
+	activity     *apitypes.AddressActivity
+}
+
+// UpdateAddressBalancesForBlock updates the address summaries with the outputs
+// funded and spent by the transactions of a block, which must be stored with
+// spending info in the addresses table.
+func UpdateAddressBalancesForBlock(db SqlExecutor, blockHash string, height, blockTime int64) error {
+	_, err := db.Exec(internal.UpdateAddressBalancesForBlock, blockHash,
+		height, blockTime)
+	return err
+}
+
+// InsertCoinDaysDestroyed inserts the coin-days destroyed by a stored block.
+func InsertCoinDaysDestroyed(db SqlExecutor, blockHash string, checked bool) error {
+	_, err := db.Exec(internal.MakeCoinDaysDestroyedInsertStatement(checked),
+		blockHash)
+	return err
+}
+
+// retrieveAddressesAboveHeight retrieves the addresses funded or spent by the
+// transactions of the blocks above the given height.
+func retrieveAddressesAboveHeight(db SqlExecutor, height int64) ([]string, error) {
+	rows, err := db.Query(internal.SelectAddressesAboveHeight, height)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	var addresses []string
+	for rows.Next() {
+		var address string
+		if err = rows.Scan(&address); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, rows.Err()
+}
+
+// recomputeAddressBalances recomputes the summaries of the given addresses from
+// the addresses table, removing those of addresses with no remaining rows.
+func recomputeAddressBalances(db SqlExecutor, addresses []string) error {
+	if len(addresses) == 0 {
+		return nil
+	}
+	if _, err := db.Exec(internal.UpsertAddressBalancesFor,
+		pq.Array(addresses)); err != nil {
+		return err
+	}
+	_, err := db.Exec(internal.DeleteAddressBalancesWithoutRows,
+		pq.Array(addresses))
+	return err
+}
+
+// RebuildAddressBalances replaces the address summaries with ones computed
+// from the entire addresses table.
+func RebuildAddressBalances(db SqlExecutor) error {
+	if _, err := db.Exec(internal.DeleteAddressBalances); err != nil {
+		return err
+	}
+	_, err := db.Exec(internal.InsertAllAddressBalances)
+	return err
+}
+
+// RebuildCoinDaysDestroyed replaces the coin-days destroyed with those computed
+// for all stored blocks.
+func RebuildCoinDaysDestroyed(db SqlExecutor) error {
+	if _, err := db.Exec(internal.DeleteCoinDaysDestroyed); err != nil {
+		return err
+	}
+	_, err := db.Exec(internal.InsertAllCoinDaysDestroyed)
+	return err
+}
+
+// RetrieveRichList retrieves the N addresses with the largest balances.
+func RetrieveRichList(db *sql.DB, N int) ([]apitypes.RichListEntry, error) {
+	rows, err := db.Query(internal.SelectRichList, N)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	var entries []apitypes.RichListEntry
+	for rows.Next() {
+		e := apitypes.RichListEntry{Rank: len(entries) + 1}
+		err = rows.Scan(&e.Address, &e.Balance, &e.TotalReceived,
+			&e.NumUnspent, &e.LastActiveHeight, &e.LastActiveTime)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// RetrieveBalanceDistribution retrieves the number and total balance of the
+// addresses with balances in each order of magnitude of DCR.
+func RetrieveBalanceDistribution(db *sql.DB) ([]apitypes.BalanceBucket, error) {
+	rows, err := db.Query(internal.SelectBalanceDistribution)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	var buckets []apitypes.BalanceBucket
+	for rows.Next() {
+		var bucket int
+		var b apitypes.BalanceBucket
+		if err = rows.Scan(&bucket, &b.Count, &b.Balance); err != nil {
+			return nil, err
+		}
+		if bucket > 0 {
+			b.Min = math.Pow10(bucket - 1)
+		}
+		b.Max = math.Pow10(bucket)
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// RetrieveAddressActivity counts the addresses that have been active within
+// each of the activity windows preceding now, and those that hold a balance
+// but have been dormant for the dormant window.
+func RetrieveAddressActivity(db *sql.DB, now time.Time) (*apitypes.AddressActivity, error) {
+	since := func(window time.Duration) int64 {
+		return now.Add(-window).Unix()
+	}
+	var a apitypes.AddressActivity
+	err := db.QueryRow(internal.SelectAddressActivity, since(activeDayWindow),
+		since(activeWeekWindow), since(activeMonthWindow),
+		since(dormantWindow)).Scan(&a.Total, &a.Funded, &a.ActiveDay,
+		&a.ActiveWeek, &a.ActiveMonth, &a.DormantYear)
+	return &a, err
+}
+
+// RetrieveCoinDaysDestroyed retrieves the coin-days destroyed by the blocks
+// mined between the UNIX times from and to.
+func RetrieveCoinDaysDestroyed(db *sql.DB, from, to int64) ([]apitypes.BlockCoinDays, error) {
+	rows, err := db.Query(internal.SelectCoinDaysDestroyed, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	var blocks []apitypes.BlockCoinDays
+	for rows.Next() {
+		var b apitypes.BlockCoinDays
+		if err = rows.Scan(&b.Height, &b.Hash, &b.Time, &b.CoinDays); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, rows.Err()
+}
+
+// RebuildAddressStats rebuilds the address summaries and the coin-days
+// destroyed by each block. This is intended to be used after the spending info
+// of the addresses table is set in bulk.
+func (pgb *ChainDB) RebuildAddressStats() error {
+	log.Infof("Rebuilding address balances...")
+	if err := RebuildAddressBalances(pgb.db); err != nil {
+		return err
+	}
+	log.Infof("Rebuilding coin days destroyed...")
+	return RebuildCoinDaysDestroyed(pgb.db)
+}
+
+// RichList retrieves the N addresses with the largest balances.
+func (pgb *ChainDB) RichList(N int) (*apitypes.RichList, error) {
+	entries, err := RetrieveRichList(pgb.db, N)
+	if err != nil {
+		return nil, err
+	}
+	return &apitypes.RichList{
+		Height:    pgb.bestBlock,
+		Addresses: entries,
+	}, nil
+}
+
+// refreshAddressStats recomputes the cached address statistics if the best
+// block has changed since they were computed. The cache must be locked.
+func (pgb *ChainDB) refreshAddressStats() error {
+	c := pgb.addressStats
+	height := pgb.bestBlock
+	if c.distribution != nil && c.height == height {
+		return nil
+	}
+
+	buckets, err := RetrieveBalanceDistribution(pgb.db)
+	if err != nil {
+		return err
+	}
+	activity, err := RetrieveAddressActivity(pgb.db, time.Now())
+	if err != nil {
+		return err
+	}
+	activity.Height = height
+
+	c.height = height
+	c.distribution = &apitypes.AddressDistribution{
+		Height:  height,
+		Buckets: buckets,
+	}
+	c.activity = activity
+	return nil
+}
+
+// AddressDistribution retrieves the distribution of address balances, which is
+// recomputed at most once per block.
+func (pgb *ChainDB) AddressDistribution() (*apitypes.AddressDistribution, error) {
+	pgb.addressStats.Lock()
+	defer pgb.addressStats.Unlock()
+	if err := pgb.refreshAddressStats(); err != nil {
+		return nil, err
+	}
+	return pgb.addressStats.distribution, nil
+}
+
+// AddressActivity retrieves the counts of active and dormant addresses, which
+// are recomputed at most once per block.
+func (pgb *ChainDB) AddressActivity() (*apitypes.AddressActivity, error) {
+	pgb.addressStats.Lock()
+	defer pgb.addressStats.Unlock()
+	if err := pgb.refreshAddressStats(); err != nil {
+		return nil, err
+	}
+	return pgb.addressStats.activity, nil
+}
+
+// CoinDaysDestroyed retrieves the coin-days destroyed by the blocks mined
+// between the UNIX times from and to.
+func (pgb *ChainDB) CoinDaysDestroyed(from, to int64) ([]apitypes.BlockCoinDays, error) {
+	return RetrieveCoinDaysDestroyed(pgb.db, from, to)
+}
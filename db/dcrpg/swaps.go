@@ -0,0 +1,141 @@
+// Copyright (c) 2018, The dcrdata developers
+// See LICENSE for details.
+
+package dcrpg
+
+import (
+	"database/sql"
+	"encoding/hex"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/wire"
+	apitypes "github.com/decred/dcrdata/api/types"
+	"github.com/decred/dcrdata/db/dbtypes"
+	"github.com/decred/dcrdata/db/dcrpg/internal"
+	"github.com/decred/dcrdata/txhelpers"
+)
+
+// The swaps table holds the atomic swap contracts redeemed or refunded by the
+// regular transactions of each stored block. Since the signature scripts are
+// not stored, the swaps resolved by the blocks stored before the table was
+// created are not found.
+
+// blockAtomicSwaps returns the atomic swaps resolved by the regular
+// transactions of a block.
+func blockAtomicSwaps(msgBlock *wire.MsgBlock, dbBlock *dbtypes.Block, params *chaincfg.Params) []*apitypes.AtomicSwap {
+	var swaps []*apitypes.AtomicSwap
+	for _, msgTx := range msgBlock.Transactions {
+		spends := txhelpers.ExtractAtomicSwapSpends(msgTx, params)
+		if len(spends) == 0 {
+			continue
+		}
+		spendTx := msgTx.TxHash().String()
+		for _, s := range spends {
+			swap := &apitypes.AtomicSwap{
+				ContractTxID:     s.ContractTx,
+				ContractVout:     s.ContractVout,
+				ContractAddress:  s.ContractAddress,
+				Contract:         hex.EncodeToString(s.Contract),
+				Value:            s.Value,
+				RecipientAddress: s.RecipientAddress,
+				RefundAddress:    s.RefundAddress,
+				SecretHash:       s.SecretHash,
+				SecretSize:       s.SecretSize,
+				LockTime:         s.LockTime,
+				Status:           apitypes.SwapRefunded,
+				SpendTxID:        spendTx,
+				SpendVin:         s.SpendVin,
+				BlockHash:        dbBlock.Hash,
+				BlockHeight:      int64(dbBlock.Height),
+				Time:             int64(dbBlock.Time),
+			}
+			if !s.IsRefund() {
+				swap.Status = apitypes.SwapRedeemed
+				swap.Secret = hex.EncodeToString(s.Secret)
+			}
+			swaps = append(swaps, swap)
+		}
+	}
+	return swaps
+}
+
+// InsertAtomicSwaps inserts the atomic swaps resolved by a block.
+func InsertAtomicSwaps(db SqlExecutor, swaps []*apitypes.AtomicSwap, checked bool) error {
+	stmt := internal.MakeAtomicSwapInsertStatement(checked)
+	for _, s := range swaps {
+		contract, err := hex.DecodeString(s.Contract)
+		if err != nil {
+			return err
+		}
+		var secret sql.NullString
+		if s.Status == apitypes.SwapRedeemed {
+			secret = sql.NullString{String: s.Secret, Valid: true}
+		}
+		_, err = db.Exec(stmt, s.ContractTxID, s.ContractVout, s.ContractAddress,
+			contract, s.Value, s.RecipientAddress, s.RefundAddress, s.SecretHash,
+			s.SecretSize, s.LockTime, secret, s.SpendTxID, s.SpendVin,
+			s.BlockHash, s.BlockHeight, s.Time)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retrieveAtomicSwaps retrieves the atomic swaps selected by the query.
+func retrieveAtomicSwaps(db *sql.DB, query string, args ...interface{}) ([]apitypes.AtomicSwap, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	var swaps []apitypes.AtomicSwap
+	for rows.Next() {
+		var s apitypes.AtomicSwap
+		var contract []byte
+		var secret sql.NullString
+		err = rows.Scan(&s.ContractTxID, &s.ContractVout, &s.ContractAddress,
+			&contract, &s.Value, &s.RecipientAddress, &s.RefundAddress,
+			&s.SecretHash, &s.SecretSize, &s.LockTime, &secret, &s.SpendTxID,
+			&s.SpendVin, &s.BlockHash, &s.BlockHeight, &s.Time)
+		if err != nil {
+			return nil, err
+		}
+		s.Contract = hex.EncodeToString(contract)
+		s.Status = apitypes.SwapRefunded
+		if secret.Valid {
+			s.Status, s.Secret = apitypes.SwapRedeemed, secret.String
+		}
+		swaps = append(swaps, s)
+	}
+	return swaps, rows.Err()
+}
+
+// RetrieveAtomicSwaps retrieves the N most recently resolved atomic swaps,
+// skipping the first offset.
+func RetrieveAtomicSwaps(db *sql.DB, N, offset int64) ([]apitypes.AtomicSwap, error) {
+	return retrieveAtomicSwaps(db, internal.SelectAtomicSwaps, N, offset)
+}
+
+// RetrieveTxAtomicSwaps retrieves the atomic swaps funded or resolved by the
+// transaction with the given hash.
+func RetrieveTxAtomicSwaps(db *sql.DB, txHash string) ([]apitypes.AtomicSwap, error) {
+	return retrieveAtomicSwaps(db, internal.SelectAtomicSwapsByTxHash, txHash)
+}
+
+// AtomicSwaps retrieves the N most recently resolved atomic swaps, skipping
+// the first offset.
+func (pgb *ChainDB) AtomicSwaps(N, offset int64) ([]apitypes.AtomicSwap, error) {
+	return RetrieveAtomicSwaps(pgb.db, N, offset)
+}
+
+// TxAtomicSwaps retrieves the atomic swaps funded or resolved by the
+// transaction with the given hash.
+func (pgb *ChainDB) TxAtomicSwaps(txid string) ([]apitypes.AtomicSwap, error) {
+	return RetrieveTxAtomicSwaps(pgb.db, txid)
+}
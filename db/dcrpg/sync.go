@@ -225,6 +225,13 @@ func (db *ChainDB) SyncChainDB(client rpcutils.MasterBlockGetter, quit chan stru
 		if err = db.IndexAddressTable(); err != nil {
 			log.Errorf("IndexAddressTable FAILED: %v", err)
 		}
+		// Summarize the addresses table now that it has spending info
+		if err = db.RebuildAddressStats(); err != nil {
+			log.Errorf("RebuildAddressStats FAILED: %v", err)
+		}
+		if err = db.RebuildUtxoSet(); err != nil {
+			log.Errorf("RebuildUtxoSet FAILED: %v", err)
+		}
 	}
 
 	// Batch update tickets table with spending info
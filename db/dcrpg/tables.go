@@ -13,15 +13,22 @@ import (
 )
 
 var createTableStatements = map[string]string{
-	"blocks":       internal.CreateBlockTable,
-	"transactions": internal.CreateTransactionTable,
-	"vins":         internal.CreateVinTable,
-	"vouts":        internal.CreateVoutTable,
-	"block_chain":  internal.CreateBlockPrevNextTable,
-	"addresses":    internal.CreateAddressTable,
-	"tickets":      internal.CreateTicketsTable,
-	"votes":        internal.CreateVotesTable,
-	"misses":       internal.CreateMissesTable,
+	"blocks":              internal.CreateBlockTable,
+	"transactions":        internal.CreateTransactionTable,
+	"vins":                internal.CreateVinTable,
+	"vouts":               internal.CreateVoutTable,
+	"block_chain":         internal.CreateBlockPrevNextTable,
+	"addresses":           internal.CreateAddressTable,
+	"tickets":             internal.CreateTicketsTable,
+	"votes":               internal.CreateVotesTable,
+	"misses":              internal.CreateMissesTable,
+	"vote_summary":        internal.CreateVoteSummaryTable,
+	"treasury":            internal.CreateTreasuryTable,
+	"address_balances":    internal.CreateAddressBalancesTable,
+	"coin_days_destroyed": internal.CreateCoinDaysDestroyedTable,
+	"utxo_heights":        internal.CreateUtxoHeightsTable,
+	"utxo_snapshots":      internal.CreateUtxoSnapshotsTable,
+	"swaps":               internal.CreateAtomicSwapsTable,
 }
 
 var createTypeStatements = map[string]string{
@@ -39,15 +46,22 @@ var createTypeStatements = map[string]string{
 const tableMajor = 2
 
 var requiredVersions = map[string]TableVersion{
-	"blocks":       NewTableVersion(tableMajor, 0, 0),
-	"transactions": NewTableVersion(tableMajor, 0, 0),
-	"vins":         NewTableVersion(tableMajor, 0, 0),
-	"vouts":        NewTableVersion(tableMajor, 0, 0),
-	"block_chain":  NewTableVersion(tableMajor, 0, 0),
-	"addresses":    NewTableVersion(tableMajor, 0, 0),
-	"tickets":      NewTableVersion(tableMajor, 0, 0),
-	"votes":        NewTableVersion(tableMajor, 0, 0),
-	"misses":       NewTableVersion(tableMajor, 0, 0),
+	"blocks":              NewTableVersion(tableMajor, 0, 1),
+	"transactions":        NewTableVersion(tableMajor, 1, 0),
+	"vins":                NewTableVersion(tableMajor, 0, 0),
+	"vouts":               NewTableVersion(tableMajor, 0, 0),
+	"block_chain":         NewTableVersion(tableMajor, 0, 0),
+	"addresses":           NewTableVersion(tableMajor, 0, 0),
+	"tickets":             NewTableVersion(tableMajor, 0, 0),
+	"votes":               NewTableVersion(tableMajor, 0, 0),
+	"misses":              NewTableVersion(tableMajor, 0, 0),
+	"vote_summary":        NewTableVersion(tableMajor, 1, 0),
+	"treasury":            NewTableVersion(tableMajor, 2, 0),
+	"address_balances":    NewTableVersion(tableMajor, 1, 0),
+	"coin_days_destroyed": NewTableVersion(tableMajor, 1, 0),
+	"utxo_heights":        NewTableVersion(tableMajor, 1, 0),
+	"utxo_snapshots":      NewTableVersion(tableMajor, 0, 0),
+	"swaps":               NewTableVersion(tableMajor, 0, 0),
 }
 
 // TableVersion models a table version by major.minor.patch
@@ -165,8 +179,43 @@ func TypeExists(db *sql.DB, tableName string) (bool, error) {
 	return false, err
 }
 
+// addedTableIndexes are the indexes of the tables added after the tables were
+// first created. IndexAll only runs after a full sync, so these indexes are
+// created with the table when it is added to existing tables. The unique
+// indexes are required by the upserts of StoreBlock.
+var addedTableIndexes = map[string][]string{
+	"vote_summary": {internal.IndexVoteSummaryTableOnHash,
+		internal.IndexVoteSummaryTableOnTime},
+	"treasury":         {internal.IndexTreasuryTableOnHashes},
+	"address_balances": {internal.IndexAddressBalancesTableOnBalance},
+	"coin_days_destroyed": {internal.IndexCoinDaysDestroyedTableOnHash,
+		internal.IndexCoinDaysDestroyedTableOnTime},
+	"utxo_snapshots": {internal.IndexUtxoSnapshotsTableOnHash,
+		internal.IndexUtxoSnapshotsTableOnTime},
+	"swaps": {internal.IndexAtomicSwapsTableOnSpend,
+		internal.IndexAtomicSwapsTableOnContract,
+		internal.IndexAtomicSwapsTableOnHeight},
+}
+
+// createAddedTableIndexes creates the indexes of a table added to existing
+// tables.
+func createAddedTableIndexes(db *sql.DB, tableName string) error {
+	for _, stmt := range addedTableIndexes[tableName] {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func CreateTables(db *sql.DB) error {
-	var err error
+	// Tables added to existing tables are created at their initial version so
+	// that their upgrades populate them, and are indexed when created.
+	existingTables, err := TableExists(db, "blocks")
+	if err != nil {
+		return err
+	}
+
 	for tableName, createCommand := range createTableStatements {
 		var exists bool
 		exists, err = TableExists(db, tableName)
@@ -174,7 +223,7 @@ func CreateTables(db *sql.DB) error {
 			return err
 		}
 
-		tableVersion, ok := requiredVersions[tableName]
+		tableVersion, ok := initialVersion(tableName, existingTables)
 		if !ok {
 			return fmt.Errorf("no version assigned to table %s", tableName)
 		}
@@ -190,6 +239,11 @@ func CreateTables(db *sql.DB) error {
 			if err != nil {
 				return err
 			}
+			if existingTables {
+				if err = createAddedTableIndexes(db, tableName); err != nil {
+					return err
+				}
+			}
 		} else {
 			log.Tracef("Table \"%s\" exist.", tableName)
 		}
@@ -204,7 +258,11 @@ func CreateTable(db *sql.DB, tableName string) error {
 	if !tableNameFound {
 		return fmt.Errorf("table name %s unknown", tableName)
 	}
-	tableVersion, ok := requiredVersions[tableName]
+	existingTables, err := TableExists(db, "blocks")
+	if err != nil {
+		return err
+	}
+	tableVersion, ok := initialVersion(tableName, existingTables)
 	if !ok {
 		return fmt.Errorf("no version assigned to table %s", tableName)
 	}
@@ -226,6 +284,11 @@ func CreateTable(db *sql.DB, tableName string) error {
 		if err != nil {
 			return err
 		}
+		if existingTables {
+			if err = createAddedTableIndexes(db, tableName); err != nil {
+				return err
+			}
+		}
 	} else {
 		log.Tracef("Table \"%s\" exist.", tableName)
 	}
@@ -350,6 +413,16 @@ func DeindexTransactionTableOnBlockIn(db *sql.DB) (err error) {
 	return
 }
 
+func IndexTransactionTableOnClass(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexTransactionTableOnClass)
+	return
+}
+
+func DeindexTransactionTableOnClass(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexTransactionTableOnClass)
+	return
+}
+
 // Blocks table indexes
 
 func IndexBlockTableOnHash(db *sql.DB) (err error) {
@@ -362,6 +435,16 @@ func DeindexBlockTableOnHash(db *sql.DB) (err error) {
 	return
 }
 
+func IndexBlockTableOnTime(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexBlockTableOnTime)
+	return
+}
+
+func DeindexBlockTableOnTime(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexBlockTableOnTime)
+	return
+}
+
 // Vouts table indexes
 
 // func IndexVoutTableOnTxHash(db *sql.DB) (err error) {
@@ -416,6 +499,16 @@ func DeindexAddressTableOnTxHash(db *sql.DB) (err error) {
 	return
 }
 
+func IndexAddressTableOnUnspentValue(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexAddressTableOnUnspentValue)
+	return
+}
+
+func DeindexAddressTableOnUnspentValue(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexAddressTableOnUnspentValue)
+	return
+}
+
 // Votes table indexes
 
 func IndexVotesTableOnHashes(db *sql.DB) (err error) {
@@ -481,3 +574,125 @@ func DeindexMissesTableOnHash(db *sql.DB) (err error) {
 	_, err = db.Exec(internal.DeindexMissesTableOnHashes)
 	return
 }
+
+// Vote summary table indexes
+
+func IndexVoteSummaryTableOnHash(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexVoteSummaryTableOnHash)
+	return
+}
+
+func DeindexVoteSummaryTableOnHash(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexVoteSummaryTableOnHash)
+	return
+}
+
+func IndexVoteSummaryTableOnTime(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexVoteSummaryTableOnTime)
+	return
+}
+
+func DeindexVoteSummaryTableOnTime(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexVoteSummaryTableOnTime)
+	return
+}
+
+// Treasury table indexes
+
+func IndexTreasuryTableOnHashes(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexTreasuryTableOnHashes)
+	return
+}
+
+func DeindexTreasuryTableOnHashes(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexTreasuryTableOnHashes)
+	return
+}
+
+// Address balances table indexes
+
+func IndexAddressBalancesTableOnBalance(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexAddressBalancesTableOnBalance)
+	return
+}
+
+func DeindexAddressBalancesTableOnBalance(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexAddressBalancesTableOnBalance)
+	return
+}
+
+// Coin days destroyed table indexes
+
+func IndexCoinDaysDestroyedTableOnHash(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexCoinDaysDestroyedTableOnHash)
+	return
+}
+
+func DeindexCoinDaysDestroyedTableOnHash(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexCoinDaysDestroyedTableOnHash)
+	return
+}
+
+func IndexCoinDaysDestroyedTableOnTime(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexCoinDaysDestroyedTableOnTime)
+	return
+}
+
+func DeindexCoinDaysDestroyedTableOnTime(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexCoinDaysDestroyedTableOnTime)
+	return
+}
+
+// UTXO set snapshots table indexes
+
+func IndexUtxoSnapshotsTableOnHash(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexUtxoSnapshotsTableOnHash)
+	return
+}
+
+func DeindexUtxoSnapshotsTableOnHash(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexUtxoSnapshotsTableOnHash)
+	return
+}
+
+func IndexUtxoSnapshotsTableOnTime(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexUtxoSnapshotsTableOnTime)
+	return
+}
+
+func DeindexUtxoSnapshotsTableOnTime(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexUtxoSnapshotsTableOnTime)
+	return
+}
+
+// Atomic swaps table indexes
+
+func IndexAtomicSwapsTableOnSpend(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexAtomicSwapsTableOnSpend)
+	return
+}
+
+func DeindexAtomicSwapsTableOnSpend(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexAtomicSwapsTableOnSpend)
+	return
+}
+
+func IndexAtomicSwapsTableOnContract(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexAtomicSwapsTableOnContract)
+	return
+}
+
+func DeindexAtomicSwapsTableOnContract(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexAtomicSwapsTableOnContract)
+	return
+}
+
+func IndexAtomicSwapsTableOnHeight(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.IndexAtomicSwapsTableOnHeight)
+	return
+}
+
+func DeindexAtomicSwapsTableOnHeight(db *sql.DB) (err error) {
+	_, err = db.Exec(internal.DeindexAtomicSwapsTableOnHeight)
+	return
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2018, The dcrdata developers
+// See LICENSE for details.
+
+package dcrpg
+
+import (
+	"database/sql"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrdata/db/dbtypes"
+	"github.com/decred/dcrdata/db/dcrpg/internal"
+)
+
+// maxTicketPriceBins is the most price bins of the live tickets.
+const maxTicketPriceBins = 20
+
+// ticketPoolStatsCache holds the ticket pool statistics as of the best block of
+// the stake database at the time they were computed.
+type ticketPoolStatsCache struct {
+	sync.Mutex
+	stats *dbtypes.TicketPoolStats
+}
+
+// ticketPriceBinWidth picks a round bin width, of 1, 2 or 5 times a power of
+// ten, dividing the prices from minPrice to maxPrice into at most
+// maxTicketPriceBins bins.
+func ticketPriceBinWidth(minPrice, maxPrice float64) float64 {
+	span := (maxPrice - minPrice) / maxTicketPriceBins
+	if span <= 0 {
+		span = maxPrice
+	}
+	if span <= 0 {
+		return 1
+	}
+	pow := math.Pow(10, math.Floor(math.Log10(span)))
+	for _, m := range []float64{1, 2, 5} {
+		if m*pow >= span {
+			return m * pow
+		}
+	}
+	return 10 * pow
+}
+
+// ticketPriceBins bins the counts of live tickets by price, given in order of
+// increasing price.
+func ticketPriceBins(prices []float64, counts []int64) []dbtypes.TicketPriceBin {
+	if len(prices) == 0 {
+		return []dbtypes.TicketPriceBin{}
+	}
+	minPrice, maxPrice := prices[0], prices[len(prices)-1]
+	width := ticketPriceBinWidth(minPrice, maxPrice)
+	start := math.Floor(minPrice/width) * width
+	numBins := int((maxPrice-start)/width) + 1
+
+	bins := make([]dbtypes.TicketPriceBin, numBins)
+	for i := range bins {
+		bins[i].MinPrice = start + float64(i)*width
+		bins[i].MaxPrice = start + float64(i+1)*width
+	}
+	for i, price := range prices {
+		b := int((price - start) / width)
+		if b >= numBins {
+			b = numBins - 1
+		}
+		bins[b].Count += counts[i]
+	}
+	return bins
+}
+
+// RetrieveTicketPoolStats retrieves the immature tickets by the height at which
+// they mature, and the live tickets by purchase height and price, after the
+// block at the given height. The size and value of the live pool are not set.
+func RetrieveTicketPoolStats(db *sql.DB, height int64, params *chaincfg.Params) (*dbtypes.TicketPoolStats, error) {
+	maturity := int64(params.TicketMaturity)
+	stats := &dbtypes.TicketPoolStats{
+		Height:          height,
+		Maturing:        make([]dbtypes.TicketMaturityBin, maturity),
+		HeightBinSize:   int64(24*time.Hour) / int64(params.TargetTimePerBlock),
+		PurchaseHeights: []dbtypes.TicketHeightBin{},
+	}
+
+	// Tickets purchased in the last maturity blocks join the live pool in one
+	// of the next maturity blocks.
+	for i := range stats.Maturing {
+		stats.Maturing[i].Height = height + int64(i) + 1
+	}
+	rows, err := db.Query(internal.SelectImmatureTicketCounts, height-maturity)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var purchaseHeight, count int64
+		if err = rows.Scan(&purchaseHeight, &count); err != nil {
+			break
+		}
+		i := purchaseHeight + maturity - height - 1
+		if i >= 0 && i < maturity {
+			stats.Maturing[i].Count = count
+			stats.Immature += count
+		}
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	if e := rows.Close(); e != nil {
+		log.Errorf("Close of Query failed: %v", e)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(internal.SelectLiveTicketHeightBins, height-maturity,
+		stats.HeightBinSize)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var bin dbtypes.TicketHeightBin
+		if err = rows.Scan(&bin.Height, &bin.Count, &bin.Value); err != nil {
+			break
+		}
+		stats.PurchaseHeights = append(stats.PurchaseHeights, bin)
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	if e := rows.Close(); e != nil {
+		log.Errorf("Close of Query failed: %v", e)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(internal.SelectLiveTicketPriceCounts, height-maturity)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+	var prices []float64
+	var counts []int64
+	for rows.Next() {
+		var price float64
+		var count int64
+		if err = rows.Scan(&price, &count); err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+		counts = append(counts, count)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	stats.Prices = ticketPriceBins(prices, counts)
+	return stats, nil
+}
+
+// TicketPoolStats retrieves the statistics of the ticket pool after the best
+// block of the stake database, which gives the size and value of the pool. The
+// statistics are recomputed at most once per block.
+func (pgb *ChainDB) TicketPoolStats() (*dbtypes.TicketPoolStats, error) {
+	pgb.ticketPoolStats.Lock()
+	defer pgb.ticketPoolStats.Unlock()
+
+	poolInfo := pgb.stakeDB.PoolInfoBest()
+	height := int64(poolInfo.Height)
+	if pgb.ticketPoolStats.stats != nil && pgb.ticketPoolStats.stats.Height == height {
+		return pgb.ticketPoolStats.stats, nil
+	}
+
+	stats, err := RetrieveTicketPoolStats(pgb.db, height, pgb.chainParams)
+	if err != nil {
+		return nil, err
+	}
+	stats.Size, stats.Value = poolInfo.Size, poolInfo.Value
+	pgb.ticketPoolStats.stats = stats
+	return stats, nil
+}
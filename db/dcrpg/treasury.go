@@ -0,0 +1,393 @@
+// Copyright (c) 2018, The dcrdata developers
+// See LICENSE for details.
+
+package dcrpg
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/txscript"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrdata/db/dbtypes"
+	"github.com/decred/dcrdata/db/dcrpg/internal"
+	"github.com/lib/pq"
+)
+
+// treasuryLedger tracks the balance and unspent outputs of the development
+// subsidy address as of the best stored block, so that the transactions of
+// each new block paying to or spending from the address are recognized
+// without querying the addresses table.
+type treasuryLedger struct {
+	sync.RWMutex
+	address string
+	balance int64
+	unspent map[string]struct{}
+}
+
+// treasuryBlock is the result of processing a block with a treasuryLedger.
+// The changes are applied to the ledger once the block is stored, after
+// reversing those of the previous block's disapproved transactions.
+type treasuryBlock struct {
+	txns        []*dbtypes.TreasuryTx
+	created     []string
+	spent       []string
+	balance     int64
+	invalidated *treasuryInvalidation
+}
+
+// treasuryInvalidation reverses the changes to the ledger by the regular
+// transactions of a block that were disapproved by the votes of the next
+// block. net is the net amount they received, restored the outpoints they
+// spent that are unspent again, and removed the outpoints they created.
+type treasuryInvalidation struct {
+	net      int64
+	restored []string
+	removed  []string
+}
+
+func outpointString(hash fmt.Stringer, index uint32) string {
+	return fmt.Sprintf("%s:%d", hash, index)
+}
+
+// loadTreasuryLedger loads the balance and unspent outputs of the address from
+// the treasury table.
+func loadTreasuryLedger(db *sql.DB, address string) (*treasuryLedger, error) {
+	tl := &treasuryLedger{
+		address: address,
+		unspent: make(map[string]struct{}),
+	}
+	err := db.QueryRow(internal.SelectTreasuryBalance).Scan(&tl.balance)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	rows, err := db.Query(internal.SelectTreasuryUnspent)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+	for rows.Next() {
+		var outpoint string
+		if err = rows.Scan(&outpoint); err != nil {
+			return nil, err
+		}
+		tl.unspent[outpoint] = struct{}{}
+	}
+	return tl, rows.Err()
+}
+
+// processBlock finds the transactions in the block paying to or spending from
+// the address, in the order they appear in the regular and then stake trees.
+// If the block disapproved the previous block, the ledger is first reversed by
+// the invalidation of its regular transactions.
+func (tl *treasuryLedger) processBlock(msgBlock *wire.MsgBlock,
+	invalidated *treasuryInvalidation, params *chaincfg.Params) *treasuryBlock {
+	tl.RLock()
+	defer tl.RUnlock()
+
+	tb := &treasuryBlock{balance: tl.balance, invalidated: invalidated}
+	if tl.address == "" {
+		return tb
+	}
+
+	// Outputs of the address that are unspent after the invalidation.
+	unspent := func(outpoint string) bool {
+		_, ok := tl.unspent[outpoint]
+		return ok
+	}
+	if invalidated != nil {
+		tb.balance -= invalidated.net
+		removed := make(map[string]struct{}, len(invalidated.removed))
+		for _, outpoint := range invalidated.removed {
+			removed[outpoint] = struct{}{}
+		}
+		restored := make(map[string]struct{}, len(invalidated.restored))
+		for _, outpoint := range invalidated.restored {
+			restored[outpoint] = struct{}{}
+		}
+		unspent = func(outpoint string) bool {
+			if _, ok := removed[outpoint]; ok {
+				return false
+			}
+			if _, ok := restored[outpoint]; ok {
+				return true
+			}
+			_, ok := tl.unspent[outpoint]
+			return ok
+		}
+	}
+
+	blockHash := msgBlock.BlockHash().String()
+	// Outputs of the address created earlier in this block.
+	created := make(map[string]struct{})
+
+	process := func(txns []*wire.MsgTx, tree int8) {
+		for i, msgTx := range txns {
+			isCoinbase := tree == wire.TxTreeRegular && i == 0
+			txHash := msgTx.TxHash()
+			ttx := &dbtypes.TreasuryTx{
+				TxHash:      txHash.String(),
+				BlockHash:   blockHash,
+				BlockHeight: int64(msgBlock.Header.Height),
+				BlockTime:   msgBlock.Header.Timestamp.Unix(),
+			}
+
+			if !isCoinbase {
+				for _, txIn := range msgTx.TxIn {
+					prevOut := &txIn.PreviousOutPoint
+					outpoint := outpointString(prevOut.Hash, prevOut.Index)
+					if _, ok := created[outpoint]; ok {
+						delete(created, outpoint)
+					} else if !unspent(outpoint) {
+						continue
+					}
+					ttx.Sent += txIn.ValueIn
+					ttx.SpentOutpoints = append(ttx.SpentOutpoints, outpoint)
+				}
+			}
+
+			for vout, txOut := range msgTx.TxOut {
+				_, addrs, _, err := txscript.ExtractPkScriptAddrs(txOut.Version,
+					txOut.PkScript, params)
+				if err == nil && len(addrs) == 1 && addrs[0].EncodeAddress() == tl.address {
+					ttx.Received += txOut.Value
+					ttx.FundingVouts = append(ttx.FundingVouts, int32(vout))
+					created[outpointString(txHash, uint32(vout))] = struct{}{}
+					continue
+				}
+				if ttx.Sent > 0 {
+					var dest string
+					if len(addrs) > 0 {
+						dest = addrs[0].EncodeAddress()
+					}
+					ttx.Destinations = append(ttx.Destinations, dest)
+					ttx.DestinationValues = append(ttx.DestinationValues, txOut.Value)
+				}
+			}
+
+			if ttx.Received == 0 && ttx.Sent == 0 {
+				continue
+			}
+			switch {
+			case ttx.Sent > 0:
+				ttx.Type = dbtypes.TreasurySpend
+			case isCoinbase:
+				ttx.Type = dbtypes.TreasurySubsidy
+			default:
+				ttx.Type = dbtypes.TreasuryReceipt
+			}
+			tb.balance += ttx.Received - ttx.Sent
+			ttx.Balance = tb.balance
+			tb.txns = append(tb.txns, ttx)
+			tb.spent = append(tb.spent, ttx.SpentOutpoints...)
+		}
+	}
+	process(msgBlock.Transactions, wire.TxTreeRegular)
+	process(msgBlock.STransactions, wire.TxTreeStake)
+
+	for outpoint := range created {
+		tb.created = append(tb.created, outpoint)
+	}
+	return tb
+}
+
+// apply updates the ledger with a stored block.
+func (tl *treasuryLedger) apply(tb *treasuryBlock) {
+	tl.Lock()
+	defer tl.Unlock()
+	if tb.invalidated != nil {
+		for _, outpoint := range tb.invalidated.removed {
+			delete(tl.unspent, outpoint)
+		}
+		for _, outpoint := range tb.invalidated.restored {
+			tl.unspent[outpoint] = struct{}{}
+		}
+	}
+	for _, outpoint := range tb.spent {
+		delete(tl.unspent, outpoint)
+	}
+	for _, outpoint := range tb.created {
+		tl.unspent[outpoint] = struct{}{}
+	}
+	tl.balance = tb.balance
+}
+
+// InsertTreasuryTxns inserts the transactions into the treasury table. With
+// checked, existing rows for the transactions are replaced.
+func InsertTreasuryTxns(db SqlExecutor, txns []*dbtypes.TreasuryTx, checked bool) error {
+	stmt := internal.MakeTreasuryInsertStatement(checked)
+	for _, tx := range txns {
+		_, err := db.Exec(stmt, tx.TxHash, tx.BlockHash, tx.BlockHeight,
+			tx.BlockTime, tx.Type, tx.Received, tx.Sent, tx.Balance,
+			pq.Array(tx.FundingVouts), pq.Array(tx.SpentOutpoints),
+			pq.Array(tx.Destinations), pq.Array(tx.DestinationValues))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invalidateTreasuryTxns flags the ledger rows of the regular transactions of
+// the block, which were disapproved by the votes of the next block, as invalid,
+// and removes their net amount from the balances of the block's other rows.
+// The changes to reverse in the ledger are returned.
+func invalidateTreasuryTxns(dbtx *sql.Tx, blockHash string) (*treasuryInvalidation, error) {
+	rows, err := dbtx.Query(internal.InvalidateTreasuryRegularTxns, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	inv := new(treasuryInvalidation)
+	created := make(map[string]struct{})
+	var spent []string
+	for rows.Next() {
+		var txHash string
+		var net int64
+		var vouts []int64
+		var outpoints []string
+		err = rows.Scan(&txHash, &net, pq.Array(&vouts), pq.Array(&outpoints))
+		if err != nil {
+			return nil, err
+		}
+		inv.net += net
+		for _, vout := range vouts {
+			outpoint := fmt.Sprintf("%s:%d", txHash, vout)
+			created[outpoint] = struct{}{}
+			inv.removed = append(inv.removed, outpoint)
+		}
+		spent = append(spent, outpoints...)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Outputs both created and spent by the invalidated transactions were
+	// never unspent.
+	for _, outpoint := range spent {
+		if _, ok := created[outpoint]; !ok {
+			inv.restored = append(inv.restored, outpoint)
+		}
+	}
+	if inv.net != 0 {
+		_, err = dbtx.Exec(internal.AdjustTreasuryBlockBalances, blockHash, -inv.net)
+	}
+	return inv, err
+}
+
+// RetrieveTreasuryMonths retrieves the treasury inflows and outflows by month.
+func RetrieveTreasuryMonths(db *sql.DB) ([]dbtypes.TreasuryMonth, error) {
+	rows, err := db.Query(internal.SelectTreasuryMonths,
+		dbtypes.TreasurySubsidy, dbtypes.TreasurySpend)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	var months []dbtypes.TreasuryMonth
+	for rows.Next() {
+		var m dbtypes.TreasuryMonth
+		err = rows.Scan(&m.Month, &m.Subsidy, &m.Received, &m.Spent,
+			&m.NumSpends, &m.Balance)
+		if err != nil {
+			return nil, err
+		}
+		months = append(months, m)
+	}
+	return months, rows.Err()
+}
+
+// RetrieveTreasuryLargestSpends retrieves the N spending transactions with the
+// largest net amounts spent from the address.
+func RetrieveTreasuryLargestSpends(db *sql.DB, N int) ([]*dbtypes.TreasuryTx, error) {
+	rows, err := db.Query(internal.SelectTreasuryLargestSpends,
+		dbtypes.TreasurySpend, N)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	var txns []*dbtypes.TreasuryTx
+	for rows.Next() {
+		tx := new(dbtypes.TreasuryTx)
+		err = rows.Scan(&tx.TxHash, &tx.BlockHash, &tx.BlockHeight,
+			&tx.BlockTime, &tx.Type, &tx.Received, &tx.Sent, &tx.Balance,
+			pq.Array(&tx.Destinations), pq.Array(&tx.DestinationValues))
+		if err != nil {
+			return nil, err
+		}
+		txns = append(txns, tx)
+	}
+	return txns, rows.Err()
+}
+
+// RebuildTreasury replaces the treasury table's ledger of the address with one
+// built from the addresses, transactions, and vouts tables.
+func RebuildTreasury(db SqlExecutor, address string) error {
+	if _, err := db.Exec(internal.DeleteTreasuryRows); err != nil {
+		return err
+	}
+	_, err := db.Exec(internal.InsertTreasuryFromAddresses, address,
+		dbtypes.TreasurySubsidy, dbtypes.TreasuryReceipt, dbtypes.TreasurySpend)
+	return err
+}
+
+// TreasuryBalance returns the balance in atoms of the development subsidy
+// address as of the best stored block.
+func (pgb *ChainDB) TreasuryBalance() int64 {
+	pgb.treasury.RLock()
+	defer pgb.treasury.RUnlock()
+	return pgb.treasury.balance
+}
+
+// TreasurySummary retrieves the balance of the development subsidy address, its
+// inflows and outflows in total and by month, and its N largest spends.
+func (pgb *ChainDB) TreasurySummary(N int) (*dbtypes.TreasurySummary, error) {
+	summary := &dbtypes.TreasurySummary{
+		Address: pgb.devAddress,
+		Height:  pgb.bestBlock,
+		Balance: pgb.TreasuryBalance(),
+	}
+	err := pgb.db.QueryRow(internal.SelectTreasuryTotals, dbtypes.TreasurySubsidy,
+		dbtypes.TreasurySpend).Scan(&summary.TotalSubsidy,
+		&summary.TotalReceived, &summary.TotalSpent)
+	if err != nil {
+		return nil, err
+	}
+	if summary.Months, err = RetrieveTreasuryMonths(pgb.db); err != nil {
+		return nil, err
+	}
+	summary.LargestSpends, err = RetrieveTreasuryLargestSpends(pgb.db, N)
+	return summary, err
+}
+
+// reloadTreasury reloads the treasury ledger after the treasury table is
+// changed other than by StoreBlock.
+func (pgb *ChainDB) reloadTreasury() error {
+	treasury, err := loadTreasuryLedger(pgb.db, pgb.devAddress)
+	if err != nil {
+		return err
+	}
+	pgb.treasury = treasury
+	return nil
+}
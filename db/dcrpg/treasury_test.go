@@ -0,0 +1,77 @@
+package dcrpg
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrdata/db/dbtypes"
+)
+
+func TestTreasuryProcessInvalidated(t *testing.T) {
+	params := &chaincfg.SimNetParams
+	devAddress, err := dbtypes.DevSubsidyAddress(params)
+	if err != nil {
+		t.Fatalf("DevSubsidyAddress failed: %v", err)
+	}
+
+	// The previous block's disapproved transactions received a net 20, spent
+	// the restored outpoint, and created the removed one.
+	kept, restored, removed := chainhash.Hash{1}, chainhash.Hash{2}, chainhash.Hash{3}
+	tl := &treasuryLedger{
+		address: devAddress,
+		balance: 100,
+		unspent: map[string]struct{}{
+			outpointString(kept, 0):    {},
+			outpointString(removed, 0): {},
+		},
+	}
+	inv := &treasuryInvalidation{
+		net:      20,
+		restored: []string{outpointString(restored, 0)},
+		removed:  []string{outpointString(removed, 0)},
+	}
+
+	spend := func(prev chainhash.Hash, value int64) *wire.MsgTx {
+		tx := wire.NewMsgTx()
+		txIn := wire.NewTxIn(wire.NewOutPoint(&prev, 0, wire.TxTreeRegular), nil)
+		txIn.ValueIn = value
+		tx.AddTxIn(txIn)
+		tx.AddTxOut(wire.NewTxOut(value, nil))
+		return tx
+	}
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, ^uint32(0),
+		wire.TxTreeRegular), nil))
+	msgBlock := &wire.MsgBlock{
+		Header: wire.BlockHeader{Height: 10},
+		Transactions: []*wire.MsgTx{coinbase, spend(restored, 40),
+			spend(removed, 50)},
+	}
+
+	tb := tl.processBlock(msgBlock, inv, params)
+	if len(tb.txns) != 1 || tb.txns[0].Sent != 40 {
+		t.Fatalf("expected one spend of 40, got %v", tb.txns)
+	}
+	if tb.balance != 40 || tb.txns[0].Balance != 40 {
+		t.Errorf("balance %d (tx balance %d), expected 40", tb.balance,
+			tb.txns[0].Balance)
+	}
+
+	tl.apply(tb)
+	if len(tl.unspent) != 1 {
+		t.Errorf("unspent outpoints %v, expected only %s", tl.unspent,
+			outpointString(kept, 0))
+	}
+	if _, ok := tl.unspent[outpointString(kept, 0)]; !ok {
+		t.Errorf("outpoint %s no longer unspent", outpointString(kept, 0))
+	}
+
+	// Without an invalidation, the removed outpoint is still unspent.
+	tl.unspent[outpointString(removed, 0)] = struct{}{}
+	if tb = tl.processBlock(msgBlock, nil, params); len(tb.txns) != 1 ||
+		tb.txns[0].Sent != 50 {
+		t.Errorf("expected one spend of 50, got %v", tb.txns)
+	}
+}
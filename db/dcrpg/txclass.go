@@ -0,0 +1,152 @@
+// Copyright (c) 2018, The dcrdata developers
+// See LICENSE for details.
+
+package dcrpg
+
+import (
+	"database/sql"
+
+	apitypes "github.com/decred/dcrdata/api/types"
+	"github.com/decred/dcrdata/db/dcrpg/internal"
+	"github.com/decred/dcrdata/txhelpers"
+	"github.com/lib/pq"
+)
+
+// The tx_class column of the transactions table holds the txhelpers.TxClass of
+// each regular transaction. The classes apparent from the transaction itself
+// are set when it is stored. The split ticket funding class is added when a
+// ticket with multiple inputs spending the transaction's outputs is stored.
+
+// AddSplitTicketFundingClass adds the split ticket funding class to the stored
+// transactions funding the split tickets of a block.
+func AddSplitTicketFundingClass(db SqlExecutor, msgBlock *MsgBlockPG) error {
+	txHashes := txhelpers.SplitTicketFundingTxns(msgBlock.MsgBlock)
+	if len(txHashes) == 0 {
+		return nil
+	}
+	_, err := db.Exec(internal.AddTxClassByHash, pq.Array(txHashes),
+		txhelpers.TxClassSplitTicket)
+	return err
+}
+
+// ClassifyAllTransactions sets the class of each stored regular transaction.
+// Since the signature scripts are not stored, multisig spends are not found,
+// and only transactions stored afterward are labeled as such.
+func ClassifyAllTransactions(db SqlExecutor) error {
+	rows, err := db.Query(internal.SelectRegularTxStructure)
+	if err != nil {
+		return err
+	}
+
+	var ids []int64
+	var classes []int32
+	for rows.Next() {
+		var id int64
+		var numVin int
+		var outValues []int64
+		err = rows.Scan(&id, &numVin, pq.Array(&outValues))
+		if err != nil {
+			break
+		}
+		if class := txhelpers.ClassifyRegularTx(numVin, nil, outValues); class != 0 {
+			ids = append(ids, id)
+			classes = append(classes, int32(class))
+		}
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	if e := rows.Close(); e != nil {
+		log.Errorf("Close of Query failed: %v", e)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(ids) > 0 {
+		_, err = db.Exec(internal.SetTxClasses, pq.Array(ids), pq.Array(classes))
+		if err != nil {
+			return err
+		}
+	}
+	_, err = db.Exec(internal.AddTxClassSplitTicketFunding,
+		txhelpers.TxClassSplitTicket)
+	return err
+}
+
+// RetrieveTxClass retrieves the class of the transaction with the given hash.
+func RetrieveTxClass(db *sql.DB, txHash string) (txhelpers.TxClass, error) {
+	var class txhelpers.TxClass
+	err := db.QueryRow(internal.SelectTxClassByHash, txHash).Scan(&class)
+	return class, err
+}
+
+// RetrieveBlockTxClasses retrieves the classes of the classified transactions
+// of the block with the given hash.
+func RetrieveBlockTxClasses(db *sql.DB, blockHash string) (map[string]txhelpers.TxClass, error) {
+	rows, err := db.Query(internal.SelectBlockTxClasses, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	classes := make(map[string]txhelpers.TxClass)
+	for rows.Next() {
+		var txHash string
+		var class txhelpers.TxClass
+		if err = rows.Scan(&txHash, &class); err != nil {
+			return nil, err
+		}
+		classes[txHash] = class
+	}
+	return classes, rows.Err()
+}
+
+// RetrieveTxnsByClass retrieves the N most recent transactions having any of
+// the given classes, skipping the first offset.
+func RetrieveTxnsByClass(db *sql.DB, class txhelpers.TxClass, N, offset int64) ([]apitypes.ClassifiedTx, error) {
+	rows, err := db.Query(internal.SelectTxnsByClass, class, N, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	var txns []apitypes.ClassifiedTx
+	for rows.Next() {
+		var tx apitypes.ClassifiedTx
+		var txClass txhelpers.TxClass
+		err = rows.Scan(&tx.TxID, &tx.BlockHash, &tx.BlockHeight, &tx.Time,
+			&txClass, &tx.NumVin, &tx.NumVout, &tx.Sent, &tx.Fees)
+		if err != nil {
+			return nil, err
+		}
+		tx.Class = txClass.Labels()
+		txns = append(txns, tx)
+	}
+	return txns, rows.Err()
+}
+
+// TxClass retrieves the stored class of the transaction with the given hash.
+func (pgb *ChainDB) TxClass(txid string) (txhelpers.TxClass, error) {
+	return RetrieveTxClass(pgb.db, txid)
+}
+
+// BlockTxClasses retrieves the stored classes of the classified transactions
+// of the block with the given hash.
+func (pgb *ChainDB) BlockTxClasses(blockHash string) (map[string]txhelpers.TxClass, error) {
+	return RetrieveBlockTxClasses(pgb.db, blockHash)
+}
+
+// TxnsByClass retrieves the N most recent transactions having any of the
+// given classes, skipping the first offset.
+func (pgb *ChainDB) TxnsByClass(class txhelpers.TxClass, N, offset int64) ([]apitypes.ClassifiedTx, error) {
+	return RetrieveTxnsByClass(pgb.db, class, N, offset)
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2018, The dcrdata developers
+// See LICENSE for details.
+
+package dcrpg
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrdata/db/dbtypes"
+	"github.com/decred/dcrdata/db/dcrpg/internal"
+)
+
+// Tables are upgraded in place by a sequence of steps, each taking a table from
+// one version to the next. A step is run in a single DB transaction that also
+// records the table's new version in the table comment, so an interrupted
+// upgrade leaves each table at the version of its last completed step, from
+// which the upgrade resumes when next run. A change of the major version still
+// requires the tables to be rebuilt.
+
+// UpgradeStep is an upgrade of the table TableName from version From to To.
+type UpgradeStep struct {
+	TableName   string
+	From, To    TableVersion
+	Description string
+	upgrade     func(dbtx *sql.Tx, params *chaincfg.Params) error
+}
+
+func (s UpgradeStep) String() string {
+	return fmt.Sprintf("%s v%s -> v%s: %s", s.TableName, s.From, s.To,
+		s.Description)
+}
+
+// upgradeSteps lists the upgrade steps of each table in order of version. A
+// table added after the tables were created starts at the From version of its
+// first step, so that its steps may populate it from the existing tables. The
+// utxo_snapshots and swaps tables cannot be populated from the existing tables,
+// since the UTXO set at past heights and the signature scripts are not stored,
+// so they have no steps. When added to existing tables, they start empty and
+// are filled as new blocks are stored.
+var upgradeSteps = []UpgradeStep{
+	{
+		TableName:   "blocks",
+		From:        NewTableVersion(tableMajor, 0, 0),
+		To:          NewTableVersion(tableMajor, 0, 1),
+		Description: "index the blocks table on time for the listings by time interval",
+		upgrade:     execUpgrade(internal.IndexBlockTableOnTime),
+	},
+	{
+		TableName:   "transactions",
+		From:        NewTableVersion(tableMajor, 0, 0),
+		To:          NewTableVersion(tableMajor, 1, 0),
+		Description: "add and index the transaction class column",
+		upgrade: func(dbtx *sql.Tx, _ *chaincfg.Params) error {
+			if _, err := dbtx.Exec(internal.AddTxClassColumn); err != nil {
+				return err
+			}
+			if err := ClassifyAllTransactions(dbtx); err != nil {
+				return err
+			}
+			_, err := dbtx.Exec(internal.IndexTransactionTableOnClass)
+			return err
+		},
+	},
+	{
+		TableName:   "vote_summary",
+		From:        NewTableVersion(tableMajor, 0, 0),
+		To:          NewTableVersion(tableMajor, 1, 0),
+		Description: "add summaries of the blocks stored before the table was created",
+		upgrade:     execUpgrade(internal.InsertMissingVoteSummaries),
+	},
+	{
+		TableName:   "treasury",
+		From:        NewTableVersion(tableMajor, 0, 0),
+		To:          NewTableVersion(tableMajor, 1, 0),
+		Description: "build the development subsidy ledger from the addresses table",
+		upgrade: func(dbtx *sql.Tx, params *chaincfg.Params) error {
+			devAddress, err := dbtypes.DevSubsidyAddress(params)
+			if err != nil {
+				return err
+			}
+			return RebuildTreasury(dbtx, devAddress)
+		},
+	},
+	{
+		TableName:   "treasury",
+		From:        NewTableVersion(tableMajor, 1, 0),
+		To:          NewTableVersion(tableMajor, 2, 0),
+		Description: "exclude the regular transactions of disapproved blocks from the ledger",
+		upgrade: func(dbtx *sql.Tx, params *chaincfg.Params) error {
+			if _, err := dbtx.Exec(internal.AddTreasuryValidColumn); err != nil {
+				return err
+			}
+			devAddress, err := dbtypes.DevSubsidyAddress(params)
+			if err != nil {
+				return err
+			}
+			return RebuildTreasury(dbtx, devAddress)
+		},
+	},
+	{
+		TableName:   "address_balances",
+		From:        NewTableVersion(tableMajor, 0, 0),
+		To:          NewTableVersion(tableMajor, 1, 0),
+		Description: "summarize the balances in the addresses table",
+		upgrade:     execUpgrade(internal.InsertAllAddressBalances),
+	},
+	{
+		TableName:   "coin_days_destroyed",
+		From:        NewTableVersion(tableMajor, 0, 0),
+		To:          NewTableVersion(tableMajor, 1, 0),
+		Description: "compute the coin-days destroyed by the stored blocks",
+		upgrade:     execUpgrade(internal.InsertAllCoinDaysDestroyed),
+	},
+	{
+		TableName:   "utxo_heights",
+		From:        NewTableVersion(tableMajor, 0, 0),
+		To:          NewTableVersion(tableMajor, 1, 0),
+		Description: "count the unspent outputs by funding height, and index the addresses table on unspent value",
+		upgrade: func(dbtx *sql.Tx, _ *chaincfg.Params) error {
+			if _, err := dbtx.Exec(internal.InsertAllUtxoHeights); err != nil {
+				return err
+			}
+			_, err := dbtx.Exec(internal.IndexAddressTableOnUnspentValue)
+			return err
+		},
+	},
+}
+
+// execUpgrade returns an upgrade function that executes the statement.
+func execUpgrade(stmt string) func(dbtx *sql.Tx, params *chaincfg.Params) error {
+	return func(dbtx *sql.Tx, _ *chaincfg.Params) error {
+		_, err := dbtx.Exec(stmt)
+		return err
+	}
+}
+
+// initialVersion returns the version at which to create the named table. In a
+// new database, this is the required version. When adding a table to existing
+// tables, it is the version preceding the table's upgrade steps.
+func initialVersion(tableName string, existingTables bool) (TableVersion, bool) {
+	if existingTables {
+		for _, s := range upgradeSteps {
+			if s.TableName == tableName {
+				return s.From, true
+			}
+		}
+	}
+	ver, ok := requiredVersions[tableName]
+	return ver, ok
+}
+
+// upgradePath returns the upgrade steps taking the named table from version
+// from to version to, or an error if there are none.
+func upgradePath(tableName string, from, to TableVersion) ([]UpgradeStep, error) {
+	var path []UpgradeStep
+	for ver := from; ver != to; {
+		var found bool
+		for _, s := range upgradeSteps {
+			if s.TableName == tableName && s.From == ver {
+				path = append(path, s)
+				ver, found = s.To, true
+				break
+			}
+		}
+		if !found || len(path) > len(upgradeSteps) {
+			return nil, fmt.Errorf("no upgrade of table %s from v%s to v%s",
+				tableName, ver, to)
+		}
+	}
+	return path, nil
+}
+
+// UpgradePlan returns the upgrade steps required to bring the tables from the
+// given versions to the required versions, ordered by table name and then
+// version. An error is returned if any table must be rebuilt instead.
+func UpgradePlan(versions map[string]TableVersion) ([]UpgradeStep, error) {
+	tableUpgrades := TableUpgradesRequired(versions)
+	sort.Slice(tableUpgrades, func(i, j int) bool {
+		return tableUpgrades[i].TableName < tableUpgrades[j].TableName
+	})
+
+	var plan []UpgradeStep
+	for _, u := range tableUpgrades {
+		if u.UpgradeType == "rebuild" || u.UpgradeType == "unknown" {
+			return nil, fmt.Errorf("table %s must be rebuilt", u.TableName)
+		}
+		steps, err := upgradePath(u.TableName, u.CurrentVer, u.RequiredVer)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, steps...)
+	}
+	return plan, nil
+}
+
+// runUpgradeStep runs the upgrade step and records the table's new version in
+// a single DB transaction.
+func runUpgradeStep(db *sql.DB, params *chaincfg.Params, step UpgradeStep) error {
+	dbtx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("unable to begin database transaction: %v", err)
+	}
+
+	if err = step.upgrade(dbtx, params); err == nil {
+		_, err = dbtx.Exec(fmt.Sprintf(`COMMENT ON TABLE %s IS 'v%s';`,
+			step.TableName, step.To))
+	}
+	if err != nil {
+		if errRoll := dbtx.Rollback(); errRoll != nil {
+			log.Errorf("Rollback failed: %v", errRoll)
+		}
+		return err
+	}
+	return dbtx.Commit()
+}
+
+// UpgradeTables upgrades the tables to the required versions, and returns the
+// upgrade steps. With dryRun, the steps are returned without running them.
+func (pgb *ChainDB) UpgradeTables(dryRun bool) ([]UpgradeStep, error) {
+	plan, err := UpgradePlan(TableVersions(pgb.db))
+	if err != nil || dryRun {
+		return plan, err
+	}
+
+	for i, step := range plan {
+		log.Infof("Upgrading table %s (step %d of %d)...", step, i+1, len(plan))
+		if err = runUpgradeStep(pgb.db, pgb.chainParams, step); err != nil {
+			return plan, fmt.Errorf("upgrade of table %s to v%s failed: %v",
+				step.TableName, step.To, err)
+		}
+	}
+	if len(plan) > 0 {
+		err = pgb.reloadTreasury()
+	}
+	return plan, err
+}
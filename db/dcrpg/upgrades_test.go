@@ -0,0 +1,148 @@
+package dcrpg
+
+import (
+	"reflect"
+	"testing"
+)
+
+// stepVersions describes each upgrade step by its table and versions.
+func stepVersions(steps []UpgradeStep) []string {
+	var s []string
+	for _, step := range steps {
+		s = append(s, step.TableName+" "+step.From.String()+"->"+step.To.String())
+	}
+	return s
+}
+
+func TestUpgradePath(t *testing.T) {
+	defer func(steps []UpgradeStep) { upgradeSteps = steps }(upgradeSteps)
+	upgradeSteps = []UpgradeStep{
+		{TableName: "a", From: NewTableVersion(1, 0, 0), To: NewTableVersion(1, 1, 0)},
+		{TableName: "b", From: NewTableVersion(1, 0, 0), To: NewTableVersion(1, 1, 0)},
+		{TableName: "a", From: NewTableVersion(1, 1, 0), To: NewTableVersion(1, 1, 1)},
+		{TableName: "a", From: NewTableVersion(1, 1, 1), To: NewTableVersion(1, 2, 0)},
+	}
+
+	tests := []struct {
+		tableName string
+		from, to  TableVersion
+		path      []string
+		err       bool
+	}{
+		{"a", NewTableVersion(1, 0, 0), NewTableVersion(1, 2, 0),
+			[]string{"a 1.0.0->1.1.0", "a 1.1.0->1.1.1", "a 1.1.1->1.2.0"}, false},
+		// An interrupted upgrade resumes from the last completed step.
+		{"a", NewTableVersion(1, 1, 1), NewTableVersion(1, 2, 0),
+			[]string{"a 1.1.1->1.2.0"}, false},
+		{"a", NewTableVersion(1, 2, 0), NewTableVersion(1, 2, 0), nil, false},
+		{"b", NewTableVersion(1, 0, 0), NewTableVersion(1, 1, 0),
+			[]string{"b 1.0.0->1.1.0"}, false},
+		// Missing steps.
+		{"a", NewTableVersion(1, 0, 0), NewTableVersion(1, 3, 0), nil, true},
+		{"a", NewTableVersion(1, 0, 1), NewTableVersion(1, 2, 0), nil, true},
+		{"b", NewTableVersion(1, 0, 0), NewTableVersion(1, 2, 0), nil, true},
+		{"a", NewTableVersion(0, 1, 0), NewTableVersion(1, 2, 0), nil, true},
+		{"c", NewTableVersion(1, 0, 0), NewTableVersion(1, 1, 0), nil, true},
+	}
+	for _, test := range tests {
+		path, err := upgradePath(test.tableName, test.from, test.to)
+		if (err != nil) != test.err {
+			t.Errorf("upgradePath(%s, %v, %v) error: %v", test.tableName,
+				test.from, test.to, err)
+			continue
+		}
+		if versions := stepVersions(path); !reflect.DeepEqual(versions, test.path) {
+			t.Errorf("upgradePath(%s, %v, %v) = %v, expected %v", test.tableName,
+				test.from, test.to, versions, test.path)
+		}
+	}
+}
+
+func TestUpgradePlan(t *testing.T) {
+	// versions returns the required versions of the tables with the given
+	// changes, where a nil version removes the table.
+	versions := func(changes map[string]*TableVersion) map[string]TableVersion {
+		v := make(map[string]TableVersion, len(requiredVersions))
+		for table, ver := range requiredVersions {
+			v[table] = ver
+		}
+		for table, ver := range changes {
+			if ver == nil {
+				delete(v, table)
+			} else {
+				v[table] = *ver
+			}
+		}
+		return v
+	}
+	ver := func(major, minor, patch uint32) *TableVersion {
+		v := NewTableVersion(major, minor, patch)
+		return &v
+	}
+
+	tests := []struct {
+		name    string
+		changes map[string]*TableVersion
+		plan    []string
+		err     bool
+	}{
+		{"up to date", nil, nil, false},
+		{"ordered by table", map[string]*TableVersion{
+			"transactions": ver(tableMajor, 0, 0),
+			"blocks":       ver(tableMajor, 0, 0),
+		}, []string{"blocks 2.0.0->2.0.1", "transactions 2.0.0->2.1.0"}, false},
+		{"missing step", map[string]*TableVersion{
+			"vote_summary": ver(tableMajor, 0, 5),
+		}, nil, true},
+		{"major version", map[string]*TableVersion{
+			"transactions": ver(tableMajor-1, 0, 0),
+		}, nil, true},
+		{"unknown version", map[string]*TableVersion{
+			"vins": nil,
+		}, nil, true},
+	}
+	for _, test := range tests {
+		plan, err := UpgradePlan(versions(test.changes))
+		if (err != nil) != test.err {
+			t.Errorf("%s: UpgradePlan error: %v", test.name, err)
+			continue
+		}
+		if steps := stepVersions(plan); !reflect.DeepEqual(steps, test.plan) {
+			t.Errorf("%s: UpgradePlan = %v, expected %v", test.name, steps,
+				test.plan)
+		}
+	}
+
+	// A table without a required version must be rebuilt.
+	defer func(ver TableVersion) { requiredVersions["swaps"] = ver }(requiredVersions["swaps"])
+	current := versions(nil)
+	delete(requiredVersions, "swaps")
+	if _, err := UpgradePlan(current); err == nil {
+		t.Errorf("UpgradePlan succeeded for a table with no required version")
+	}
+}
+
+func TestInitialVersion(t *testing.T) {
+	tests := []struct {
+		tableName      string
+		existingTables bool
+		ver            TableVersion
+		ok             bool
+	}{
+		{"transactions", false, NewTableVersion(tableMajor, 1, 0), true},
+		{"transactions", true, NewTableVersion(tableMajor, 0, 0), true},
+		{"blocks", true, NewTableVersion(tableMajor, 0, 0), true},
+		// Tables without upgrade steps start at the required version.
+		{"vins", true, NewTableVersion(tableMajor, 0, 0), true},
+		{"swaps", true, NewTableVersion(tableMajor, 0, 0), true},
+		{"unknown", false, TableVersion{}, false},
+		{"unknown", true, TableVersion{}, false},
+	}
+	for _, test := range tests {
+		ver, ok := initialVersion(test.tableName, test.existingTables)
+		if ver != test.ver || ok != test.ok {
+			t.Errorf("initialVersion(%s, %v) = %v, %v, expected %v, %v",
+				test.tableName, test.existingTables, ver, ok, test.ver, test.ok)
+		}
+	}
+}
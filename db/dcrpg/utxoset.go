@@ -0,0 +1,257 @@
+// Copyright (c) 2018, The dcrdata developers
+// See LICENSE for details.
+
+package dcrpg
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+	apitypes "github.com/decred/dcrdata/api/types"
+	"github.com/decred/dcrdata/db/dcrpg/internal"
+	"github.com/lib/pq"
+)
+
+// The utxo_heights table holds the number and value of the unspent outputs
+// funded at each height, and is updated along with the address summaries as
+// each block is stored. The UTXO set statistics are computed from it, and
+// recorded in the utxo_snapshots table every UtxoSnapshotInterval blocks. No
+// snapshots are recorded for the blocks stored before the table was created.
+
+// UtxoSnapshotInterval is the number of blocks between UTXO set snapshots.
+const UtxoSnapshotInterval = 288
+
+// numLargestUtxos is the number of largest unspent outputs in the UTXO set
+// statistics.
+const numLargestUtxos = 10
+
+// utxoAgeBucketDays are the ages in days starting each UTXO age bucket after
+// the first.
+var utxoAgeBucketDays = []int64{1, 7, 30, 90, 180, 365, 730}
+
+// utxoStatsCache holds the UTXO set statistics as of the best block at the time
+// they were computed.
+type utxoStatsCache struct {
+	sync.Mutex
+	stats *apitypes.UtxoSetStats
+}
+
+// utxoAgeThresholds returns the ages in blocks starting each UTXO age bucket
+// after the first.
+func utxoAgeThresholds(params *chaincfg.Params) []int32 {
+	blocksPerDay := int64(24*time.Hour) / int64(params.TargetTimePerBlock)
+	thresholds := make([]int32, len(utxoAgeBucketDays))
+	for i, days := range utxoAgeBucketDays {
+		thresholds[i] = int32(days * blocksPerDay)
+	}
+	return thresholds
+}
+
+// utxoAgeBuckets makes the UTXO age buckets from the counts and values by
+// bucket index.
+func utxoAgeBuckets(counts, values []int64) []apitypes.UtxoAgeBucket {
+	buckets := make([]apitypes.UtxoAgeBucket, len(utxoAgeBucketDays)+1)
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].MinDays = utxoAgeBucketDays[i-1]
+		}
+		if i < len(utxoAgeBucketDays) {
+			buckets[i].MaxDays = utxoAgeBucketDays[i]
+		}
+		if i < len(counts) && i < len(values) {
+			buckets[i].Count, buckets[i].Value = counts[i], values[i]
+		}
+	}
+	return buckets
+}
+
+// UpdateUtxoHeights updates the utxo_heights table with the outputs funded and
+// spent by a stored block.
+func UpdateUtxoHeights(db SqlExecutor, blockHash string, height int64) error {
+	if _, err := db.Exec(internal.InsertUtxoHeight, blockHash, height); err != nil {
+		return err
+	}
+	_, err := db.Exec(internal.UpdateUtxoHeightsSpent, blockHash)
+	return err
+}
+
+// RebuildUtxoHeights replaces the utxo_heights table with one computed from the
+// entire vouts and vins tables.
+func RebuildUtxoHeights(db SqlExecutor) error {
+	if _, err := db.Exec(internal.DeleteUtxoHeights); err != nil {
+		return err
+	}
+	_, err := db.Exec(internal.InsertAllUtxoHeights)
+	return err
+}
+
+// RetrieveUtxoSetStats computes the UTXO set statistics at the best block,
+// with the given height, including the N largest unspent outputs.
+func RetrieveUtxoSetStats(db SqlExecutor, height int64, params *chaincfg.Params, N int) (*apitypes.UtxoSetStats, error) {
+	stats := &apitypes.UtxoSetStats{Height: height}
+	err := db.QueryRow(internal.SelectUtxoSetTotals).Scan(&stats.NumUtxos,
+		&stats.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(internal.SelectUtxoAgeDistribution, height,
+		pq.Array(utxoAgeThresholds(params)))
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int64, len(utxoAgeBucketDays)+1)
+	values := make([]int64, len(utxoAgeBucketDays)+1)
+	for rows.Next() {
+		var bucket int
+		var count, value int64
+		if err = rows.Scan(&bucket, &count, &value); err != nil {
+			break
+		}
+		if bucket >= 0 && bucket < len(counts) {
+			counts[bucket], values[bucket] = count, value
+		}
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	if e := rows.Close(); e != nil {
+		log.Errorf("Close of Query failed: %v", e)
+	}
+	if err != nil {
+		return nil, err
+	}
+	stats.Ages = utxoAgeBuckets(counts, values)
+
+	rows, err = db.Query(internal.SelectLargestUtxos, N)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+	for rows.Next() {
+		var utxo apitypes.Utxo
+		err = rows.Scan(&utxo.TxHash, &utxo.Vout, &utxo.Address, &utxo.Value)
+		if err != nil {
+			return nil, err
+		}
+		stats.Largest = append(stats.Largest, utxo)
+	}
+	return stats, rows.Err()
+}
+
+// InsertUtxoSnapshot inserts the UTXO set statistics as a snapshot.
+func InsertUtxoSnapshot(db SqlExecutor, stats *apitypes.UtxoSetStats, checked bool) error {
+	ageCounts := make([]int64, len(stats.Ages))
+	ageValues := make([]int64, len(stats.Ages))
+	for i, b := range stats.Ages {
+		ageCounts[i], ageValues[i] = b.Count, b.Value
+	}
+	n := len(stats.Largest)
+	txHashes, vouts := make([]string, n), make([]int32, n)
+	addresses, values := make([]string, n), make([]int64, n)
+	for i, utxo := range stats.Largest {
+		txHashes[i], vouts[i] = utxo.TxHash, int32(utxo.Vout)
+		addresses[i], values[i] = utxo.Address, utxo.Value
+	}
+
+	_, err := db.Exec(internal.MakeUtxoSnapshotInsertStatement(checked),
+		stats.Height, stats.Hash, stats.Time, stats.NumUtxos, stats.Value,
+		pq.Array(ageCounts), pq.Array(ageValues), pq.Array(txHashes),
+		pq.Array(vouts), pq.Array(addresses), pq.Array(values))
+	return err
+}
+
+// RetrieveUtxoSnapshots retrieves the UTXO set snapshots of the blocks mined
+// between the UNIX times from and to, without their largest outputs.
+func RetrieveUtxoSnapshots(db *sql.DB, from, to int64) ([]apitypes.UtxoSetStats, error) {
+	rows, err := db.Query(internal.SelectUtxoSnapshots, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := rows.Close(); e != nil {
+			log.Errorf("Close of Query failed: %v", e)
+		}
+	}()
+
+	var snapshots []apitypes.UtxoSetStats
+	for rows.Next() {
+		var s apitypes.UtxoSetStats
+		var ageCounts, ageValues []int64
+		err = rows.Scan(&s.Height, &s.Hash, &s.Time, &s.NumUtxos, &s.Value,
+			pq.Array(&ageCounts), pq.Array(&ageValues))
+		if err != nil {
+			return nil, err
+		}
+		s.Ages = utxoAgeBuckets(ageCounts, ageValues)
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+// storeUtxoSnapshot computes the UTXO set statistics at the given block and
+// stores them as a snapshot.
+func (pgb *ChainDB) storeUtxoSnapshot(db SqlExecutor, height int64, hash string, blockTime int64) error {
+	stats, err := RetrieveUtxoSetStats(db, height, pgb.chainParams, numLargestUtxos)
+	if err != nil {
+		return err
+	}
+	stats.Hash, stats.Time = hash, blockTime
+	return InsertUtxoSnapshot(db, stats, pgb.dupChecks)
+}
+
+// RebuildUtxoSet rebuilds the utxo_heights table. This is intended to be used
+// after the spending info of the addresses table is set in bulk.
+func (pgb *ChainDB) RebuildUtxoSet() error {
+	log.Infof("Rebuilding UTXO set by funding height...")
+	return RebuildUtxoHeights(pgb.db)
+}
+
+// UtxoSetStats retrieves the UTXO set statistics at the best block, which are
+// recomputed at most once per block.
+func (pgb *ChainDB) UtxoSetStats() (*apitypes.UtxoSetStats, error) {
+	pgb.utxoStats.Lock()
+	defer pgb.utxoStats.Unlock()
+
+	height := pgb.bestBlock
+	if pgb.utxoStats.stats != nil && pgb.utxoStats.stats.Height == height {
+		return pgb.utxoStats.stats, nil
+	}
+
+	stats, err := RetrieveUtxoSetStats(pgb.db, height, pgb.chainParams,
+		numLargestUtxos)
+	if err != nil {
+		return nil, err
+	}
+	err = pgb.db.QueryRow(internal.SelectBlockHashTimeByHeight, height).
+		Scan(&stats.Hash, &stats.Time)
+	if err != nil {
+		return nil, err
+	}
+	pgb.utxoStats.stats = stats
+	return stats, nil
+}
+
+// UtxoSetHistory retrieves the UTXO set snapshots of the blocks mined between
+// the UNIX times from and to.
+func (pgb *ChainDB) UtxoSetHistory(from, to int64) (*apitypes.UtxoSetHistory, error) {
+	snapshots, err := RetrieveUtxoSnapshots(pgb.db, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if snapshots == nil {
+		snapshots = []apitypes.UtxoSetStats{}
+	}
+	return &apitypes.UtxoSetHistory{
+		From:      from,
+		To:        to,
+		Interval:  UtxoSnapshotInterval,
+		Snapshots: snapshots,
+	}, nil
+}
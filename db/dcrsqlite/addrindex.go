@@ -0,0 +1,705 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package dcrsqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/txscript"
+	"github.com/decred/dcrd/wire"
+	apitypes "github.com/decred/dcrdata/api/types"
+	"github.com/decred/dcrdata/blockdata"
+	"github.com/decred/dcrdata/explorer"
+	"github.com/decred/dcrdata/rpcutils"
+	humanize "github.com/dustin/go-humanize"
+)
+
+// The address index has a row in the addresses table for each address paid by
+// a transaction output, recording the output's value and the block containing
+// it. When the output is spent, the spending transaction and its block are
+// recorded in the same row. Regular transactions of blocks disapproved by
+// stakeholders are indexed like any others. A disapproved transaction that is
+// mined again keeps the rows of its first block, as the outputs are unique.
+
+// AddressIndexOutput is an output paying to an address, for the address index.
+type AddressIndexOutput struct {
+	Address string
+	TxHash  string
+	TxIndex uint32
+	TxTree  int8
+	Value   int64
+}
+
+// AddressIndexSpend is an input spending a previous output, for the address
+// index.
+type AddressIndexSpend struct {
+	PrevTxHash  string
+	PrevTxIndex uint32
+	TxHash      string
+	TxVin       uint32
+}
+
+// AddressIndexTx is a transaction funding or spending from an address, with
+// the amounts received and sent by the address in atoms.
+type AddressIndexTx struct {
+	TxHash   string
+	Height   int64
+	Time     int64
+	Received int64
+	Sent     int64
+}
+
+// AddressIndexSummary contains the numbers and total values of the outputs
+// paying to an address and of those that are spent, and the numbers of
+// transactions funding and spending from it.
+type AddressIndexSummary struct {
+	NumOutputs      int64
+	TotalReceived   int64
+	NumSpent        int64
+	TotalSent       int64
+	NumFundingTxns  int64
+	NumSpendingTxns int64
+	NumTxns         int64
+}
+
+// createAddrOutputUniqueIndex creates the unique index of the address index
+// outputs. Duplicate rows of an output, from transactions mined again after
+// their block was disapproved, are first removed from an existing index,
+// keeping the row of the first block.
+func createAddrOutputUniqueIndex(db *sql.DB) error {
+	indexName := TableNameAddresses + "_output_uix"
+	var exists bool
+	err := db.QueryRow(`select count(*) > 0 from sqlite_master
+		where type = 'index' and name = ?`, indexName).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	stmt := fmt.Sprintf(`
+        delete from %[1]s where rowid not in (
+            select min(rowid) from %[1]s group by tx_hash, tx_index, address
+        );
+        create unique index %[2]s on %[1]s(tx_hash, tx_index, address);
+        `, TableNameAddresses, indexName)
+	if _, err = db.Exec(stmt); err != nil {
+		log.Errorf("%q: %s\n", err, stmt)
+		return err
+	}
+	return nil
+}
+
+// AddrIndexEnabled indicates if the address index is maintained.
+func (db *DB) AddrIndexEnabled() bool {
+	return db.addrIndex
+}
+
+// AddrIndexTip returns the height and hash of the last block in the address
+// index. The height is -1 if the index is empty.
+func (db *DB) AddrIndexTip() (int64, string, error) {
+	var height int64
+	var hash string
+	err := db.QueryRow(db.getAddrIndexTipSQL).Scan(&height, &hash)
+	if err == sql.ErrNoRows {
+		return -1, "", nil
+	}
+	return height, hash, err
+}
+
+// StoreAddressIndexBlock adds the outputs and spends of the block at the
+// specified height to the address index, in a single DB transaction. Outputs
+// are stored first so that they may be spent by later transactions in the
+// same block.
+func (db *DB) StoreAddressIndexBlock(height int64, hash string, blockTime int64,
+	outputs []*AddressIndexOutput, spends []*AddressIndexSpend) error {
+	db.Lock()
+	defer db.Unlock()
+
+	dbtx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("unable to begin database transaction: %v", err)
+	}
+
+	stmt, err := dbtx.Prepare(db.insertAddrOutputSQL)
+	if err != nil {
+		_ = dbtx.Rollback()
+		return err
+	}
+	for _, out := range outputs {
+		_, err = stmt.Exec(out.Address, out.TxHash, out.TxIndex, out.TxTree,
+			out.Value, height, blockTime)
+		if err != nil {
+			stmt.Close()
+			_ = dbtx.Rollback()
+			return fmt.Errorf("failed to insert address output: %v", err)
+		}
+	}
+	stmt.Close()
+
+	stmt, err = dbtx.Prepare(db.updateAddrSpendingSQL)
+	if err != nil {
+		_ = dbtx.Rollback()
+		return err
+	}
+	for _, spend := range spends {
+		_, err = stmt.Exec(spend.TxHash, spend.TxVin, height, blockTime,
+			spend.PrevTxHash, spend.PrevTxIndex)
+		if err != nil {
+			stmt.Close()
+			_ = dbtx.Rollback()
+			return fmt.Errorf("failed to update spent address output: %v", err)
+		}
+	}
+	stmt.Close()
+
+	if _, err = dbtx.Exec(db.insertAddrIndexBlockSQL, height, hash); err != nil {
+		_ = dbtx.Rollback()
+		return fmt.Errorf("failed to insert address index block: %v", err)
+	}
+	return dbtx.Commit()
+}
+
+// rewindAddressIndex removes the outputs and spends of all blocks above the
+// specified height from the address index.
+func rewindAddressIndex(dbtx *sql.Tx, height int64) error {
+	stmts := []string{
+		fmt.Sprintf(`DELETE FROM %s WHERE block_height > ?`, TableNameAddresses),
+		fmt.Sprintf(`UPDATE %s SET spending_tx_hash = NULL, spending_tx_vin = NULL,
+			spending_height = NULL, spending_time = NULL
+			WHERE spending_height > ?`, TableNameAddresses),
+		fmt.Sprintf(`DELETE FROM %s WHERE height > ?`, TableNameAddrIndexBlocks),
+	}
+	for _, stmt := range stmts {
+		if _, err := dbtx.Exec(stmt, height); err != nil {
+			return fmt.Errorf("failed to rewind address index: %v", err)
+		}
+	}
+	return nil
+}
+
+// RewindAddressIndex removes the outputs and spends of all blocks above the
+// specified height from the address index. Unlike RewindToHeight, the block
+// summaries and stake info are not modified.
+func (db *DB) RewindAddressIndex(height int64) error {
+	db.Lock()
+	defer db.Unlock()
+
+	dbtx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("unable to begin database transaction: %v", err)
+	}
+	if err = rewindAddressIndex(dbtx, height); err != nil {
+		_ = dbtx.Rollback()
+		return err
+	}
+	return dbtx.Commit()
+}
+
+// RetrieveAddressTxns retrieves the transactions funding or spending from the
+// address, newest first, skipping the first skip transactions.
+func (db *DB) RetrieveAddressTxns(address string, count, skip int) ([]*AddressIndexTx, error) {
+	rows, err := db.Query(db.getAddrTxnsSQL, address, address, count, skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var txns []*AddressIndexTx
+	for rows.Next() {
+		tx := new(AddressIndexTx)
+		err = rows.Scan(&tx.TxHash, &tx.Height, &tx.Time, &tx.Received, &tx.Sent)
+		if err != nil {
+			return nil, err
+		}
+		txns = append(txns, tx)
+	}
+	return txns, rows.Err()
+}
+
+// RetrieveAddressSummary retrieves the numbers and values of outputs paying to
+// and spent from the address, and the numbers of transactions involved.
+func (db *DB) RetrieveAddressSummary(address string) (*AddressIndexSummary, error) {
+	s := new(AddressIndexSummary)
+	err := db.QueryRow(db.getAddrSummarySQL, address).Scan(&s.NumOutputs,
+		&s.TotalReceived, &s.NumSpent, &s.TotalSent, &s.NumFundingTxns,
+		&s.NumSpendingTxns)
+	if err != nil {
+		return nil, err
+	}
+	err = db.QueryRow(db.getAddrNumTxnsSQL, address, address).Scan(&s.NumTxns)
+	return s, err
+}
+
+// RetrieveAddressOutpoint retrieves the addresses paid by the specified output,
+// and its value. No addresses are returned if the output is not indexed.
+func (db *DB) RetrieveAddressOutpoint(txHash string, index uint32) ([]string, int64, error) {
+	rows, err := db.Query(db.getAddrOutpointSQL, txHash, index)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var addresses []string
+	var value int64
+	for rows.Next() {
+		var address string
+		if err = rows.Scan(&address, &value); err != nil {
+			return nil, 0, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, value, rows.Err()
+}
+
+// RetrieveSpendingTxByTxOut retrieves the transaction spending the specified
+// output, and the index of the spending input. sql.ErrNoRows is returned if the
+// output is unspent or not indexed.
+func (db *DB) RetrieveSpendingTxByTxOut(txHash string, index uint32) (string, uint32, error) {
+	var spendingTx string
+	var vin uint32
+	err := db.QueryRow(db.getAddrSpendingSQL, txHash, index).Scan(&spendingTx, &vin)
+	return spendingTx, vin, err
+}
+
+// RetrieveSpendingTxsByFundingTx retrieves the transactions spending the
+// indexed outputs of the specified transaction, the indexes of the spending
+// inputs, and the indexes of the spent outputs.
+func (db *DB) RetrieveSpendingTxsByFundingTx(txHash string) ([]string, []uint32, []uint32, error) {
+	rows, err := db.Query(db.getAddrSpendingsSQL, txHash)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer rows.Close()
+
+	var spendingTxns []string
+	var vinInds, voutInds []uint32
+	for rows.Next() {
+		var spendingTx string
+		var vout, vin uint32
+		if err = rows.Scan(&vout, &spendingTx, &vin); err != nil {
+			return nil, nil, nil, err
+		}
+		spendingTxns = append(spendingTxns, spendingTx)
+		vinInds = append(vinInds, vin)
+		voutInds = append(voutInds, vout)
+	}
+	return spendingTxns, vinInds, voutInds, rows.Err()
+}
+
+// Store satisfies the blockdata.BlockDataSaver interface, storing the block
+// data with DBDataSaver.Store and the fee rates of the block's regular
+// transactions, and adding the block to the address index when it is enabled.
+func (db *wiredDB) Store(data *blockdata.BlockData, msgBlock *wire.MsgBlock) error {
+	if err := db.DBDataSaver.Store(data, msgBlock); err != nil {
+		return err
+	}
+	if msgBlock == nil {
+		return nil
+	}
+	if err := db.storeBlockFeeRates(msgBlock); err != nil {
+		return err
+	}
+	if !db.AddrIndexEnabled() {
+		return nil
+	}
+	return db.indexBlock(msgBlock)
+}
+
+// indexBlock adds the block to the address index. If the index already has a
+// block at this height, as with a reorganization, the index is first rewound
+// to the previous block.
+func (db *wiredDB) indexBlock(msgBlock *wire.MsgBlock) error {
+	height := int64(msgBlock.Header.Height)
+	tipHeight, _, err := db.AddrIndexTip()
+	if err != nil {
+		return fmt.Errorf("AddrIndexTip failed: %v", err)
+	}
+	if tipHeight >= height {
+		log.Infof("Rewinding address index from %d to %d.", tipHeight, height-1)
+		if err = db.RewindAddressIndex(height - 1); err != nil {
+			return err
+		}
+	} else if tipHeight < height-1 {
+		return fmt.Errorf("address index at height %d, unable to add block %d",
+			tipHeight, height)
+	}
+
+	var outputs []*AddressIndexOutput
+	var spends []*AddressIndexSpend
+	addTxns := func(txns []*wire.MsgTx, tree int8) {
+		for _, tx := range txns {
+			txHash := tx.TxHash().String()
+			for i, txIn := range tx.TxIn {
+				// Skip coinbase and stakebase inputs.
+				prevOut := &txIn.PreviousOutPoint
+				if prevOut.Hash == zeroHash {
+					continue
+				}
+				spends = append(spends, &AddressIndexSpend{
+					PrevTxHash:  prevOut.Hash.String(),
+					PrevTxIndex: prevOut.Index,
+					TxHash:      txHash,
+					TxVin:       uint32(i),
+				})
+			}
+			for i, txOut := range tx.TxOut {
+				_, addrs, _, err := txscript.ExtractPkScriptAddrs(txOut.Version,
+					txOut.PkScript, db.params)
+				if err != nil {
+					log.Tracef("ExtractPkScriptAddrs: %v", err)
+					continue
+				}
+				for _, addr := range addrs {
+					outputs = append(outputs, &AddressIndexOutput{
+						Address: addr.EncodeAddress(),
+						TxHash:  txHash,
+						TxIndex: uint32(i),
+						TxTree:  tree,
+						Value:   txOut.Value,
+					})
+				}
+			}
+		}
+	}
+	addTxns(msgBlock.Transactions, wire.TxTreeRegular)
+	addTxns(msgBlock.STransactions, wire.TxTreeStake)
+
+	return db.StoreAddressIndexBlock(height, msgBlock.BlockHash().String(),
+		msgBlock.Header.Timestamp.Unix(), outputs, spends)
+}
+
+var zeroHash chainhash.Hash
+
+// syncAddrIndex removes blocks from the address index that are not in the
+// block summary table, and then adds the blocks up to the best block summary.
+// This builds the index when it is first enabled. Other blocks are indexed as
+// they are stored.
+func (db *wiredDB) syncAddrIndex(quit chan struct{}) error {
+	summaryHeight, err := db.GetBlockSummaryHeight()
+	if err != nil {
+		return err
+	}
+
+	// Step back to a block shared with the block summary table.
+	for {
+		tipHeight, tipHash, err := db.AddrIndexTip()
+		if err != nil {
+			return fmt.Errorf("AddrIndexTip failed: %v", err)
+		}
+		if tipHeight < 0 {
+			break
+		}
+		if tipHeight <= summaryHeight {
+			hash, err := db.RetrieveBlockHash(tipHeight)
+			if err != nil {
+				return fmt.Errorf("RetrieveBlockHash(%d) failed: %v", tipHeight, err)
+			}
+			if hash == tipHash {
+				break
+			}
+		}
+		log.Infof("Removing block %d from the address index.", tipHeight)
+		if err = db.RewindAddressIndex(tipHeight - 1); err != nil {
+			return err
+		}
+	}
+
+	tipHeight, _, err := db.AddrIndexTip()
+	if err != nil {
+		return fmt.Errorf("AddrIndexTip failed: %v", err)
+	}
+	if tipHeight < summaryHeight {
+		log.Infof("Indexing addresses for blocks %d to %d...", tipHeight+1,
+			summaryHeight)
+	}
+	for i := tipHeight + 1; i <= summaryHeight; i++ {
+		select {
+		case <-quit:
+			log.Infof("Address indexing cancelled at height %d.", i-1)
+			return nil
+		default:
+		}
+		if i%rescanLogBlockChunk == 0 {
+			log.Infof("Indexing addresses for block %d...", i)
+		}
+		block, _, err := db.getBlock(i)
+		if err != nil {
+			return err
+		}
+		if err = db.indexBlock(block.MsgBlock()); err != nil {
+			return fmt.Errorf("failed to index block %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// addrMempoolTx is an unconfirmed transaction funding or spending from an
+// address, with the amounts received and sent by the address in atoms.
+type addrMempoolTx struct {
+	tx       *wire.MsgTx
+	hash     string
+	time     int64
+	received int64
+	sent     int64
+}
+
+// mempoolTxCache holds the decoded transactions of the node's mempool, so that
+// each transaction is requested from the node once while it is in mempool
+// rather than for every address lookup.
+type mempoolTxCache struct {
+	sync.Mutex
+	txns map[string]*wire.MsgTx
+}
+
+// update returns the decoded transactions of the mempool listing, requesting
+// only those not already cached. Transactions no longer in mempool are removed
+// from the cache. The returned map is not modified later.
+func (c *mempoolTxCache) update(client rpcutils.NodeClient,
+	mempoolTxns map[string]dcrjson.GetRawMempoolVerboseResult) map[string]*wire.MsgTx {
+	c.Lock()
+	defer c.Unlock()
+	txns := make(map[string]*wire.MsgTx, len(mempoolTxns))
+	for txid := range mempoolTxns {
+		if msgTx, ok := c.txns[txid]; ok {
+			txns[txid] = msgTx
+			continue
+		}
+		txHash, err := chainhash.NewHashFromStr(txid)
+		if err != nil {
+			continue
+		}
+		tx, err := client.GetRawTransaction(txHash)
+		if err != nil {
+			// The transaction may have left mempool.
+			log.Debugf("GetRawTransaction(%s) failed: %v", txid, err)
+			continue
+		}
+		txns[txid] = tx.MsgTx()
+	}
+	c.txns = txns
+	return txns
+}
+
+// mempoolAddressTxns finds the transactions in mempool funding or spending from
+// the address, newest first. Spends are found only for outputs in the address
+// index.
+func (db *wiredDB) mempoolAddressTxns(address string) ([]*addrMempoolTx, error) {
+	mempoolTxns, err := db.client.GetRawMempoolVerbose(dcrjson.GRMAll)
+	if err != nil {
+		return nil, fmt.Errorf("GetRawMempoolVerbose failed: %v", err)
+	}
+	msgTxns := db.mempoolTxns.update(db.client, mempoolTxns)
+
+	var txns []*addrMempoolTx
+	for txid, info := range mempoolTxns {
+		msgTx, ok := msgTxns[txid]
+		if !ok {
+			continue
+		}
+		atx := &addrMempoolTx{tx: msgTx, hash: txid, time: info.Time}
+		var found bool
+		for _, txOut := range msgTx.TxOut {
+			_, addrs, _, err := txscript.ExtractPkScriptAddrs(txOut.Version,
+				txOut.PkScript, db.params)
+			if err != nil {
+				continue
+			}
+			for _, addr := range addrs {
+				if addr.EncodeAddress() == address {
+					atx.received += txOut.Value
+					found = true
+				}
+			}
+		}
+		for _, txIn := range msgTx.TxIn {
+			prevOut := &txIn.PreviousOutPoint
+			if prevOut.Hash == zeroHash {
+				continue
+			}
+			addrs, value, err := db.RetrieveAddressOutpoint(prevOut.Hash.String(),
+				prevOut.Index)
+			if err != nil {
+				return nil, err
+			}
+			for _, addr := range addrs {
+				if addr == address {
+					atx.sent += value
+					found = true
+				}
+			}
+		}
+		if found {
+			txns = append(txns, atx)
+		}
+	}
+
+	sort.Slice(txns, func(i, j int) bool {
+		return txns[i].time > txns[j].time
+	})
+	return txns, nil
+}
+
+// addrIndexedTx is a transaction funding or spending from an address, with
+// the amounts received and sent by the address in atoms.
+type addrIndexedTx struct {
+	txid          string
+	size          int
+	total         int64
+	time          int64
+	confirmations int64
+	received      int64
+	sent          int64
+}
+
+func makeAddrIndexedTx(msgTx *wire.MsgTx, txid string, txTime, confirmations,
+	received, sent int64) *addrIndexedTx {
+	var total int64
+	for _, txOut := range msgTx.TxOut {
+		total += txOut.Value
+	}
+	return &addrIndexedTx{
+		txid:          txid,
+		size:          msgTx.SerializeSize(),
+		total:         total,
+		time:          txTime,
+		confirmations: confirmations,
+		received:      received,
+		sent:          sent,
+	}
+}
+
+// addrIndexTxns gets up to count transactions funding or spending from the
+// address after skipping skip of them, newest first. Transactions in mempool
+// come before those in blocks. The number of transactions in mempool is also
+// returned.
+func (db *wiredDB) addrIndexTxns(address string, count, skip int) ([]*addrIndexedTx, int64, error) {
+	mempoolTxns, err := db.mempoolAddressTxns(address)
+	if err != nil {
+		return nil, 0, err
+	}
+	numUnconfirmed := int64(len(mempoolTxns))
+
+	txns := make([]*addrIndexedTx, 0, count)
+	for i := skip; i < len(mempoolTxns) && len(txns) < count; i++ {
+		mtx := mempoolTxns[i]
+		txns = append(txns, makeAddrIndexedTx(mtx.tx, mtx.hash, mtx.time,
+			0, mtx.received, mtx.sent))
+	}
+	if len(txns) == count {
+		return txns, numUnconfirmed, nil
+	}
+
+	skip -= len(mempoolTxns)
+	if skip < 0 {
+		skip = 0
+	}
+	indexed, err := db.RetrieveAddressTxns(address, count-len(txns), skip)
+	if err != nil {
+		return nil, 0, err
+	}
+	bestHeight := db.GetBestBlockHeight()
+	for _, itx := range indexed {
+		txHash, err := chainhash.NewHashFromStr(itx.TxHash)
+		if err != nil {
+			return nil, 0, err
+		}
+		tx, err := db.client.GetRawTransaction(txHash)
+		if err != nil {
+			return nil, 0, fmt.Errorf("GetRawTransaction(%s) failed: %v",
+				itx.TxHash, err)
+		}
+		txns = append(txns, makeAddrIndexedTx(tx.MsgTx(), itx.TxHash, itx.Time,
+			bestHeight-itx.Height+1, itx.Received, itx.Sent))
+	}
+	return txns, numUnconfirmed, nil
+}
+
+// getAddressTransactionsIndexed is GetAddressTransactionsWithSkip using the
+// address index.
+func (db *wiredDB) getAddressTransactionsIndexed(addr string, count, skip int) *apitypes.Address {
+	txns, _, err := db.addrIndexTxns(addr, count, skip)
+	if err != nil {
+		log.Warnf("GetAddressTransactions failed for address %s: %v", addr, err)
+		return nil
+	}
+	tx := make([]*apitypes.AddressTxShort, 0, len(txns))
+	for _, itx := range txns {
+		tx = append(tx, &apitypes.AddressTxShort{
+			TxID:          itx.txid,
+			Time:          itx.time,
+			Value:         dcrutil.Amount(itx.total).ToCoin(),
+			Confirmations: itx.confirmations,
+			Size:          int32(itx.size),
+		})
+	}
+	return &apitypes.Address{
+		Address:      addr,
+		Transactions: tx,
+	}
+}
+
+// getExplorerAddressIndexed is GetExplorerAddress using the address index.
+func (db *wiredDB) getExplorerAddressIndexed(address string, addr dcrutil.Address,
+	count, offset int64) *explorer.AddressInfo {
+	summary, err := db.RetrieveAddressSummary(address)
+	if err != nil {
+		log.Warnf("RetrieveAddressSummary failed for address %s: %v", address, err)
+		return nil
+	}
+	txns, numUnconfirmed, err := db.addrIndexTxns(address, int(count), int(offset))
+	if err != nil {
+		log.Warnf("Address index lookup failed for address %s: %v", address, err)
+		return nil
+	}
+	if summary.NumTxns+numUnconfirmed == 0 && !ValidateNetworkAddress(addr, db.params) {
+		log.Warnf("Address %s is not valid for this network", address)
+		return nil
+	}
+
+	addressTxs := make([]*explorer.AddressTx, 0, len(txns))
+	for _, itx := range txns {
+		addressTxs = append(addressTxs, &explorer.AddressTx{
+			TxID:          itx.txid,
+			FormattedSize: humanize.Bytes(uint64(itx.size)),
+			Total:         dcrutil.Amount(itx.total).ToCoin(),
+			Confirmations: uint64(itx.confirmations),
+			Time:          itx.time,
+			FormattedTime: time.Unix(itx.time, 0).Format("2006-01-02 15:04:05"),
+			RecievedTotal: dcrutil.Amount(itx.received).ToCoin(),
+			SentTotal:     dcrutil.Amount(itx.sent).ToCoin(),
+		})
+	}
+
+	received := dcrutil.Amount(summary.TotalReceived)
+	sent := dcrutil.Amount(summary.TotalSent)
+	balance := &explorer.AddressBalance{
+		Address:      address,
+		NumSpent:     summary.NumSpent,
+		NumUnspent:   summary.NumOutputs - summary.NumSpent,
+		TotalSpent:   summary.TotalSent,
+		TotalUnspent: summary.TotalReceived - summary.TotalSent,
+	}
+	return &explorer.AddressInfo{
+		Address:           address,
+		Limit:             count,
+		MaxTxLimit:        explorer.MaxAddressRows,
+		Offset:            offset,
+		Transactions:      addressTxs,
+		NumTransactions:   int64(len(addressTxs)),
+		KnownTransactions: summary.NumTxns + numUnconfirmed,
+		KnownFundingTxns:  summary.NumFundingTxns,
+		NumSpendingTxns:   summary.NumSpendingTxns,
+		NumUnconfirmed:    numUnconfirmed,
+		TotalReceived:     received,
+		TotalSent:         sent,
+		Unspent:           received - sent,
+		Balance:           balance,
+	}
+}
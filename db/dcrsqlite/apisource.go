@@ -17,7 +17,6 @@ import (
 	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/dcrjson"
 	"github.com/decred/dcrd/dcrutil"
-	"github.com/decred/dcrd/rpcclient"
 	"github.com/decred/dcrd/txscript"
 	"github.com/decred/dcrd/wire"
 	apitypes "github.com/decred/dcrdata/api/types"
@@ -34,20 +33,22 @@ import (
 // not stored in the DB, so the RPC client is used to get it on demand.
 type wiredDB struct {
 	*DBDataSaver
-	MPC      *mempool.MempoolDataCache
-	client   *rpcclient.Client
-	params   *chaincfg.Params
-	sDB      *stakedb.StakeDatabase
-	waitChan chan chainhash.Hash
+	MPC         *mempool.MempoolDataCache
+	client      rpcutils.NodeClient
+	params      *chaincfg.Params
+	sDB         *stakedb.StakeDatabase
+	waitChan    chan chainhash.Hash
+	mempoolTxns *mempoolTxCache
 }
 
-func newWiredDB(DB *DB, statusC chan uint32, cl *rpcclient.Client,
+func newWiredDB(DB *DB, statusC chan uint32, cl rpcutils.NodeClient,
 	p *chaincfg.Params, datadir string) (wiredDB, func() error) {
 	wDB := wiredDB{
 		DBDataSaver: &DBDataSaver{DB, statusC},
 		MPC:         new(mempool.MempoolDataCache),
 		client:      cl,
 		params:      p,
+		mempoolTxns: new(mempoolTxCache),
 	}
 
 	var err error
@@ -62,7 +63,7 @@ func newWiredDB(DB *DB, statusC chan uint32, cl *rpcclient.Client,
 // NewWiredDB creates a new wiredDB from a *sql.DB, a node client, network
 // parameters, and a status update channel. It calls dcrsqlite.NewDB to create a
 // new DB that wrapps the sql.DB.
-func NewWiredDB(db *sql.DB, statusC chan uint32, cl *rpcclient.Client,
+func NewWiredDB(db *sql.DB, statusC chan uint32, cl rpcutils.NodeClient,
 	p *chaincfg.Params, datadir string) (wiredDB, func() error, error) {
 	// Create the sqlite.DB
 	DB, err := NewDB(db)
@@ -79,7 +80,7 @@ func NewWiredDB(db *sql.DB, statusC chan uint32, cl *rpcclient.Client,
 
 // InitWiredDB creates a new wiredDB from a file containing the data for a
 // sql.DB. The other parameters are same as those for NewWiredDB.
-func InitWiredDB(dbInfo *DBInfo, statusC chan uint32, cl *rpcclient.Client,
+func InitWiredDB(dbInfo *DBInfo, statusC chan uint32, cl rpcutils.NodeClient,
 	p *chaincfg.Params, datadir string) (wiredDB, func() error, error) {
 	db, err := InitDB(dbInfo)
 	if err != nil {
@@ -237,6 +238,37 @@ func (db *wiredDB) GetBlockVerboseByHash(hash string, verboseTx bool) *dcrjson.G
 	return rpcutils.GetBlockVerboseByHash(db.client, db.params, hash, verboseTx)
 }
 
+// GetRawBlock returns the serialized block at the given height.
+func (db *wiredDB) GetRawBlock(idx int64) ([]byte, error) {
+	hash, err := db.client.GetBlockHash(idx)
+	if err != nil {
+		log.Errorf("GetBlockHash(%d) failed: %v", idx, err)
+		return nil, err
+	}
+	msgBlock, err := db.client.GetBlock(hash)
+	if err != nil {
+		log.Errorf("GetBlock(%v) failed: %v", hash, err)
+		return nil, err
+	}
+	return msgBlock.Bytes()
+}
+
+// GetRawBlockHeader returns the serialized header of the block at the given
+// height.
+func (db *wiredDB) GetRawBlockHeader(idx int64) ([]byte, error) {
+	hash, err := db.client.GetBlockHash(idx)
+	if err != nil {
+		log.Errorf("GetBlockHash(%d) failed: %v", idx, err)
+		return nil, err
+	}
+	header, err := db.client.GetBlockHeader(hash)
+	if err != nil {
+		log.Errorf("GetBlockHeader(%v) failed: %v", hash, err)
+		return nil, err
+	}
+	return header.Bytes()
+}
+
 func (db *wiredDB) GetCoinSupply() dcrutil.Amount {
 	coinSupply, err := db.client.GetCoinSupply()
 	if err != nil {
@@ -481,6 +513,10 @@ func (db *wiredDB) getRawTransaction(txid string) (*apitypes.Tx, string) {
 
 	tx.Confirmations = txraw.Confirmations
 
+	if msgTx, err := txhelpers.MsgTxFromHex(txraw.Hex); err == nil {
+		tx.Class = txhelpers.ClassifyTx(msgTx).Labels()
+	}
+
 	// BlockID
 	tx.Block = new(apitypes.BlockID)
 	tx.Block.BlockHash = txraw.BlockHash
@@ -655,6 +691,72 @@ func (db *wiredDB) GetPool(idx int64) ([]string, error) {
 	return hss, nil
 }
 
+// GetTicketPoolHistory returns the heights at which the ticket with the given
+// hash entered and left the live ticket pool.
+func (db *wiredDB) GetTicketPoolHistory(txid string) *apitypes.TicketPoolHistory {
+	ticket, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		log.Errorf("Invalid ticket hash %s: %v", txid, err)
+		return nil
+	}
+	entered, left := db.sDB.PoolDB.TicketLiveHeights(*ticket)
+	return &apitypes.TicketPoolHistory{
+		Ticket:        txid,
+		EnteredHeight: entered,
+		LeftHeight:    left,
+	}
+}
+
+// GetPoolPage returns up to count tickets, starting at offset, from the live
+// ticket pool at height idx sorted by hash.
+func (db *wiredDB) GetPoolPage(idx int64, offset, count int) *apitypes.TicketPoolPage {
+	hs, err := db.sDB.PoolDB.SortedPool(idx)
+	if err != nil {
+		log.Errorf("Unable to get ticket pool from stakedb: %v", err)
+		return nil
+	}
+	page := &apitypes.TicketPoolPage{
+		Height:  idx,
+		Total:   len(hs),
+		Offset:  offset,
+		Tickets: []string{},
+	}
+	for i := offset; i < len(hs) && i < offset+count; i++ {
+		page.Tickets = append(page.Tickets, hs[i].String())
+	}
+	return page
+}
+
+// GetPoolAges returns the distribution of the ages of the tickets in the live
+// ticket pool at height idx, in bins of binSize blocks.
+func (db *wiredDB) GetPoolAges(idx int64, binSize int64) *apitypes.TicketPoolAges {
+	liveHeights, err := db.sDB.PoolDB.LiveTicketHeights(idx)
+	if err != nil {
+		log.Errorf("Unable to get ticket pool from stakedb: %v", err)
+		return nil
+	}
+	ages := &apitypes.TicketPoolAges{
+		Height:  idx,
+		Total:   len(liveHeights),
+		BinSize: binSize,
+		Counts:  []int{},
+	}
+	var totalAge int64
+	for _, entered := range liveHeights {
+		age := idx - entered
+		totalAge += age
+		bin := int(age / binSize)
+		for len(ages.Counts) <= bin {
+			ages.Counts = append(ages.Counts, 0)
+		}
+		ages.Counts[bin]++
+	}
+	if ages.Total > 0 {
+		ages.MeanAge = float64(totalAge) / float64(ages.Total)
+	}
+	return ages
+}
+
 func (db *wiredDB) GetPoolByHash(hash string) ([]string, error) {
 	idx, err := db.GetBlockHeight(hash)
 	if err != nil {
@@ -766,9 +868,44 @@ func (db *wiredDB) GetMempoolSSTxDetails(N int) *apitypes.MempoolTicketDetails {
 	return &mpTicketDetails
 }
 
+// GetMempoolHistory returns the mempool snapshots stored for collections made
+// between the times from and to, up to maxMempoolHistorySnapshots of them.
+func (db *wiredDB) GetMempoolHistory(from, to int64) *apitypes.MempoolHistory {
+	// Retrieve one extra snapshot to tell if the range has more.
+	snapshots, err := db.RetrieveMempoolHistory(from, to, maxMempoolHistorySnapshots+1)
+	if err != nil {
+		log.Errorf("Unable to retrieve mempool history: %v", err)
+		return nil
+	}
+	truncated := len(snapshots) > maxMempoolHistorySnapshots
+	if truncated {
+		snapshots = snapshots[:maxMempoolHistorySnapshots]
+	}
+	return &apitypes.MempoolHistory{
+		From:      from,
+		To:        to,
+		Truncated: truncated,
+		Snapshots: snapshots,
+	}
+}
+
+// GetChartData returns the chart of the block summaries of the blocks mined
+// between the times from and to.
+func (db *wiredDB) GetChartData(chart string, from, to int64) *apitypes.ChartData {
+	data, err := db.RetrieveChartData(chart, from, to)
+	if err != nil {
+		log.Errorf("Unable to retrieve %s chart: %v", chart, err)
+		return nil
+	}
+	return data
+}
+
 // GetAddressTransactionsWithSkip returns an apitypes.Address Object with at most the
 // last count transactions the address was in
 func (db *wiredDB) GetAddressTransactionsWithSkip(addr string, count, skip int) *apitypes.Address {
+	if db.AddrIndexEnabled() {
+		return db.getAddressTransactionsIndexed(addr, count, skip)
+	}
 	address, err := dcrutil.DecodeAddress(addr)
 	if err != nil {
 		log.Infof("Invalid address %s: %v", addr, err)
@@ -799,6 +936,9 @@ func (db *wiredDB) GetAddressTransactionsWithSkip(addr string, count, skip int)
 // GetAddressTransactions returns an apitypes.Address Object with at most the
 // last count transactions the address was in
 func (db *wiredDB) GetAddressTransactions(addr string, count int) *apitypes.Address {
+	if db.AddrIndexEnabled() {
+		return db.getAddressTransactionsIndexed(addr, count, 0)
+	}
 	address, err := dcrutil.DecodeAddress(addr)
 	if err != nil {
 		log.Infof("Invalid address %s: %v", addr, err)
@@ -910,6 +1050,7 @@ func makeExplorerTxBasic(data dcrjson.TxRawResult, msgTx *wire.MsgTx, params *ch
 	tx.FormattedSize = humanize.Bytes(uint64(len(data.Hex) / 2))
 	tx.Total = txhelpers.TotalVout(data.Vout).ToCoin()
 	tx.Fee, tx.FeeRate = txhelpers.TxFeeRate(msgTx)
+	tx.Class = txhelpers.ClassifyTx(msgTx)
 	for _, i := range data.Vin {
 		if i.IsCoinBase() {
 			tx.Coinbase = true
@@ -1138,6 +1279,7 @@ func (db *wiredDB) GetExplorerTx(txid string) *explorer.TxInfo {
 	inputs := make([]explorer.Vin, 0, len(txraw.Vin))
 	for i, vin := range txraw.Vin {
 		var addresses []string
+		var redeemScript *txhelpers.RedeemScript
 		if !(vin.IsCoinBase() || (vin.IsStakeBase() && i == 0)) {
 			addrs, err := txhelpers.OutPointAddresses(&msgTx.TxIn[i].PreviousOutPoint, db.client, db.params)
 			if err != nil {
@@ -1145,6 +1287,9 @@ func (db *wiredDB) GetExplorerTx(txid string) *explorer.TxInfo {
 				continue
 			}
 			addresses = addrs
+			if len(addrs) == 1 {
+				redeemScript = txhelpers.SpentRedeemScript(msgTx, uint32(i), addrs[0], db.params)
+			}
 		}
 		inputs = append(inputs, explorer.Vin{
 			Vin: &dcrjson.Vin{
@@ -1157,6 +1302,7 @@ func (db *wiredDB) GetExplorerTx(txid string) *explorer.TxInfo {
 			},
 			Addresses:       addresses,
 			FormattedAmount: humanize.Commaf(vin.AmountIn),
+			RedeemScript:    redeemScript,
 		})
 	}
 	tx.Vin = inputs
@@ -1218,6 +1364,9 @@ func (db *wiredDB) GetExplorerAddress(address string, count, offset int64) *expl
 		log.Infof("Invalid address %s: %v", address, err)
 		return nil
 	}
+	if db.AddrIndexEnabled() {
+		return db.getExplorerAddressIndexed(address, addr, count, offset)
+	}
 
 	maxcount := explorer.MaxAddressRows
 	txs, err := db.client.SearchRawTransactionsVerbose(addr,
@@ -1318,6 +1467,11 @@ func (db *wiredDB) CountUnconfirmedTransactions(address string, maxUnconfirmedPo
 		log.Infof("Invalid address %s: %v", address, err)
 		return
 	}
+	if db.AddrIndexEnabled() {
+		var txns []*addrMempoolTx
+		txns, err = db.mempoolAddressTxns(address)
+		return int64(len(txns)), err
+	}
 	txs, err := db.client.SearchRawTransactionsVerbose(addr, 0, int(maxUnconfirmedPossible), false, true, nil)
 	if err != nil {
 		log.Warnf("GetAddressTransactionsRaw failed for address %s: %v", addr, err)
@@ -191,6 +191,19 @@ func (p *ChainMonitor) switchToSideChain() (int32, *chainhash.Hash, error) {
 		if err := p.db.StoreStakeInfoExtended(stakeInfoSummaryExtended); err != nil {
 			log.Errorf("Failed to store stake info data: %v", err)
 		}
+		msgBlock, err := p.db.client.GetBlock(&p.sideChain[i])
+		if err != nil {
+			log.Errorf("Failed to get block %v: %v", p.sideChain[i], err)
+		} else {
+			if err = p.db.storeBlockFeeRates(msgBlock); err != nil {
+				log.Errorf("Failed to store block fee rates: %v", err)
+			}
+			if p.db.AddrIndexEnabled() {
+				if err = p.db.indexBlock(msgBlock); err != nil {
+					log.Errorf("Failed to index block addresses: %v", err)
+				}
+			}
+		}
 		log.Infof("Stored block %v (height %d) from side chain.",
 			blockDataSummary.Hash, blockDataSummary.Height)
 	}
@@ -0,0 +1,67 @@
+// Copyright (c) 2018, The dcrdata developers
+// See LICENSE for details.
+
+package dcrsqlite
+
+import (
+	"fmt"
+
+	apitypes "github.com/decred/dcrdata/api/types"
+)
+
+// summaryChartColumns are the block summary table columns plotted by each of
+// the charts of the block summaries.
+var summaryChartColumns = map[string]string{
+	apitypes.ChartTicketPrice: "sdiff",
+	apitypes.ChartPoolSize:    "poolsize",
+	apitypes.ChartPoolValue:   "poolval",
+	apitypes.ChartBlockSize:   "size",
+	apitypes.ChartDifficulty:  "diff",
+}
+
+// RetrieveChartData returns the chart of the block summaries of the blocks
+// mined between the times from and to (UNIX seconds, inclusive), with up to
+// apitypes.MaxChartPoints points.
+func (db *DB) RetrieveChartData(chart string, from, to int64) (*apitypes.ChartData, error) {
+	column, ok := summaryChartColumns[chart]
+	if !ok {
+		return nil, fmt.Errorf("unknown chart %s", chart)
+	}
+
+	var numBlocks int64
+	err := db.QueryRow(fmt.Sprintf(`select count(*) from %s where time between ? and ?`,
+		TableNameSummaries), from, to).Scan(&numBlocks)
+	if err != nil {
+		return nil, err
+	}
+	bin := (numBlocks + apitypes.MaxChartPoints - 1) / apitypes.MaxChartPoints
+	if bin < 1 {
+		bin = 1
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`select max(height), max(time), avg(%s)
+		from %s where time between ? and ? group by height / ? order by 1`,
+		column, TableNameSummaries), from, to, bin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := &apitypes.ChartData{
+		Chart: chart,
+		From:  from,
+		To:    to,
+		Bin:   bin,
+	}
+	for rows.Next() {
+		var height, t int64
+		var value float64
+		if err = rows.Scan(&height, &t, &value); err != nil {
+			return nil, err
+		}
+		data.Heights = append(data.Heights, height)
+		data.Times = append(data.Times, t)
+		data.Values = append(data.Values, value)
+	}
+	return data, rows.Err()
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package dcrsqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrdata/db/dbtypes"
+)
+
+// The functions in this file provide the explorer with the spending
+// transactions of outputs, the fate of tickets, and the missed votes of blocks
+// without PostgreSQL. Ticket status comes from the best node of the stake
+// database and the index of the ticket pool diffs, and winners from the SQLite
+// pool info.
+// The spending transactions of outputs other than tickets are only available
+// when the address index is enabled.
+
+// SpendingTransaction returns the transaction spending the specified outpoint,
+// the index of the spending input, and the tree of the spending transaction.
+// The address index is searched when it is enabled. Without it, only the votes
+// spending tickets are found. sql.ErrNoRows is returned if no spending
+// transaction is found.
+func (db *wiredDB) SpendingTransaction(fundingTxID string, vout uint32) (string, uint32, int8, error) {
+	if db.AddrIndexEnabled() {
+		spendingTx, vin, err := db.RetrieveSpendingTxByTxOut(fundingTxID, vout)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		tree, err := db.txTree(spendingTx)
+		return spendingTx, vin, tree, err
+	}
+
+	if vout != 0 {
+		return "", 0, 0, sql.ErrNoRows
+	}
+	vote, err := db.ticketVote(fundingTxID)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if vote == "" {
+		return "", 0, 0, sql.ErrNoRows
+	}
+	return vote, 1, wire.TxTreeStake, nil
+}
+
+// SpendingTransactions returns the transactions spending the outputs of the
+// specified transaction, the indexes of the spending inputs, and the indexes of
+// the spent outputs. Like SpendingTransaction, only the votes spending tickets
+// are found when the address index is disabled.
+func (db *wiredDB) SpendingTransactions(fundingTxID string) ([]string, []uint32, []uint32, error) {
+	if db.AddrIndexEnabled() {
+		return db.RetrieveSpendingTxsByFundingTx(fundingTxID)
+	}
+
+	vote, err := db.ticketVote(fundingTxID)
+	if err != nil || vote == "" {
+		return nil, nil, nil, err
+	}
+	return []string{vote}, []uint32{1}, []uint32{0}, nil
+}
+
+// PoolStatusForTicket retrieves the specified ticket's spend status and ticket
+// pool status, and an error value. Tickets that have not entered the live pool
+// are reported as unspent and live, as they are by the primary data source.
+func (db *wiredDB) PoolStatusForTicket(txid string) (dbtypes.TicketSpendType, dbtypes.TicketPoolStatus, error) {
+	ticket, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	live, missed, revoked, expired := db.sDB.TicketStatus(*ticket)
+	spendType := dbtypes.TicketUnspent
+	if revoked {
+		spendType = dbtypes.TicketRevoked
+	}
+	switch {
+	case live:
+		return dbtypes.TicketUnspent, dbtypes.PoolStatusLive, nil
+	case expired:
+		return spendType, dbtypes.PoolStatusExpired, nil
+	case missed:
+		return spendType, dbtypes.PoolStatusMissed, nil
+	}
+
+	// Neither live nor missed, the ticket voted if it left the live pool.
+	if _, left := db.sDB.PoolDB.TicketLiveHeights(*ticket); left >= 0 {
+		return dbtypes.TicketVoted, dbtypes.PoolStatusVoted, nil
+	}
+	return dbtypes.TicketUnspent, dbtypes.PoolStatusLive, nil
+}
+
+// BlockMissedVotes retrieves the ticket IDs for all missed votes in the
+// specified block, and an error value. These are the winners drawn after
+// connecting the previous block that did not vote in this block.
+func (db *wiredDB) BlockMissedVotes(blockHash string) ([]string, error) {
+	hash, err := chainhash.NewHashFromStr(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	msgBlock, err := db.client.GetBlock(hash)
+	if err != nil {
+		return nil, fmt.Errorf("GetBlock failed for %v: %v", hash, err)
+	}
+
+	prevHash := msgBlock.Header.PrevBlock
+	if prevHash == zeroHash {
+		return nil, nil
+	}
+	var winners []string
+	if tpi, found := db.sDB.PoolInfo(prevHash); found {
+		winners = tpi.Winners
+	} else if winners, _, err = db.RetrieveWinnersByHash(prevHash.String()); err != nil {
+		return nil, err
+	}
+
+	voted := make(map[string]struct{}, len(winners))
+	for _, stx := range msgBlock.STransactions {
+		if stake.IsSSGen(stx) {
+			voted[stx.TxIn[1].PreviousOutPoint.Hash.String()] = struct{}{}
+		}
+	}
+
+	var misses []string
+	for _, ticket := range winners {
+		if ticket == "" {
+			continue
+		}
+		if _, ok := voted[ticket]; !ok {
+			misses = append(misses, ticket)
+		}
+	}
+	return misses, nil
+}
+
+// ticketVote returns the ID of the vote spending the specified ticket, or an
+// empty string if the transaction is not a ticket or the ticket has not voted.
+// The vote is found in the block in which the ticket left the live pool, so
+// the node is only queried for tickets that voted.
+func (db *wiredDB) ticketVote(txid string) (string, error) {
+	ticket, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		return "", err
+	}
+	// Transactions other than tickets never enter the live pool.
+	_, left := db.sDB.PoolDB.TicketLiveHeights(*ticket)
+	if left < 0 {
+		return "", nil
+	}
+	if _, missed, _, _ := db.sDB.TicketStatus(*ticket); missed {
+		return "", nil
+	}
+
+	blockHashStr, err := db.RetrieveBlockHash(left)
+	if err != nil {
+		return "", fmt.Errorf("RetrieveBlockHash failed for height %d: %v", left, err)
+	}
+	blockHash, err := chainhash.NewHashFromStr(blockHashStr)
+	if err != nil {
+		return "", err
+	}
+	msgBlock, err := db.client.GetBlock(blockHash)
+	if err != nil {
+		return "", fmt.Errorf("GetBlock failed for %v: %v", blockHash, err)
+	}
+	for _, stx := range msgBlock.STransactions {
+		if stake.IsSSGen(stx) && stx.TxIn[1].PreviousOutPoint.Hash == *ticket {
+			return stx.TxHash().String(), nil
+		}
+	}
+	return "", nil
+}
+
+// txTree returns the tree of the specified transaction, wire.TxTreeStake for
+// stake transactions and wire.TxTreeRegular otherwise.
+func (db *wiredDB) txTree(txid string) (int8, error) {
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := db.client.GetRawTransaction(hash)
+	if err != nil {
+		return 0, fmt.Errorf("GetRawTransaction failed for %v: %v", hash, err)
+	}
+	if stake.DetermineTxType(tx.MsgTx()) != stake.TxTypeRegular {
+		return wire.TxTreeStake, nil
+	}
+	return wire.TxTreeRegular, nil
+}
@@ -0,0 +1,268 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package dcrsqlite
+
+import (
+	"math"
+	"sort"
+
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/decred/dcrd/wire"
+	apitypes "github.com/decred/dcrdata/api/types"
+	"github.com/decred/dcrdata/txhelpers"
+	"github.com/decred/dcrwallet/wallet/txrules"
+)
+
+// Fee rates are estimated by assuming that each block includes a transaction
+// paying at least a threshold fee rate, the 10th percentile of the fee rates
+// of its regular transactions, independently of other blocks. The confidence
+// that a transaction is included within N blocks is then 1 - f^N, where f is
+// the fraction of recent blocks with a threshold above its fee rate. When the
+// regular transactions in mempool could fill those N blocks, the fee rate must
+// also exceed that of the transactions that would fill them.
+
+// FeeEstimateWindow is the number of recent blocks on which fee rate estimates
+// are based.
+const FeeEstimateWindow = 144
+
+// Target confidences of the low, medium, and high fee rate estimates.
+const (
+	feeEstimateConfidenceLow    = 0.5
+	feeEstimateConfidenceMedium = 0.8
+	feeEstimateConfidenceHigh   = 0.95
+)
+
+// BlockFeeRates contains statistics of the fee rates in DCR/kB of the regular
+// transactions, excluding the coinbase, included in a block.
+type BlockFeeRates struct {
+	Height  int64
+	Hash    string
+	NumTxns int
+	Min     float64
+	P10     float64
+	Median  float64
+	P90     float64
+	Max     float64
+	Mean    float64
+}
+
+// NewBlockFeeRates computes the fee rate statistics of the block.
+func NewBlockFeeRates(msgBlock *wire.MsgBlock) *BlockFeeRates {
+	feeRates := txhelpers.RegularTxFeeRates(msgBlock)
+	fr := &BlockFeeRates{
+		Height:  int64(msgBlock.Header.Height),
+		Hash:    msgBlock.BlockHash().String(),
+		NumTxns: len(feeRates),
+	}
+	if fr.NumTxns == 0 {
+		return fr
+	}
+
+	var sum float64
+	for _, feeRate := range feeRates {
+		sum += feeRate
+	}
+	fr.Min = feeRates[0]
+	fr.P10 = txhelpers.PercentileCoin(feeRates, 0.1)
+	fr.Median = txhelpers.MedianCoin(feeRates)
+	fr.P90 = txhelpers.PercentileCoin(feeRates, 0.9)
+	fr.Max = feeRates[fr.NumTxns-1]
+	fr.Mean = sum / float64(fr.NumTxns)
+	return fr
+}
+
+// StoreBlockFeeRates stores the fee rate statistics of a block, replacing any
+// for another block at the same height.
+func (db *DB) StoreBlockFeeRates(fr *BlockFeeRates) error {
+	_, err := db.Exec(db.insertFeeRatesSQL, fr.Height, fr.Hash, fr.NumTxns,
+		fr.Min, fr.P10, fr.Median, fr.P90, fr.Max, fr.Mean)
+	return err
+}
+
+// RetrieveBlockFeeRatesRange retrieves the fee rate statistics stored for the
+// blocks on [ind0, ind1].
+func (db *DB) RetrieveBlockFeeRatesRange(ind0, ind1 int64) ([]*BlockFeeRates, error) {
+	rows, err := db.Query(db.getFeeRatesRangeSQL, ind0, ind1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var feeRates []*BlockFeeRates
+	for rows.Next() {
+		fr := new(BlockFeeRates)
+		err = rows.Scan(&fr.Height, &fr.Hash, &fr.NumTxns, &fr.Min, &fr.P10,
+			&fr.Median, &fr.P90, &fr.Max, &fr.Mean)
+		if err != nil {
+			return nil, err
+		}
+		feeRates = append(feeRates, fr)
+	}
+	return feeRates, rows.Err()
+}
+
+// storeBlockFeeRates computes and stores the fee rate statistics of the block.
+func (db *wiredDB) storeBlockFeeRates(msgBlock *wire.MsgBlock) error {
+	return db.StoreBlockFeeRates(NewBlockFeeRates(msgBlock))
+}
+
+// syncFeeRates stores the fee rate statistics missing for the blocks in the
+// fee estimation window ending at the best block summary.
+func (db *wiredDB) syncFeeRates(quit chan struct{}) error {
+	height := db.GetBestBlockHeight()
+	from := height - FeeEstimateWindow + 1
+	if from < 1 {
+		from = 1
+	}
+	stored, err := db.RetrieveBlockFeeRatesRange(from, height)
+	if err != nil {
+		return err
+	}
+	have := make(map[int64]bool, len(stored))
+	for _, fr := range stored {
+		have[fr.Height] = true
+	}
+
+	for h := from; h <= height; h++ {
+		if have[h] {
+			continue
+		}
+		select {
+		case <-quit:
+			return nil
+		default:
+		}
+		block, _, err := db.getBlock(h)
+		if err != nil {
+			return err
+		}
+		if err = db.storeBlockFeeRates(block.MsgBlock()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mempoolFeeRate is the fee rate in DCR/kB and size of a mempool transaction.
+type mempoolFeeRate struct {
+	feeRate float64
+	size    int64
+}
+
+// mempoolFeeRateFloor returns the fee rate of the first transaction, in order
+// of descending fee rate, that does not fit in capacity bytes with those before
+// it. A transaction must pay more than this to be included before it. 0 is
+// returned if all of the transactions fit.
+func mempoolFeeRateFloor(txns []mempoolFeeRate, capacity int64) float64 {
+	sort.Slice(txns, func(i, j int) bool {
+		return txns[i].feeRate > txns[j].feeRate
+	})
+	var size int64
+	for _, tx := range txns {
+		size += tx.size
+		if size > capacity {
+			return tx.feeRate
+		}
+	}
+	return 0
+}
+
+// inclusionConfidence returns the confidence that a transaction paying the fee
+// rate is included within the number of blocks, given the ascending threshold
+// fee rates of recent blocks. 0 is returned if there are no thresholds.
+func inclusionConfidence(thresholds []float64, blocks int, feeRate float64) float64 {
+	if len(thresholds) == 0 {
+		return 0
+	}
+	// The number of blocks with a threshold above the fee rate.
+	numAbove := len(thresholds) - sort.Search(len(thresholds), func(i int) bool {
+		return thresholds[i] > feeRate
+	})
+	f := float64(numAbove) / float64(len(thresholds))
+	return 1 - math.Pow(f, float64(blocks))
+}
+
+// estimateFeeRate returns the lowest fee rate, no less than minFeeRate, for
+// which the confidence of inclusion within the number of blocks is at least
+// target, and that confidence. If no fee rate reaches target, the highest
+// threshold is returned.
+func estimateFeeRate(thresholds []float64, blocks int, minFeeRate, target float64) (float64, float64) {
+	feeRate := minFeeRate
+	confidence := inclusionConfidence(thresholds, blocks, feeRate)
+	for i := 0; confidence < target && i < len(thresholds); i++ {
+		if thresholds[i] > feeRate {
+			feeRate = thresholds[i]
+			confidence = inclusionConfidence(thresholds, blocks, feeRate)
+		}
+	}
+	return feeRate, confidence
+}
+
+// GetFeeEstimate estimates the fee rates for a regular transaction to be
+// included within the given number of blocks, from the fee rate statistics of
+// recent blocks and the regular transactions in mempool.
+func (db *wiredDB) GetFeeEstimate(blocks int) *apitypes.FeeEstimate {
+	if blocks < 1 || blocks > apitypes.FeeEstimateMaxBlocks {
+		log.Errorf("Invalid number of blocks for fee estimate: %d", blocks)
+		return nil
+	}
+
+	height := db.GetBestBlockHeight()
+	recent, err := db.RetrieveBlockFeeRatesRange(height-FeeEstimateWindow+1, height)
+	if err != nil {
+		log.Errorf("Unable to retrieve block fee rates: %v", err)
+		return nil
+	}
+	thresholds := make([]float64, 0, len(recent))
+	for _, fr := range recent {
+		if fr.NumTxns > 0 {
+			thresholds = append(thresholds, fr.P10)
+		}
+	}
+	sort.Float64s(thresholds)
+
+	mempoolTxns, err := db.client.GetRawMempoolVerbose(dcrjson.GRMRegular)
+	if err != nil {
+		log.Errorf("GetRawMempoolVerbose failed: %v", err)
+		return nil
+	}
+	txns := make([]mempoolFeeRate, 0, len(mempoolTxns))
+	var mempoolSize int64
+	for _, tx := range mempoolTxns {
+		if tx.Size <= 0 {
+			continue
+		}
+		txns = append(txns, mempoolFeeRate{
+			feeRate: 1000 * tx.Fee / float64(tx.Size),
+			size:    int64(tx.Size),
+		})
+		mempoolSize += int64(tx.Size)
+	}
+	capacity := int64(blocks) * int64(db.params.MaximumBlockSizes[0])
+	floor := mempoolFeeRateFloor(txns, capacity)
+
+	estimate := func(target float64) apitypes.FeeRateEstimate {
+		feeRate, confidence := estimateFeeRate(thresholds, blocks,
+			txrules.DefaultRelayFeePerKb.ToCoin(), target)
+		if floor > feeRate {
+			feeRate = floor
+			confidence = inclusionConfidence(thresholds, blocks, feeRate)
+		}
+		return apitypes.FeeRateEstimate{
+			FeeRate:    feeRate,
+			Confidence: confidence,
+		}
+	}
+
+	return &apitypes.FeeEstimate{
+		Blocks:      blocks,
+		Height:      height,
+		NumBlocks:   len(thresholds),
+		MempoolTxns: len(txns),
+		MempoolSize: mempoolSize,
+		Low:         estimate(feeEstimateConfidenceLow),
+		Medium:      estimate(feeEstimateConfidenceMedium),
+		High:        estimate(feeEstimateConfidenceHigh),
+	}
+}
@@ -0,0 +1,88 @@
+package dcrsqlite
+
+import "testing"
+
+func TestInclusionConfidence(t *testing.T) {
+	thresholds := []float64{1, 2, 3, 4}
+	tests := []struct {
+		thresholds []float64
+		blocks     int
+		feeRate    float64
+		confidence float64
+	}{
+		{nil, 1, 1, 0},
+		{thresholds, 1, 0.5, 0},
+		{thresholds, 1, 1, 0.25},
+		{thresholds, 1, 2, 0.5},
+		{thresholds, 2, 2, 0.75},
+		{thresholds, 1, 2.5, 0.5},
+		{thresholds, 1, 4, 1},
+		{thresholds, 3, 5, 1},
+	}
+	for _, test := range tests {
+		confidence := inclusionConfidence(test.thresholds, test.blocks, test.feeRate)
+		if confidence != test.confidence {
+			t.Errorf("inclusionConfidence(%v, %d, %v) = %v, expected %v",
+				test.thresholds, test.blocks, test.feeRate, confidence,
+				test.confidence)
+		}
+	}
+}
+
+func TestEstimateFeeRate(t *testing.T) {
+	thresholds := []float64{1, 2, 3, 4}
+	tests := []struct {
+		thresholds []float64
+		blocks     int
+		minFeeRate float64
+		target     float64
+		feeRate    float64
+		confidence float64
+	}{
+		// Without thresholds the minimum fee rate has no confidence.
+		{nil, 1, 0.5, 0.5, 0.5, 0},
+		{thresholds, 1, 0.5, 0.5, 2, 0.5},
+		{thresholds, 1, 0.5, 0.95, 4, 1},
+		{thresholds, 2, 0.5, 0.8, 3, 0.9375},
+		{thresholds, 4, 0.5, 0.5, 1, 1 - 0.75*0.75*0.75*0.75},
+		// The minimum fee rate is used if it reaches the target.
+		{thresholds, 1, 3, 0.5, 3, 0.75},
+		{thresholds, 1, 5, 0.5, 5, 1},
+	}
+	for _, test := range tests {
+		feeRate, confidence := estimateFeeRate(test.thresholds, test.blocks,
+			test.minFeeRate, test.target)
+		if feeRate != test.feeRate || confidence != test.confidence {
+			t.Errorf("estimateFeeRate(%v, %d, %v, %v) = %v, %v, expected %v, %v",
+				test.thresholds, test.blocks, test.minFeeRate, test.target,
+				feeRate, confidence, test.feeRate, test.confidence)
+		}
+	}
+}
+
+func TestMempoolFeeRateFloor(t *testing.T) {
+	txns := []mempoolFeeRate{
+		{feeRate: 1, size: 100},
+		{feeRate: 3, size: 100},
+		{feeRate: 2, size: 100},
+	}
+	tests := []struct {
+		txns     []mempoolFeeRate
+		capacity int64
+		floor    float64
+	}{
+		{nil, 100, 0},
+		{txns, 50, 3},
+		{txns, 100, 2},
+		{txns, 150, 2},
+		{txns, 250, 1},
+		{txns, 300, 0},
+	}
+	for _, test := range tests {
+		floor := mempoolFeeRateFloor(test.txns, test.capacity)
+		if floor != test.floor {
+			t.Errorf("mempoolFeeRateFloor(%v, %d) = %v, expected %v",
+				test.txns, test.capacity, floor, test.floor)
+		}
+	}
+}
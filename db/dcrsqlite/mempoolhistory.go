@@ -0,0 +1,77 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package dcrsqlite
+
+import (
+	"encoding/json"
+	"time"
+
+	apitypes "github.com/decred/dcrdata/api/types"
+	"github.com/decred/dcrdata/mempool"
+)
+
+// maxMempoolHistorySnapshots limits the number of snapshots returned by a
+// single mempool history request.
+const maxMempoolHistorySnapshots = 2000
+
+// StoreMPData satisfies the mempool.MempoolDataSaver interface, recording the
+// collected ticket fee data and ticket details in the mempool history table.
+func (db *DB) StoreMPData(data *mempool.MempoolData, timestamp time.Time) error {
+	tickets, err := json.Marshal(data.AllTicketsDetails)
+	if err != nil {
+		return err
+	}
+
+	feeInfo := data.Ticketfees.FeeInfoMempool
+	res, err := db.Exec(db.insertMempoolHistorySQL, data.Height,
+		timestamp.Unix(), data.NumTickets, data.NumVotes, data.NewTickets,
+		feeInfo.Min, feeInfo.Max, feeInfo.Mean, feeInfo.Median, feeInfo.StdDev,
+		data.GetLowestMineableFee(), string(tickets))
+	if err != nil {
+		log.Errorf("Failed to store mempool history: %v", err)
+		return err
+	}
+	if err = logDBResult(res); err != nil {
+		return err
+	}
+
+	// Remove the snapshots older than the retention period.
+	if db.mempoolHistoryRetention <= 0 {
+		return nil
+	}
+	oldest := timestamp.Add(-db.mempoolHistoryRetention).Unix()
+	res, err = db.Exec(db.pruneMempoolHistorySQL, oldest)
+	if err != nil {
+		log.Errorf("Failed to prune mempool history: %v", err)
+		return err
+	}
+	return logDBResult(res)
+}
+
+// RetrieveMempoolHistory returns up to limit mempool snapshots collected
+// between the times from and to (UNIX seconds, inclusive), oldest first.
+func (db *DB) RetrieveMempoolHistory(from, to int64, limit int) ([]*apitypes.MempoolSnapshot, error) {
+	rows, err := db.Query(db.getMempoolHistorySQL, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []*apitypes.MempoolSnapshot
+	for rows.Next() {
+		var tickets string
+		ss := new(apitypes.MempoolSnapshot)
+		err = rows.Scan(&ss.Height, &ss.Time, &ss.Number, &ss.NumVotes,
+			&ss.NewTickets, &ss.Min, &ss.Max, &ss.Mean, &ss.Median, &ss.StdDev,
+			&ss.LowestMineable, &tickets)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal([]byte(tickets), &ss.Tickets); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, ss)
+	}
+	return snapshots, rows.Err()
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/btcsuite/btclog"
 
@@ -33,6 +34,12 @@ type BlockSummaryDatabaser interface {
 // DBInfo contains db configuration
 type DBInfo struct {
 	FileName string
+	// AddrIndex enables the address index, which is used instead of the
+	// node's searchrawtransactions RPC (requiring dcrd's --addrindex).
+	AddrIndex bool
+	// MempoolHistoryRetention is how long mempool snapshots are kept in the
+	// mempool history. Zero keeps all snapshots.
+	MempoolHistoryRetention time.Duration
 }
 
 const (
@@ -40,6 +47,18 @@ const (
 	TableNameSummaries = "dcrdata_block_summary"
 	// TableNameStakeInfo is name of the table used to store extended stake info
 	TableNameStakeInfo = "dcrdata_stakeinfo_extended"
+	// TableNameMempoolHistory is name of the table used to store mempool data
+	// collections
+	TableNameMempoolHistory = "dcrdata_mempool_history"
+	// TableNameAddresses is name of the table used to store the outputs paying
+	// to each address, and the inputs spending them
+	TableNameAddresses = "dcrdata_addresses"
+	// TableNameAddrIndexBlocks is name of the table used to store the blocks
+	// included in the address index
+	TableNameAddrIndexBlocks = "dcrdata_addrindex_blocks"
+	// TableNameFeeRates is name of the table used to store statistics of the
+	// fee rates of each block's regular transactions
+	TableNameFeeRates = "dcrdata_fee_rates"
 )
 
 // DB is a wrapper around sql.DB that adds methods for storing and retrieving
@@ -50,6 +69,8 @@ type DB struct {
 	sync.RWMutex
 	dbSummaryHeight                                              int64
 	dbStakeInfoHeight                                            int64
+	addrIndex                                                    bool
+	mempoolHistoryRetention                                      time.Duration
 	getPoolSQL, getPoolRangeSQL, getPoolValSizeRangeSQL          string
 	getPoolByHashSQL                                             string
 	getWinnersByHashSQL, getWinnersSQL                           string
@@ -63,6 +84,13 @@ type DB struct {
 	getLatestStakeInfoExtendedSQL                                string
 	getStakeInfoExtendedSQL, insertStakeInfoExtendedSQL          string
 	getStakeInfoWinnersSQL                                       string
+	getMempoolHistorySQL, insertMempoolHistorySQL                string
+	pruneMempoolHistorySQL                                       string
+	insertAddrOutputSQL, updateAddrSpendingSQL                   string
+	insertAddrIndexBlockSQL, getAddrIndexTipSQL                  string
+	getAddrTxnsSQL, getAddrSummarySQL, getAddrNumTxnsSQL         string
+	getAddrOutpointSQL, getAddrSpendingSQL, getAddrSpendingsSQL  string
+	insertFeeRatesSQL, getFeeRatesRangeSQL                       string
 }
 
 // NewDB creates a new DB instance with pre-generated sql statements from an
@@ -132,6 +160,72 @@ func NewDB(db *sql.DB) (*DB, error) {
         ) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
         `, TableNameStakeInfo)
 
+	// Mempool history queries
+	d.getMempoolHistorySQL = fmt.Sprintf(`select height, time, num_tickets,
+		num_votes, new_tickets, fee_min, fee_max, fee_mean, fee_med, fee_std,
+		lowest_mineable, tickets from %s where time between ? and ?
+		ORDER BY time LIMIT ?`, TableNameMempoolHistory)
+	d.insertMempoolHistorySQL = fmt.Sprintf(`
+        INSERT INTO %s(
+            height, time, num_tickets, num_votes, new_tickets, fee_min, fee_max,
+			fee_mean, fee_med, fee_std, lowest_mineable, tickets
+        ) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        `, TableNameMempoolHistory)
+	d.pruneMempoolHistorySQL = fmt.Sprintf(`delete from %s where time < ?`,
+		TableNameMempoolHistory)
+
+	// Address index queries
+	d.insertAddrOutputSQL = fmt.Sprintf(`
+        INSERT OR IGNORE INTO %s(
+            address, tx_hash, tx_index, tx_tree, value, block_height, block_time
+        ) values(?, ?, ?, ?, ?, ?, ?)
+        `, TableNameAddresses)
+	d.updateAddrSpendingSQL = fmt.Sprintf(`update %s set spending_tx_hash = ?,
+		spending_tx_vin = ?, spending_height = ?, spending_time = ?
+		where tx_hash = ? and tx_index = ?`, TableNameAddresses)
+	d.getAddrSpendingSQL = fmt.Sprintf(`select spending_tx_hash, spending_tx_vin
+		from %s where tx_hash = ? and tx_index = ? and spending_tx_hash is not null
+		limit 1`, TableNameAddresses)
+	d.getAddrSpendingsSQL = fmt.Sprintf(`select distinct tx_index,
+		spending_tx_hash, spending_tx_vin from %s
+		where tx_hash = ? and spending_tx_hash is not null
+		order by tx_index`, TableNameAddresses)
+	d.insertFeeRatesSQL = fmt.Sprintf(`INSERT OR REPLACE INTO %s(
+		height, hash, num_txns, fee_rate_min, fee_rate_p10, fee_rate_med,
+		fee_rate_p90, fee_rate_max, fee_rate_mean)
+		values(?, ?, ?, ?, ?, ?, ?, ?, ?)`, TableNameFeeRates)
+	d.getFeeRatesRangeSQL = fmt.Sprintf(`select height, hash, num_txns,
+		fee_rate_min, fee_rate_p10, fee_rate_med, fee_rate_p90, fee_rate_max,
+		fee_rate_mean from %s where height between ? and ?
+		ORDER BY height`, TableNameFeeRates)
+	d.insertAddrIndexBlockSQL = fmt.Sprintf(`INSERT OR REPLACE INTO %s(height, hash)
+		values(?, ?)`, TableNameAddrIndexBlocks)
+	d.getAddrIndexTipSQL = fmt.Sprintf(`select height, hash from %s
+		ORDER BY height DESC LIMIT 0, 1`, TableNameAddrIndexBlocks)
+	d.getAddrTxnsSQL = fmt.Sprintf(`select tx_hash, max(height), max(time),
+		sum(received), sum(sent) from (
+			select tx_hash, block_height as height, block_time as time,
+				value as received, 0 as sent
+			from %[1]s where address = ?
+			UNION ALL
+			select spending_tx_hash, spending_height, spending_time, 0, value
+			from %[1]s where address = ? and spending_tx_hash is not null
+		) GROUP BY tx_hash ORDER BY max(height) DESC, tx_hash LIMIT ? OFFSET ?`,
+		TableNameAddresses)
+	d.getAddrSummarySQL = fmt.Sprintf(`select count(*), ifnull(sum(value), 0),
+		count(spending_tx_hash), ifnull(sum(case when spending_tx_hash is null
+			then 0 else value end), 0),
+		count(distinct tx_hash), count(distinct spending_tx_hash)
+		from %s where address = ?`, TableNameAddresses)
+	d.getAddrNumTxnsSQL = fmt.Sprintf(`select count(*) from (
+			select tx_hash from %[1]s where address = ?
+			UNION
+			select spending_tx_hash from %[1]s
+			where address = ? and spending_tx_hash is not null
+		)`, TableNameAddresses)
+	d.getAddrOutpointSQL = fmt.Sprintf(`select address, value from %s
+		where tx_hash = ? and tx_index = ?`, TableNameAddresses)
+
 	var err error
 	if d.dbSummaryHeight, err = d.GetBlockSummaryHeight(); err != nil {
 		return nil, err
@@ -194,10 +288,79 @@ func InitDB(dbInfo *DBInfo) (*DB, error) {
 		return nil, err
 	}
 
+	createMempoolHistoryStmt := fmt.Sprintf(`
+        create table if not exists %[1]s(
+            height INTEGER,
+            time INTEGER,
+            num_tickets INTEGER, num_votes INTEGER, new_tickets INTEGER,
+            fee_min FLOAT, fee_max FLOAT, fee_mean FLOAT,
+			fee_med FLOAT, fee_std FLOAT,
+			lowest_mineable FLOAT,
+			tickets TEXT
+        );
+        create index if not exists %[1]s_time_idx on %[1]s(time);
+        `, TableNameMempoolHistory)
+
+	_, err = db.Exec(createMempoolHistoryStmt)
+	if err != nil {
+		log.Errorf("%q: %s\n", err, createMempoolHistoryStmt)
+		return nil, err
+	}
+
+	createAddressesStmt := fmt.Sprintf(`
+        create table if not exists %[1]s(
+            address TEXT,
+            tx_hash TEXT, tx_index INTEGER, tx_tree INTEGER,
+            value INTEGER,
+            block_height INTEGER, block_time INTEGER,
+            spending_tx_hash TEXT, spending_tx_vin INTEGER,
+            spending_height INTEGER, spending_time INTEGER
+        );
+        create index if not exists %[1]s_address_idx on %[1]s(address);
+        create index if not exists %[1]s_outpoint_idx on %[1]s(tx_hash, tx_index);
+        create index if not exists %[1]s_height_idx on %[1]s(block_height);
+        create index if not exists %[1]s_spending_height_idx on %[1]s(spending_height);
+        create table if not exists %[2]s(
+            height INTEGER PRIMARY KEY,
+            hash TEXT
+        );
+        `, TableNameAddresses, TableNameAddrIndexBlocks)
+
+	_, err = db.Exec(createAddressesStmt)
+	if err != nil {
+		log.Errorf("%q: %s\n", err, createAddressesStmt)
+		return nil, err
+	}
+	if err = createAddrOutputUniqueIndex(db); err != nil {
+		return nil, err
+	}
+
+	createFeeRatesStmt := fmt.Sprintf(`
+        create table if not exists %s(
+            height INTEGER PRIMARY KEY,
+            hash TEXT,
+            num_txns INTEGER,
+            fee_rate_min FLOAT, fee_rate_p10 FLOAT, fee_rate_med FLOAT,
+            fee_rate_p90 FLOAT, fee_rate_max FLOAT, fee_rate_mean FLOAT
+        );
+        `, TableNameFeeRates)
+
+	_, err = db.Exec(createFeeRatesStmt)
+	if err != nil {
+		log.Errorf("%q: %s\n", err, createFeeRatesStmt)
+		return nil, err
+	}
+
 	if err = db.Ping(); err != nil {
 		return nil, err
 	}
-	return NewDB(db)
+	d, err := NewDB(db)
+	if err != nil {
+		return nil, err
+	}
+	d.addrIndex = dbInfo.AddrIndex
+	d.mempoolHistoryRetention = dbInfo.MempoolHistoryRetention
+	return d, nil
 }
 
 // DBDataSaver models a DB with a channel to communicate new block height to the web interface
@@ -312,6 +475,63 @@ func (db *DB) GetStakeInfoHeight() (int64, error) {
 	return db.dbStakeInfoHeight, nil
 }
 
+// Checkpoint returns the height and hash of the best block for which both the
+// block summary and stake info are stored. The height is -1 if either table is
+// empty.
+func (db *DB) Checkpoint() (int64, string, error) {
+	summaryHeight, err := db.GetBlockSummaryHeight()
+	if err != nil {
+		return -1, "", err
+	}
+	stakeInfoHeight, err := db.GetStakeInfoHeight()
+	if err != nil {
+		return -1, "", err
+	}
+	height := summaryHeight
+	if stakeInfoHeight < height {
+		height = stakeInfoHeight
+	}
+	if height < 0 {
+		return -1, "", nil
+	}
+	hash, err := db.RetrieveBlockHash(height)
+	return height, hash, err
+}
+
+// RewindToHeight deletes the block summaries, stake info and address index
+// data for all blocks above the specified height.
+func (db *DB) RewindToHeight(height int64) error {
+	db.Lock()
+	defer db.Unlock()
+
+	dbtx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("unable to begin database transaction: %v", err)
+	}
+	for _, table := range []string{TableNameSummaries, TableNameStakeInfo, TableNameFeeRates} {
+		_, err = dbtx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE height > ?`, table), height)
+		if err != nil {
+			_ = dbtx.Rollback()
+			return fmt.Errorf("failed to delete from %s: %v", table, err)
+		}
+	}
+	if err = rewindAddressIndex(dbtx, height); err != nil {
+		_ = dbtx.Rollback()
+		return err
+	}
+	if err = dbtx.Commit(); err != nil {
+		return err
+	}
+
+	if db.dbSummaryHeight > height {
+		db.dbSummaryHeight = height
+	}
+	if db.dbStakeInfoHeight > height {
+		db.dbStakeInfoHeight = height
+	}
+	return nil
+}
+
 // RetrievePoolInfoRange returns an array of apitypes.TicketPoolInfo for block
 // range ind0 to ind1 and a non-nil error on success
 func (db *DB) RetrievePoolInfoRange(ind0, ind1 int64) ([]apitypes.TicketPoolInfo, []string, error) {
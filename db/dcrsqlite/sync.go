@@ -16,8 +16,21 @@ import (
 
 const (
 	rescanLogBlockChunk = 250
+
+	// maxRecoveryRewind is the most blocks that a database will be rewound by
+	// RecoverToCommonHeight. A database further behind the others is assumed
+	// to be rebuilding, and does not hold the others back.
+	maxRecoveryRewind = 1024
 )
 
+// RecoverableDB is a block data store that can report the best block it has
+// completely stored, and may be rewound to an earlier block.
+type RecoverableDB interface {
+	Checkpoint() (height int64, hash string, err error)
+	GetBlockHash(idx int64) (string, error)
+	RewindToHeight(height int64) error
+}
+
 // DBHeights returns the best block heights of: SQLite database tables (block
 // summary and stake info tables), the stake database (ffldb_stake), and the
 // lowest of these. An error value is returned if any database is inaccessible.
@@ -82,6 +95,116 @@ func (db *wiredDB) RewindStakeDB(toHeight int64, quit chan struct{}) (stakeDBHei
 	return
 }
 
+// RecoverToCommonHeight rewinds the SQLite tables, the stake database, and
+// the other given databases to the best block they all have stored that is
+// also on the node's main chain. This undoes the effects of an unclean
+// shutdown, where each database may have stopped at a different block, and of
+// a chain reorganization while dcrdata was not running. The common height is
+// returned, or -1 if there is no block data.
+func (db *wiredDB) RecoverToCommonHeight(others ...RecoverableDB) (int64, error) {
+	stores := append([]RecoverableDB{db}, others...)
+
+	checkpoints := make([]int64, len(stores))
+	best := int64(-1)
+	for i, store := range stores {
+		height, _, err := store.Checkpoint()
+		if err != nil {
+			return -1, fmt.Errorf("Checkpoint failed: %v", err)
+		}
+		checkpoints[i] = height
+		if height > best {
+			best = height
+		}
+	}
+
+	common := best
+	for i, height := range checkpoints {
+		if height >= common {
+			continue
+		}
+		if best-height > maxRecoveryRewind {
+			log.Infof("Database %d is at height %d, far behind %d. Not rewinding "+
+				"the others for it.", i, height, best)
+			continue
+		}
+		common = height
+	}
+
+	// Step back past blocks that are not on the node's main chain.
+	for common >= 0 {
+		nodeHash, err := db.client.GetBlockHash(common)
+		if err != nil {
+			return -1, fmt.Errorf("GetBlockHash(%d) failed: %v", common, err)
+		}
+		onMainChain := true
+		for i, store := range stores {
+			if checkpoints[i] < common {
+				continue
+			}
+			hash, err := store.GetBlockHash(common)
+			if err != nil {
+				return -1, err
+			}
+			if hash != nodeHash.String() {
+				onMainChain = false
+				break
+			}
+		}
+		if onMainChain {
+			break
+		}
+		if best-common >= maxRecoveryRewind {
+			return -1, fmt.Errorf("no block on the node's main chain found "+
+				"within %d blocks of height %d", maxRecoveryRewind, best)
+		}
+		log.Warnf("Stored block at height %d is not on the node's main chain.", common)
+		common--
+	}
+
+	for i, store := range stores {
+		if checkpoints[i] <= common {
+			continue
+		}
+		log.Infof("Rewinding database %d from height %d to %d.", i,
+			checkpoints[i], common)
+		if err := store.RewindToHeight(common); err != nil {
+			return -1, fmt.Errorf("RewindToHeight failed: %v", err)
+		}
+	}
+
+	// The stake database is rebuilt as the other databases sync, so it just
+	// needs to be at or below the common height, and on the main chain.
+	stakeDBHeight := int64(db.sDB.Height())
+	if stakeDBHeight > common {
+		var err error
+		stakeDBHeight, err = db.RewindStakeDB(common, nil)
+		if err != nil {
+			return -1, fmt.Errorf("RewindStakeDB failed: %v", err)
+		}
+	}
+	for stakeDBHeight > 0 {
+		_, stakeDBHash, err := db.sDB.DBState()
+		if err != nil {
+			return -1, fmt.Errorf("DBState failed: %v", err)
+		}
+		nodeHash, err := db.client.GetBlockHash(stakeDBHeight)
+		if err != nil {
+			return -1, fmt.Errorf("GetBlockHash(%d) failed: %v", stakeDBHeight, err)
+		}
+		if *nodeHash == *stakeDBHash {
+			break
+		}
+		log.Warnf("Stake DB block at height %d is not on the node's main chain.",
+			stakeDBHeight)
+		if err = db.sDB.DisconnectBlock(); err != nil {
+			return -1, fmt.Errorf("DisconnectBlock failed: %v", err)
+		}
+		stakeDBHeight = int64(db.sDB.Height())
+	}
+
+	return common, nil
+}
+
 func (db *wiredDB) resyncDB(quit chan struct{}, blockGetter rpcutils.BlockGetter,
 	fetchToHeight int64) (int64, error) {
 	// Determine if we're in lite mode, when we are the "master" who sets the
@@ -118,11 +241,21 @@ func (db *wiredDB) resyncDB(quit chan struct{}, blockGetter rpcutils.BlockGetter
 	}
 	log.Info("Current best block (stakedb):         ", stakeDBHeight)
 
+	// Bring the address index up to the block summaries. Blocks above them are
+	// indexed as they are stored below.
+	if db.AddrIndexEnabled() {
+		if err = db.syncAddrIndex(quit); err != nil {
+			return startHeight, fmt.Errorf("syncAddrIndex failed: %v", err)
+		}
+	}
+
+	// Store the fee rates of recent blocks for fee estimates, if missing.
+	if err = db.syncFeeRates(quit); err != nil {
+		return startHeight, fmt.Errorf("syncFeeRates failed: %v", err)
+	}
+
 	// Attempt to rewind stake database, if needed
 	if stakeDBHeight > startHeight && stakeDBHeight > 0 {
-		if startHeight < 0 || stakeDBHeight > 2*startHeight {
-			return -1, fmt.Errorf("delete stake db (ffldb_stake) and try again")
-		}
 		log.Infof("Rewinding stake node from %d to %d", stakeDBHeight, startHeight)
 		// rewind best node in ticket db
 		stakeDBHeight, err = db.RewindStakeDB(startHeight, quit)
@@ -241,6 +374,14 @@ func (db *wiredDB) resyncDB(quit chan struct{}, blockGetter rpcutils.BlockGetter
 			if err = db.StoreBlockSummary(&blockSummary); err != nil {
 				return i - 1, fmt.Errorf("Unable to store block summary in database: %v", err)
 			}
+			if err = db.storeBlockFeeRates(block.MsgBlock()); err != nil {
+				return i - 1, fmt.Errorf("Unable to store block fee rates in database: %v", err)
+			}
+			if db.AddrIndexEnabled() {
+				if err = db.indexBlock(block.MsgBlock()); err != nil {
+					return i - 1, fmt.Errorf("Unable to index block addresses: %v", err)
+				}
+			}
 		}
 
 		if i <= stakeInfoHeight {
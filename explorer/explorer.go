@@ -19,6 +19,7 @@ import (
 	"github.com/decred/dcrd/wire"
 	"github.com/decred/dcrdata/blockdata"
 	"github.com/decred/dcrdata/db/dbtypes"
+	"github.com/decred/dcrdata/txhelpers"
 	humanize "github.com/dustin/go-humanize"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
@@ -41,6 +42,7 @@ type explorerDataSourceLite interface {
 	GetBlockHeight(hash string) (int64, error)
 	GetBlockHash(idx int64) (string, error)
 	GetExplorerTx(txid string) *TxInfo
+	GetTransactionHex(txid string) string
 	GetExplorerAddress(address string, count, offset int64) *AddressInfo
 	DecodeRawTransaction(txhex string) (*dcrjson.TxRawResult, error)
 	SendRawTransaction(txhex string) (string, error)
@@ -51,15 +53,28 @@ type explorerDataSourceLite interface {
 	TxHeight(txid string) (height int64)
 }
 
-// explorerDataSource implements extra data retrieval functions that require a
-// faster solution than RPC.
-type explorerDataSource interface {
+// explorerDataSourceStake implements retrieval of the spending transactions of
+// outputs, the fate of tickets, and the missed votes of blocks. The primary
+// data source implements it, and a lite data source may implement it too.
+type explorerDataSourceStake interface {
 	SpendingTransaction(fundingTx string, vout uint32) (string, uint32, int8, error)
 	SpendingTransactions(fundingTxID string) ([]string, []uint32, []uint32, error)
 	PoolStatusForTicket(txid string) (dbtypes.TicketSpendType, dbtypes.TicketPoolStatus, error)
+	BlockMissedVotes(blockHash string) ([]string, error)
+}
+
+// explorerDataSource implements extra data retrieval functions that require a
+// faster solution than RPC.
+type explorerDataSource interface {
+	explorerDataSourceStake
 	AddressHistory(address string, N, offset int64) ([]*dbtypes.AddressRow, *AddressBalance, error)
 	FillAddressTransactions(addrInfo *AddressInfo) error
-	BlockMissedVotes(blockHash string) ([]string, error)
+	TreasuryBalance() int64
+	TreasurySummary(N int) (*dbtypes.TreasurySummary, error)
+	TicketPoolStats() (*dbtypes.TicketPoolStats, error)
+	BlocksByInterval(interval string, from, to int64) ([]dbtypes.BlocksInterval, error)
+	TxClass(txid string) (txhelpers.TxClass, error)
+	BlockTxClasses(blockHash string) (map[string]txhelpers.TxClass, error)
 }
 
 // TicketStatusText generates the text to display on the explorer's transaction
@@ -97,6 +112,7 @@ type explorerUI struct {
 	Mux             *chi.Mux
 	blockData       explorerDataSourceLite
 	explorerSource  explorerDataSource
+	stakeSource     explorerDataSourceStake
 	liteMode        bool
 	templates       templates
 	wsHub           *WebsocketHub
@@ -155,6 +171,13 @@ func New(dataSource explorerDataSourceLite, primaryDataSource explorerDataSource
 	if exp.explorerSource == nil || reflect.ValueOf(exp.explorerSource).IsNil() {
 		exp.liteMode = true
 	}
+	// In lite mode, spending and ticket info come from the lite data source
+	// if it is able to provide them.
+	if !exp.liteMode {
+		exp.stakeSource = exp.explorerSource
+	} else if stakeSource, ok := dataSource.(explorerDataSourceStake); ok {
+		exp.stakeSource = stakeSource
+	}
 
 	if useRealIP {
 		exp.Mux.Use(middleware.RealIP)
@@ -177,7 +200,7 @@ func New(dataSource explorerDataSourceLite, primaryDataSource explorerDataSource
 		log.Errorf("Unable to create new html template: %v", err)
 		return nil
 	}
-	tmpls := []string{"home", "explorer", "mempool", "block", "tx", "address", "rawtx", "error"}
+	tmpls := []string{"home", "explorer", "mempool", "block", "tx", "address", "rawtx", "treasury", "charts", "ticketpool", "blocksinterval", "error"}
 
 	tempDefaults := []string{"extras"}
 
@@ -270,11 +293,27 @@ func (exp *explorerUI) updateDevFundBalance() {
 	exp.NewBlockDataMtx.Lock()
 	defer exp.NewBlockDataMtx.Unlock()
 
-	_, devBalance, err := exp.explorerSource.AddressHistory(exp.ExtraInfo.DevAddress, 1, 0)
-	if err == nil && devBalance != nil {
-		exp.ExtraInfo.DevFund = devBalance.TotalUnspent
-	} else {
-		log.Warnf("explorerUI.updateDevFundBalance failed: %v", err)
+	exp.ExtraInfo.DevFund = exp.explorerSource.TreasuryBalance()
+}
+
+// setSpentRedeemScripts sets the redeem scripts of the spent P2SH outputs of a
+// transaction from the signature scripts of the inputs spending them, which
+// must already be set in tx.SpendingTxns.
+func (exp *explorerUI) setSpentRedeemScripts(tx *TxInfo) {
+	for i := range tx.Vout {
+		vout := &tx.Vout[i]
+		if !vout.Spent || i >= len(tx.SpendingTxns) || tx.SpendingTxns[i].Hash == "" ||
+			len(vout.Addresses) != 1 || !txhelpers.IsScriptHashAddress(vout.Addresses[0]) {
+			continue
+		}
+		spending := tx.SpendingTxns[i]
+		msgTx, err := txhelpers.MsgTxFromHex(exp.blockData.GetTransactionHex(spending.Hash))
+		if err != nil {
+			log.Warnf("Unable to decode spending transaction %s: %v", spending.Hash, err)
+			continue
+		}
+		vout.RedeemScript = txhelpers.SpentRedeemScript(msgTx, spending.Index,
+			vout.Addresses[0], exp.ChainParams)
 	}
 }
 
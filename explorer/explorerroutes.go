@@ -7,9 +7,15 @@ import (
 	"database/sql"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/decred/dcrd/chaincfg/chainhash"
+	apitypes "github.com/decred/dcrdata/api/types"
+	"github.com/decred/dcrdata/db/dbtypes"
+	"github.com/decred/dcrdata/txhelpers"
 )
 
 // Home is the page handler for the "/" path
@@ -94,6 +100,38 @@ func (exp *explorerUI) Block(w http.ResponseWriter, r *http.Request) {
 		exp.ErrorPage(w, "Something went wrong...", "could not find that block", true)
 		return
 	}
+
+	// The stored transaction classes also identify split ticket funding
+	// transactions.
+	if !exp.liteMode {
+		classes, err := exp.explorerSource.BlockTxClasses(hash)
+		if err != nil {
+			log.Warnf("Unable to retrieve transaction classes for block %s: %v", hash, err)
+		} else {
+			for _, tx := range data.Tx {
+				tx.Class = classes[tx.TxID]
+			}
+		}
+	}
+
+	// Show only the regular transactions with the class in the "class" URL
+	// query parameter, if given.
+	if label := r.URL.Query().Get("class"); label != "" {
+		class, ok := txhelpers.TxClassFromLabel(label)
+		if !ok {
+			exp.ErrorPage(w, "Something went wrong...", "unknown transaction class "+label, true)
+			return
+		}
+		txs := make([]*TxBasic, 0, len(data.Tx))
+		for _, tx := range data.Tx {
+			if tx.Coinbase || tx.Class&class != 0 {
+				txs = append(txs, tx)
+			}
+		}
+		data.Tx = txs
+		data.TxClassFilter = label
+	}
+
 	// Checking if there exists any regular non-Coinbase transactions in the block.
 	var count int
 	data.TxAvailable = true
@@ -106,9 +144,9 @@ func (exp *explorerUI) Block(w http.ResponseWriter, r *http.Request) {
 		data.TxAvailable = false
 	}
 
-	if !exp.liteMode {
+	if exp.stakeSource != nil {
 		var err error
-		data.Misses, err = exp.explorerSource.BlockMissedVotes(hash)
+		data.Misses, err = exp.stakeSource.BlockMissedVotes(hash)
 		if err != nil && err != sql.ErrNoRows {
 			log.Warnf("Unable to retrieve missed votes for block %s: %v", hash, err)
 		}
@@ -130,7 +168,11 @@ func (exp *explorerUI) Block(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.Header().Set("Content-Type", "text/html")
-	w.Header().Set("Turbolinks-Location", "/block/"+hash)
+	location := "/block/" + hash
+	if data.TxClassFilter != "" {
+		location += "?class=" + data.TxClassFilter
+	}
+	w.Header().Set("Turbolinks-Location", location)
 	w.WriteHeader(http.StatusOK)
 	io.WriteString(w, str)
 }
@@ -157,6 +199,328 @@ func (exp *explorerUI) Mempool(w http.ResponseWriter, r *http.Request) {
 	io.WriteString(w, str)
 }
 
+// Treasury is the page handler for the "/treasury" path
+func (exp *explorerUI) Treasury(w http.ResponseWriter, r *http.Request) {
+	if exp.liteMode {
+		exp.ErrorPage(w, "Not available in lite mode", "the treasury ledger is only stored by the PostgreSQL database", true)
+		return
+	}
+
+	summary, err := exp.explorerSource.TreasurySummary(10)
+	if err != nil {
+		log.Errorf("Unable to get treasury summary: %v", err)
+		exp.ErrorPage(w, "Something went wrong...", "could not load the treasury", true)
+		return
+	}
+
+	// Show the most recent months first.
+	months := make([]dbtypes.TreasuryMonth, 0, len(summary.Months))
+	for i := len(summary.Months) - 1; i >= 0; i-- {
+		months = append(months, summary.Months[i])
+	}
+
+	str, err := exp.templates.execTemplateToString("treasury", struct {
+		*dbtypes.TreasurySummary
+		RecentMonths []dbtypes.TreasuryMonth
+		Version      string
+	}{
+		summary,
+		months,
+		exp.Version,
+	})
+	if err != nil {
+		log.Errorf("Template execute failure: %v", err)
+		exp.ErrorPage(w, "Something went wrong...", "and it's not your fault, try refreshing... that usually fixes things", false)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, str)
+}
+
+// TicketPool is the page handler for the "/ticketpool" path
+func (exp *explorerUI) TicketPool(w http.ResponseWriter, r *http.Request) {
+	if exp.liteMode {
+		exp.ErrorPage(w, "Not available in lite mode", "the ticket pool statistics are computed from the tickets stored by the PostgreSQL database", true)
+		return
+	}
+
+	stats, err := exp.explorerSource.TicketPoolStats()
+	if err != nil {
+		log.Errorf("Unable to get ticket pool stats: %v", err)
+		exp.ErrorPage(w, "Something went wrong...", "could not load the ticket pool", true)
+		return
+	}
+
+	var avgValue float64
+	if stats.Size > 0 {
+		avgValue = stats.Value / float64(stats.Size)
+	}
+
+	str, err := exp.templates.execTemplateToString("ticketpool", struct {
+		*dbtypes.TicketPoolStats
+		AvgValue float64
+		Target   uint16
+		Version  string
+	}{
+		stats,
+		avgValue,
+		exp.ChainParams.TicketPoolSize * exp.ChainParams.TicketsPerBlock,
+		exp.Version,
+	})
+	if err != nil {
+		log.Errorf("Template execute failure: %v", err)
+		exp.ErrorPage(w, "Something went wrong...", "and it's not your fault, try refreshing... that usually fixes things", false)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, str)
+}
+
+// blockIntervalPaths are the paths of the listings of the blocks by time
+// interval.
+var blockIntervalPaths = map[string]string{
+	"day":   "/days",
+	"week":  "/weeks",
+	"month": "/months",
+}
+
+// intervalStart truncates t to the start of its time interval in UTC. Weeks
+// start on Monday, as with PostgreSQL's date_trunc.
+func intervalStart(interval string, t time.Time) time.Time {
+	t = t.UTC()
+	switch interval {
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// addIntervals adds n time intervals to the start of an interval.
+func addIntervals(interval string, start time.Time, n int) time.Time {
+	switch interval {
+	case "week":
+		return start.AddDate(0, 0, 7*n)
+	case "month":
+		return start.AddDate(0, n, 0)
+	default:
+		return start.AddDate(0, 0, n)
+	}
+}
+
+// intervalsBetween counts the time intervals from the interval starting at
+// first to the one starting at last, inclusive.
+func intervalsBetween(interval string, first, last time.Time) int {
+	if last.Before(first) {
+		return 0
+	}
+	switch interval {
+	case "week":
+		return int(last.Sub(first).Hours()/(7*24)) + 1
+	case "month":
+		return (last.Year()-first.Year())*12 + int(last.Month()-first.Month()) + 1
+	default:
+		return int(last.Sub(first).Hours()/24) + 1
+	}
+}
+
+// intervalOffset parses the offset of a page of a listing of numIntervals
+// intervals. Offsets that are invalid or beyond the last interval give 0.
+func intervalOffset(offsetStr string, numIntervals int) int {
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 || offset >= numIntervals {
+		return 0
+	}
+	return offset
+}
+
+// intervalPageRange returns the time range of the page of rows intervals
+// following the first offset intervals of a listing, newest first, ending with
+// the interval starting at last. The range is limited to the range from-to of
+// the listing.
+func intervalPageRange(interval string, last time.Time, from, to int64, offset, rows int) (int64, int64) {
+	pageFrom := addIntervals(interval, last, -(offset + rows - 1)).Unix()
+	pageTo := addIntervals(interval, last, 1-offset).Unix() - 1
+	if pageFrom < from {
+		pageFrom = from
+	}
+	if pageTo > to {
+		pageTo = to
+	}
+	return pageFrom, pageTo
+}
+
+// DayBlocksListing is the page handler for the "/days" path
+func (exp *explorerUI) DayBlocksListing(w http.ResponseWriter, r *http.Request) {
+	exp.blocksIntervalListing(w, r, "day")
+}
+
+// WeekBlocksListing is the page handler for the "/weeks" path
+func (exp *explorerUI) WeekBlocksListing(w http.ResponseWriter, r *http.Request) {
+	exp.blocksIntervalListing(w, r, "week")
+}
+
+// MonthBlocksListing is the page handler for the "/months" path
+func (exp *explorerUI) MonthBlocksListing(w http.ResponseWriter, r *http.Request) {
+	exp.blocksIntervalListing(w, r, "month")
+}
+
+// blocksIntervalListing writes the page listing the blocks by time interval,
+// newest first. The "from" and "to" URL query parameters (UNIX times) limit
+// the listing, e.g. to the days of a month, and "offset" and "rows" page it by
+// interval.
+func (exp *explorerUI) blocksIntervalListing(w http.ResponseWriter, r *http.Request, interval string) {
+	if exp.liteMode {
+		exp.ErrorPage(w, "Not available in lite mode", "the blocks are aggregated by the PostgreSQL database", true)
+		return
+	}
+
+	query := r.URL.Query()
+	from := exp.ChainParams.GenesisBlock.Header.Timestamp.Unix()
+	if t, err := strconv.ParseInt(query.Get("from"), 10, 64); err == nil && t > from {
+		from = t
+	}
+	to := time.Now().Unix()
+	if t, err := strconv.ParseInt(query.Get("to"), 10, 64); err == nil && t < to {
+		to = t
+	}
+	first := intervalStart(interval, time.Unix(from, 0))
+	last := intervalStart(interval, time.Unix(to, 0))
+	numIntervals := intervalsBetween(interval, first, last)
+
+	rows, err := strconv.Atoi(query.Get("rows"))
+	if err != nil || rows < 1 || rows > maxExplorerRows {
+		rows = minExplorerRows
+	}
+	offset := intervalOffset(query.Get("offset"), numIntervals)
+
+	// Retrieve the intervals of the page.
+	pageFrom, pageTo := intervalPageRange(interval, last, from, to, offset, rows)
+	var data []dbtypes.BlocksInterval
+	if numIntervals > 0 {
+		data, err = exp.explorerSource.BlocksByInterval(interval, pageFrom, pageTo)
+		if err != nil {
+			log.Errorf("Unable to get blocks by %s: %v", interval, err)
+			exp.ErrorPage(w, "Something went wrong...", "could not load the blocks", true)
+			return
+		}
+	}
+	intervals := make([]BlocksIntervalInfo, 0, len(data))
+	for _, bi := range data {
+		end := addIntervals(interval, time.Unix(bi.Start, 0).UTC(), 1)
+		intervals = append(intervals, BlocksIntervalInfo{bi, end.Unix() - 1})
+	}
+
+	// The links to the newer and older pages keep the range of the listing.
+	path := blockIntervalPaths[interval]
+	pageLink := func(offset int) string {
+		v := url.Values{}
+		if query.Get("from") != "" {
+			v.Set("from", strconv.FormatInt(from, 10))
+		}
+		if query.Get("to") != "" {
+			v.Set("to", strconv.FormatInt(to, 10))
+		}
+		v.Set("offset", strconv.Itoa(offset))
+		v.Set("rows", strconv.Itoa(rows))
+		return path + "?" + v.Encode()
+	}
+	var newer, older string
+	if offset > 0 {
+		newerOffset := offset - rows
+		if newerOffset < 0 {
+			newerOffset = 0
+		}
+		newer = pageLink(newerOffset)
+	}
+	if offset+rows < numIntervals {
+		older = pageLink(offset + rows)
+	}
+
+	var rangeFrom, rangeTo int64
+	if query.Get("from") != "" || query.Get("to") != "" {
+		rangeFrom, rangeTo = from, to
+	}
+
+	str, err := exp.templates.execTemplateToString("blocksinterval", struct {
+		Interval  string
+		Title     string
+		Path      string
+		Intervals []BlocksIntervalInfo
+		RangeFrom int64
+		RangeTo   int64
+		Newer     string
+		Older     string
+		Version   string
+	}{
+		interval,
+		strings.Title(interval),
+		path,
+		intervals,
+		rangeFrom,
+		rangeTo,
+		newer,
+		older,
+		exp.Version,
+	})
+	if err != nil {
+		log.Errorf("Template execute failure: %v", err)
+		exp.ErrorPage(w, "Something went wrong...", "and it's not your fault, try refreshing... that usually fixes things", false)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, str)
+}
+
+// summaryCharts are the charts made from the block summaries, which are stored
+// in lite mode too.
+var summaryCharts = []ChartInfo{
+	{apitypes.ChartTicketPrice, "Ticket Price", "DCR"},
+	{apitypes.ChartPoolSize, "Ticket Pool Size", "tickets"},
+	{apitypes.ChartPoolValue, "Ticket Pool Value", "DCR"},
+	{apitypes.ChartBlockSize, "Block Size", "bytes"},
+	{apitypes.ChartDifficulty, "Difficulty", ""},
+}
+
+// chainCharts are the charts only made by the PostgreSQL database.
+var chainCharts = []ChartInfo{
+	{apitypes.ChartTxCount, "Transactions per Block", "txns"},
+	{apitypes.ChartFees, "Fees per Block", "DCR"},
+	{apitypes.ChartSupply, "Coin Supply", "DCR"},
+}
+
+// Charts is the page handler for the "/charts" path
+func (exp *explorerUI) Charts(w http.ResponseWriter, r *http.Request) {
+	charts := summaryCharts
+	if !exp.liteMode {
+		charts = append(charts[:len(charts):len(charts)], chainCharts...)
+	}
+
+	str, err := exp.templates.execTemplateToString("charts", struct {
+		Charts   []ChartInfo
+		LiteMode bool
+		Version  string
+	}{
+		charts,
+		exp.liteMode,
+		exp.Version,
+	})
+	if err != nil {
+		log.Errorf("Template execute failure: %v", err)
+		exp.ErrorPage(w, "Something went wrong...", "and it's not your fault, try refreshing... that usually fixes things", false)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, str)
+}
+
 // TxPage is the page handler for the "/tx" path
 func (exp *explorerUI) TxPage(w http.ResponseWriter, r *http.Request) {
 	// attempt to get tx hash string from URL path
@@ -172,10 +536,18 @@ func (exp *explorerUI) TxPage(w http.ResponseWriter, r *http.Request) {
 		exp.ErrorPage(w, "Something went wrong...", "could not find that transaction", true)
 		return
 	}
-	if !exp.liteMode {
+	if !exp.liteMode && tx.Confirmations > 0 {
+		class, err := exp.explorerSource.TxClass(hash)
+		if err == nil {
+			tx.Class = class
+		} else if err != sql.ErrNoRows {
+			log.Warnf("Unable to retrieve class of transaction %s: %v", hash, err)
+		}
+	}
+	if exp.stakeSource != nil {
 		// For each output of this transaction, look up any spending transactions,
 		// and the index of the spending transaction input.
-		spendingTxHashes, spendingTxVinInds, voutInds, err := exp.explorerSource.SpendingTransactions(hash)
+		spendingTxHashes, spendingTxVinInds, voutInds, err := exp.stakeSource.SpendingTransactions(hash)
 		if err != nil {
 			log.Errorf("Unable to retrieve spending transactions for %s: %v", hash, err)
 			exp.ErrorPage(w, "Something went wrong...", "and it's not your fault, try refreshing... that usually fixes things", false)
@@ -191,8 +563,9 @@ func (exp *explorerUI) TxPage(w http.ResponseWriter, r *http.Request) {
 				Index: spendingTxVinInds[i],
 			}
 		}
+		exp.setSpentRedeemScripts(tx)
 		if tx.Type == "Ticket" {
-			spendStatus, poolStatus, err := exp.explorerSource.PoolStatusForTicket(hash)
+			spendStatus, poolStatus, err := exp.stakeSource.PoolStatusForTicket(hash)
 			if err != nil {
 				log.Errorf("Unable to retrieve ticket spend and pool status for %s: %v", hash, err)
 			} else {
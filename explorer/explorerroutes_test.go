@@ -0,0 +1,153 @@
+package explorer
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestIntervalStart(t *testing.T) {
+	aest := time.FixedZone("AEST", 10*60*60)
+	tests := []struct {
+		interval string
+		t        time.Time
+		start    time.Time
+	}{
+		{"day", time.Date(2018, 6, 13, 15, 4, 5, 0, time.UTC), date(2018, 6, 13)},
+		{"day", date(2018, 6, 13), date(2018, 6, 13)},
+		// Times are truncated in UTC.
+		{"day", time.Date(2018, 6, 14, 8, 0, 0, 0, aest), date(2018, 6, 13)},
+		// Weeks start on Monday.
+		{"week", time.Date(2018, 6, 13, 15, 4, 5, 0, time.UTC), date(2018, 6, 11)},
+		{"week", date(2018, 6, 11), date(2018, 6, 11)},
+		{"week", time.Date(2018, 6, 17, 23, 59, 59, 0, time.UTC), date(2018, 6, 11)},
+		{"week", date(2018, 6, 18), date(2018, 6, 18)},
+		{"week", date(2018, 1, 3), date(2018, 1, 1)},
+		{"week", date(2017, 1, 1), date(2016, 12, 26)},
+		{"month", time.Date(2018, 6, 30, 23, 59, 59, 0, time.UTC), date(2018, 6, 1)},
+		{"month", date(2018, 6, 1), date(2018, 6, 1)},
+		{"month", time.Date(2018, 7, 1, 8, 0, 0, 0, aest), date(2018, 6, 1)},
+	}
+	for _, test := range tests {
+		start := intervalStart(test.interval, test.t)
+		if !start.Equal(test.start) {
+			t.Errorf("intervalStart(%s, %v) = %v, expected %v", test.interval,
+				test.t, start, test.start)
+		}
+	}
+}
+
+func TestAddIntervals(t *testing.T) {
+	tests := []struct {
+		interval string
+		start    time.Time
+		n        int
+		result   time.Time
+	}{
+		{"day", date(2018, 6, 13), 0, date(2018, 6, 13)},
+		{"day", date(2018, 6, 30), 1, date(2018, 7, 1)},
+		{"day", date(2018, 3, 1), -1, date(2018, 2, 28)},
+		{"week", date(2018, 6, 11), 1, date(2018, 6, 18)},
+		{"week", date(2018, 1, 1), -2, date(2017, 12, 18)},
+		{"month", date(2018, 1, 1), 1, date(2018, 2, 1)},
+		{"month", date(2018, 1, 1), -1, date(2017, 12, 1)},
+		{"month", date(2018, 11, 1), 3, date(2019, 2, 1)},
+	}
+	for _, test := range tests {
+		result := addIntervals(test.interval, test.start, test.n)
+		if !result.Equal(test.result) {
+			t.Errorf("addIntervals(%s, %v, %d) = %v, expected %v", test.interval,
+				test.start, test.n, result, test.result)
+		}
+	}
+}
+
+func TestIntervalsBetween(t *testing.T) {
+	tests := []struct {
+		interval    string
+		first, last time.Time
+		n           int
+	}{
+		{"day", date(2018, 6, 13), date(2018, 6, 13), 1},
+		{"day", date(2018, 6, 14), date(2018, 6, 13), 0},
+		{"day", date(2018, 6, 1), date(2018, 6, 30), 30},
+		{"day", date(2016, 2, 1), date(2016, 3, 1), 30},
+		{"week", date(2018, 6, 11), date(2018, 6, 11), 1},
+		{"week", date(2018, 6, 11), date(2018, 6, 25), 3},
+		{"week", date(2017, 12, 25), date(2018, 1, 1), 2},
+		{"week", date(2018, 6, 18), date(2018, 6, 11), 0},
+		{"month", date(2018, 6, 1), date(2018, 6, 1), 1},
+		{"month", date(2017, 11, 1), date(2018, 2, 1), 4},
+		{"month", date(2016, 2, 1), date(2018, 2, 1), 25},
+		{"month", date(2018, 2, 1), date(2018, 1, 1), 0},
+	}
+	for _, test := range tests {
+		n := intervalsBetween(test.interval, test.first, test.last)
+		if n != test.n {
+			t.Errorf("intervalsBetween(%s, %v, %v) = %d, expected %d",
+				test.interval, test.first, test.last, n, test.n)
+		}
+	}
+}
+
+func TestIntervalOffset(t *testing.T) {
+	tests := []struct {
+		offset       string
+		numIntervals int
+		result       int
+	}{
+		{"", 10, 0},
+		{"x", 10, 0},
+		{"-1", 10, 0},
+		{"0", 10, 0},
+		{"5", 10, 5},
+		{"9", 10, 9},
+		{"10", 10, 0},
+		{"1", 0, 0},
+	}
+	for _, test := range tests {
+		result := intervalOffset(test.offset, test.numIntervals)
+		if result != test.result {
+			t.Errorf("intervalOffset(%q, %d) = %d, expected %d", test.offset,
+				test.numIntervals, result, test.result)
+		}
+	}
+}
+
+func TestIntervalPageRange(t *testing.T) {
+	// A listing of the days from June 1 noon to June 10 noon.
+	from := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC).Unix()
+	to := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC).Unix()
+	last := date(2018, 6, 10)
+	tests := []struct {
+		offset, rows     int
+		pageFrom, pageTo int64
+	}{
+		// The newest page ends at the end of the listing.
+		{0, 3, date(2018, 6, 8).Unix(), to},
+		{3, 3, date(2018, 6, 5).Unix(), date(2018, 6, 8).Unix() - 1},
+		// The oldest page starts at the start of the listing.
+		{8, 3, from, date(2018, 6, 3).Unix() - 1},
+		{0, 20, from, to},
+	}
+	for _, test := range tests {
+		pageFrom, pageTo := intervalPageRange("day", last, from, to,
+			test.offset, test.rows)
+		if pageFrom != test.pageFrom || pageTo != test.pageTo {
+			t.Errorf("intervalPageRange(%d, %d) = %d, %d, expected %d, %d",
+				test.offset, test.rows, pageFrom, pageTo, test.pageFrom,
+				test.pageTo)
+		}
+	}
+
+	// Pages of months span months of different lengths.
+	pageFrom, pageTo := intervalPageRange("month", date(2018, 3, 1),
+		date(2017, 1, 1).Unix(), date(2018, 3, 15).Unix(), 1, 2)
+	if pageFrom != date(2018, 1, 1).Unix() || pageTo != date(2018, 3, 1).Unix()-1 {
+		t.Errorf("intervalPageRange(month) = %v, %v", time.Unix(pageFrom, 0).UTC(),
+			time.Unix(pageTo, 0).UTC())
+	}
+}
@@ -35,6 +35,7 @@ type TxBasic struct {
 	FeeRate       dcrutil.Amount
 	VoteInfo      *VoteInfo
 	Coinbase      bool
+	Class         txhelpers.TxClass
 }
 
 //AddressTx models data for transactions on the address page
@@ -109,6 +110,7 @@ type Vin struct {
 	*dcrjson.Vin
 	Addresses       []string
 	FormattedAmount string
+	RedeemScript    *txhelpers.RedeemScript
 }
 
 // Vout models basic data about a tx output for display
@@ -119,6 +121,7 @@ type Vout struct {
 	Type            string
 	Spent           bool
 	OP_RETURN       string
+	RedeemScript    *txhelpers.RedeemScript
 }
 
 // BlockInfo models data for display on the block page
@@ -130,6 +133,7 @@ type BlockInfo struct {
 	StakeRoot             string
 	MerkleRoot            string
 	TxAvailable           bool
+	TxClassFilter         string
 	Tx                    []*TxBasic
 	Tickets               []*TxBasic
 	Revs                  []*TxBasic
@@ -295,8 +299,9 @@ func ReduceAddressHistory(addrHist []*dbtypes.AddressRow) *AddressInfo {
 
 // WebsocketBlock wraps the new block info for use in the websocket
 type WebsocketBlock struct {
-	Block *BlockBasic `json:"block"`
-	Extra *HomeInfo   `json:"extra"`
+	Block      *BlockBasic              `json:"block"`
+	Extra      *HomeInfo                `json:"extra"`
+	TicketPool *dbtypes.TicketPoolStats `json:"ticket_pool,omitempty"`
 }
 
 type TicketPoolInfo struct {
@@ -323,3 +328,18 @@ type NewMempoolTx struct {
 	Time int64
 	Hex  string
 }
+
+// ChartInfo models a chart of the charts page, with the name used by the chart
+// data API endpoint
+type ChartInfo struct {
+	Name  string
+	Title string
+	Unit  string
+}
+
+// BlocksIntervalInfo models a row of the listing of the blocks by time
+// interval. End is the UNIX time of the last second of the interval.
+type BlocksIntervalInfo struct {
+	dbtypes.BlocksInterval
+	End int64
+}
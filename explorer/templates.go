@@ -124,6 +124,9 @@ func makeTemplateFuncMap(params *chaincfg.Params) template.FuncMap {
 			t, _ := time.Now().Zone()
 			return t
 		},
+		"formatUnixTimeUTC": func(t int64, layout string) string {
+			return time.Unix(t, 0).UTC().Format(layout)
+		},
 		"percentage": func(a int64, b int64) float64 {
 			return (float64(a) / float64(b)) * 100
 		},
@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/decred/dcrdata/db/dbtypes"
 	"golang.org/x/net/websocket"
 )
 
@@ -153,10 +154,21 @@ func (exp *explorerUI) RootWebsocket(w http.ResponseWriter, r *http.Request) {
 				enc := json.NewEncoder(buff)
 				switch sig {
 				case sigNewBlock:
+					// The ticket pool statistics are cached by the source, so
+					// they are computed once per block for all clients.
+					var ticketPool *dbtypes.TicketPoolStats
+					if !exp.liteMode {
+						var err error
+						ticketPool, err = exp.explorerSource.TicketPoolStats()
+						if err != nil {
+							log.Errorf("Unable to get ticket pool stats: %v", err)
+						}
+					}
 					exp.NewBlockDataMtx.Lock()
 					enc.Encode(WebsocketBlock{
-						Block: exp.NewBlockData,
-						Extra: exp.ExtraInfo,
+						Block:      exp.NewBlockData,
+						Extra:      exp.ExtraInfo,
+						TicketPool: ticketPool,
 					})
 					exp.NewBlockDataMtx.Unlock()
 					webData.Message = buff.String()
@@ -14,6 +14,7 @@ import (
 	"github.com/decred/dcrdata/api"
 	"github.com/decred/dcrdata/api/insight"
 	"github.com/decred/dcrdata/blockdata"
+	"github.com/decred/dcrdata/datafeed"
 	"github.com/decred/dcrdata/db/dcrpg"
 	"github.com/decred/dcrdata/db/dcrsqlite"
 	"github.com/decred/dcrdata/explorer"
@@ -60,6 +61,7 @@ var (
 	mempoolLog    = backendLog.Logger("MEMP")
 	expLog        = backendLog.Logger("EXPR")
 	apiLog        = backendLog.Logger("JAPI")
+	feedLog       = backendLog.Logger("FEED")
 	log           = backendLog.Logger("DATD")
 )
 
@@ -76,6 +78,7 @@ func init() {
 	api.UseLogger(apiLog)
 	insight.UseLogger(apiLog)
 	middleware.UseLogger(apiLog)
+	datafeed.UseLogger(feedLog)
 }
 
 // subsystemLoggers maps each subsystem identifier to its associated logger.
@@ -88,6 +91,7 @@ var subsystemLoggers = map[string]btclog.Logger{
 	"MEMP": mempoolLog,
 	"EXPR": expLog,
 	"JAPI": apiLog,
+	"FEED": feedLog,
 	"DATD": log,
 }
 
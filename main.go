@@ -17,6 +17,7 @@ import (
 	"runtime/pprof"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/decred/dcrd/chaincfg/chainhash"
@@ -24,6 +25,7 @@ import (
 	"github.com/decred/dcrdata/api"
 	"github.com/decred/dcrdata/api/insight"
 	"github.com/decred/dcrdata/blockdata"
+	"github.com/decred/dcrdata/datafeed"
 	"github.com/decred/dcrdata/db/dbtypes"
 	"github.com/decred/dcrdata/db/dcrpg"
 	"github.com/decred/dcrdata/db/dcrsqlite"
@@ -103,8 +105,8 @@ func mainCore() error {
 	if err != nil {
 		return fmt.Errorf("Unable to get current network from dcrd: %v", err)
 	}
-	log.Infof("Connected to dcrd (JSON-RPC API v%s) on %v",
-		nodeVer.String(), curnet.String())
+	log.Infof("Connected to dcrd at %s (JSON-RPC API v%s) on %v",
+		dcrdClient.ActiveHost(), nodeVer.String(), curnet.String())
 
 	if curnet != activeNet.Net {
 		log.Criticalf("Network of connected node, %s, does not match expected "+
@@ -114,7 +116,11 @@ func mainCore() error {
 
 	// Sqlite output
 	dbPath := filepath.Join(cfg.DataDir, cfg.DBFileName)
-	dbInfo := dcrsqlite.DBInfo{FileName: dbPath}
+	dbInfo := dcrsqlite.DBInfo{
+		FileName:                dbPath,
+		AddrIndex:               cfg.LiteAddrIndex,
+		MempoolHistoryRetention: time.Duration(cfg.MPHistoryDays) * 24 * time.Hour,
+	}
 	baseDB, cleanupDB, err := dcrsqlite.InitWiredDB(&dbInfo,
 		notify.NtfnChans.UpdateStatusDBHeight, dcrdClient, activeChain, cfg.DataDir)
 	defer cleanupDB()
@@ -150,6 +156,9 @@ func mainCore() error {
 			return err
 		}
 
+		if _, err = auxDB.UpgradeTables(false); err != nil {
+			return err
+		}
 		if err = auxDB.VersionCheck(); err != nil {
 			return err
 		}
@@ -167,19 +176,30 @@ func mainCore() error {
 		}
 	}
 
+	// An unclean shutdown may have left the databases at different heights, or
+	// on blocks that are no longer in the main chain. Roll everything back to
+	// the best height on which all of them agree with the node before syncing.
+	var recoverable []dcrsqlite.RecoverableDB
+	if usePG {
+		recoverable = append(recoverable, auxDB)
+	}
+	if _, err = baseDB.RecoverToCommonHeight(recoverable...); err != nil {
+		return fmt.Errorf("startup recovery failed: %v", err)
+	}
+
 	// Ctrl-C to shut down.
 	// Nothing should be sent the quit channel.  It should only be closed.
 	quit := make(chan struct{})
-	// Only accept a single CTRL+C
+	// Only accept a single CTRL+C or SIGTERM
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	// Start waiting for the interrupt signal
 	go func() {
 		<-c
 		signal.Stop(c)
 		// Close the channel so multiple goroutines can get the message
-		log.Infof("CTRL+C hit.  Closing goroutines.")
+		log.Infof("Shutdown signal received.  Closing goroutines.")
 		close(quit)
 	}()
 
@@ -279,6 +299,8 @@ func mainCore() error {
 
 	blockDataSavers = append(blockDataSavers, &baseDB)
 	mempoolSavers = append(mempoolSavers, baseDB.MPC)
+	// Keep a history of the mempool data collections in the SQLite DB.
+	mempoolSavers = append(mempoolSavers, baseDB.DB)
 
 	// Create the explorer system
 	explore := explorer.New(&baseDB, auxDB, cfg.UseRealIP, ver.String())
@@ -291,6 +313,19 @@ func mainCore() error {
 
 	blockDataSavers = append(blockDataSavers, explore)
 
+	// Continuous data feeds for external consumers, as configured
+	dataFeeds, err := newDataFeeds(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to start data feeds: %v", err)
+	}
+	var reorgFeedSavers []blockdata.BlockDataSaver
+	for _, feed := range dataFeeds {
+		defer feed.Close()
+		blockDataSavers = append(blockDataSavers, feed)
+		reorgFeedSavers = append(reorgFeedSavers, feed)
+		mempoolSavers = append(mempoolSavers, feed)
+	}
+
 	// Sync up with the blockchain
 	getSyncd := func(updateAddys, updateVotes, newPGInds bool,
 		fetchHeight int64) (int64, int64, error) {
@@ -345,21 +380,56 @@ func mainCore() error {
 	}
 	log.Infof("All ready, at height %d.", baseDBHeight)
 
+	// Blocks after the synchronized height will be queued by the block
+	// connected notification handler. After switching to a different dcrd
+	// node, any blocks missed from the new node are queued.
+	syncHash, err := dcrdClient.GetBlockHash(baseDBHeight)
+	if err != nil {
+		return fmt.Errorf("unable to get block hash from node: %v", err)
+	}
+	collectionQueue.SetTip(*syncHash, baseDBHeight)
+	collectionQueue.SetNodeClient(dcrdClient)
+	dcrdClient.OnSwitch(func(nodeHeight int64) {
+		if err := collectionQueue.CatchUp(dcrdClient); err != nil {
+			log.Errorf("Failed to catch up with new dcrd node: %v", err)
+		}
+	})
+
 	// Register for notifications from dcrd
-	cerr := notify.RegisterNodeNtfnHandlers(dcrdClient)
-	if cerr != nil {
-		return fmt.Errorf("RPC client error: %v (%v)", cerr.Error(), cerr.Cause())
+	err = dcrdClient.RegisterNotifications(func(c *rpcclient.Client) error {
+		if cerr := notify.RegisterNodeNtfnHandlers(c); cerr != nil {
+			return fmt.Errorf("%v (%v)", cerr.Error(), cerr.Cause())
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("RPC client error: %v", err)
+	}
+
+	// Blocks connected after the final sync, but before notifications were
+	// registered, are not notified.
+	if err = collectionQueue.CatchUp(dcrdClient); err != nil {
+		return fmt.Errorf("failed to catch up with node after sync: %v", err)
 	}
 	// now create and start the monitors that respond to the notification chans
 
 	// WaitGroup for the monitor goroutines
 	var wg sync.WaitGroup
 
+	// Health check the dcrd nodes, failing over to a backup as needed.
+	if len(cfg.DcrdBackups) > 0 {
+		wg.Add(1)
+		go dcrdClient.Monitor(time.Duration(cfg.NodeCheckInterval)*time.Second,
+			quit, &wg)
+	}
+
 	// Blockchain monitor for the collector
 	addrMap := make(map[string]txhelpers.TxAction) // for support of watched addresses
 	// On reorg, only update web UI since dcrsqlite's own reorg handler will
 	// deal with patching up the block info database.
 	reorgBlockDataSavers := []blockdata.BlockDataSaver{explore}
+	// The data feeds mark the reorg, and publish the blocks of the new chain.
+	reorgBlockDataSavers = append(reorgBlockDataSavers, reorgFeedSavers...)
 	wsChainMonitor := blockdata.NewChainMonitor(collector, blockDataSavers,
 		reorgBlockDataSavers, quit, &wg, addrMap,
 		notify.NtfnChans.ConnectChan, notify.NtfnChans.RecvTxBlockChan,
@@ -456,6 +526,21 @@ func mainCore() error {
 
 	// Start web API
 	app := api.NewContext(dcrdClient, &baseDB, cfg.IndentJSON)
+	if usePG {
+		// Vote summaries, the treasury, address and UTXO set statistics,
+		// transaction classes, atomic swaps, and the transaction count, fee
+		// and supply charts are only stored by the PostgreSQL DB.
+		app.VoteSource = auxDB
+		app.TreasurySource = auxDB
+		app.AddressStats = auxDB
+		app.UtxoSetSource = auxDB
+		app.TxClassSource = auxDB
+		app.SwapSource = auxDB
+		app.ChartSource = auxDB
+		app.SpendingSource = auxDB
+	} else {
+		app.SpendingSource = &baseDB
+	}
 	// Start notification hander to keep /status up-to-date
 	wg.Add(1)
 	go app.StatusNtfnHandler(&wg, quit)
@@ -480,7 +565,13 @@ func mainCore() error {
 
 	webMux.Mount("/explorer", explore.Mux)
 	webMux.Get("/blocks", explore.Blocks)
+	webMux.Get("/days", explore.DayBlocksListing)
+	webMux.Get("/weeks", explore.WeekBlocksListing)
+	webMux.Get("/months", explore.MonthBlocksListing)
 	webMux.Get("/mempool", explore.Mempool)
+	webMux.Get("/treasury", explore.Treasury)
+	webMux.Get("/charts", explore.Charts)
+	webMux.Get("/ticketpool", explore.TicketPool)
 	webMux.With(explore.BlockHashPathOrIndexCtx).Get("/block/{blockhash}", explore.Block)
 	webMux.With(explorer.TransactionHashCtx).Get("/tx/{txid}", explore.TxPage)
 	webMux.With(explorer.AddressPathCtx).Get("/address/{address}", explore.AddressPage)
@@ -572,9 +663,27 @@ func waitForSync(base chan dbtypes.SyncResult, aux chan dbtypes.SyncResult,
 	return baseDBHeight, auxDBHeight, nil
 }
 
-func connectNodeRPC(cfg *config, ntfnHandlers *rpcclient.NotificationHandlers) (*rpcclient.Client, semver.Semver, error) {
-	return rpcutils.ConnectNodeRPC(cfg.DcrdServ, cfg.DcrdUser, cfg.DcrdPass,
-		cfg.DcrdCert, cfg.DisableDaemonTLS, ntfnHandlers)
+// connectNodeRPC connects to dcrd at dcrdserv and any backup nodes. The
+// returned client uses the node at dcrdserv, if it is available.
+func connectNodeRPC(cfg *config, ntfnHandlers *rpcclient.NotificationHandlers) (*rpcutils.MultiNodeClient, semver.Semver, error) {
+	hosts := append([]string{cfg.DcrdServ}, cfg.DcrdBackups...)
+	endpoints := make([]rpcutils.NodeEndpoint, 0, len(hosts))
+	for _, host := range hosts {
+		endpoints = append(endpoints, rpcutils.NodeEndpoint{
+			Host:       host,
+			User:       cfg.DcrdUser,
+			Pass:       cfg.DcrdPass,
+			Cert:       cfg.DcrdCert,
+			DisableTLS: cfg.DisableDaemonTLS,
+		})
+	}
+	client, nodeVer, err := rpcutils.NewMultiNodeClient(endpoints, ntfnHandlers)
+	if err != nil {
+		return nil, nodeVer, err
+	}
+	client.MaxLag = cfg.NodeMaxLag
+	client.Net = activeNet.Net
+	return client, nodeVer, nil
 }
 
 func listenAndServeProto(listen, proto string, mux http.Handler) error {
@@ -602,6 +711,53 @@ func listenAndServeProto(listen, proto string, mux http.Handler) error {
 	}
 }
 
+// newDataFeeds creates a datafeed.Saver for each data feed output enabled in
+// the config.
+func newDataFeeds(cfg *config) ([]*datafeed.Saver, error) {
+	var pubs []datafeed.Publisher
+	closeAll := func() {
+		for _, pub := range pubs {
+			pub.Close()
+		}
+	}
+
+	if cfg.FeedNDJSONFile != "" {
+		pub, err := datafeed.NewNDJSONFile(cfg.FeedNDJSONFile,
+			cfg.FeedNDJSONMaxSize*1024*1024)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("Writing NDJSON data feed to %s.", cfg.FeedNDJSONFile)
+		pubs = append(pubs, pub)
+	}
+
+	if cfg.FeedSocket != "" {
+		pub, err := datafeed.NewUnixSocket(cfg.FeedSocket)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		log.Infof("Streaming data feed on UNIX socket %s.", cfg.FeedSocket)
+		pubs = append(pubs, pub)
+	}
+
+	if cfg.FeedBusDir != "" {
+		pub, err := datafeed.NewFileBus(cfg.FeedBusDir)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		log.Infof("Publishing data feed to message bus logs in %s.", cfg.FeedBusDir)
+		pubs = append(pubs, pub)
+	}
+
+	savers := make([]*datafeed.Saver, 0, len(pubs))
+	for _, pub := range pubs {
+		savers = append(savers, datafeed.NewSaver(pub))
+	}
+	return savers, nil
+}
+
 // FileServer conveniently sets up a http.FileServer handler to serve
 // static files from a http.FileSystem.
 func FileServer(r chi.Router, path string, root http.FileSystem, CacheControlMaxAge int64) {
@@ -19,8 +19,8 @@ import (
 	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/dcrjson"
 	"github.com/decred/dcrd/dcrutil"
-	"github.com/decred/dcrd/rpcclient"
 	apitypes "github.com/decred/dcrdata/api/types"
+	"github.com/decred/dcrdata/rpcutils"
 )
 
 // NewTx models data for a new transaction
@@ -110,7 +110,7 @@ func (tix ByAbsoluteFee) Less(i, j int) bool {
 // mechanism used by main. The newTxChan contains a chain hash for the
 // transaction from the notificiation, or a zero value hash indicating it was
 // from a Ticker or manually triggered.
-func (p *mempoolMonitor) TxHandler(client *rpcclient.Client) {
+func (p *mempoolMonitor) TxHandler(client rpcutils.NodeClient) {
 	defer p.wg.Done()
 	for {
 		select {
@@ -301,14 +301,23 @@ func (m *MempoolData) GetNumTickets() uint32 {
 	return m.NumTickets
 }
 
+// GetLowestMineableFee returns the lowest fee rate of the tickets that may be
+// mined in the next block, or 0 if there are no tickets
+func (m *MempoolData) GetLowestMineableFee() float64 {
+	if m.MinableFees == nil {
+		return 0
+	}
+	return m.MinableFees.lowestMineableFee
+}
+
 type mempoolDataCollector struct {
 	mtx          sync.Mutex
-	dcrdChainSvr *rpcclient.Client
+	dcrdChainSvr rpcutils.NodeClient
 	activeChain  *chaincfg.Params
 }
 
 // NewMempoolDataCollector creates a new mempoolDataCollector.
-func NewMempoolDataCollector(dcrdChainSvr *rpcclient.Client, params *chaincfg.Params) *mempoolDataCollector {
+func NewMempoolDataCollector(dcrdChainSvr rpcutils.NodeClient, params *chaincfg.Params) *mempoolDataCollector {
 	return &mempoolDataCollector{
 		mtx:          sync.Mutex{},
 		dcrdChainSvr: dcrdChainSvr,
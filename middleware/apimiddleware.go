@@ -40,6 +40,9 @@ const (
 	ctxGetStatus
 	ctxStakeVersionLatest
 	ctxRawHexTx
+	ctxTrackingID
+	ctxTxClass
+	ctxChart
 )
 
 type DataSource interface {
@@ -106,6 +109,39 @@ func GetRawHexTx(r *http.Request) string {
 	return rawHexTx
 }
 
+// GetTrackingIDCtx retrieves the ctxTrackingID data from the request context.
+// If not set, the return value is an empty string.
+func GetTrackingIDCtx(r *http.Request) string {
+	id, ok := r.Context().Value(ctxTrackingID).(string)
+	if !ok {
+		apiLog.Trace("tracking id not set")
+		return ""
+	}
+	return id
+}
+
+// GetTxClassCtx retrieves the ctxTxClass data from the request context. If not
+// set, the return value is an empty string.
+func GetTxClassCtx(r *http.Request) string {
+	class, ok := r.Context().Value(ctxTxClass).(string)
+	if !ok {
+		apiLog.Trace("tx class not set")
+		return ""
+	}
+	return class
+}
+
+// GetChartCtx retrieves the ctxChart data from the request context. If not
+// set, the return value is an empty string.
+func GetChartCtx(r *http.Request) string {
+	chart, ok := r.Context().Value(ctxChart).(string)
+	if !ok {
+		apiLog.Trace("chart not set")
+		return ""
+	}
+	return chart
+}
+
 // GetTxIDCtx accepts http request
 // returns transaction hash
 func GetTxIDCtx(r *http.Request) string {
@@ -333,6 +369,36 @@ func TransactionHashCtx(next http.Handler) http.Handler {
 	})
 }
 
+// TrackingIDPathCtx returns a http.HandlerFunc that embeds the value at the url
+// part {trackingid} into the request context
+func TrackingIDPathCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "trackingid")
+		ctx := context.WithValue(r.Context(), ctxTrackingID, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// TxClassPathCtx returns a http.HandlerFunc that embeds the value at the url
+// part {class} into the request context
+func TxClassPathCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := chi.URLParam(r, "class")
+		ctx := context.WithValue(r.Context(), ctxTxClass, class)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ChartPathCtx returns a http.HandlerFunc that embeds the value at the url
+// part {chart} into the request context
+func ChartPathCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chart := chi.URLParam(r, "chart")
+		ctx := context.WithValue(r.Context(), ctxChart, chart)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // TransactionIOIndexCtx returns a http.HandlerFunc that embeds the value at the url
 // part {txinoutindex} into the request context
 func TransactionIOIndexCtx(next http.Handler) http.Handler {
@@ -4,6 +4,7 @@
 package notification
 
 import (
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -17,6 +18,7 @@ import (
 	"github.com/decred/dcrdata/db/dcrsqlite"
 	"github.com/decred/dcrdata/explorer"
 	"github.com/decred/dcrdata/mempool"
+	"github.com/decred/dcrdata/rpcutils"
 	"github.com/decred/dcrdata/stakedb"
 	"github.com/decred/dcrwallet/wallet/udb"
 )
@@ -60,17 +62,32 @@ type blockHashHeight struct {
 	height int64
 }
 
+// maxRecentBlocks is the number of most recently queued block hashes that are
+// retained by collectionQueue for detecting duplicate notifications and for
+// locating a common ancestor when reconciling with a node.
+const maxRecentBlocks = 256
+
 type collectionQueue struct {
 	sync.Mutex
 	q            chan *blockHashHeight
 	syncHandlers []func(hash *chainhash.Hash)
+	// recent maps heights to the hashes of recently queued blocks, which are
+	// on the chain ending at tipHash, tipHeight.
+	recent    map[int64]chainhash.Hash
+	tipHash   chainhash.Hash
+	tipHeight int64
+	// client is used to retrieve blocks missed while disconnected from the
+	// node, or otherwise not notified.
+	client rpcutils.NodeClient
 }
 
 // newCollectionQueue creates a new collectionQueue with a queue channel large
 // enough for 10 million block pointers.
 func NewCollectionQueue() *collectionQueue {
 	return &collectionQueue{
-		q: make(chan *blockHashHeight, 1e7),
+		q:         make(chan *blockHashHeight, 1e7),
+		recent:    make(map[int64]chainhash.Hash, maxRecentBlocks),
+		tipHeight: -1,
 	}
 }
 
@@ -78,6 +95,165 @@ func (q *collectionQueue) SetSynchronousHandlers(syncHandlers []func(hash *chain
 	q.syncHandlers = syncHandlers
 }
 
+// SetNodeClient sets the node client used to catch up on missed blocks after
+// a reconnect or a gap in the block connected notifications.
+func (q *collectionQueue) SetNodeClient(client rpcutils.NodeClient) {
+	q.Lock()
+	q.client = client
+	q.Unlock()
+}
+
+// catchUpWithNode runs CatchUp with the queue's node client, if it is set.
+// Since CatchUp makes RPCs, this must not be called from a notification
+// handler's goroutine.
+func (q *collectionQueue) catchUpWithNode() {
+	q.Lock()
+	client := q.client
+	q.Unlock()
+	if client == nil {
+		return
+	}
+	if err := q.CatchUp(client); err != nil {
+		log.Errorf("Failed to catch up with node: %v", err)
+	}
+}
+
+// SetTip records the block that the data stores are synchronized to, which
+// must be done before notifications are registered so that any blocks missed
+// may be identified.
+func (q *collectionQueue) SetTip(hash chainhash.Hash, height int64) {
+	q.Lock()
+	defer q.Unlock()
+	q.recent = map[int64]chainhash.Hash{height: hash}
+	q.tipHash, q.tipHeight = hash, height
+}
+
+// enqueue queues a block for processing by the synchronous handlers unless it
+// is the same block previously queued at that height. The caller must hold
+// the lock.
+func (q *collectionQueue) enqueue(hash chainhash.Hash, height int64) bool {
+	if prev, ok := q.recent[height]; ok && prev == hash {
+		log.Debugf("Ignoring repeat notification for block %v (%d).", hash, height)
+		return false
+	}
+
+	// Blocks above this height are no longer on the chain being tracked.
+	for h := range q.recent {
+		if h > height || h <= height-maxRecentBlocks {
+			delete(q.recent, h)
+		}
+	}
+	q.recent[height] = hash
+	q.tipHash, q.tipHeight = hash, height
+
+	q.q <- &blockHashHeight{
+		hash:   hash,
+		height: height,
+	}
+	return true
+}
+
+// maxCatchUpAttempts is the number of times CatchUp retrieves the node's blocks
+// when the queue's tip is moved by notifications while they are retrieved.
+const maxCatchUpAttempts = 10
+
+// CatchUp reconciles the queued blocks with the chain of the given node, such
+// as after switching to a different node. If the node has blocks beyond the
+// last queued block, they are queued for the synchronous handlers. If the
+// node's chain has diverged from the queued blocks, a reorganization from the
+// last queued block to the node's best block is signaled first, just as dcrd
+// would before connecting the blocks of the new chain. Nothing is done if the
+// node is not ahead of the last queued block, as it will send notifications
+// for any new blocks.
+//
+// The RPCs are made without holding the lock, since the notification handlers
+// that take the lock run on the goroutine reading the RPC responses. If the
+// last queued block changes in the meantime, the blocks are retrieved again.
+func (q *collectionQueue) CatchUp(client rpcutils.NodeClient) error {
+	for i := 0; i < maxCatchUpAttempts; i++ {
+		done, err := q.catchUp(client)
+		if err != nil || done {
+			return err
+		}
+		log.Debugf("Queued blocks changed while catching up. Retrying.")
+	}
+	return fmt.Errorf("queued blocks changed during each of %d attempts to "+
+		"catch up", maxCatchUpAttempts)
+}
+
+// catchUp makes one attempt of CatchUp, returning false if the last queued
+// block changed while the node's blocks were retrieved.
+func (q *collectionQueue) catchUp(client rpcutils.NodeClient) (bool, error) {
+	q.Lock()
+	tipHash, tipHeight := q.tipHash, q.tipHeight
+	recent := make(map[int64]chainhash.Hash, len(q.recent))
+	for h, hash := range q.recent {
+		recent[h] = hash
+	}
+	q.Unlock()
+	if tipHeight < 0 {
+		return true, nil
+	}
+
+	bestHash, bestHeight, err := client.GetBestBlock()
+	if err != nil {
+		return false, fmt.Errorf("GetBestBlock failed: %v", err)
+	}
+	if bestHeight <= tipHeight {
+		return true, nil
+	}
+
+	// Find the most recent queued block that is in the node's main chain.
+	common := int64(-1)
+	for h := tipHeight; h > tipHeight-maxRecentBlocks && h >= 0; h-- {
+		queuedHash, ok := recent[h]
+		if !ok {
+			break
+		}
+		nodeHash, err := client.GetBlockHash(h)
+		if err != nil {
+			return false, fmt.Errorf("GetBlockHash(%d) failed: %v", h, err)
+		}
+		if *nodeHash == queuedHash {
+			common = h
+			break
+		}
+	}
+	if common < 0 {
+		return false, fmt.Errorf("no common ancestor with node's chain within "+
+			"%d blocks of height %d", maxRecentBlocks, tipHeight)
+	}
+
+	hashes := make([]chainhash.Hash, 0, bestHeight-common)
+	for h := common + 1; h <= bestHeight; h++ {
+		hash, err := client.GetBlockHash(h)
+		if err != nil {
+			return false, fmt.Errorf("GetBlockHash(%d) failed: %v", h, err)
+		}
+		hashes = append(hashes, *hash)
+	}
+
+	q.Lock()
+	defer q.Unlock()
+	if q.tipHash != tipHash || q.tipHeight != tipHeight {
+		return false, nil
+	}
+
+	if common < tipHeight {
+		log.Infof("Node's chain diverged after block %d. Reorganizing from "+
+			"%v (%d) to %v (%d).", common, tipHash, tipHeight,
+			bestHash, bestHeight)
+		signalReorg(&tipHash, int32(tipHeight), bestHash, int32(bestHeight))
+	}
+
+	log.Infof("Catching up %d blocks, from %d to %d.", bestHeight-common,
+		common+1, bestHeight)
+	for i, hash := range hashes {
+		q.enqueue(hash, common+1+int64(i))
+	}
+	return true, nil
+}
+
 func (q *collectionQueue) ProcessBlocks() {
 	// process queued blocks one at a time
 	for bh := range q.q {
@@ -130,6 +306,42 @@ func (q *collectionQueue) ProcessBlocks() {
 // 	return b
 // }
 
+// signalReorg sends the reorganization data to the reorg handlers of the
+// block data, stakedb and dcrsqlite monitors.
+func signalReorg(oldHash *chainhash.Hash, oldHeight int32,
+	newHash *chainhash.Hash, newHeight int32) {
+	// Send reorg data to dcrsqlite's monitor
+	select {
+	case NtfnChans.ReorgChanWiredDB <- &dcrsqlite.ReorgData{
+		OldChainHead:   *oldHash,
+		OldChainHeight: oldHeight,
+		NewChainHead:   *newHash,
+		NewChainHeight: newHeight,
+	}:
+	default:
+	}
+	// Send reorg data to blockdata's monitor (so that it stops collecting)
+	select {
+	case NtfnChans.ReorgChanBlockData <- &blockdata.ReorgData{
+		OldChainHead:   *oldHash,
+		OldChainHeight: oldHeight,
+		NewChainHead:   *newHash,
+		NewChainHeight: newHeight,
+	}:
+	default:
+	}
+	// Send reorg data to stakedb's monitor
+	select {
+	case NtfnChans.ReorgChanStakeDB <- &stakedb.ReorgData{
+		OldChainHead:   *oldHash,
+		OldChainHeight: oldHeight,
+		NewChainHead:   *newHash,
+		NewChainHeight: newHeight,
+	}:
+	default:
+	}
+}
+
 // Define notification handlers
 func MakeNodeNtfnHandlers() (*rpcclient.NotificationHandlers, *collectionQueue) {
 	blockQueue := NewCollectionQueue()
@@ -144,44 +356,31 @@ func MakeNodeNtfnHandlers() (*rpcclient.NotificationHandlers, *collectionQueue)
 			height := int32(blockHeader.Height)
 			hash := blockHeader.BlockHash()
 
-			// queue this block
-			blockQueue.q <- &blockHashHeight{
-				hash:   hash,
-				height: int64(height),
+			// Queue this block, unless blocks were missed since the last one
+			// queued. In that case, fetch the missing blocks and this one from
+			// the node. The RPCs cannot be made from this goroutine.
+			blockQueue.Lock()
+			lastHeight := blockQueue.tipHeight
+			gap := lastHeight >= 0 && int64(height) > lastHeight+1
+			if !gap {
+				blockQueue.enqueue(hash, int64(height))
+			}
+			blockQueue.Unlock()
+			if gap {
+				log.Warnf("Block %d connected, but the last block seen was %d. "+
+					"Catching up.", height, lastHeight)
+				go blockQueue.catchUpWithNode()
 			}
 		},
+		// OnClientConnected is invoked when the websocket connection is
+		// established or reestablished. Any blocks connected while the
+		// connection was down are queued.
+		OnClientConnected: func() {
+			go blockQueue.catchUpWithNode()
+		},
 		OnReorganization: func(oldHash *chainhash.Hash, oldHeight int32,
 			newHash *chainhash.Hash, newHeight int32) {
-			// Send reorg data to dcrsqlite's monitor
-			select {
-			case NtfnChans.ReorgChanWiredDB <- &dcrsqlite.ReorgData{
-				OldChainHead:   *oldHash,
-				OldChainHeight: oldHeight,
-				NewChainHead:   *newHash,
-				NewChainHeight: newHeight,
-			}:
-			default:
-			}
-			// Send reorg data to blockdata's monitor (so that it stops collecting)
-			select {
-			case NtfnChans.ReorgChanBlockData <- &blockdata.ReorgData{
-				OldChainHead:   *oldHash,
-				OldChainHeight: oldHeight,
-				NewChainHead:   *newHash,
-				NewChainHeight: newHeight,
-			}:
-			default:
-			}
-			// Send reorg data to stakedb's monitor
-			select {
-			case NtfnChans.ReorgChanStakeDB <- &stakedb.ReorgData{
-				OldChainHead:   *oldHash,
-				OldChainHeight: oldHeight,
-				NewChainHead:   *newHash,
-				NewChainHeight: newHeight,
-			}:
-			default:
-			}
+			signalReorg(oldHash, oldHeight, newHash, newHeight)
 		},
 
 		OnWinningTickets: func(blockHash *chainhash.Hash, blockHeight int64,
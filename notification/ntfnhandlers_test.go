@@ -0,0 +1,231 @@
+package notification
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/rpcclient"
+	"github.com/decred/dcrdata/rpcutils"
+	"github.com/decred/dcrdata/rpcutils/mocknode"
+)
+
+func TestMain(m *testing.M) {
+	MakeNtfnChans(false)
+	os.Exit(m.Run())
+}
+
+func startNode(t *testing.T, numBlocks int) *mocknode.Server {
+	chain, err := mocknode.GenerateChain(&chaincfg.SimNetParams, numBlocks, nil)
+	if err != nil {
+		t.Fatalf("GenerateChain failed: %v", err)
+	}
+	s := mocknode.NewServer(chain, "user", "pass")
+	if err = s.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	return s
+}
+
+// connectQueue connects to the node with the notification handlers, and
+// returns the handlers, the block queue synchronized to the node's best block,
+// and a channel receiving the hashes of the blocks processed from the queue.
+func connectQueue(t *testing.T, s *mocknode.Server) (*rpcclient.Client,
+	*rpcclient.NotificationHandlers, chan chainhash.Hash) {
+	handlers, queue := MakeNodeNtfnHandlers()
+	processed := make(chan chainhash.Hash, 100)
+	queue.SetSynchronousHandlers([]func(hash *chainhash.Hash){
+		func(hash *chainhash.Hash) { processed <- *hash },
+	})
+
+	client, _, err := rpcutils.ConnectNodeRPC(s.Addr(), "user", "pass", "", true, handlers)
+	if err != nil {
+		t.Fatalf("ConnectNodeRPC failed: %v", err)
+	}
+	queue.SetNodeClient(client)
+	hash, height := s.Chain().Best()
+	queue.SetTip(hash, height)
+	if cerr := RegisterNodeNtfnHandlers(client); cerr != nil {
+		t.Fatalf("RegisterNodeNtfnHandlers failed: %v", cerr)
+	}
+	return client, handlers, processed
+}
+
+// expectBlocks checks that the blocks of the node's main chain from height
+// from to height to are processed in order.
+func expectBlocks(t *testing.T, s *mocknode.Server, processed chan chainhash.Hash, from, to int64) {
+	for h := from; h <= to; h++ {
+		b, ok := s.Chain().BlockByHeight(h)
+		if !ok {
+			t.Fatalf("no block at height %d", h)
+		}
+		select {
+		case hash := <-processed:
+			if hash != b.BlockHash() {
+				t.Fatalf("processed block %v, expected %v at height %d",
+					hash, b.BlockHash(), h)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("block %d not processed", h)
+		}
+	}
+	select {
+	case hash := <-processed:
+		t.Fatalf("unexpected block %v processed", hash)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestCatchUpOnGap(t *testing.T) {
+	s := startNode(t, 10)
+	defer s.Stop()
+	client, _, processed := connectQueue(t, s)
+	defer client.Shutdown()
+
+	// A notified block is queued.
+	if _, err := s.MineBlock(); err != nil {
+		t.Fatalf("MineBlock failed: %v", err)
+	}
+	expectBlocks(t, s, processed, 10, 10)
+
+	// Two blocks connected without notifications leave a gap before the next
+	// notified block, which is filled from the node.
+	for i := 0; i < 2; i++ {
+		if _, err := s.Chain().AddBlock(s.Chain().NextBlock(nil)); err != nil {
+			t.Fatalf("AddBlock failed: %v", err)
+		}
+	}
+	if _, err := s.MineBlock(); err != nil {
+		t.Fatalf("MineBlock failed: %v", err)
+	}
+	expectBlocks(t, s, processed, 11, 13)
+}
+
+func TestCatchUpOnReconnect(t *testing.T) {
+	s := startNode(t, 10)
+	defer s.Stop()
+	client, handlers, processed := connectQueue(t, s)
+	defer client.Shutdown()
+
+	// Blocks connected while the connection is down are queued when the
+	// client reconnects. The reconnect is simulated by invoking the handler
+	// rpcclient calls when the connection is reestablished.
+	for i := 0; i < 3; i++ {
+		if _, err := s.Chain().AddBlock(s.Chain().NextBlock(nil)); err != nil {
+			t.Fatalf("AddBlock failed: %v", err)
+		}
+	}
+	handlers.OnClientConnected()
+	expectBlocks(t, s, processed, 10, 12)
+
+	// Notifications for later blocks follow on from the caught up blocks.
+	if _, err := s.MineBlock(); err != nil {
+		t.Fatalf("MineBlock failed: %v", err)
+	}
+	expectBlocks(t, s, processed, 13, 13)
+}
+
+func TestCatchUpNotifiedDuringRPC(t *testing.T) {
+	s := startNode(t, 10)
+	defer s.Stop()
+	client, handlers, processed := connectQueue(t, s)
+	defer client.Shutdown()
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Chain().AddBlock(s.Chain().NextBlock(nil)); err != nil {
+			t.Fatalf("AddBlock failed: %v", err)
+		}
+	}
+
+	// Hold the response to the first getblockhash of the catch up, and send a
+	// block notification meanwhile. The notification is read before the
+	// response, so its handler must not wait on the catch up.
+	held, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	s.SetRequestHook(func(method string) {
+		if method == "getblockhash" {
+			once.Do(func() {
+				close(held)
+				<-release
+			})
+		}
+	})
+	handlers.OnClientConnected()
+	select {
+	case <-held:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("catch up did not request a block hash")
+	}
+	if _, err := s.MineBlock(); err != nil {
+		t.Fatalf("MineBlock failed: %v", err)
+	}
+	close(release)
+	expectBlocks(t, s, processed, 10, 12)
+}
+
+func TestCatchUpReorg(t *testing.T) {
+	s := startNode(t, 10)
+	defer s.Stop()
+	client, _, err := rpcutils.ConnectNodeRPC(s.Addr(), "user", "pass", "", true)
+	if err != nil {
+		t.Fatalf("ConnectNodeRPC failed: %v", err)
+	}
+	defer client.Shutdown()
+
+	// Queue the node's chain, then switch the node to a longer side chain
+	// forking after block 7.
+	queue := NewCollectionQueue()
+	queue.Lock()
+	for h := int64(0); h < 10; h++ {
+		b, _ := s.Chain().BlockByHeight(h)
+		queue.enqueue(b.BlockHash(), h)
+	}
+	queue.Unlock()
+	for len(queue.q) > 0 {
+		<-queue.q
+	}
+	oldTip, _ := s.Chain().Best()
+
+	side, err := s.Chain().SideChain(7, 4, nil, 1)
+	if err != nil {
+		t.Fatalf("SideChain failed: %v", err)
+	}
+	if err = s.Reorg(7, side); err != nil {
+		t.Fatalf("Reorg failed: %v", err)
+	}
+
+	if err = queue.CatchUp(client); err != nil {
+		t.Fatalf("CatchUp failed: %v", err)
+	}
+	select {
+	case reorg := <-NtfnChans.ReorgChanWiredDB:
+		if reorg.OldChainHead != oldTip || reorg.OldChainHeight != 9 ||
+			reorg.NewChainHead != side[3].BlockHash() || reorg.NewChainHeight != 11 {
+			t.Errorf("unexpected reorg data %+v", reorg)
+		}
+	default:
+		t.Fatalf("reorg not signaled")
+	}
+	for i, b := range side {
+		select {
+		case bh := <-queue.q:
+			if bh.hash != b.BlockHash() || bh.height != int64(8+i) {
+				t.Errorf("queued block %v (%d), expected %v (%d)", bh.hash,
+					bh.height, b.BlockHash(), 8+i)
+			}
+		default:
+			t.Fatalf("side chain block %d not queued", 8+i)
+		}
+	}
+
+	// Catching up again does nothing.
+	if err = queue.CatchUp(client); err != nil {
+		t.Fatalf("CatchUp failed: %v", err)
+	}
+	if len(queue.q) != 0 {
+		t.Errorf("%d blocks queued again", len(queue.q))
+	}
+}
@@ -9,7 +9,6 @@ import (
 
 	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/dcrutil"
-	"github.com/decred/dcrd/rpcclient"
 )
 
 // BlockGetter is an interface for requesting blocks
@@ -36,7 +35,7 @@ type MasterBlockGetter interface {
 // BlockGate is an implementation of MasterBlockGetter with cache
 type BlockGate struct {
 	sync.RWMutex
-	client        *rpcclient.Client
+	client        NodeClient
 	height        int64
 	fetchToHeight int64
 	hashAtHeight  map[int64]chainhash.Hash
@@ -79,7 +78,7 @@ var _ BlockGetter = (*BlockGate)(nil)
 
 // NewBlockGate constructs a new BlockGate, wrapping an RPC client, with a
 // specified block cache capacity.
-func NewBlockGate(client *rpcclient.Client, capacity int) *BlockGate {
+func NewBlockGate(client NodeClient, capacity int) *BlockGate {
 	return &BlockGate{
 		client:        client,
 		height:        -1,
@@ -0,0 +1,544 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package mocknode
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/txscript"
+	"github.com/decred/dcrd/wire"
+)
+
+// txLocation records where in the main chain a transaction was mined.
+type txLocation struct {
+	tx     *wire.MsgTx
+	height int64
+	block  chainhash.Hash
+}
+
+// Chain is an in-memory main chain plus mempool served by a Server. All
+// methods are safe for concurrent access.
+type Chain struct {
+	mtx     sync.RWMutex
+	params  *chaincfg.Params
+	blocks  []*wire.MsgBlock
+	hashes  map[chainhash.Hash]int64
+	txns    map[chainhash.Hash]*txLocation
+	mempool map[chainhash.Hash]*wire.MsgTx
+	// accepted records when each mempool transaction entered the mempool.
+	accepted map[chainhash.Hash]time.Time
+	// orphaned blocks remain retrievable by hash after a reorg, as with dcrd.
+	orphans map[chainhash.Hash]*wire.MsgBlock
+}
+
+// NewChain creates a Chain from the given blocks, which must be ordered by
+// height starting at the genesis block.
+func NewChain(params *chaincfg.Params, blocks []*wire.MsgBlock) (*Chain, error) {
+	c := &Chain{
+		params:   params,
+		hashes:   make(map[chainhash.Hash]int64),
+		txns:     make(map[chainhash.Hash]*txLocation),
+		mempool:  make(map[chainhash.Hash]*wire.MsgTx),
+		accepted: make(map[chainhash.Hash]time.Time),
+		orphans:  make(map[chainhash.Hash]*wire.MsgBlock),
+	}
+	for _, b := range blocks {
+		if err := c.connect(b); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// GenerateChain creates a Chain of numBlocks blocks, including a genesis
+// block, each containing only a coinbase paying to payTo. If payTo is empty,
+// the coinbase outputs pay to an empty script.
+func GenerateChain(params *chaincfg.Params, numBlocks int, payTo []byte) (*Chain, error) {
+	c, err := NewChain(params, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < numBlocks; i++ {
+		if _, err = c.AddBlock(c.NextBlock(payTo)); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// LoadChain reads a fixture chain from r, which must contain one hex-encoded
+// serialized block per line, ordered by height. Blank lines and lines
+// beginning with '#' are ignored.
+func LoadChain(params *chaincfg.Params, r io.Reader) (*Chain, error) {
+	var blocks []*wire.MsgBlock
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1<<20), wire.MaxBlockPayload*2+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blockBytes, err := hex.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block hex: %v", err)
+		}
+		var msgBlock wire.MsgBlock
+		if err = msgBlock.FromBytes(blockBytes); err != nil {
+			return nil, fmt.Errorf("invalid serialized block: %v", err)
+		}
+		blocks = append(blocks, &msgBlock)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewChain(params, blocks)
+}
+
+// WriteChain writes the main chain to w in the format read by LoadChain.
+func (c *Chain) WriteChain(w io.Writer) error {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	for _, b := range c.blocks {
+		blockBytes, err := b.Bytes()
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintln(w, hex.EncodeToString(blockBytes)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Params returns the network parameters of the chain.
+func (c *Chain) Params() *chaincfg.Params {
+	return c.params
+}
+
+// connect appends b to the main chain. The caller must hold the write lock or
+// have exclusive access to the Chain.
+func (c *Chain) connect(b *wire.MsgBlock) error {
+	height := int64(len(c.blocks))
+	if int64(b.Header.Height) != height {
+		return fmt.Errorf("block height %d does not extend chain at height %d",
+			b.Header.Height, height-1)
+	}
+	if height > 0 {
+		tipHash := c.blocks[height-1].BlockHash()
+		if b.Header.PrevBlock != tipHash {
+			return fmt.Errorf("block %v does not build on tip %v",
+				b.BlockHash(), tipHash)
+		}
+	}
+
+	hash := b.BlockHash()
+	c.blocks = append(c.blocks, b)
+	c.hashes[hash] = height
+	delete(c.orphans, hash)
+	for _, txns := range [][]*wire.MsgTx{b.Transactions, b.STransactions} {
+		for _, tx := range txns {
+			txHash := tx.TxHash()
+			c.txns[txHash] = &txLocation{tx: tx, height: height, block: hash}
+			delete(c.mempool, txHash)
+			delete(c.accepted, txHash)
+		}
+	}
+	return nil
+}
+
+// disconnectTip removes the tip block from the main chain, returning its
+// transactions to the mempool as dcrd would (coinbases and votes excepted).
+func (c *Chain) disconnectTip() *wire.MsgBlock {
+	height := len(c.blocks) - 1
+	tip := c.blocks[height]
+	c.blocks = c.blocks[:height]
+
+	hash := tip.BlockHash()
+	delete(c.hashes, hash)
+	c.orphans[hash] = tip
+	for i, tx := range tip.Transactions {
+		delete(c.txns, tx.TxHash())
+		if i > 0 {
+			c.addMempoolTx(tx)
+		}
+	}
+	for _, tx := range tip.STransactions {
+		delete(c.txns, tx.TxHash())
+		if !stake.IsSSGen(tx) {
+			c.addMempoolTx(tx)
+		}
+	}
+	return tip
+}
+
+// AddBlock connects b to the tip of the main chain and returns its height.
+func (c *Chain) AddBlock(b *wire.MsgBlock) (int64, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err := c.connect(b); err != nil {
+		return -1, err
+	}
+	return int64(len(c.blocks) - 1), nil
+}
+
+// Reorganize disconnects blocks above commonHeight and connects newBlocks in
+// their place. The disconnected blocks are returned.
+func (c *Chain) Reorganize(commonHeight int64, newBlocks []*wire.MsgBlock) ([]*wire.MsgBlock, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if commonHeight < 0 || commonHeight >= int64(len(c.blocks)) {
+		return nil, fmt.Errorf("invalid common ancestor height %d", commonHeight)
+	}
+
+	var detached []*wire.MsgBlock
+	for int64(len(c.blocks)-1) > commonHeight {
+		detached = append(detached, c.disconnectTip())
+	}
+	for _, b := range newBlocks {
+		if err := c.connect(b); err != nil {
+			// Restore the original chain before failing.
+			for int64(len(c.blocks)-1) > commonHeight {
+				c.disconnectTip()
+			}
+			for i := len(detached) - 1; i >= 0; i-- {
+				_ = c.connect(detached[i])
+			}
+			return nil, err
+		}
+	}
+	return detached, nil
+}
+
+// NextBlock creates, but does not connect, a block extending the current tip
+// with a coinbase paying to payTo and any transactions currently in the
+// mempool.
+func (c *Chain) NextBlock(payTo []byte) *wire.MsgBlock {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	var prev *wire.MsgBlock
+	if len(c.blocks) > 0 {
+		prev = c.blocks[len(c.blocks)-1]
+	}
+	b := c.newBlock(prev, payTo, 0)
+	for _, tx := range c.mempool {
+		if stake.IsSSGen(tx) || stake.IsSStx(tx) || stake.IsSSRtx(tx) {
+			b.STransactions = append(b.STransactions, tx)
+			continue
+		}
+		b.Transactions = append(b.Transactions, tx)
+	}
+	b.Header.MerkleRoot = merkleRoot(b.Transactions)
+	b.Header.StakeRoot = merkleRoot(b.STransactions)
+	return b
+}
+
+// SideChain creates, but does not connect, n coinbase-only blocks building on
+// the main chain block at height forkHeight. The nonce is used to make the
+// resulting blocks distinct from any previously generated side chain.
+func (c *Chain) SideChain(forkHeight int64, n int, payTo []byte, nonce uint32) ([]*wire.MsgBlock, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if forkHeight < 0 || forkHeight >= int64(len(c.blocks)) {
+		return nil, fmt.Errorf("invalid fork height %d", forkHeight)
+	}
+	prev := c.blocks[forkHeight]
+	blocks := make([]*wire.MsgBlock, 0, n)
+	for i := 0; i < n; i++ {
+		b := c.newBlock(prev, payTo, nonce)
+		blocks = append(blocks, b)
+		prev = b
+	}
+	return blocks, nil
+}
+
+// newBlock creates a coinbase-only block building on prev, or a genesis block
+// if prev is nil.
+func (c *Chain) newBlock(prev *wire.MsgBlock, payTo []byte, nonce uint32) *wire.MsgBlock {
+	var height uint32
+	var prevHash chainhash.Hash
+	timestamp := time.Unix(1454954400, 0)
+	if prev != nil {
+		height = prev.Header.Height + 1
+		prevHash = prev.BlockHash()
+		timestamp = prev.Header.Timestamp.Add(c.params.TargetTimePerBlock)
+	}
+
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: *wire.NewOutPoint(&chainhash.Hash{},
+			wire.MaxPrevOutIndex, wire.TxTreeRegular),
+		Sequence:        wire.MaxTxInSequenceNum,
+		ValueIn:         c.params.BaseSubsidy,
+		BlockHeight:     wire.NullBlockHeight,
+		BlockIndex:      wire.NullBlockIndex,
+		SignatureScript: coinbaseScript(height, nonce),
+	})
+	coinbase.AddTxOut(wire.NewTxOut(c.params.BaseSubsidy, payTo))
+
+	b := &wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:   1,
+			PrevBlock: prevHash,
+			VoteBits:  1,
+			Bits:      c.params.PowLimitBits,
+			SBits:     c.params.MinimumStakeDiff,
+			Height:    height,
+			Timestamp: timestamp,
+			Nonce:     nonce,
+		},
+		Transactions: []*wire.MsgTx{coinbase},
+	}
+	b.Header.MerkleRoot = merkleRoot(b.Transactions)
+	b.Header.StakeRoot = merkleRoot(b.STransactions)
+	return b
+}
+
+// coinbaseScript makes a unique coinbase signature script for the height and
+// nonce so that every generated block has a distinct hash.
+func coinbaseScript(height, nonce uint32) []byte {
+	script := make([]byte, 8)
+	binary.LittleEndian.PutUint32(script[0:4], height)
+	binary.LittleEndian.PutUint32(script[4:8], nonce)
+	return script
+}
+
+// merkleRoot computes the merkle root of the transaction hashes.
+func merkleRoot(txns []*wire.MsgTx) chainhash.Hash {
+	if len(txns) == 0 {
+		return chainhash.Hash{}
+	}
+	level := make([]chainhash.Hash, len(txns))
+	for i, tx := range txns {
+		level[i] = tx.TxHash()
+	}
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([]chainhash.Hash, len(level)/2)
+		for i := range next {
+			var buf [chainhash.HashSize * 2]byte
+			copy(buf[:chainhash.HashSize], level[2*i][:])
+			copy(buf[chainhash.HashSize:], level[2*i+1][:])
+			next[i] = chainhash.HashH(buf[:])
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// AddMempoolTx adds a transaction to the mempool.
+func (c *Chain) AddMempoolTx(tx *wire.MsgTx) {
+	c.mtx.Lock()
+	c.addMempoolTx(tx)
+	c.mtx.Unlock()
+}
+
+// addMempoolTx adds a transaction to the mempool, accepted now. The caller must
+// hold the write lock.
+func (c *Chain) addMempoolTx(tx *wire.MsgTx) {
+	txHash := tx.TxHash()
+	c.mempool[txHash] = tx
+	c.accepted[txHash] = time.Now()
+}
+
+// RemoveMempoolTx removes a transaction from the mempool.
+func (c *Chain) RemoveMempoolTx(hash chainhash.Hash) {
+	c.mtx.Lock()
+	delete(c.mempool, hash)
+	delete(c.accepted, hash)
+	c.mtx.Unlock()
+}
+
+// MempoolTime returns the time a mempool transaction was accepted.
+func (c *Chain) MempoolTime(hash chainhash.Hash) (time.Time, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	t, ok := c.accepted[hash]
+	return t, ok
+}
+
+// MempoolTxns returns the hashes of all transactions in the mempool.
+func (c *Chain) MempoolTxns() []chainhash.Hash {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	hashes := make([]chainhash.Hash, 0, len(c.mempool))
+	for h := range c.mempool {
+		hashes = append(hashes, h)
+	}
+	return hashes
+}
+
+// Best returns the hash and height of the main chain tip. The height is -1 if
+// the chain is empty.
+func (c *Chain) Best() (chainhash.Hash, int64) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if len(c.blocks) == 0 {
+		return chainhash.Hash{}, -1
+	}
+	tip := c.blocks[len(c.blocks)-1]
+	return tip.BlockHash(), int64(len(c.blocks) - 1)
+}
+
+// BlockByHeight returns the main chain block at the given height.
+func (c *Chain) BlockByHeight(height int64) (*wire.MsgBlock, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if height < 0 || height >= int64(len(c.blocks)) {
+		return nil, false
+	}
+	return c.blocks[height], true
+}
+
+// BlockByHash returns the block with the given hash, which may be an orphaned
+// block. The returned height is -1 for blocks not in the main chain.
+func (c *Chain) BlockByHash(hash chainhash.Hash) (*wire.MsgBlock, int64, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if height, ok := c.hashes[hash]; ok {
+		return c.blocks[height], height, true
+	}
+	if b, ok := c.orphans[hash]; ok {
+		return b, -1, true
+	}
+	return nil, -1, false
+}
+
+// NextHash returns the hash of the main chain block following the block at
+// the given height, if there is one.
+func (c *Chain) NextHash(height int64) (chainhash.Hash, bool) {
+	b, ok := c.BlockByHeight(height + 1)
+	if !ok {
+		return chainhash.Hash{}, false
+	}
+	return b.BlockHash(), true
+}
+
+// Transaction looks up a transaction in the main chain and then the mempool.
+// The returned height is -1 for mempool transactions.
+func (c *Chain) Transaction(hash chainhash.Hash) (tx *wire.MsgTx, height int64, block chainhash.Hash, found bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if loc, ok := c.txns[hash]; ok {
+		return loc.tx, loc.height, loc.block, true
+	}
+	if tx, ok := c.mempool[hash]; ok {
+		return tx, -1, chainhash.Hash{}, true
+	}
+	return nil, -1, chainhash.Hash{}, false
+}
+
+// TxOut returns an unspent output of a main chain transaction, and the height
+// of its block. If mempool is true, outputs of mempool transactions (height
+// -1) are included, and outputs spent by mempool transactions are excluded.
+func (c *Chain) TxOut(hash chainhash.Hash, index uint32, mempool bool) (*wire.TxOut, int64, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	var tx *wire.MsgTx
+	height := int64(-1)
+	if loc, ok := c.txns[hash]; ok {
+		tx, height = loc.tx, loc.height
+	} else if mempool {
+		tx = c.mempool[hash]
+	}
+	if tx == nil || index >= uint32(len(tx.TxOut)) {
+		return nil, -1, false
+	}
+
+	spends := func(tx *wire.MsgTx) bool {
+		for _, in := range tx.TxIn {
+			if in.PreviousOutPoint.Hash == hash && in.PreviousOutPoint.Index == index {
+				return true
+			}
+		}
+		return false
+	}
+	for _, loc := range c.txns {
+		if spends(loc.tx) {
+			return nil, -1, false
+		}
+	}
+	if mempool {
+		for _, tx := range c.mempool {
+			if spends(tx) {
+				return nil, -1, false
+			}
+		}
+	}
+	return tx.TxOut[index], height, true
+}
+
+// AddressTransactions returns the hashes of main chain transactions with an
+// output paying to addr, in the order they were mined.
+func (c *Chain) AddressTransactions(addr string) []chainhash.Hash {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	var hashes []chainhash.Hash
+	for _, b := range c.blocks {
+		for _, txns := range [][]*wire.MsgTx{b.Transactions, b.STransactions} {
+			for _, tx := range txns {
+				if c.paysTo(tx, addr) {
+					hashes = append(hashes, tx.TxHash())
+				}
+			}
+		}
+	}
+	return hashes
+}
+
+func (c *Chain) paysTo(tx *wire.MsgTx, addr string) bool {
+	for _, out := range tx.TxOut {
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(out.Version,
+			out.PkScript, c.params)
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if a.EncodeAddress() == addr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LiveTickets returns the hashes of tickets purchased in the main chain that
+// have not yet been spent by a vote or revocation. Ticket maturity is not
+// considered.
+func (c *Chain) LiveTickets() []chainhash.Hash {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	live := make(map[chainhash.Hash]struct{})
+	for _, b := range c.blocks {
+		for _, tx := range b.STransactions {
+			switch {
+			case stake.IsSStx(tx):
+				live[tx.TxHash()] = struct{}{}
+			case stake.IsSSGen(tx):
+				// The ticket is spent by the second input of a vote.
+				if len(tx.TxIn) > 1 {
+					delete(live, tx.TxIn[1].PreviousOutPoint.Hash)
+				}
+			case stake.IsSSRtx(tx):
+				delete(live, tx.TxIn[0].PreviousOutPoint.Hash)
+			}
+		}
+	}
+	tickets := make([]chainhash.Hash, 0, len(live))
+	for h := range live {
+		tickets = append(tickets, h)
+	}
+	return tickets
+}
@@ -0,0 +1,741 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+// Package mocknode provides a fake dcrd JSON-RPC server for testing. It serves
+// the subset of dcrd's RPC and websocket notification API used by dcrdata from
+// an in-memory fixture chain, and allows tests to script new blocks, chain
+// reorganizations and mempool activity.
+package mocknode
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/btcsuite/websocket"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrdata/rpcutils"
+)
+
+// The JSON-RPC API version advertised by the mock node.
+const (
+	apiMajor = 3
+	apiMinor = 1
+	apiPatch = 0
+)
+
+// Error codes returned by the mock node, matching those used by dcrd.
+const (
+	errCodeMethodNotFound = -32601
+	errCodeInvalidParams  = -32602
+	errCodeParse          = -32700
+	errCodeNoData         = -5
+	errCodeInvalidParam   = -8
+	errCodeDeserialize    = -22
+	errCodeDuplicateTx    = -40
+)
+
+// rpcError is a JSON-RPC error object.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+func newRPCError(code int, format string, args ...interface{}) *rpcError {
+	return &rpcError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+type rpcRequest struct {
+	Jsonrpc string            `json:"jsonrpc"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+	ID      interface{}       `json:"id"`
+}
+
+type rpcResponse struct {
+	Result interface{} `json:"result"`
+	Error  *rpcError   `json:"error"`
+	ID     interface{} `json:"id"`
+}
+
+type rpcNotification struct {
+	Jsonrpc string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+	ID      interface{}   `json:"id"`
+}
+
+// wsClient is a websocket connection and its notification registrations.
+type wsClient struct {
+	conn      *websocket.Conn
+	writeMtx  sync.Mutex
+	regMtx    sync.Mutex
+	blocks    bool
+	txns      bool
+	txVerbose bool
+	winners   bool
+}
+
+func (c *wsClient) write(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, b)
+}
+
+// Server is a mock dcrd RPC server. HTTP POST requests are served at "/" and
+// websocket connections at "/ws".
+type Server struct {
+	chain    *Chain
+	user     string
+	pass     string
+	listener net.Listener
+	srv      *http.Server
+	upgrader websocket.Upgrader
+
+	mtx         sync.Mutex
+	clients     map[*wsClient]struct{}
+	connections int64
+	net         wire.CurrencyNet
+	hook        func(method string)
+}
+
+// NewServer creates a Server for the given chain. Requests must use HTTP basic
+// authentication with the given user and pass.
+func NewServer(chain *Chain, user, pass string) *Server {
+	s := &Server{
+		chain:       chain,
+		user:        user,
+		pass:        pass,
+		clients:     make(map[*wsClient]struct{}),
+		connections: 8,
+		net:         chain.Params().Net,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handlePOST)
+	mux.HandleFunc("/ws", s.handleWebsocket)
+	s.srv = &http.Server{Handler: mux}
+	return s
+}
+
+// Start begins listening on addr (e.g. "127.0.0.1:0") without TLS. Use Addr to
+// get the address actually bound.
+func (s *Server) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	go func() {
+		if err := s.srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Errorf("mock node server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the listening address of a started server.
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Chain returns the chain served by the Server.
+func (s *Server) Chain() *Chain {
+	return s.chain
+}
+
+// Stop closes the listener and all websocket connections.
+func (s *Server) Stop() {
+	s.srv.Close()
+	s.DropClients()
+}
+
+// DropClients closes all websocket connections, as happens when dcrd restarts.
+// The server continues to accept new connections.
+func (s *Server) DropClients() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for c := range s.clients {
+		c.conn.Close()
+		delete(s.clients, c)
+	}
+}
+
+// SetConnectionCount sets the peer count reported by getconnectioncount.
+func (s *Server) SetConnectionCount(n int64) {
+	s.mtx.Lock()
+	s.connections = n
+	s.mtx.Unlock()
+}
+
+// SetNet sets the network reported by getcurrentnet, which is that of the
+// chain by default.
+func (s *Server) SetNet(net wire.CurrencyNet) {
+	s.mtx.Lock()
+	s.net = net
+	s.mtx.Unlock()
+}
+
+// SetRequestHook sets a function called with the method of each request before
+// it is handled. The hook may block to hold up the response, and the responses
+// to later requests from the same websocket client, while notifications are
+// still sent. A nil hook removes it.
+func (s *Server) SetRequestHook(hook func(method string)) {
+	s.mtx.Lock()
+	s.hook = hook
+	s.mtx.Unlock()
+}
+
+// ConnectBlock connects b to the main chain and sends blockconnected
+// notifications.
+func (s *Server) ConnectBlock(b *wire.MsgBlock) error {
+	if _, err := s.chain.AddBlock(b); err != nil {
+		return err
+	}
+	return s.notifyBlockConnected(b)
+}
+
+// MineBlock creates a block from the current mempool, connects it, and sends
+// notifications.
+func (s *Server) MineBlock() (*wire.MsgBlock, error) {
+	b := s.chain.NextBlock(nil)
+	return b, s.ConnectBlock(b)
+}
+
+// Reorg replaces the main chain above commonHeight with newBlocks. As with
+// dcrd, a reorganization notification is sent, followed by blockdisconnected
+// for each detached block and blockconnected for each attached block.
+func (s *Server) Reorg(commonHeight int64, newBlocks []*wire.MsgBlock) error {
+	oldHash, oldHeight := s.chain.Best()
+	detached, err := s.chain.Reorganize(commonHeight, newBlocks)
+	if err != nil {
+		return err
+	}
+	newHash, newHeight := s.chain.Best()
+
+	s.broadcast(isBlockClient, &rpcNotification{
+		Jsonrpc: "1.0",
+		Method:  dcrjson.ReorganizationNtfnMethod,
+		Params: []interface{}{oldHash.String(), int32(oldHeight),
+			newHash.String(), int32(newHeight)},
+	})
+	for _, b := range detached {
+		headerBytes, err := b.Header.Bytes()
+		if err != nil {
+			return err
+		}
+		s.broadcast(isBlockClient, &rpcNotification{
+			Jsonrpc: "1.0",
+			Method:  dcrjson.BlockDisconnectedNtfnMethod,
+			Params:  []interface{}{hex.EncodeToString(headerBytes)},
+		})
+	}
+	for _, b := range newBlocks {
+		if err = s.notifyBlockConnected(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AcceptTx adds tx to the mempool and sends txaccepted or txacceptedverbose
+// notifications.
+func (s *Server) AcceptTx(tx *wire.MsgTx) error {
+	s.chain.AddMempoolTx(tx)
+
+	txRes, err := s.txRawResult(tx, -1, chainhash.Hash{})
+	if err != nil {
+		return err
+	}
+	var amt int64
+	for _, out := range tx.TxOut {
+		amt += out.Value
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for c := range s.clients {
+		c.regMtx.Lock()
+		txns, verbose := c.txns, c.txVerbose
+		c.regMtx.Unlock()
+		if !txns {
+			continue
+		}
+		ntfn := &rpcNotification{Jsonrpc: "1.0"}
+		if verbose {
+			ntfn.Method = dcrjson.TxAcceptedVerboseNtfnMethod
+			ntfn.Params = []interface{}{txRes}
+		} else {
+			ntfn.Method = dcrjson.TxAcceptedNtfnMethod
+			ntfn.Params = []interface{}{txRes.Txid, dcrutil.Amount(amt).ToCoin()}
+		}
+		if err = c.write(ntfn); err != nil {
+			log.Debugf("failed to send %s notification: %v", ntfn.Method, err)
+		}
+	}
+	return nil
+}
+
+func (s *Server) notifyBlockConnected(b *wire.MsgBlock) error {
+	headerBytes, err := b.Header.Bytes()
+	if err != nil {
+		return err
+	}
+	// Transactions matching a loaded tx filter are not tracked by the mock.
+	s.broadcast(isBlockClient, &rpcNotification{
+		Jsonrpc: "1.0",
+		Method:  dcrjson.BlockConnectedNtfnMethod,
+		Params:  []interface{}{hex.EncodeToString(headerBytes), []string{}},
+	})
+	return nil
+}
+
+func isBlockClient(c *wsClient) bool {
+	c.regMtx.Lock()
+	defer c.regMtx.Unlock()
+	return c.blocks
+}
+
+// broadcast sends a notification to each websocket client selected by the
+// filter function.
+func (s *Server) broadcast(filter func(*wsClient) bool, ntfn *rpcNotification) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for c := range s.clients {
+		if !filter(c) {
+			continue
+		}
+		if err := c.write(ntfn); err != nil {
+			log.Debugf("failed to send %s notification: %v", ntfn.Method, err)
+		}
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte(s.user+":"+s.pass))
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")),
+		[]byte(want)) == 1
+}
+
+func (s *Server) handlePOST(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := s.respond(body, nil)
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
+		log.Debugf("failed to write response: %v", err)
+	}
+}
+
+func (s *Server) handleWebsocket(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Errorf("websocket upgrade failed: %v", err)
+		return
+	}
+
+	c := &wsClient{conn: conn}
+	s.mtx.Lock()
+	s.clients[c] = struct{}{}
+	s.mtx.Unlock()
+
+	defer func() {
+		s.mtx.Lock()
+		delete(s.clients, c)
+		s.mtx.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err = c.write(s.respond(msg, c)); err != nil {
+			return
+		}
+	}
+}
+
+// respond decodes and handles a single JSON-RPC request. The websocket client
+// is nil for HTTP POST requests, which may not register for notifications.
+func (s *Server) respond(body []byte, c *wsClient) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return &rpcResponse{Error: newRPCError(errCodeParse, "parse error: %v", err)}
+	}
+	s.mtx.Lock()
+	hook := s.hook
+	s.mtx.Unlock()
+	if hook != nil {
+		hook(req.Method)
+	}
+	result, rpcErr := s.handle(&req, c)
+	return &rpcResponse{Result: result, Error: rpcErr, ID: req.ID}
+}
+
+// param unmarshals the i-th request parameter into v, leaving v untouched if
+// the parameter was omitted or null.
+func param(req *rpcRequest, i int, v interface{}) *rpcError {
+	if i >= len(req.Params) || string(req.Params[i]) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(req.Params[i], v); err != nil {
+		return newRPCError(errCodeInvalidParams, "invalid parameter %d: %v", i, err)
+	}
+	return nil
+}
+
+func (s *Server) handle(req *rpcRequest, c *wsClient) (interface{}, *rpcError) {
+	switch req.Method {
+	case "version":
+		ver := dcrjson.VersionResult{
+			VersionString: fmt.Sprintf("%d.%d.%d", apiMajor, apiMinor, apiPatch),
+			Major:         apiMajor,
+			Minor:         apiMinor,
+			Patch:         apiPatch,
+		}
+		return map[string]dcrjson.VersionResult{
+			"dcrdjsonrpcapi": ver,
+			"dcrd":           ver,
+		}, nil
+
+	case "getbestblock":
+		hash, height := s.chain.Best()
+		return &dcrjson.GetBestBlockResult{Hash: hash.String(), Height: height}, nil
+
+	case "getbestblockhash":
+		hash, _ := s.chain.Best()
+		return hash.String(), nil
+
+	case "getblockcount":
+		_, height := s.chain.Best()
+		return height, nil
+
+	case "getconnectioncount":
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		return s.connections, nil
+
+	case "getcurrentnet":
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		return uint32(s.net), nil
+
+	case "getblockhash":
+		var height int64
+		if err := param(req, 0, &height); err != nil {
+			return nil, err
+		}
+		b, ok := s.chain.BlockByHeight(height)
+		if !ok {
+			return nil, newRPCError(errCodeInvalidParam, "Block number out of range")
+		}
+		return b.BlockHash().String(), nil
+
+	case "getblock", "getblockheader":
+		var hashStr string
+		verbose, verboseTx := true, false
+		if err := param(req, 0, &hashStr); err != nil {
+			return nil, err
+		}
+		if err := param(req, 1, &verbose); err != nil {
+			return nil, err
+		}
+		if err := param(req, 2, &verboseTx); err != nil {
+			return nil, err
+		}
+		hash, err := chainhash.NewHashFromStr(hashStr)
+		if err != nil {
+			return nil, newRPCError(errCodeInvalidParam, "invalid hash: %v", err)
+		}
+		b, height, ok := s.chain.BlockByHash(*hash)
+		if !ok {
+			return nil, newRPCError(errCodeNoData, "Block not found")
+		}
+		if req.Method == "getblockheader" {
+			return s.blockHeader(b, height, verbose)
+		}
+		if !verbose {
+			blockBytes, err := b.Bytes()
+			if err != nil {
+				return nil, newRPCError(errCodeNoData, err.Error())
+			}
+			return hex.EncodeToString(blockBytes), nil
+		}
+		res, err := s.blockVerboseResult(b, height, verboseTx)
+		if err != nil {
+			return nil, newRPCError(errCodeNoData, err.Error())
+		}
+		return res, nil
+
+	case "getrawtransaction":
+		var txid string
+		var verbose int
+		if err := param(req, 0, &txid); err != nil {
+			return nil, err
+		}
+		if err := param(req, 1, &verbose); err != nil {
+			return nil, err
+		}
+		hash, err := chainhash.NewHashFromStr(txid)
+		if err != nil {
+			return nil, newRPCError(errCodeInvalidParam, "invalid txid: %v", err)
+		}
+		tx, height, block, found := s.chain.Transaction(*hash)
+		if !found {
+			return nil, newRPCError(errCodeNoData, "No information available about transaction")
+		}
+		if verbose == 0 {
+			txBytes, err := tx.Bytes()
+			if err != nil {
+				return nil, newRPCError(errCodeNoData, err.Error())
+			}
+			return hex.EncodeToString(txBytes), nil
+		}
+		res, err := s.txRawResult(tx, height, block)
+		if err != nil {
+			return nil, newRPCError(errCodeNoData, err.Error())
+		}
+		return res, nil
+
+	case "searchrawtransactions":
+		return s.searchRawTransactions(req)
+
+	case "getrawmempool":
+		var verbose bool
+		if err := param(req, 0, &verbose); err != nil {
+			return nil, err
+		}
+		hashes := s.chain.MempoolTxns()
+		if verbose {
+			// Fees and priorities are not computed by the mock.
+			_, best := s.chain.Best()
+			res := make(map[string]dcrjson.GetRawMempoolVerboseResult, len(hashes))
+			for _, hash := range hashes {
+				tx, _, _, found := s.chain.Transaction(hash)
+				accepted, ok := s.chain.MempoolTime(hash)
+				if !found || !ok {
+					continue
+				}
+				res[hash.String()] = dcrjson.GetRawMempoolVerboseResult{
+					Size:    int32(tx.SerializeSize()),
+					Time:    accepted.Unix(),
+					Height:  best,
+					Depends: []string{},
+				}
+			}
+			return res, nil
+		}
+		txids := make([]string, len(hashes))
+		for i := range hashes {
+			txids[i] = hashes[i].String()
+		}
+		return txids, nil
+
+	case "gettxout":
+		var txid string
+		var vout uint32
+		includeMempool := true
+		for i, v := range []interface{}{&txid, &vout, &includeMempool} {
+			if err := param(req, i, v); err != nil {
+				return nil, err
+			}
+		}
+		hash, err := chainhash.NewHashFromStr(txid)
+		if err != nil {
+			return nil, newRPCError(errCodeInvalidParam, "invalid txid: %v", err)
+		}
+		// As with dcrd, a spent or unknown output gives a null result.
+		out, height, found := s.chain.TxOut(*hash, vout, includeMempool)
+		if !found {
+			return nil, nil
+		}
+		bestHash, _ := s.chain.Best()
+		tx, _, _, _ := s.chain.Transaction(*hash)
+		return &dcrjson.GetTxOutResult{
+			BestBlock:     bestHash.String(),
+			Confirmations: s.txConfirmations(height),
+			Value:         dcrutil.Amount(out.Value).ToCoin(),
+			ScriptPubKey:  s.scriptPubKeyResult(out),
+			Version:       int32(out.Version),
+			Coinbase:      isCoinBase(tx),
+		}, nil
+
+	case "sendrawtransaction":
+		// Transactions are accepted to the mempool without validation.
+		var txHex string
+		if err := param(req, 0, &txHex); err != nil {
+			return nil, err
+		}
+		txBytes, err := hex.DecodeString(txHex)
+		if err != nil {
+			return nil, newRPCError(errCodeDeserialize, "invalid transaction hex: %v", err)
+		}
+		tx := wire.NewMsgTx()
+		if err = tx.FromBytes(txBytes); err != nil {
+			return nil, newRPCError(errCodeDeserialize, "TX decode failed: %v", err)
+		}
+		hash := tx.TxHash()
+		if _, _, _, found := s.chain.Transaction(hash); found {
+			return nil, newRPCError(errCodeDuplicateTx, "already have transaction %v", hash)
+		}
+		if err = s.AcceptTx(tx); err != nil {
+			return nil, newRPCError(errCodeDeserialize, err.Error())
+		}
+		return hash.String(), nil
+
+	case "livetickets":
+		tickets := s.chain.LiveTickets()
+		res := &dcrjson.LiveTicketsResult{Tickets: make([]string, len(tickets))}
+		for i := range tickets {
+			res.Tickets[i] = tickets[i].String()
+		}
+		return res, nil
+
+	case "getstakedifficulty":
+		hash, _ := s.chain.Best()
+		b, _, _ := s.chain.BlockByHash(hash)
+		var sdiff float64
+		if b != nil {
+			sdiff = dcrutil.Amount(b.Header.SBits).ToCoin()
+		}
+		return &dcrjson.GetStakeDifficultyResult{
+			CurrentStakeDifficulty: sdiff,
+			NextStakeDifficulty:    sdiff,
+		}, nil
+
+	case "notifyblocks", "stopnotifyblocks", "notifynewtransactions",
+		"stopnotifynewtransactions", "notifywinningtickets":
+		if c == nil {
+			return nil, newRPCError(errCodeMethodNotFound,
+				"Method not found; websocket only: %s", req.Method)
+		}
+		var verbose bool
+		if err := param(req, 0, &verbose); err != nil {
+			return nil, err
+		}
+		c.regMtx.Lock()
+		switch req.Method {
+		case "notifyblocks":
+			c.blocks = true
+		case "stopnotifyblocks":
+			c.blocks = false
+		case "notifynewtransactions":
+			c.txns, c.txVerbose = true, verbose
+		case "stopnotifynewtransactions":
+			c.txns = false
+		case "notifywinningtickets":
+			c.winners = true
+		}
+		c.regMtx.Unlock()
+		return nil, nil
+	}
+
+	return nil, newRPCError(errCodeMethodNotFound, "Method not found: %s", req.Method)
+}
+
+func (s *Server) blockHeader(b *wire.MsgBlock, height int64, verbose bool) (interface{}, *rpcError) {
+	if !verbose {
+		headerBytes, err := b.Header.Bytes()
+		if err != nil {
+			return nil, newRPCError(errCodeNoData, err.Error())
+		}
+		return hex.EncodeToString(headerBytes), nil
+	}
+	_, best := s.chain.Best()
+	var next []string
+	if height >= 0 {
+		if nextHash, ok := s.chain.NextHash(height); ok {
+			next = append(next, nextHash.String())
+		}
+	}
+	res := rpcutils.BuildBlockHeaderVerbose(&b.Header, s.chain.Params(), best, next...)
+	res.Confirmations = best - int64(b.Header.Height) + 1
+	return res, nil
+}
+
+func (s *Server) searchRawTransactions(req *rpcRequest) (interface{}, *rpcError) {
+	var addr string
+	verbose, skip, count, vinExtra := 1, 0, 100, 0
+	var reverse bool
+	for i, v := range []interface{}{&addr, &verbose, &skip, &count, &vinExtra, &reverse} {
+		if err := param(req, i, v); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := dcrutil.DecodeAddress(addr); err != nil {
+		return nil, newRPCError(errCodeInvalidParam, "Invalid address: %v", err)
+	}
+
+	hashes := s.chain.AddressTransactions(addr)
+	if reverse {
+		for i, j := 0, len(hashes)-1; i < j; i, j = i+1, j-1 {
+			hashes[i], hashes[j] = hashes[j], hashes[i]
+		}
+	}
+	if skip > len(hashes) {
+		skip = len(hashes)
+	}
+	hashes = hashes[skip:]
+	if count < len(hashes) {
+		hashes = hashes[:count]
+	}
+	if len(hashes) == 0 {
+		return nil, newRPCError(errCodeNoData, "No information available about address")
+	}
+
+	if verbose == 0 {
+		hexTxns := make([]string, 0, len(hashes))
+		for _, h := range hashes {
+			tx, _, _, _ := s.chain.Transaction(h)
+			txBytes, err := tx.Bytes()
+			if err != nil {
+				return nil, newRPCError(errCodeNoData, err.Error())
+			}
+			hexTxns = append(hexTxns, hex.EncodeToString(txBytes))
+		}
+		return hexTxns, nil
+	}
+
+	results := make([]*dcrjson.SearchRawTransactionsResult, 0, len(hashes))
+	for _, h := range hashes {
+		tx, height, block, _ := s.chain.Transaction(h)
+		res, err := s.searchRawTxResult(tx, height, block, vinExtra != 0)
+		if err != nil {
+			return nil, newRPCError(errCodeNoData, err.Error())
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
@@ -0,0 +1,175 @@
+package mocknode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/rpcclient"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrdata/rpcutils"
+)
+
+const (
+	testUser = "user"
+	testPass = "pass"
+)
+
+func startServer(t *testing.T, numBlocks int) *Server {
+	chain, err := GenerateChain(&chaincfg.SimNetParams, numBlocks, nil)
+	if err != nil {
+		t.Fatalf("GenerateChain failed: %v", err)
+	}
+	s := NewServer(chain, testUser, testPass)
+	if err = s.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	return s
+}
+
+func TestChainQueries(t *testing.T) {
+	s := startServer(t, 20)
+	defer s.Stop()
+
+	client, _, err := rpcutils.ConnectNodeRPC(s.Addr(), testUser, testPass, "", true)
+	if err != nil {
+		t.Fatalf("ConnectNodeRPC failed: %v", err)
+	}
+	defer client.Shutdown()
+
+	hash, height, err := client.GetBestBlock()
+	if err != nil {
+		t.Fatalf("GetBestBlock failed: %v", err)
+	}
+	if height != 19 {
+		t.Errorf("expected best block height 19, got %d", height)
+	}
+
+	hash19, err := client.GetBlockHash(19)
+	if err != nil {
+		t.Fatalf("GetBlockHash failed: %v", err)
+	}
+	if *hash19 != *hash {
+		t.Errorf("GetBlockHash(19) = %v, expected %v", hash19, hash)
+	}
+
+	msgBlock, err := client.GetBlock(hash)
+	if err != nil {
+		t.Fatalf("GetBlock failed: %v", err)
+	}
+	if msgBlock.BlockHash() != *hash {
+		t.Errorf("GetBlock returned block %v, expected %v", msgBlock.BlockHash(), hash)
+	}
+
+	coinbase := msgBlock.Transactions[0].TxHash()
+	txRaw, err := client.GetRawTransactionVerbose(&coinbase)
+	if err != nil {
+		t.Fatalf("GetRawTransactionVerbose failed: %v", err)
+	}
+	if txRaw.BlockHeight != 19 || txRaw.Confirmations != 1 {
+		t.Errorf("unexpected height %d and confirmations %d",
+			txRaw.BlockHeight, txRaw.Confirmations)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	s := startServer(t, 10)
+	defer s.Stop()
+
+	connected := make(chan chainhash.Hash, 16)
+	reorgs := make(chan int32, 1)
+	accepted := make(chan string, 1)
+	ntfnHandlers := &rpcclient.NotificationHandlers{
+		OnBlockConnected: func(blockHeader []byte, transactions [][]byte) {
+			var header wire.BlockHeader
+			if err := header.FromBytes(blockHeader); err != nil {
+				t.Errorf("invalid block header: %v", err)
+				return
+			}
+			connected <- header.BlockHash()
+		},
+		OnReorganization: func(oldHash *chainhash.Hash, oldHeight int32,
+			newHash *chainhash.Hash, newHeight int32) {
+			reorgs <- newHeight
+		},
+		OnTxAccepted: func(hash *chainhash.Hash, amount dcrutil.Amount) {
+			accepted <- hash.String()
+		},
+	}
+
+	client, _, err := rpcutils.ConnectNodeRPC(s.Addr(), testUser, testPass, "",
+		true, ntfnHandlers)
+	if err != nil {
+		t.Fatalf("ConnectNodeRPC failed: %v", err)
+	}
+	defer client.Shutdown()
+
+	if err = client.NotifyBlocks(); err != nil {
+		t.Fatalf("NotifyBlocks failed: %v", err)
+	}
+	if err = client.NotifyNewTransactions(false); err != nil {
+		t.Fatalf("NotifyNewTransactions failed: %v", err)
+	}
+
+	// A mempool transaction is announced and then mined.
+	tx := wire.NewMsgTx()
+	tx.AddTxOut(wire.NewTxOut(1e8, nil))
+	if err = s.AcceptTx(tx); err != nil {
+		t.Fatalf("AcceptTx failed: %v", err)
+	}
+	select {
+	case txid := <-accepted:
+		if txid != tx.TxHash().String() {
+			t.Errorf("txaccepted for %s, expected %v", txid, tx.TxHash())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for txaccepted")
+	}
+
+	b, err := s.MineBlock()
+	if err != nil {
+		t.Fatalf("MineBlock failed: %v", err)
+	}
+	if len(b.Transactions) != 2 {
+		t.Errorf("expected mined block to include mempool tx")
+	}
+	waitConnected(t, connected, b.BlockHash())
+
+	// Reorganize away the last 3 blocks with a longer side chain.
+	side, err := s.Chain().SideChain(7, 4, nil, 1)
+	if err != nil {
+		t.Fatalf("SideChain failed: %v", err)
+	}
+	if err = s.Reorg(7, side); err != nil {
+		t.Fatalf("Reorg failed: %v", err)
+	}
+	select {
+	case newHeight := <-reorgs:
+		if newHeight != 11 {
+			t.Errorf("reorganization to height %d, expected 11", newHeight)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reorganization")
+	}
+	for _, b := range side {
+		waitConnected(t, connected, b.BlockHash())
+	}
+
+	// The reorged-out tx is back in mempool.
+	if len(s.Chain().MempoolTxns()) != 1 {
+		t.Errorf("expected detached tx to return to mempool")
+	}
+}
+
+func waitConnected(t *testing.T, connected chan chainhash.Hash, want chainhash.Hash) {
+	select {
+	case hash := <-connected:
+		if hash != want {
+			t.Errorf("blockconnected for %v, expected %v", hash, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for blockconnected %v", want)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package mocknode
+
+import (
+	"encoding/hex"
+	"strconv"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/txscript"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrdata/txhelpers"
+)
+
+// isCoinBase checks if the transaction's only input spends the null outpoint,
+// as a coinbase does.
+func isCoinBase(tx *wire.MsgTx) bool {
+	if len(tx.TxIn) != 1 {
+		return false
+	}
+	prevOut := &tx.TxIn[0].PreviousOutPoint
+	return prevOut.Index == wire.MaxPrevOutIndex &&
+		prevOut.Hash == (chainhash.Hash{})
+}
+
+// txConfirmations returns the confirmations for a transaction mined at the
+// given height, or 0 for mempool transactions (height -1).
+func (s *Server) txConfirmations(height int64) int64 {
+	if height < 0 {
+		return 0
+	}
+	_, best := s.chain.Best()
+	return best - height + 1
+}
+
+// blockTime returns the timestamp of the main chain block at height, or 0.
+func (s *Server) blockTime(height int64) int64 {
+	b, ok := s.chain.BlockByHeight(height)
+	if !ok {
+		return 0
+	}
+	return b.Header.Timestamp.Unix()
+}
+
+// scriptPubKeyResult decodes an output script as dcrd does for verbose
+// transaction results.
+func (s *Server) scriptPubKeyResult(out *wire.TxOut) dcrjson.ScriptPubKeyResult {
+	disbuf, _ := txscript.DisasmString(out.PkScript)
+	class, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(out.Version,
+		out.PkScript, s.chain.Params())
+	addresses := make([]string, len(addrs))
+	for i, a := range addrs {
+		addresses[i] = a.EncodeAddress()
+	}
+	return dcrjson.ScriptPubKeyResult{
+		Asm:       disbuf,
+		Hex:       hex.EncodeToString(out.PkScript),
+		ReqSigs:   int32(reqSigs),
+		Type:      class.String(),
+		Addresses: addresses,
+	}
+}
+
+func (s *Server) vouts(tx *wire.MsgTx) []dcrjson.Vout {
+	vouts := make([]dcrjson.Vout, len(tx.TxOut))
+	for i, out := range tx.TxOut {
+		vouts[i] = dcrjson.Vout{
+			Value:        dcrutil.Amount(out.Value).ToCoin(),
+			N:            uint32(i),
+			Version:      out.Version,
+			ScriptPubKey: s.scriptPubKeyResult(out),
+		}
+	}
+	return vouts
+}
+
+// txRawResult builds the verbose getrawtransaction result for tx.
+func (s *Server) txRawResult(tx *wire.MsgTx, height int64, block chainhash.Hash) (*dcrjson.TxRawResult, error) {
+	txBytes, err := tx.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	isCoinbase := isCoinBase(tx)
+	vins := make([]dcrjson.Vin, len(tx.TxIn))
+	for i, in := range tx.TxIn {
+		vin := dcrjson.Vin{
+			Sequence:    in.Sequence,
+			AmountIn:    dcrutil.Amount(in.ValueIn).ToCoin(),
+			BlockHeight: in.BlockHeight,
+			BlockIndex:  in.BlockIndex,
+		}
+		if isCoinbase {
+			vin.Coinbase = hex.EncodeToString(in.SignatureScript)
+		} else {
+			vin.Txid = in.PreviousOutPoint.Hash.String()
+			vin.Vout = in.PreviousOutPoint.Index
+			vin.Tree = in.PreviousOutPoint.Tree
+			disbuf, _ := txscript.DisasmString(in.SignatureScript)
+			vin.ScriptSig = &dcrjson.ScriptSig{
+				Asm: disbuf,
+				Hex: hex.EncodeToString(in.SignatureScript),
+			}
+		}
+		vins[i] = vin
+	}
+
+	res := &dcrjson.TxRawResult{
+		Hex:           hex.EncodeToString(txBytes),
+		Txid:          tx.TxHash().String(),
+		Version:       int32(tx.Version),
+		LockTime:      tx.LockTime,
+		Expiry:        tx.Expiry,
+		Vin:           vins,
+		Vout:          s.vouts(tx),
+		Confirmations: s.txConfirmations(height),
+	}
+	if height >= 0 {
+		res.BlockHash = block.String()
+		res.BlockHeight = height
+		res.Time = s.blockTime(height)
+		res.Blocktime = res.Time
+	}
+	return res, nil
+}
+
+// searchRawTxResult builds a searchrawtransactions result for tx, including
+// previous output details for the inputs when vinExtra is set.
+func (s *Server) searchRawTxResult(tx *wire.MsgTx, height int64, block chainhash.Hash,
+	vinExtra bool) (*dcrjson.SearchRawTransactionsResult, error) {
+	txBytes, err := tx.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	isCoinbase := isCoinBase(tx)
+	vins := make([]dcrjson.VinPrevOut, len(tx.TxIn))
+	for i, in := range tx.TxIn {
+		amtIn := dcrutil.Amount(in.ValueIn).ToCoin()
+		vin := dcrjson.VinPrevOut{
+			Sequence: in.Sequence,
+			AmountIn: &amtIn,
+		}
+		if isCoinbase {
+			vin.Coinbase = hex.EncodeToString(in.SignatureScript)
+			vins[i] = vin
+			continue
+		}
+		vin.Txid = in.PreviousOutPoint.Hash.String()
+		vin.Vout = in.PreviousOutPoint.Index
+		vin.Tree = in.PreviousOutPoint.Tree
+		if vinExtra {
+			prevTx, _, _, found := s.chain.Transaction(in.PreviousOutPoint.Hash)
+			if found && int(in.PreviousOutPoint.Index) < len(prevTx.TxOut) {
+				prevOut := prevTx.TxOut[in.PreviousOutPoint.Index]
+				vin.PrevOut = &dcrjson.PrevOut{
+					Addresses: s.scriptPubKeyResult(prevOut).Addresses,
+					Value:     dcrutil.Amount(prevOut.Value).ToCoin(),
+				}
+			}
+		}
+		vins[i] = vin
+	}
+
+	res := &dcrjson.SearchRawTransactionsResult{
+		Hex:           hex.EncodeToString(txBytes),
+		Txid:          tx.TxHash().String(),
+		Version:       int32(tx.Version),
+		LockTime:      tx.LockTime,
+		Vin:           vins,
+		Vout:          s.vouts(tx),
+		Confirmations: uint64(s.txConfirmations(height)),
+	}
+	if height >= 0 {
+		res.BlockHash = block.String()
+		res.Time = s.blockTime(height)
+		res.Blocktime = res.Time
+	}
+	return res, nil
+}
+
+// blockVerboseResult builds the verbose getblock result for a block.
+func (s *Server) blockVerboseResult(b *wire.MsgBlock, height int64, verboseTx bool) (*dcrjson.GetBlockVerboseResult, error) {
+	_, best := s.chain.Best()
+	var next string
+	if height >= 0 {
+		if nextHash, ok := s.chain.NextHash(height); ok {
+			next = nextHash.String()
+		}
+	}
+	header := &b.Header
+	confirmations := int64(-1)
+	if height >= 0 {
+		confirmations = best - height + 1
+	}
+
+	res := &dcrjson.GetBlockVerboseResult{
+		Hash:          b.BlockHash().String(),
+		Confirmations: confirmations,
+		Size:          int32(b.SerializeSize()),
+		Height:        int64(header.Height),
+		Version:       header.Version,
+		MerkleRoot:    header.MerkleRoot.String(),
+		StakeRoot:     header.StakeRoot.String(),
+		Time:          header.Timestamp.Unix(),
+		Nonce:         header.Nonce,
+		VoteBits:      header.VoteBits,
+		FinalState:    hex.EncodeToString(header.FinalState[:]),
+		Voters:        header.Voters,
+		FreshStake:    header.FreshStake,
+		Revocations:   header.Revocations,
+		PoolSize:      header.PoolSize,
+		Bits:          strconv.FormatInt(int64(header.Bits), 16),
+		SBits:         dcrutil.Amount(header.SBits).ToCoin(),
+		Difficulty:    txhelpers.GetDifficultyRatio(header.Bits, s.chain.Params()),
+		ExtraData:     hex.EncodeToString(header.ExtraData[:]),
+		StakeVersion:  header.StakeVersion,
+		PreviousHash:  header.PrevBlock.String(),
+		NextHash:      next,
+	}
+
+	hash := b.BlockHash()
+	for _, tx := range b.Transactions {
+		if !verboseTx {
+			res.Tx = append(res.Tx, tx.TxHash().String())
+			continue
+		}
+		txRes, err := s.txRawResult(tx, height, hash)
+		if err != nil {
+			return nil, err
+		}
+		res.RawTx = append(res.RawTx, *txRes)
+	}
+	for _, tx := range b.STransactions {
+		if !verboseTx {
+			res.STx = append(res.STx, tx.TxHash().String())
+			continue
+		}
+		txRes, err := s.txRawResult(tx, height, hash)
+		if err != nil {
+			return nil, err
+		}
+		res.RawSTx = append(res.RawSTx, *txRes)
+	}
+	return res, nil
+}
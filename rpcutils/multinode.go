@@ -0,0 +1,655 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package rpcutils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/rpcclient"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrdata/semver"
+)
+
+// NodeEndpoint describes how to connect to a dcrd RPC server.
+type NodeEndpoint struct {
+	Host       string
+	User       string
+	Pass       string
+	Cert       string
+	DisableTLS bool
+}
+
+// NodeHealth is the result of the most recent health check of a node.
+type NodeHealth struct {
+	Host      string    `json:"host"`
+	Active    bool      `json:"active"`
+	Connected bool      `json:"connected"`
+	Height    int64     `json:"height"`
+	Peers     int64     `json:"peers"`
+	LastCheck time.Time `json:"last_check"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// MultiNodeClient is a NodeClient that delegates to one of several dcrd
+// nodes. The nodes are periodically health checked (connection state, best
+// block height, and peer count), and the active node is switched when it is
+// disconnected, has no peers, or falls too far behind the best of the other
+// nodes. Notifications are only delivered from the active node.
+type MultiNodeClient struct {
+	mtx       sync.RWMutex
+	endpoints []NodeEndpoint
+	clients   []*rpcclient.Client
+	health    []NodeHealth
+	active    int
+	handlers  *rpcclient.NotificationHandlers
+
+	// MaxLag is the number of blocks a node may be behind the best node
+	// before it is considered stale.
+	MaxLag int64
+	// MinPeers is the fewest peers a node may have and still be considered
+	// healthy.
+	MinPeers int64
+	// CheckTimeout is how long the queries of a health check may take before
+	// the node is considered unhealthy.
+	CheckTimeout time.Duration
+	// Net is the network the nodes must be on. A node on another network is
+	// not healthy. The network is not checked if Net is zero.
+	Net wire.CurrencyNet
+
+	// checking flags the nodes with health check queries still pending.
+	checking []bool
+
+	registerNtfns   func(*rpcclient.Client) error
+	ntfnsEnabled    bool
+	ntfnsRegistered bool
+	onSwitch        []func(height int64)
+
+	checkNow chan struct{}
+}
+
+// Ensure MultiNodeClient satisfies NodeClient.
+var _ NodeClient = (*MultiNodeClient)(nil)
+
+// NewMultiNodeClient connects to each of the endpoints, which are given in
+// order of preference, and returns a MultiNodeClient using the first node that
+// connected. An error is returned only if no node could be connected. The
+// notification handlers are used for every node, but are only invoked for
+// notifications from the active node.
+func NewMultiNodeClient(endpoints []NodeEndpoint,
+	ntfnHandlers *rpcclient.NotificationHandlers) (*MultiNodeClient, semver.Semver, error) {
+	if len(endpoints) == 0 {
+		return nil, semver.Semver{}, fmt.Errorf("no dcrd endpoints specified")
+	}
+
+	m := &MultiNodeClient{
+		endpoints: endpoints,
+		clients:   make([]*rpcclient.Client, len(endpoints)),
+		health:    make([]NodeHealth, len(endpoints)),
+		checking:  make([]bool, len(endpoints)),
+		active:    -1,
+		handlers:  ntfnHandlers,
+		MaxLag:    2,
+		MinPeers:  1,
+		checkNow:  make(chan struct{}, 1),
+
+		CheckTimeout: 5 * time.Second,
+	}
+
+	var nodeVer semver.Semver
+	for i := range endpoints {
+		m.health[i].Host = endpoints[i].Host
+		ver, err := m.connect(i)
+		if err != nil {
+			log.Warnf("Unable to connect to dcrd at %s: %v", endpoints[i].Host, err)
+			m.health[i].Error = err.Error()
+			continue
+		}
+		if m.active < 0 {
+			m.active = i
+			m.health[i].Active = true
+			nodeVer = ver
+		}
+	}
+	if m.active < 0 {
+		return nil, nodeVer, fmt.Errorf("unable to connect to any of %d dcrd nodes",
+			len(endpoints))
+	}
+	log.Infof("Using dcrd at %s as the active node.", endpoints[m.active].Host)
+	return m, nodeVer, nil
+}
+
+// connect creates the RPC client for node i.
+func (m *MultiNodeClient) connect(i int) (semver.Semver, error) {
+	ep := &m.endpoints[i]
+	var handlers []*rpcclient.NotificationHandlers
+	if m.handlers != nil {
+		handlers = append(handlers, m.activeOnlyHandlers(i))
+	}
+	client, ver, err := ConnectNodeRPC(ep.Host, ep.User, ep.Pass, ep.Cert,
+		ep.DisableTLS, handlers...)
+	if err != nil {
+		return ver, err
+	}
+	m.mtx.Lock()
+	m.clients[i] = client
+	m.mtx.Unlock()
+	return ver, nil
+}
+
+func (m *MultiNodeClient) isActive(i int) bool {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.active == i
+}
+
+// activeOnlyHandlers wraps the notification handlers used by dcrdata so that
+// they ignore notifications unless node i is the active node.
+func (m *MultiNodeClient) activeOnlyHandlers(i int) *rpcclient.NotificationHandlers {
+	h := m.handlers
+	wrapped := new(rpcclient.NotificationHandlers)
+	if h.OnClientConnected != nil {
+		wrapped.OnClientConnected = func() {
+			if m.isActive(i) {
+				h.OnClientConnected()
+			}
+		}
+	}
+	if h.OnBlockConnected != nil {
+		wrapped.OnBlockConnected = func(blockHeader []byte, transactions [][]byte) {
+			if m.isActive(i) {
+				h.OnBlockConnected(blockHeader, transactions)
+			}
+		}
+	}
+	if h.OnBlockDisconnected != nil {
+		wrapped.OnBlockDisconnected = func(blockHeader []byte) {
+			if m.isActive(i) {
+				h.OnBlockDisconnected(blockHeader)
+			}
+		}
+	}
+	if h.OnReorganization != nil {
+		wrapped.OnReorganization = func(oldHash *chainhash.Hash, oldHeight int32,
+			newHash *chainhash.Hash, newHeight int32) {
+			if m.isActive(i) {
+				h.OnReorganization(oldHash, oldHeight, newHash, newHeight)
+			}
+		}
+	}
+	if h.OnWinningTickets != nil {
+		wrapped.OnWinningTickets = func(blockHash *chainhash.Hash, blockHeight int64,
+			tickets []*chainhash.Hash) {
+			if m.isActive(i) {
+				h.OnWinningTickets(blockHash, blockHeight, tickets)
+			}
+		}
+	}
+	if h.OnNewTickets != nil {
+		wrapped.OnNewTickets = func(hash *chainhash.Hash, height int64, stakeDiff int64,
+			tickets []*chainhash.Hash) {
+			if m.isActive(i) {
+				h.OnNewTickets(hash, height, stakeDiff, tickets)
+			}
+		}
+	}
+	if h.OnRelevantTxAccepted != nil {
+		wrapped.OnRelevantTxAccepted = func(transaction []byte) {
+			if m.isActive(i) {
+				h.OnRelevantTxAccepted(transaction)
+			}
+		}
+	}
+	if h.OnTxAccepted != nil {
+		wrapped.OnTxAccepted = func(hash *chainhash.Hash, amount dcrutil.Amount) {
+			if m.isActive(i) {
+				h.OnTxAccepted(hash, amount)
+			}
+		}
+	}
+	if h.OnTxAcceptedVerbose != nil {
+		wrapped.OnTxAcceptedVerbose = func(txDetails *dcrjson.TxRawResult) {
+			if m.isActive(i) {
+				h.OnTxAcceptedVerbose(txDetails)
+			}
+		}
+	}
+	return wrapped
+}
+
+// Active returns the RPC client of the active node.
+func (m *MultiNodeClient) Active() *rpcclient.Client {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.clients[m.active]
+}
+
+// ActiveHost returns the host of the active node.
+func (m *MultiNodeClient) ActiveHost() string {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.endpoints[m.active].Host
+}
+
+// Health returns the most recent health check results for all nodes.
+func (m *MultiNodeClient) Health() []NodeHealth {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	health := make([]NodeHealth, len(m.health))
+	copy(health, m.health)
+	return health
+}
+
+// OnSwitch registers a function to be called, with the new node's best block
+// height, after the active node has been switched and notifications have been
+// registered with it. These are used to reconcile the data stores with the
+// new node.
+func (m *MultiNodeClient) OnSwitch(f func(height int64)) {
+	m.mtx.Lock()
+	m.onSwitch = append(m.onSwitch, f)
+	m.mtx.Unlock()
+}
+
+// RegisterNotifications uses register to request notifications from the
+// active node, and from any node that later becomes active. If registration
+// fails, it is retried by the health checks until it succeeds.
+func (m *MultiNodeClient) RegisterNotifications(register func(*rpcclient.Client) error) error {
+	m.mtx.Lock()
+	m.registerNtfns = register
+	m.ntfnsEnabled = true
+	active := m.active
+	client := m.clients[active]
+	m.mtx.Unlock()
+
+	if err := register(client); err != nil {
+		return err
+	}
+	m.setRegistered(active)
+	return nil
+}
+
+// setRegistered records that notifications are registered with node i, unless
+// another node has since become active.
+func (m *MultiNodeClient) setRegistered(i int) {
+	m.mtx.Lock()
+	if m.active == i {
+		m.ntfnsRegistered = true
+	}
+	m.mtx.Unlock()
+}
+
+// Shutdown shuts down the RPC clients for all nodes.
+func (m *MultiNodeClient) Shutdown() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for _, c := range m.clients {
+		if c != nil {
+			c.Shutdown()
+		}
+	}
+}
+
+// Monitor health checks the nodes at the given interval, or immediately after
+// a request to the active node fails because it is disconnected, switching the
+// active node as needed. Monitor returns when quit is closed.
+func (m *MultiNodeClient) Monitor(interval time.Duration, quit chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-m.checkNow:
+		case <-quit:
+			return
+		}
+		m.CheckHealth()
+	}
+}
+
+// requestCheck asks Monitor to perform a health check as soon as possible.
+func (m *MultiNodeClient) requestCheck() {
+	select {
+	case m.checkNow <- struct{}{}:
+	default:
+	}
+}
+
+// checkErr requests a health check if err indicates the node is unreachable.
+func (m *MultiNodeClient) checkErr(err error) {
+	switch err {
+	case rpcclient.ErrClientDisconnect, rpcclient.ErrClientShutdown,
+		rpcclient.ErrClientNotConnected:
+		m.requestCheck()
+	}
+}
+
+// checkNode queries the connection state, height and peer count of node i,
+// attempting to reconnect a node that was never connected.
+func (m *MultiNodeClient) checkNode(i int) NodeHealth {
+	m.mtx.RLock()
+	client := m.clients[i]
+	m.mtx.RUnlock()
+
+	h := NodeHealth{
+		Host:      m.endpoints[i].Host,
+		LastCheck: time.Now(),
+	}
+	if client == nil {
+		if _, err := m.connect(i); err != nil {
+			h.Error = err.Error()
+			return h
+		}
+		m.mtx.RLock()
+		client = m.clients[i]
+		m.mtx.RUnlock()
+	}
+	if client.Disconnected() {
+		h.Error = "disconnected"
+		return h
+	}
+	h.Connected = true
+
+	var height, peers int64
+	err := m.withTimeout(i, func() (err error) {
+		if m.Net != 0 {
+			var net wire.CurrencyNet
+			if net, err = client.GetCurrentNet(); err != nil {
+				return err
+			}
+			if net != m.Net {
+				return fmt.Errorf("wrong network %v, expected %v", net, m.Net)
+			}
+		}
+		if _, height, err = client.GetBestBlock(); err != nil {
+			return err
+		}
+		peers, err = client.GetConnectionCount()
+		return err
+	})
+	if err != nil {
+		h.Error = err.Error()
+		return h
+	}
+	h.Height, h.Peers = height, peers
+	return h
+}
+
+// withTimeout runs the queries of a health check of node i, giving up after
+// CheckTimeout. A client queues its requests until it reconnects, so the
+// queries of a node that was just dropped would otherwise block until it is
+// back. While the queries of a previous check are still pending, the node is
+// not queried again, so at most one check per node is left waiting.
+func (m *MultiNodeClient) withTimeout(i int, queries func() error) error {
+	m.mtx.Lock()
+	if m.checking[i] {
+		m.mtx.Unlock()
+		return fmt.Errorf("previous health check still pending")
+	}
+	m.checking[i] = true
+	m.mtx.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		err := queries()
+		m.mtx.Lock()
+		m.checking[i] = false
+		m.mtx.Unlock()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(m.CheckTimeout):
+		return fmt.Errorf("timed out after %v", m.CheckTimeout)
+	}
+}
+
+func (m *MultiNodeClient) healthy(h *NodeHealth, bestHeight int64) bool {
+	return h.Connected && h.Error == "" && h.Peers >= m.MinPeers &&
+		h.Height >= bestHeight-m.MaxLag
+}
+
+// CheckHealth checks all nodes, and switches the active node if it is not
+// healthy and another node is. The most preferred healthy node with the
+// greatest height is chosen. Registering for notifications with the active
+// node is retried until it succeeds.
+func (m *MultiNodeClient) CheckHealth() {
+	health := make([]NodeHealth, len(m.endpoints))
+	bestHeight := int64(-1)
+	for i := range m.endpoints {
+		health[i] = m.checkNode(i)
+		if health[i].Connected && health[i].Error == "" && health[i].Height > bestHeight {
+			bestHeight = health[i].Height
+		}
+	}
+
+	m.mtx.Lock()
+	active := m.active
+	newActive := active
+	if !m.healthy(&health[active], bestHeight) {
+		for i := range health {
+			if m.healthy(&health[i], bestHeight) &&
+				(newActive == active || health[i].Height > health[newActive].Height) {
+				newActive = i
+			}
+		}
+	}
+	for i := range health {
+		health[i].Active = i == newActive
+	}
+	m.health = health
+	if newActive == active {
+		if !m.ntfnsEnabled || m.ntfnsRegistered {
+			m.mtx.Unlock()
+			return
+		}
+		log.Infof("Retrying registration for notifications with %s.",
+			m.endpoints[active].Host)
+	} else {
+		log.Warnf("Switching active dcrd node from %s (%s) to %s (height %d).",
+			m.endpoints[active].Host, healthSummary(&health[active]),
+			m.endpoints[newActive].Host, health[newActive].Height)
+		m.active = newActive
+		m.ntfnsRegistered = false
+	}
+	client := m.clients[newActive]
+	register, ntfnsEnabled := m.registerNtfns, m.ntfnsEnabled
+	onSwitch := make([]func(int64), len(m.onSwitch))
+	copy(onSwitch, m.onSwitch)
+	m.mtx.Unlock()
+
+	// The data stores are reconciled with the new node once notifications
+	// from it are registered.
+	if ntfnsEnabled {
+		if err := register(client); err != nil {
+			log.Errorf("Failed to register for notifications with %s: %v",
+				m.endpoints[newActive].Host, err)
+			return
+		}
+		m.setRegistered(newActive)
+	}
+
+	for _, f := range onSwitch {
+		f(health[newActive].Height)
+	}
+}
+
+func healthSummary(h *NodeHealth) string {
+	if h.Error != "" {
+		return h.Error
+	}
+	return fmt.Sprintf("height %d, %d peers", h.Height, h.Peers)
+}
+
+// GetCurrentNet returns the network of the active node.
+func (m *MultiNodeClient) GetCurrentNet() (wire.CurrencyNet, error) {
+	net, err := m.Active().GetCurrentNet()
+	m.checkErr(err)
+	return net, err
+}
+
+// The remaining methods implement NodeClient by delegating to the active node.
+
+func (m *MultiNodeClient) GetBestBlock() (*chainhash.Hash, int64, error) {
+	hash, height, err := m.Active().GetBestBlock()
+	m.checkErr(err)
+	return hash, height, err
+}
+
+func (m *MultiNodeClient) GetBestBlockHash() (*chainhash.Hash, error) {
+	hash, err := m.Active().GetBestBlockHash()
+	m.checkErr(err)
+	return hash, err
+}
+
+func (m *MultiNodeClient) GetBlockCount() (int64, error) {
+	count, err := m.Active().GetBlockCount()
+	m.checkErr(err)
+	return count, err
+}
+
+func (m *MultiNodeClient) GetConnectionCount() (int64, error) {
+	count, err := m.Active().GetConnectionCount()
+	m.checkErr(err)
+	return count, err
+}
+
+func (m *MultiNodeClient) GetCoinSupply() (dcrutil.Amount, error) {
+	supply, err := m.Active().GetCoinSupply()
+	m.checkErr(err)
+	return supply, err
+}
+
+func (m *MultiNodeClient) GetBlockSubsidy(height int64, voters uint16) (*dcrjson.GetBlockSubsidyResult, error) {
+	res, err := m.Active().GetBlockSubsidy(height, voters)
+	m.checkErr(err)
+	return res, err
+}
+
+func (m *MultiNodeClient) Ping() error {
+	err := m.Active().Ping()
+	m.checkErr(err)
+	return err
+}
+
+func (m *MultiNodeClient) GetBlockHash(blockHeight int64) (*chainhash.Hash, error) {
+	hash, err := m.Active().GetBlockHash(blockHeight)
+	m.checkErr(err)
+	return hash, err
+}
+
+func (m *MultiNodeClient) GetBlock(blockHash *chainhash.Hash) (*wire.MsgBlock, error) {
+	block, err := m.Active().GetBlock(blockHash)
+	m.checkErr(err)
+	return block, err
+}
+
+func (m *MultiNodeClient) GetBlockVerbose(blockHash *chainhash.Hash, verboseTx bool) (*dcrjson.GetBlockVerboseResult, error) {
+	res, err := m.Active().GetBlockVerbose(blockHash, verboseTx)
+	m.checkErr(err)
+	return res, err
+}
+
+func (m *MultiNodeClient) GetBlockHeader(blockHash *chainhash.Hash) (*wire.BlockHeader, error) {
+	header, err := m.Active().GetBlockHeader(blockHash)
+	m.checkErr(err)
+	return header, err
+}
+
+func (m *MultiNodeClient) GetBlockHeaderVerbose(blockHash *chainhash.Hash) (*dcrjson.GetBlockHeaderVerboseResult, error) {
+	res, err := m.Active().GetBlockHeaderVerbose(blockHash)
+	m.checkErr(err)
+	return res, err
+}
+
+func (m *MultiNodeClient) GetRawTransaction(txHash *chainhash.Hash) (*dcrutil.Tx, error) {
+	tx, err := m.Active().GetRawTransaction(txHash)
+	m.checkErr(err)
+	return tx, err
+}
+
+// GetRawTransactionAsync allows stakedb to make concurrent requests to the
+// active node.
+func (m *MultiNodeClient) GetRawTransactionAsync(txHash *chainhash.Hash) rpcclient.FutureGetRawTransactionResult {
+	return m.Active().GetRawTransactionAsync(txHash)
+}
+
+func (m *MultiNodeClient) GetRawTransactionVerbose(txHash *chainhash.Hash) (*dcrjson.TxRawResult, error) {
+	res, err := m.Active().GetRawTransactionVerbose(txHash)
+	m.checkErr(err)
+	return res, err
+}
+
+func (m *MultiNodeClient) GetTxOut(txHash *chainhash.Hash, index uint32, mempool bool) (*dcrjson.GetTxOutResult, error) {
+	res, err := m.Active().GetTxOut(txHash, index, mempool)
+	m.checkErr(err)
+	return res, err
+}
+
+func (m *MultiNodeClient) DecodeRawTransaction(serializedTx []byte) (*dcrjson.TxRawResult, error) {
+	res, err := m.Active().DecodeRawTransaction(serializedTx)
+	m.checkErr(err)
+	return res, err
+}
+
+func (m *MultiNodeClient) SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainhash.Hash, error) {
+	hash, err := m.Active().SendRawTransaction(tx, allowHighFees)
+	m.checkErr(err)
+	return hash, err
+}
+
+func (m *MultiNodeClient) SearchRawTransactionsVerbose(address dcrutil.Address, skip, count int,
+	includePrevOut, reverse bool, filterAddrs []string) ([]*dcrjson.SearchRawTransactionsResult, error) {
+	res, err := m.Active().SearchRawTransactionsVerbose(address, skip, count,
+		includePrevOut, reverse, filterAddrs)
+	m.checkErr(err)
+	return res, err
+}
+
+func (m *MultiNodeClient) GetRawMempoolVerbose(txType dcrjson.GetRawMempoolTxTypeCmd) (map[string]dcrjson.GetRawMempoolVerboseResult, error) {
+	res, err := m.Active().GetRawMempoolVerbose(txType)
+	m.checkErr(err)
+	return res, err
+}
+
+func (m *MultiNodeClient) LiveTickets() ([]*chainhash.Hash, error) {
+	tickets, err := m.Active().LiveTickets()
+	m.checkErr(err)
+	return tickets, err
+}
+
+func (m *MultiNodeClient) GetStakeDifficulty() (*dcrjson.GetStakeDifficultyResult, error) {
+	res, err := m.Active().GetStakeDifficulty()
+	m.checkErr(err)
+	return res, err
+}
+
+func (m *MultiNodeClient) EstimateStakeDiff(tickets *uint32) (*dcrjson.EstimateStakeDiffResult, error) {
+	res, err := m.Active().EstimateStakeDiff(tickets)
+	m.checkErr(err)
+	return res, err
+}
+
+func (m *MultiNodeClient) TicketFeeInfo(blocks *uint32, windows *uint32) (*dcrjson.TicketFeeInfoResult, error) {
+	res, err := m.Active().TicketFeeInfo(blocks, windows)
+	m.checkErr(err)
+	return res, err
+}
+
+func (m *MultiNodeClient) GetVoteInfo(version uint32) (*dcrjson.GetVoteInfoResult, error) {
+	res, err := m.Active().GetVoteInfo(version)
+	m.checkErr(err)
+	return res, err
+}
+
+func (m *MultiNodeClient) GetStakeVersions(hash string, count int32) (*dcrjson.GetStakeVersionsResult, error) {
+	res, err := m.Active().GetStakeVersions(hash, count)
+	m.checkErr(err)
+	return res, err
+}
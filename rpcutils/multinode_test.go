@@ -0,0 +1,238 @@
+package rpcutils_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/rpcclient"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrdata/rpcutils"
+	"github.com/decred/dcrdata/rpcutils/mocknode"
+)
+
+// startNodes starts n mock nodes serving identical chains.
+func startNodes(t *testing.T, n, numBlocks int) []*mocknode.Server {
+	chain, err := mocknode.GenerateChain(&chaincfg.SimNetParams, numBlocks, nil)
+	if err != nil {
+		t.Fatalf("GenerateChain failed: %v", err)
+	}
+	blocks := make([]*wire.MsgBlock, numBlocks)
+	for i := range blocks {
+		blocks[i], _ = chain.BlockByHeight(int64(i))
+	}
+
+	servers := make([]*mocknode.Server, n)
+	for i := range servers {
+		c, err := mocknode.NewChain(&chaincfg.SimNetParams, blocks)
+		if err != nil {
+			t.Fatalf("NewChain failed: %v", err)
+		}
+		servers[i] = mocknode.NewServer(c, "user", "pass")
+		if err = servers[i].Start("127.0.0.1:0"); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+	}
+	return servers
+}
+
+func endpoints(servers []*mocknode.Server) []rpcutils.NodeEndpoint {
+	eps := make([]rpcutils.NodeEndpoint, len(servers))
+	for i, s := range servers {
+		eps[i] = rpcutils.NodeEndpoint{
+			Host:       s.Addr(),
+			User:       "user",
+			Pass:       "pass",
+			DisableTLS: true,
+		}
+	}
+	return eps
+}
+
+func TestMultiNodeFailoverOnDisconnect(t *testing.T) {
+	servers := startNodes(t, 2, 10)
+	defer servers[1].Stop()
+
+	client, _, err := rpcutils.NewMultiNodeClient(endpoints(servers), nil)
+	if err != nil {
+		t.Fatalf("NewMultiNodeClient failed: %v", err)
+	}
+	defer client.Shutdown()
+
+	if client.ActiveHost() != servers[0].Addr() {
+		t.Fatalf("expected first node to be active")
+	}
+
+	switched := make(chan int64, 1)
+	client.OnSwitch(func(height int64) { switched <- height })
+
+	servers[0].Stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for client.ActiveHost() != servers[1].Addr() && time.Now().Before(deadline) {
+		client.CheckHealth()
+		time.Sleep(50 * time.Millisecond)
+	}
+	if client.ActiveHost() != servers[1].Addr() {
+		t.Fatalf("failed to switch to backup node")
+	}
+
+	select {
+	case height := <-switched:
+		if height != 9 {
+			t.Errorf("switch callback got height %d, expected 9", height)
+		}
+	default:
+		t.Errorf("switch callback not called")
+	}
+
+	if _, height, err := client.GetBestBlock(); err != nil || height != 9 {
+		t.Errorf("GetBestBlock via backup node: height %d, err %v", height, err)
+	}
+}
+
+func TestMultiNodeFailoverOnLag(t *testing.T) {
+	servers := startNodes(t, 2, 10)
+	defer servers[0].Stop()
+	defer servers[1].Stop()
+
+	client, _, err := rpcutils.NewMultiNodeClient(endpoints(servers), nil)
+	if err != nil {
+		t.Fatalf("NewMultiNodeClient failed: %v", err)
+	}
+	defer client.Shutdown()
+
+	// Within the allowed lag, the preferred node stays active.
+	for i := int64(0); i < client.MaxLag; i++ {
+		if _, err = servers[1].MineBlock(); err != nil {
+			t.Fatalf("MineBlock failed: %v", err)
+		}
+	}
+	client.CheckHealth()
+	if client.ActiveHost() != servers[0].Addr() {
+		t.Fatalf("switched nodes within allowed lag")
+	}
+
+	// One more block and the first node is stale.
+	if _, err = servers[1].MineBlock(); err != nil {
+		t.Fatalf("MineBlock failed: %v", err)
+	}
+	client.CheckHealth()
+	if client.ActiveHost() != servers[1].Addr() {
+		t.Fatalf("failed to switch away from stale node")
+	}
+
+	// A node without peers is not healthy.
+	servers[1].SetConnectionCount(0)
+	for i := 0; i < 3; i++ {
+		if _, err = servers[0].MineBlock(); err != nil {
+			t.Fatalf("MineBlock failed: %v", err)
+		}
+	}
+	client.CheckHealth()
+	if client.ActiveHost() != servers[0].Addr() {
+		t.Fatalf("failed to switch away from node without peers")
+	}
+	for _, h := range client.Health() {
+		if h.Active != (h.Host == servers[0].Addr()) {
+			t.Errorf("unexpected Active flag in health of %s", h.Host)
+		}
+	}
+}
+
+func TestMultiNodeRegistrationRetry(t *testing.T) {
+	servers := startNodes(t, 2, 10)
+	defer servers[1].Stop()
+
+	client, _, err := rpcutils.NewMultiNodeClient(endpoints(servers), nil)
+	if err != nil {
+		t.Fatalf("NewMultiNodeClient failed: %v", err)
+	}
+	defer client.Shutdown()
+
+	// Registration fails the first time with the backup node.
+	var registrations int
+	err = client.RegisterNotifications(func(c *rpcclient.Client) error {
+		registrations++
+		if registrations == 2 {
+			return fmt.Errorf("registration failed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterNotifications failed: %v", err)
+	}
+	switched := make(chan int64, 2)
+	client.OnSwitch(func(height int64) { switched <- height })
+
+	servers[0].Stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for client.ActiveHost() != servers[1].Addr() && time.Now().Before(deadline) {
+		client.CheckHealth()
+		time.Sleep(50 * time.Millisecond)
+	}
+	if client.ActiveHost() != servers[1].Addr() {
+		t.Fatalf("failed to switch to backup node")
+	}
+	if registrations != 2 {
+		t.Fatalf("expected 2 registrations, got %d", registrations)
+	}
+	select {
+	case <-switched:
+		t.Fatalf("switch callback called before notifications were registered")
+	default:
+	}
+
+	// The next check retries the registration with the healthy active node.
+	client.CheckHealth()
+	if registrations != 3 {
+		t.Fatalf("expected 3 registrations, got %d", registrations)
+	}
+	select {
+	case height := <-switched:
+		if height != 9 {
+			t.Errorf("switch callback got height %d, expected 9", height)
+		}
+	default:
+		t.Errorf("switch callback not called after registration")
+	}
+
+	// Once registered, notifications are not registered again.
+	client.CheckHealth()
+	if registrations != 3 {
+		t.Errorf("expected 3 registrations, got %d", registrations)
+	}
+}
+
+func TestMultiNodeWrongNetwork(t *testing.T) {
+	servers := startNodes(t, 2, 10)
+	defer servers[0].Stop()
+	defer servers[1].Stop()
+
+	client, _, err := rpcutils.NewMultiNodeClient(endpoints(servers), nil)
+	if err != nil {
+		t.Fatalf("NewMultiNodeClient failed: %v", err)
+	}
+	defer client.Shutdown()
+	client.Net = chaincfg.SimNetParams.Net
+
+	// The backup node is ahead, but on another network.
+	servers[1].SetNet(chaincfg.TestNet2Params.Net)
+	for i := int64(0); i <= client.MaxLag; i++ {
+		if _, err = servers[1].MineBlock(); err != nil {
+			t.Fatalf("MineBlock failed: %v", err)
+		}
+	}
+	client.CheckHealth()
+	if client.ActiveHost() != servers[0].Addr() {
+		t.Fatalf("switched to a node on the wrong network")
+	}
+	health := client.Health()
+	if health[1].Error == "" {
+		t.Errorf("no error in the health of the node on the wrong network")
+	}
+	if health[0].Error != "" {
+		t.Errorf("unexpected error in the health of the active node: %s",
+			health[0].Error)
+	}
+}
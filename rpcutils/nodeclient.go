@@ -0,0 +1,55 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package rpcutils
+
+import (
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/rpcclient"
+	"github.com/decred/dcrd/wire"
+)
+
+// NodeClient is the set of chain server RPCs used by dcrdata's data
+// collectors, databases and APIs. It is satisfied by *rpcclient.Client, and
+// may be implemented by caching clients, multi-node failover clients, or test
+// doubles.
+type NodeClient interface {
+	// Chain state
+	GetBestBlock() (*chainhash.Hash, int64, error)
+	GetBestBlockHash() (*chainhash.Hash, error)
+	GetBlockCount() (int64, error)
+	GetConnectionCount() (int64, error)
+	GetCoinSupply() (dcrutil.Amount, error)
+	GetBlockSubsidy(height int64, voters uint16) (*dcrjson.GetBlockSubsidyResult, error)
+	Ping() error
+
+	// Blocks and headers
+	GetBlockHash(blockHeight int64) (*chainhash.Hash, error)
+	GetBlock(blockHash *chainhash.Hash) (*wire.MsgBlock, error)
+	GetBlockVerbose(blockHash *chainhash.Hash, verboseTx bool) (*dcrjson.GetBlockVerboseResult, error)
+	GetBlockHeader(blockHash *chainhash.Hash) (*wire.BlockHeader, error)
+	GetBlockHeaderVerbose(blockHash *chainhash.Hash) (*dcrjson.GetBlockHeaderVerboseResult, error)
+
+	// Transactions
+	GetRawTransaction(txHash *chainhash.Hash) (*dcrutil.Tx, error)
+	GetRawTransactionVerbose(txHash *chainhash.Hash) (*dcrjson.TxRawResult, error)
+	GetTxOut(txHash *chainhash.Hash, index uint32, mempool bool) (*dcrjson.GetTxOutResult, error)
+	DecodeRawTransaction(serializedTx []byte) (*dcrjson.TxRawResult, error)
+	SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainhash.Hash, error)
+	SearchRawTransactionsVerbose(address dcrutil.Address, skip, count int,
+		includePrevOut, reverse bool, filterAddrs []string) ([]*dcrjson.SearchRawTransactionsResult, error)
+	GetRawMempoolVerbose(txType dcrjson.GetRawMempoolTxTypeCmd) (map[string]dcrjson.GetRawMempoolVerboseResult, error)
+
+	// Staking
+	LiveTickets() ([]*chainhash.Hash, error)
+	GetStakeDifficulty() (*dcrjson.GetStakeDifficultyResult, error)
+	EstimateStakeDiff(tickets *uint32) (*dcrjson.EstimateStakeDiffResult, error)
+	TicketFeeInfo(blocks *uint32, windows *uint32) (*dcrjson.TicketFeeInfoResult, error)
+	GetVoteInfo(version uint32) (*dcrjson.GetVoteInfoResult, error)
+	GetStakeVersions(hash string, count int32) (*dcrjson.GetStakeVersionsResult, error)
+}
+
+// Ensure rpcclient.Client satisfies NodeClient.
+var _ NodeClient = (*rpcclient.Client)(nil)
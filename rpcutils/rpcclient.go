@@ -128,7 +128,7 @@ func BuildBlockHeaderVerbose(header *wire.BlockHeader, params *chaincfg.Params,
 
 // GetBlockHeaderVerbose creates a *dcrjson.GetBlockHeaderVerboseResult for the
 // block index specified by idx via an RPC connection to a chain server.
-func GetBlockHeaderVerbose(client *rpcclient.Client, params *chaincfg.Params,
+func GetBlockHeaderVerbose(client NodeClient, params *chaincfg.Params,
 	idx int64) *dcrjson.GetBlockHeaderVerboseResult {
 	blockhash, err := client.GetBlockHash(idx)
 	if err != nil {
@@ -147,7 +147,7 @@ func GetBlockHeaderVerbose(client *rpcclient.Client, params *chaincfg.Params,
 
 // GetBlockVerbose creates a *dcrjson.GetBlockVerboseResult for the block index
 // specified by idx via an RPC connection to a chain server.
-func GetBlockVerbose(client *rpcclient.Client, params *chaincfg.Params,
+func GetBlockVerbose(client NodeClient, params *chaincfg.Params,
 	idx int64, verboseTx bool) *dcrjson.GetBlockVerboseResult {
 	blockhash, err := client.GetBlockHash(idx)
 	if err != nil {
@@ -166,7 +166,7 @@ func GetBlockVerbose(client *rpcclient.Client, params *chaincfg.Params,
 
 // GetBlockVerboseByHash creates a *dcrjson.GetBlockVerboseResult for the
 // specified block hash via an RPC connection to a chain server.
-func GetBlockVerboseByHash(client *rpcclient.Client, params *chaincfg.Params,
+func GetBlockVerboseByHash(client NodeClient, params *chaincfg.Params,
 	hash string, verboseTx bool) *dcrjson.GetBlockVerboseResult {
 	blockhash, err := chainhash.NewHashFromStr(hash)
 	if err != nil {
@@ -185,7 +185,7 @@ func GetBlockVerboseByHash(client *rpcclient.Client, params *chaincfg.Params,
 
 // GetStakeDiffEstimates combines the results of EstimateStakeDiff and
 // GetStakeDifficulty into a *apitypes.StakeDiff.
-func GetStakeDiffEstimates(client *rpcclient.Client) *apitypes.StakeDiff {
+func GetStakeDiffEstimates(client NodeClient) *apitypes.StakeDiff {
 	stakeDiff, err := client.GetStakeDifficulty()
 	if err != nil {
 		return nil
@@ -205,7 +205,7 @@ func GetStakeDiffEstimates(client *rpcclient.Client) *apitypes.StakeDiff {
 }
 
 // GetBlock gets a block at the given height from a chain server.
-func GetBlock(ind int64, client *rpcclient.Client) (*dcrutil.Block, *chainhash.Hash, error) {
+func GetBlock(ind int64, client NodeClient) (*dcrutil.Block, *chainhash.Hash, error) {
 	blockhash, err := client.GetBlockHash(ind)
 	if err != nil {
 		return nil, nil, fmt.Errorf("GetBlockHash(%d) failed: %v", ind, err)
@@ -222,7 +222,7 @@ func GetBlock(ind int64, client *rpcclient.Client) (*dcrutil.Block, *chainhash.H
 }
 
 // GetBlockByHash gets the block with the given hash from a chain server.
-func GetBlockByHash(blockhash *chainhash.Hash, client *rpcclient.Client) (*dcrutil.Block, error) {
+func GetBlockByHash(blockhash *chainhash.Hash, client NodeClient) (*dcrutil.Block, error) {
 	msgBlock, err := client.GetBlock(blockhash)
 	if err != nil {
 		return nil, fmt.Errorf("GetBlock failed (%s): %v", blockhash, err)
@@ -233,7 +233,7 @@ func GetBlockByHash(blockhash *chainhash.Hash, client *rpcclient.Client) (*dcrut
 }
 
 // GetTransactionVerboseByID get a transaction by transaction id
-func GetTransactionVerboseByID(client *rpcclient.Client, txid string) (*dcrjson.TxRawResult, error) {
+func GetTransactionVerboseByID(client NodeClient, txid string) (*dcrjson.TxRawResult, error) {
 	txhash, err := chainhash.NewHashFromStr(txid)
 	if err != nil {
 		log.Errorf("Invalid transaction hash %s", txid)
@@ -250,7 +250,7 @@ func GetTransactionVerboseByID(client *rpcclient.Client, txid string) (*dcrjson.
 
 // SearchRawTransaction fetch transactions the belong to an
 // address
-func SearchRawTransaction(client *rpcclient.Client, count int, address string) ([]*dcrjson.SearchRawTransactionsResult, error) {
+func SearchRawTransaction(client NodeClient, count int, address string) ([]*dcrjson.SearchRawTransactionsResult, error) {
 	addr, err := dcrutil.DecodeAddress(address)
 	if err != nil {
 		log.Infof("Invalid address %s: %v", address, err)
@@ -0,0 +1,483 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package stakedb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+)
+
+// The ticket pool diff store is a directory with three kinds of files, each
+// starting with a 4-byte magic and a 4-byte version:
+//
+//	diffs.dat        Append-only pool diff records, one per block from height 1.
+//	                 A record is the number of tickets in and out (uint32 each),
+//	                 followed by the 32-byte hashes of the tickets in, then out.
+//	diffs.idx        The offset (uint64) in diffs.dat of the record for each
+//	                 height, making the height of a diff its index.
+//	snap_<height>    A snapshot of the live pool at <height>: the number of
+//	                 tickets (uint32) followed by their 32-byte hashes.
+//
+// All integers are little endian. A record is written and synced to diffs.dat
+// before its offset is written to diffs.idx, so a partially written diff is
+// detected and discarded when the store is opened.
+const (
+	poolDiffDataFile      = "diffs.dat"
+	poolDiffIndexFile     = "diffs.idx"
+	poolSnapshotPrefix    = "snap_"
+	poolDiffStoreVersion  = 1
+	poolDiffFileHeaderLen = 8
+	poolDiffRecordHdrLen  = 8
+)
+
+var (
+	poolDiffDataMagic     = [4]byte{'T', 'P', 'D', 'D'}
+	poolDiffIndexMagic    = [4]byte{'T', 'P', 'D', 'I'}
+	poolDiffSnapshotMagic = [4]byte{'T', 'P', 'D', 'S'}
+)
+
+// poolDiffStore is the on-disk storage of a TicketPool's diffs and snapshots.
+// Only the index of diff offsets is kept in memory. Diffs are read from disk as
+// they are needed. It is not safe for concurrent use.
+type poolDiffStore struct {
+	dir       string
+	data      *os.File
+	index     *os.File
+	offsets   []int64
+	dataEnd   int64
+	snapshots []int64
+}
+
+func fileHeader(magic [4]byte) []byte {
+	hdr := make([]byte, poolDiffFileHeaderLen)
+	copy(hdr, magic[:])
+	binary.LittleEndian.PutUint32(hdr[4:], poolDiffStoreVersion)
+	return hdr
+}
+
+// openStoreFile opens or creates a store file, writing the header to a new
+// file and checking the header of an existing one. The file size is returned.
+func openStoreFile(path string, magic [4]byte) (*os.File, int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, 0, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	hdr := fileHeader(magic)
+	if fi.Size() < poolDiffFileHeaderLen {
+		if _, err = f.WriteAt(hdr, 0); err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, poolDiffFileHeaderLen, nil
+	}
+	existing := make([]byte, poolDiffFileHeaderLen)
+	if _, err = f.ReadAt(existing, 0); err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	if !bytes.Equal(existing, hdr) {
+		f.Close()
+		return nil, 0, fmt.Errorf("%s is not a version %d ticket pool file",
+			path, poolDiffStoreVersion)
+	}
+	return f, fi.Size(), nil
+}
+
+// openPoolDiffStore opens the store in dir, creating it if needed. Diffs that
+// were not completely written, and snapshots above the last diff, are removed.
+func openPoolDiffStore(dir string) (*poolDiffStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	data, dataSize, err := openStoreFile(filepath.Join(dir, poolDiffDataFile),
+		poolDiffDataMagic)
+	if err != nil {
+		return nil, err
+	}
+	index, _, err := openStoreFile(filepath.Join(dir, poolDiffIndexFile),
+		poolDiffIndexMagic)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+
+	s := &poolDiffStore{
+		dir:   dir,
+		data:  data,
+		index: index,
+	}
+	if err = s.load(dataSize); err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the index, drops entries for records that are not complete in
+// the data file, truncates both files after the last complete record, and
+// finds the stored snapshots.
+func (s *poolDiffStore) load(dataSize int64) error {
+	if _, err := s.index.Seek(poolDiffFileHeaderLen, io.SeekStart); err != nil {
+		return err
+	}
+	indexBytes, err := ioutil.ReadAll(s.index)
+	if err != nil {
+		return err
+	}
+	s.offsets = make([]int64, len(indexBytes)/8)
+	for i := range s.offsets {
+		s.offsets[i] = int64(binary.LittleEndian.Uint64(indexBytes[8*i:]))
+	}
+
+	// The offsets must increase from the start of the data. Only the records
+	// at the end may not have been completely written, since each is synced
+	// before its index entry is written, so only the last record is read. It
+	// must start where the previous one ends, and be complete with no zero
+	// ticket hashes. Otherwise it is discarded, along with any index entries
+	// that were not completely written, and the new last record is checked.
+	for i := range s.offsets {
+		if (i == 0 && s.offsets[i] != poolDiffFileHeaderLen) ||
+			(i > 0 && s.offsets[i] <= s.offsets[i-1]) {
+			log.Warnf("Discarding %d ticket pool diffs from height %d: offset "+
+				"%d out of order", len(s.offsets)-i, i+1, s.offsets[i])
+			s.offsets = s.offsets[:i]
+			break
+		}
+	}
+	s.dataEnd = poolDiffFileHeaderLen
+	for i := len(s.offsets) - 1; i >= 0; i-- {
+		end, err := s.checkTail(i, dataSize)
+		if err == nil {
+			s.dataEnd = end
+			break
+		}
+		log.Warnf("Discarding incomplete ticket pool diff at height %d: %v",
+			i+1, err)
+		s.offsets = s.offsets[:i]
+	}
+	if err = s.truncateFiles(); err != nil {
+		return err
+	}
+
+	// Find the snapshots, removing those above the tip.
+	names, err := filepath.Glob(filepath.Join(s.dir, poolSnapshotPrefix+"*"))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		height, err := strconv.ParseInt(strings.TrimPrefix(filepath.Base(name),
+			poolSnapshotPrefix), 10, 64)
+		if err != nil {
+			continue
+		}
+		if height > s.tip() {
+			if err = os.Remove(name); err != nil {
+				return err
+			}
+			continue
+		}
+		s.snapshots = append(s.snapshots, height)
+	}
+	sort.Slice(s.snapshots, func(i, j int) bool {
+		return s.snapshots[i] < s.snapshots[j]
+	})
+	return nil
+}
+
+// checkTail checks the diff record at index i, the last in the offsets slice,
+// and returns the offset following it.
+func (s *poolDiffStore) checkTail(i int, dataSize int64) (int64, error) {
+	if i > 0 {
+		prevEnd, err := s.recordEnd(s.offsets[i-1], dataSize)
+		if err != nil {
+			return 0, err
+		}
+		if s.offsets[i] != prevEnd {
+			return 0, fmt.Errorf("offset %d, expected %d", s.offsets[i], prevEnd)
+		}
+	}
+	end, err := s.recordEnd(s.offsets[i], dataSize)
+	if err != nil {
+		return 0, err
+	}
+	if err = checkRecord(io.NewSectionReader(s.data, s.offsets[i],
+		end-s.offsets[i])); err != nil {
+		return 0, err
+	}
+	return end, nil
+}
+
+// recordEnd returns the offset following the diff record at offset, checking
+// that the record ends within dataSize.
+func (s *poolDiffStore) recordEnd(offset, dataSize int64) (int64, error) {
+	if offset < poolDiffFileHeaderLen || offset+poolDiffRecordHdrLen > dataSize {
+		return 0, fmt.Errorf("offset %d outside of data", offset)
+	}
+	var hdr [poolDiffRecordHdrLen]byte
+	if _, err := s.data.ReadAt(hdr[:], offset); err != nil {
+		return 0, err
+	}
+	numIn := int64(binary.LittleEndian.Uint32(hdr[:4]))
+	numOut := int64(binary.LittleEndian.Uint32(hdr[4:]))
+	end := offset + poolDiffRecordHdrLen + chainhash.HashSize*(numIn+numOut)
+	if end > dataSize {
+		return 0, fmt.Errorf("record at offset %d ends beyond data", offset)
+	}
+	return end, nil
+}
+
+// checkRecord reads the next diff record from r, checking that none of its
+// ticket hashes are zero, as they are in a record that was not written.
+func checkRecord(r io.Reader) error {
+	diff, err := readDiff(r)
+	if err != nil {
+		return err
+	}
+	var zeroHash chainhash.Hash
+	for _, hashes := range [][]chainhash.Hash{diff.In, diff.Out} {
+		for i := range hashes {
+			if hashes[i] == zeroHash {
+				return fmt.Errorf("zero ticket hash")
+			}
+		}
+	}
+	return nil
+}
+
+// truncateFiles truncates the data and index files to the end of the diffs
+// in the offsets slice.
+func (s *poolDiffStore) truncateFiles() error {
+	if err := s.data.Truncate(s.dataEnd); err != nil {
+		return err
+	}
+	return s.index.Truncate(poolDiffFileHeaderLen + 8*int64(len(s.offsets)))
+}
+
+// tip returns the height of the last stored diff.
+func (s *poolDiffStore) tip() int64 {
+	return int64(len(s.offsets))
+}
+
+// encodeDiff serializes a pool diff record.
+func encodeDiff(diff *PoolDiff) []byte {
+	rec := make([]byte, poolDiffRecordHdrLen,
+		poolDiffRecordHdrLen+chainhash.HashSize*(len(diff.In)+len(diff.Out)))
+	binary.LittleEndian.PutUint32(rec[:4], uint32(len(diff.In)))
+	binary.LittleEndian.PutUint32(rec[4:], uint32(len(diff.Out)))
+	for i := range diff.In {
+		rec = append(rec, diff.In[i][:]...)
+	}
+	for i := range diff.Out {
+		rec = append(rec, diff.Out[i][:]...)
+	}
+	return rec
+}
+
+// readHashes reads n 32-byte hashes from r.
+func readHashes(r io.Reader, n uint32) ([]chainhash.Hash, error) {
+	hashes := make([]chainhash.Hash, n)
+	for i := range hashes {
+		if _, err := io.ReadFull(r, hashes[i][:]); err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
+
+// readDiff reads a pool diff record from r.
+func readDiff(r io.Reader) (*PoolDiff, error) {
+	var hdr [poolDiffRecordHdrLen]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	in, err := readHashes(r, binary.LittleEndian.Uint32(hdr[:4]))
+	if err != nil {
+		return nil, err
+	}
+	out, err := readHashes(r, binary.LittleEndian.Uint32(hdr[4:]))
+	if err != nil {
+		return nil, err
+	}
+	return &PoolDiff{In: in, Out: out}, nil
+}
+
+// append stores the diff for the block at height tip+1. The record is synced
+// before its index entry is written so that the entry never refers to data
+// that was not stored.
+func (s *poolDiffStore) append(diff *PoolDiff) error {
+	rec := encodeDiff(diff)
+	if _, err := s.data.WriteAt(rec, s.dataEnd); err != nil {
+		return err
+	}
+	if err := s.data.Sync(); err != nil {
+		return err
+	}
+	var offset [8]byte
+	binary.LittleEndian.PutUint64(offset[:], uint64(s.dataEnd))
+	indexEnd := poolDiffFileHeaderLen + 8*int64(len(s.offsets))
+	if _, err := s.index.WriteAt(offset[:], indexEnd); err != nil {
+		return err
+	}
+	s.offsets = append(s.offsets, s.dataEnd)
+	s.dataEnd += int64(len(rec))
+	return nil
+}
+
+// trim removes the diffs above the specified height, and any snapshots above
+// it.
+func (s *poolDiffStore) trim(height int64) error {
+	if height < 0 || height >= s.tip() {
+		return nil
+	}
+	s.dataEnd = s.offsets[height]
+	s.offsets = s.offsets[:height]
+	if err := s.truncateFiles(); err != nil {
+		return err
+	}
+	above := make([]int64, 0, len(s.snapshots))
+	for _, h := range s.snapshots {
+		if h > height {
+			above = append(above, h)
+		}
+	}
+	for _, h := range above {
+		if err := s.deleteSnapshot(h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diff reads the diff at index idx, which is for the block at height idx+1.
+func (s *poolDiffStore) diff(idx int64) (*PoolDiff, error) {
+	if idx < 0 || idx >= s.tip() {
+		return nil, fmt.Errorf("no pool diff at index %d, tip is %d", idx, s.tip())
+	}
+	end := s.dataEnd
+	if idx+1 < s.tip() {
+		end = s.offsets[idx+1]
+	}
+	rec := make([]byte, end-s.offsets[idx])
+	if _, err := s.data.ReadAt(rec, s.offsets[idx]); err != nil {
+		return nil, err
+	}
+	return readDiff(bytes.NewReader(rec))
+}
+
+// forEach calls fn with each diff, in order, starting at index from. It stops
+// at the first error, which is returned.
+func (s *poolDiffStore) forEach(from int64, fn func(idx int64, diff *PoolDiff) error) error {
+	if from < 0 || from >= s.tip() {
+		return nil
+	}
+	offset := s.offsets[from]
+	r := bufio.NewReaderSize(io.NewSectionReader(s.data, offset,
+		s.dataEnd-offset), 1<<20)
+	for idx := from; idx < s.tip(); idx++ {
+		diff, err := readDiff(r)
+		if err != nil {
+			return fmt.Errorf("failed to read pool diff at index %d: %v", idx, err)
+		}
+		if err = fn(idx, diff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *poolDiffStore) snapshotPath(height int64) string {
+	return filepath.Join(s.dir, poolSnapshotPrefix+strconv.FormatInt(height, 10))
+}
+
+// lastSnapshot returns the height of the highest stored snapshot, or 0.
+func (s *poolDiffStore) lastSnapshot() int64 {
+	var last int64
+	for _, h := range s.snapshots {
+		if h > last {
+			last = h
+		}
+	}
+	return last
+}
+
+// storeSnapshot writes the snapshot of the pool at height to a temporary file
+// that is renamed when complete.
+func (s *poolDiffStore) storeSnapshot(height int64, pool []chainhash.Hash) error {
+	buf := bytes.NewBuffer(make([]byte, 0,
+		poolDiffFileHeaderLen+4+chainhash.HashSize*len(pool)))
+	buf.Write(fileHeader(poolDiffSnapshotMagic))
+	binary.Write(buf, binary.LittleEndian, uint32(len(pool)))
+	for i := range pool {
+		buf.Write(pool[i][:])
+	}
+
+	path := s.snapshotPath(height)
+	if err := ioutil.WriteFile(path+".tmp", buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	s.snapshots = append(s.snapshots, height)
+	return nil
+}
+
+// deleteSnapshot removes the snapshot at height.
+func (s *poolDiffStore) deleteSnapshot(height int64) error {
+	if err := os.Remove(s.snapshotPath(height)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := range s.snapshots {
+		if s.snapshots[i] == height {
+			s.snapshots = append(s.snapshots[:i], s.snapshots[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// fetchSnapshot reads the snapshot of the pool at height.
+func (s *poolDiffStore) fetchSnapshot(height int64) ([]chainhash.Hash, error) {
+	b, err := ioutil.ReadFile(s.snapshotPath(height))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < poolDiffFileHeaderLen+4 ||
+		!bytes.Equal(b[:poolDiffFileHeaderLen], fileHeader(poolDiffSnapshotMagic)) {
+		return nil, fmt.Errorf("invalid pool snapshot at height %d", height)
+	}
+	r := bytes.NewReader(b[poolDiffFileHeaderLen+4:])
+	return readHashes(r, binary.LittleEndian.Uint32(b[poolDiffFileHeaderLen:]))
+}
+
+// close syncs and closes the data and index files.
+func (s *poolDiffStore) close() error {
+	var firstErr error
+	for _, f := range []*os.File{s.data, s.index} {
+		if err := f.Sync(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -86,7 +86,7 @@ func (c *PoolInfoCache) SetCapacity(cap int) error {
 // StakeDatabase models data for the stake database
 type StakeDatabase struct {
 	params          *chaincfg.Params
-	NodeClient      *rpcclient.Client
+	NodeClient      rpcutils.NodeClient
 	nodeMtx         sync.RWMutex
 	StakeDB         database.DB
 	BestNode        *stake.Node
@@ -108,22 +108,48 @@ const (
 	dbType = "ffldb"
 	// DefaultStakeDbName is the default name of the stakedb database folder
 	DefaultStakeDbName = "stakenodes"
-	// DefaultTicketPoolDbName is the default name of the ticket pool database
+	// DefaultTicketPoolDbName is the default name of the legacy storm ticket
+	// pool database, which is migrated to DefaultTicketPoolDirName
 	DefaultTicketPoolDbName = "ticket_pool.db"
+	// DefaultTicketPoolDirName is the default name of the ticket pool diff
+	// store folder
+	DefaultTicketPoolDirName = "ticket_pool"
 )
 
+// migrateTicketPoolDB migrates a legacy storm ticket pool DB in dbFolder, if
+// present, to the diff store in ticketPoolDir, and then removes it.
+func migrateTicketPoolDB(dbFolder, ticketPoolDir string) error {
+	stormFile := filepath.Join(dbFolder, DefaultTicketPoolDbName)
+	if _, err := os.Stat(stormFile); os.IsNotExist(err) {
+		return nil
+	}
+	// The store is renamed into place when a migration completes, so an
+	// existing store means only the removal of the storm DB remains.
+	if _, err := os.Stat(ticketPoolDir); os.IsNotExist(err) {
+		log.Infof("Migrating ticket pool DB %s to %s. This may take a minute...",
+			stormFile, ticketPoolDir)
+		if err = MigrateStormTicketPool(stormFile, ticketPoolDir); err != nil {
+			return err
+		}
+	}
+	log.Infof("Removing migrated ticket pool DB %s.", stormFile)
+	return os.Remove(stormFile)
+}
+
 // NewStakeDatabase creates a StakeDatabase instance, opening or creating a new
 // ffldb-backed stake database, and loads all live tickets into a cache.
-func NewStakeDatabase(client *rpcclient.Client, params *chaincfg.Params,
+func NewStakeDatabase(client rpcutils.NodeClient, params *chaincfg.Params,
 	dbFolder string) (*StakeDatabase, error) {
 	// Create DB folder
 	err := os.MkdirAll(dbFolder, 0700)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create DB folder: %v", err)
 	}
-	log.Infof("Loading ticket pool DB. This may take a minute...")
-	ticketPoolDBPath := filepath.Join(dbFolder, DefaultTicketPoolDbName)
-	poolDB, err := NewTicketPool(ticketPoolDBPath)
+	ticketPoolDir := filepath.Join(dbFolder, DefaultTicketPoolDirName)
+	if err = migrateTicketPoolDB(dbFolder, ticketPoolDir); err != nil {
+		return nil, fmt.Errorf("unable to migrate ticket pool DB: %v", err)
+	}
+	poolDB, err := NewTicketPool(ticketPoolDir)
 	if err != nil {
 		return nil, fmt.Errorf("unable to open ticket pool DB: %v", err)
 	}
@@ -152,14 +178,20 @@ func NewStakeDatabase(client *rpcclient.Client, params *chaincfg.Params,
 	}
 
 	// Check if stake DB and ticket pool DB are at the same height, and attempt
-	// to recover.
+	// to recover. A block is connected in the stake DB before its diff is
+	// appended to the ticket pool DB, so an interrupted ConnectBlock leaves
+	// the stake DB ahead.
 	heightStakeDB, heightTicketPool := int64(sDB.Height()), sDB.PoolDB.Tip()
-	if heightStakeDB != heightTicketPool {
-		if heightStakeDB > heightTicketPool {
-			return nil, fmt.Errorf("StakeDB height (%d) and TicketPool (%d) height not equal. "+
-				"Delete both and try again.", sDB.Height(), sDB.PoolDB.Tip())
+	if heightStakeDB > heightTicketPool {
+		log.Infof("Rewinding stake DB from %d to ticket pool DB height %d.",
+			heightStakeDB, heightTicketPool)
+		if err = sDB.rewindBestNode(heightTicketPool); err != nil {
+			return nil, fmt.Errorf("unable to rewind stake DB to height %d: %v",
+				heightTicketPool, err)
 		}
-
+		heightStakeDB = int64(sDB.Height())
+	}
+	if heightTicketPool > heightStakeDB {
 		// Trim ticket pool DB back to the height of the stake DB
 		for heightTicketPool > heightStakeDB {
 			heightTicketPool = sDB.PoolDB.Trim()
@@ -186,54 +218,64 @@ func NewStakeDatabase(client *rpcclient.Client, params *chaincfg.Params,
 		}
 
 		log.Info("Pre-populating live ticket cache...")
+		sDB.loadLiveTicketCache(liveTickets)
+	}
 
-		type promiseGetRawTransaction struct {
-			result rpcclient.FutureGetRawTransactionResult
-			ticket *chainhash.Hash
-		}
-		promisesGetRawTransaction := make([]promiseGetRawTransaction, 0, len(liveTickets))
+	return sDB, nil
+}
 
-		// Send all the live ticket requests
-		for _, hash := range liveTickets {
-			promisesGetRawTransaction = append(promisesGetRawTransaction, promiseGetRawTransaction{
-				result: sDB.NodeClient.GetRawTransactionAsync(hash),
-				ticket: hash,
-			})
-		}
+// rawTransactionAsyncGetter is satisfied by node clients such as
+// *rpcclient.Client that can issue getrawtransaction requests concurrently.
+type rawTransactionAsyncGetter interface {
+	GetRawTransactionAsync(txHash *chainhash.Hash) rpcclient.FutureGetRawTransactionResult
+}
 
-		// Receive the live ticket tx results
-		for _, p := range promisesGetRawTransaction {
-			ticketTx, err := p.result.Receive()
+// loadLiveTicketCache stores the value of each of the given live tickets in
+// the live ticket cache. If the node client supports asynchronous requests,
+// they are all sent before any results are received.
+func (db *StakeDatabase) loadLiveTicketCache(liveTickets []*chainhash.Hash) {
+	asyncClient, ok := db.NodeClient.(rawTransactionAsyncGetter)
+	if !ok {
+		for _, hash := range liveTickets {
+			tx, err := db.NodeClient.GetRawTransaction(hash)
 			if err != nil {
-				log.Error(err)
+				log.Errorf("Unable to get transaction %v: %v\n", hash, err)
 				continue
 			}
-			if !ticketTx.Hash().IsEqual(p.ticket) {
-				panic(fmt.Sprintf("Failed to receive Tx details for requested ticket hash: %v, %v", p.ticket, ticketTx.Hash()))
-			}
+			// This isn't quite right for pool tickets where the small
+			// pool fees are included in vout[0], but it's close.
+			db.liveTicketCache[*hash] = tx.MsgTx().TxOut[0].Value
+		}
+		return
+	}
 
-			sDB.liveTicketCache[*p.ticket] = ticketTx.MsgTx().TxOut[0].Value
+	type promiseGetRawTransaction struct {
+		result rpcclient.FutureGetRawTransactionResult
+		ticket *chainhash.Hash
+	}
+	promisesGetRawTransaction := make([]promiseGetRawTransaction, 0, len(liveTickets))
+
+	// Send all the live ticket requests
+	for _, hash := range liveTickets {
+		promisesGetRawTransaction = append(promisesGetRawTransaction, promiseGetRawTransaction{
+			result: asyncClient.GetRawTransactionAsync(hash),
+			ticket: hash,
+		})
+	}
 
-			// txHeight := ticketTx.BlockHeight
-			// unconfirmed := (txHeight == 0)
-			// immature := (tipHeight-int32(txHeight) < int32(w.ChainParams().TicketMaturity))
+	// Receive the live ticket tx results
+	for _, p := range promisesGetRawTransaction {
+		ticketTx, err := p.result.Receive()
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		if !ticketTx.Hash().IsEqual(p.ticket) {
+			panic(fmt.Sprintf("Failed to receive Tx details for requested ticket hash: %v, %v", p.ticket, ticketTx.Hash()))
 		}
 
-		// Old synchronous way
-		// for _, hash := range liveTickets {
-		// 	var txid *dcrutil.Tx
-		// 	txid, err = sDB.NodeClient.GetRawTransaction(hash)
-		// 	if err != nil {
-		// 		log.Errorf("Unable to get transaction %v: %v\n", hash, err)
-		// 		continue
-		// 	}
-		// 	// This isn't quite right for pool tickets where the small
-		// 	// pool fees are included in vout[0], but it's close.
-		// 	sDB.liveTicketCache[*hash] = txid.MsgTx().TxOut[0].Value
-		// }
+		db.liveTicketCache[*p.ticket] = ticketTx.MsgTx().TxOut[0].Value
 	}
-
-	return sDB, nil
 }
 
 // LockStakeNode locks the StakeNode from functions that respect the mutex.
@@ -486,6 +528,13 @@ func (db *StakeDatabase) disconnectBlock() error {
 		}
 	}
 
+	return db.disconnectBestNode(parentBlock)
+}
+
+// disconnectBestNode replaces the best stake node with that of the parent
+// block, without modifying the ticket pool DB.
+func (db *StakeDatabase) disconnectBestNode(parentBlock *dcrutil.Block) error {
+	childHeight := db.BestNode.Height()
 	childUndoData := append(stake.UndoTicketDataSlice(nil), db.BestNode.UndoData()...)
 
 	log.Debugf("Disconnecting block %d.", childHeight)
@@ -498,7 +547,7 @@ func (db *StakeDatabase) disconnectBlock() error {
 	parentIV := stake.CalcHash256PRNGIV(hB)
 
 	var parentStakeNode *stake.Node
-	err = db.StakeDB.View(func(dbTx database.Tx) error {
+	err := db.StakeDB.View(func(dbTx database.Tx) error {
 		var errLocal error
 		parentStakeNode, errLocal = db.BestNode.DisconnectNode(parentIV, nil, nil, dbTx)
 		return errLocal
@@ -514,6 +563,25 @@ func (db *StakeDatabase) disconnectBlock() error {
 	})
 }
 
+// rewindBestNode disconnects blocks from the best stake node until it is at
+// the specified height, without modifying the ticket pool DB. This is used to
+// bring the stake DB back in line with the ticket pool DB.
+func (db *StakeDatabase) rewindBestNode(height int64) error {
+	db.nodeMtx.Lock()
+	defer db.nodeMtx.Unlock()
+
+	for int64(db.BestNode.Height()) > height {
+		parentBlock, err := db.dbPrevBlock()
+		if err != nil {
+			return err
+		}
+		if err = db.disconnectBestNode(parentBlock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // DisconnectBlocks disconnects N blocks from the head of the chain.
 func (db *StakeDatabase) DisconnectBlocks(count int64) error {
 	db.nodeMtx.Lock()
@@ -706,6 +774,20 @@ func (db *StakeDatabase) PoolSize() int {
 	return db.BestNode.PoolSize()
 }
 
+// TicketStatus reports whether the ticket is live in the best node of the stake
+// database, and whether it was missed, revoked, or expired. Revoked tickets are
+// also reported as missed. A ticket that is none of these has either voted or
+// never entered the live pool, which TicketPool.TicketLiveHeights tells apart.
+func (db *StakeDatabase) TicketStatus(ticket chainhash.Hash) (live, missed, revoked, expired bool) {
+	db.nodeMtx.RLock()
+	defer db.nodeMtx.RUnlock()
+	live = db.BestNode.ExistsLiveTicket(ticket)
+	revoked = db.BestNode.ExistsRevokedTicket(ticket)
+	missed = revoked || db.BestNode.ExistsMissedTicket(ticket)
+	expired = db.BestNode.ExistsExpiredTicket(ticket)
+	return
+}
+
 // PoolAtHeight gets the entire list of live tickets at the given chain height.
 func (db *StakeDatabase) PoolAtHeight(height int64) ([]chainhash.Hash, error) {
 	return db.PoolDB.Pool(height)
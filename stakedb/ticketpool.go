@@ -5,26 +5,44 @@ package stakedb
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
-	"github.com/asdine/storm"
 	"github.com/decred/dcrd/chaincfg/chainhash"
 )
 
 // TicketPool contains the live ticket pool diffs (tickets in/out) between
 // adjacent block heights in a chain. Diffs are applied in sequence by inserting
-// and removing ticket hashes from a pool, represented as a map. A []PoolDiff
-// stores these diffs, with a cursor pointing to the next unapplied diff. An
-// on-disk database of diffs is maintained using the storm wrapper for boltdb.
+// and removing ticket hashes from a pool, represented as a map, with a cursor
+// pointing to the next unapplied diff. The diffs are kept in an append-only
+// on-disk store (see poolDiffStore), and read from it as they are applied.
+// Snapshots of the pool every PoolSnapshotInterval blocks are also stored so
+// that the pool at any height may be built without replaying all diffs.
 type TicketPool struct {
 	*sync.RWMutex
-	cursor int64
-	tip    int64
-	diffs  []PoolDiff
-	pool   map[chainhash.Hash]struct{}
-	diffDB *storm.DB
+	cursor      int64
+	tip         int64
+	pool        map[chainhash.Hash]struct{}
+	store       *poolDiffStore
+	snapshotTip int64
+	// liveHeights indexes the heights at which each ticket in the stored diffs
+	// entered and left the live pool. It is built from the stored diffs when
+	// first needed, and is nil until then.
+	liveHeights map[chainhash.Hash]ticketLiveHeights
 }
 
+// ticketLiveHeights are the heights of the blocks in which a ticket entered
+// and left the live pool, with left -1 while the ticket is live.
+type ticketLiveHeights struct {
+	entered, left int32
+}
+
+const (
+	// PoolSnapshotInterval is the number of blocks between stored snapshots
+	// of the live ticket pool.
+	PoolSnapshotInterval = 4096
+)
+
 // PoolDiff represents the tickets going in and out of the live ticket pool from
 // one height to the next.
 type PoolDiff struct {
@@ -32,49 +50,83 @@ type PoolDiff struct {
 	Out []chainhash.Hash
 }
 
-// PoolDiffDBItem is the type in the storm live ticket DB. The primary key (id)
-// is Height.
-type PoolDiffDBItem struct {
-	Height   int64 `storm:"id"`
-	PoolDiff `storm:"inline"`
+// NewTicketPool constructs a TicketPool by opening the diff store in the
+// specified directory, which is created if it does not exist. Only the index
+// of the diffs is loaded. The pool map is empty, with the cursor at 0.
+func NewTicketPool(dir string) (*TicketPool, error) {
+	store, err := openPoolDiffStore(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ticket pool diff store: %v", err)
+	}
+
+	// Snapshots are stored at each multiple of PoolSnapshotInterval up to
+	// snapshotTip, which is not above the tip.
+	tp := &TicketPool{
+		RWMutex:     new(sync.RWMutex),
+		pool:        make(map[chainhash.Hash]struct{}),
+		tip:         store.tip(), // number of blocks connected over genesis
+		store:       store,
+		snapshotTip: store.lastSnapshot(),
+	}
+	return tp, nil
 }
 
-// NewTicketPool constructs a TicketPool by opening the persistent diff db,
-// loading all known diffs, initializing the TicketPool values.
-func NewTicketPool(dbFile string) (*TicketPool, error) {
-	// Open ticket pool diffs database
-	db, err := storm.Open(dbFile)
+// indexLiveHeights builds the index of the heights at which tickets entered
+// and left the pool from all of the stored diffs.
+func (tp *TicketPool) indexLiveHeights() error {
+	tp.liveHeights = make(map[chainhash.Hash]ticketLiveHeights)
+	err := tp.store.forEach(0, func(idx int64, diff *PoolDiff) error {
+		tp.indexDiff(idx, diff)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed storm.Open: %v", err)
+		tp.liveHeights = nil
 	}
+	return err
+}
 
-	// Load all diffs
-	var poolDiffs []PoolDiffDBItem
-	err = db.AllByIndex("Height", &poolDiffs)
-	if err != nil {
-		return nil, fmt.Errorf("failed (*storm.DB).AllByIndex: %v", err)
+// indexDiff records the heights at which the tickets in the diff at index idx
+// entered and left the pool, if the index has been built.
+func (tp *TicketPool) indexDiff(idx int64, diff *PoolDiff) {
+	if tp.liveHeights == nil {
+		return
 	}
-	diffs := make([]PoolDiff, len(poolDiffs))
-	for i := range poolDiffs {
-		diffs[i] = poolDiffs[i].PoolDiff
+	height := int32(idx + 1)
+	for i := range diff.In {
+		tp.liveHeights[diff.In[i]] = ticketLiveHeights{entered: height, left: -1}
+	}
+	for i := range diff.Out {
+		if h, ok := tp.liveHeights[diff.Out[i]]; ok {
+			h.left = height
+			tp.liveHeights[diff.Out[i]] = h
+		}
 	}
+}
 
-	// Construct TicketPool with loaded diffs and diff DB
-	return &TicketPool{
-		RWMutex: new(sync.RWMutex),
-		pool:    make(map[chainhash.Hash]struct{}),
-		diffs:   diffs,             // make([]PoolDiff, 0, 100000),
-		tip:     int64(len(diffs)), // number of blocks connected over genesis
-		diffDB:  db,
-	}, nil
+// unindexDiff reverts indexDiff for the diff that is being removed.
+func (tp *TicketPool) unindexDiff(diff *PoolDiff) {
+	if tp.liveHeights == nil {
+		return
+	}
+	for i := range diff.Out {
+		if h, ok := tp.liveHeights[diff.Out[i]]; ok {
+			h.left = -1
+			tp.liveHeights[diff.Out[i]] = h
+		}
+	}
+	for i := range diff.In {
+		delete(tp.liveHeights, diff.In[i])
+	}
 }
 
-// Close closes the persistent diff DB.
+// Close closes the on-disk diff store.
 func (tp *TicketPool) Close() error {
-	return tp.diffDB.Close()
+	tp.Lock()
+	defer tp.Unlock()
+	return tp.store.close()
 }
 
-// Tip returns the current length of the diffs slice.
+// Tip returns the number of stored diffs, the height of the last one.
 func (tp *TicketPool) Tip() int64 {
 	tp.RLock()
 	defer tp.RUnlock()
@@ -88,17 +140,29 @@ func (tp *TicketPool) Cursor() int64 {
 	return tp.cursor
 }
 
-// append grows the diffs slice and advances the tip height.
-func (tp *TicketPool) append(diff *PoolDiff) {
+// append stores the diff and advances the tip height.
+func (tp *TicketPool) append(diff *PoolDiff) error {
+	if err := tp.store.append(diff); err != nil {
+		return err
+	}
+	tp.indexDiff(tp.tip, diff)
 	tp.tip++
-	tp.diffs = append(tp.diffs, *diff)
+	return nil
 }
 
 // trim is the non-thread-safe version of Trim.
 func (tp *TicketPool) trim() int64 {
-	if tp.tip == 0 || len(tp.diffs) == 0 {
+	if tp.tip == 0 {
 		return tp.tip
 	}
+	if tp.liveHeights != nil {
+		if diff, err := tp.diff(tp.tip - 1); err != nil {
+			log.Errorf("Failed to read pool diff at height %d: %v", tp.tip, err)
+			tp.liveHeights = nil
+		} else {
+			tp.unindexDiff(diff)
+		}
+	}
 	tp.tip--
 	newMaxCursor := tp.maxCursor()
 	if tp.cursor > newMaxCursor {
@@ -106,7 +170,12 @@ func (tp *TicketPool) trim() int64 {
 			log.Errorf("retreatTo failed: %v", err)
 		}
 	}
-	tp.diffs = tp.diffs[:len(tp.diffs)-1]
+	// Remove the diff, and any snapshot above the new tip, from the on-disk
+	// store too, or they would be loaded again by NewTicketPool.
+	if err := tp.store.trim(tp.tip); err != nil {
+		log.Errorf("Failed to remove pool diff at height %d: %v", tp.tip+1, err)
+	}
+	tp.snapshotTip = tp.store.lastSnapshot()
 	return tp.tip
 }
 
@@ -118,48 +187,76 @@ func (tp *TicketPool) Trim() int64 {
 	return tp.trim()
 }
 
-// storeDiff stores the input diff for the specified height in the on-disk DB.
-func (tp *TicketPool) storeDiff(diff *PoolDiff, height int64) error {
-	d := &PoolDiffDBItem{
-		Height:   height,
-		PoolDiff: *diff,
+// storeSnapshot stores the current pool map, which must be at a height that is
+// the next multiple of PoolSnapshotInterval above snapshotTip, as a snapshot.
+func (tp *TicketPool) storeSnapshot() error {
+	pool, height := tp.currentPool()
+	if err := tp.store.storeSnapshot(height, pool); err != nil {
+		return err
+	}
+	tp.snapshotTip = height
+	return nil
+}
+
+// fetchSnapshot retrieves the pool snapshot at the specified height as a pool
+// map. The pool at height 0 is empty, and is not stored.
+func (tp *TicketPool) fetchSnapshot(height int64) (map[chainhash.Hash]struct{}, error) {
+	if height == 0 {
+		return make(map[chainhash.Hash]struct{}), nil
+	}
+	tickets, err := tp.store.fetchSnapshot(height)
+	if err != nil {
+		return nil, err
+	}
+	pool := make(map[chainhash.Hash]struct{}, len(tickets))
+	for i := range tickets {
+		pool[tickets[i]] = struct{}{}
+	}
+	return pool, nil
+}
+
+// nearestSnapshot returns the height of the stored snapshot closest to the
+// specified height, which may be 0 for the empty pool.
+func (tp *TicketPool) nearestSnapshot(height int64) int64 {
+	below := height - height%PoolSnapshotInterval
+	if below >= tp.snapshotTip {
+		return tp.snapshotTip
+	}
+	if above := below + PoolSnapshotInterval; above-height < height-below {
+		return above
 	}
-	return tp.diffDB.Save(d)
+	return below
 }
 
-// fetchDiff retrieves the diff at the specified height from the on-disk DB.
-func (tp *TicketPool) fetchDiff(height int64) (*PoolDiffDBItem, error) {
-	var diff PoolDiffDBItem
-	err := tp.diffDB.One("Height", height, &diff)
-	return &diff, err
+// diff reads the diff at index idx, for the block at height idx+1, from the
+// on-disk store.
+func (tp *TicketPool) diff(idx int64) (*PoolDiff, error) {
+	return tp.store.diff(idx)
 }
 
-// Append grows the diffs slice with the specified diff, and stores it in the
-// on-disk DB. The height of the diff is used to check that it builds on the
-// chain tip, and as a primary key in the DB.
+// Append stores the specified diff in the on-disk store. The height of the diff
+// is used to check that it builds on the chain tip.
 func (tp *TicketPool) Append(diff *PoolDiff, height int64) error {
+	tp.Lock()
+	defer tp.Unlock()
 	if height != tp.tip+1 {
 		return fmt.Errorf("block height %d does not build on %d", height, tp.tip)
 	}
-	tp.Lock()
-	defer tp.Unlock()
-	tp.append(diff)
-	return tp.storeDiff(diff, height)
+	return tp.append(diff)
 }
 
-// AppendAndAdvancePool functions like Append, except that after growing the
-// diffs slice and storing the diff in DB, the ticket pool is advanced.
+// AppendAndAdvancePool functions like Append, except that after storing the
+// diff, the ticket pool is advanced.
 func (tp *TicketPool) AppendAndAdvancePool(diff *PoolDiff, height int64) error {
+	tp.Lock()
+	defer tp.Unlock()
 	if height != tp.tip+1 {
 		return fmt.Errorf("block height %d does not build on %d", height, tp.tip)
 	}
-	tp.Lock()
-	defer tp.Unlock()
-	tp.append(diff)
-	if err := tp.storeDiff(diff, height); err != nil {
+	if err := tp.append(diff); err != nil {
 		return err
 	}
-	return tp.advance()
+	return tp.moveTo(tp.tip)
 }
 
 // currentPool is the non-thread-safe version of CurrentPool.
@@ -191,40 +288,189 @@ func (tp *TicketPool) CurrentPoolSize() int {
 	return len(tp.pool)
 }
 
+// poolAt builds a new pool map for the specified height, starting from either
+// the current pool map or the nearest snapshot, whichever is fewer diffs away.
+// The current pool map and cursor are not modified.
+func (tp *TicketPool) poolAt(height int64) (map[chainhash.Hash]struct{}, error) {
+	if height < 0 || height > tp.tip {
+		return nil, fmt.Errorf("block height %d is not connected yet, tip is %d", height, tp.tip)
+	}
+
+	var pool map[chainhash.Hash]struct{}
+	cursor := tp.nearestSnapshot(height)
+	if distance(cursor, height) < distance(tp.cursor, height) {
+		var err error
+		if pool, err = tp.fetchSnapshot(cursor); err != nil {
+			return nil, fmt.Errorf("unable to load pool snapshot at height %d: %v",
+				cursor, err)
+		}
+	} else {
+		cursor = tp.cursor
+		pool = make(map[chainhash.Hash]struct{}, len(tp.pool))
+		for h := range tp.pool {
+			pool[h] = struct{}{}
+		}
+	}
+
+	for ; cursor < height; cursor++ {
+		diff, err := tp.diff(cursor)
+		if err != nil {
+			return nil, err
+		}
+		applyDiff(pool, diff.In, diff.Out)
+	}
+	for ; cursor > height; cursor-- {
+		diff, err := tp.diff(cursor - 1)
+		if err != nil {
+			return nil, err
+		}
+		applyDiff(pool, diff.Out, diff.In)
+	}
+	return pool, nil
+}
+
+func distance(a, b int64) int64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// moveTo advances or retreats the pool map and cursor to the specified height.
+// If a stored snapshot is fewer diffs away than the cursor, the pool map is
+// first replaced by the snapshot.
+func (tp *TicketPool) moveTo(height int64) error {
+	if height < 0 || height > tp.tip {
+		return fmt.Errorf("block height %d is not connected yet, tip is %d", height, tp.tip)
+	}
+	snapHeight := tp.nearestSnapshot(height)
+	if distance(snapHeight, height) < distance(tp.cursor, height) {
+		pool, err := tp.fetchSnapshot(snapHeight)
+		if err != nil {
+			return fmt.Errorf("unable to load pool snapshot at height %d: %v",
+				snapHeight, err)
+		}
+		tp.pool, tp.cursor = pool, snapHeight
+	}
+	if err := tp.advanceTo(height); err != nil {
+		return err
+	}
+	return tp.retreatTo(height)
+}
+
 // Pool attempts to get the tickets in the live pool at the specified height. It
-// will advance/retreat the cursor as needed to reach the desired height, and
-// then extract the tickets from the resulting pool map.
+// will advance/retreat the cursor as needed to reach the desired height,
+// starting from the nearest snapshot if it is closer than the cursor, and then
+// extract the tickets from the resulting pool map.
 func (tp *TicketPool) Pool(height int64) ([]chainhash.Hash, error) {
 	tp.Lock()
 	defer tp.Unlock()
 
-	if height > tp.tip {
-		return nil, fmt.Errorf("block height %d is not connected yet, tip is %d", height, tp.tip)
+	if err := tp.moveTo(height); err != nil {
+		return nil, err
 	}
+	p, _ := tp.currentPool()
+	return p, nil
+}
 
-	for height > tp.cursor {
-		if err := tp.advance(); err != nil {
-			return nil, err
+// SortedPool gets the tickets in the live pool at the specified height, sorted
+// by their hash strings so that the order is stable. Unlike Pool, the cursor
+// is not moved.
+func (tp *TicketPool) SortedPool(height int64) ([]chainhash.Hash, error) {
+	tp.RLock()
+	poolMap, err := tp.poolAt(height)
+	tp.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	pool := make([]chainhash.Hash, 0, len(poolMap))
+	poolStrs := make([]string, 0, len(poolMap))
+	for h := range poolMap {
+		pool = append(pool, h)
+		poolStrs = append(poolStrs, h.String())
+	}
+	sort.Sort(byString{pool, poolStrs})
+	return pool, nil
+}
+
+// byString sorts ticket hashes by their precomputed strings.
+type byString struct {
+	hashes []chainhash.Hash
+	strs   []string
+}
+
+func (b byString) Len() int           { return len(b.hashes) }
+func (b byString) Less(i, j int) bool { return b.strs[i] < b.strs[j] }
+func (b byString) Swap(i, j int) {
+	b.hashes[i], b.hashes[j] = b.hashes[j], b.hashes[i]
+	b.strs[i], b.strs[j] = b.strs[j], b.strs[i]
+}
+
+// TicketLiveHeights returns the heights of the blocks in which the ticket
+// entered and left the live pool. left is -1 if the ticket has not left the
+// pool, and both are -1 if the ticket never entered it. The first call reads
+// all of the stored diffs to build the index of these heights.
+func (tp *TicketPool) TicketLiveHeights(ticket chainhash.Hash) (entered, left int64) {
+	tp.RLock()
+	if tp.liveHeights == nil {
+		tp.RUnlock()
+		tp.Lock()
+		if tp.liveHeights == nil {
+			if err := tp.indexLiveHeights(); err != nil {
+				log.Errorf("Failed to index ticket pool diffs: %v", err)
+			}
 		}
+		tp.Unlock()
+		tp.RLock()
 	}
-	for tp.cursor > height {
-		if err := tp.retreat(); err != nil {
+	defer tp.RUnlock()
+	h, ok := tp.liveHeights[ticket]
+	if !ok {
+		return -1, -1
+	}
+	return int64(h.entered), int64(h.left)
+}
+
+// LiveTicketHeights returns the tickets in the live pool at the specified
+// height, mapped to the heights of the blocks in which they entered the pool.
+func (tp *TicketPool) LiveTicketHeights(height int64) (map[chainhash.Hash]int64, error) {
+	tp.RLock()
+	defer tp.RUnlock()
+
+	pool, err := tp.poolAt(height)
+	if err != nil {
+		return nil, err
+	}
+
+	// Search back from height for the diffs that added each live ticket.
+	heights := make(map[chainhash.Hash]int64, len(pool))
+	for i := height - 1; i >= 0 && len(heights) < len(pool); i-- {
+		diff, err := tp.diff(i)
+		if err != nil {
 			return nil, err
 		}
+		for _, h := range diff.In {
+			if _, live := pool[h]; live {
+				heights[h] = i + 1
+			}
+		}
 	}
-	p, _ := tp.currentPool()
-	return p, nil
+	return heights, nil
 }
 
 // advance applies the pool diff at the current cursor location, and advances
 // the cursor. Note that when advancing at the last diff, the resulting cursor
-// will be beyond the last element in the diffs slice.
+// will be beyond the last stored diff.
 func (tp *TicketPool) advance() error {
 	if tp.cursor > tp.maxCursor() {
 		return fmt.Errorf("cursor at tip, unable to advance")
 	}
 
-	diffToNext := tp.diffs[tp.cursor]
+	diffToNext, err := tp.diff(tp.cursor)
+	if err != nil {
+		return err
+	}
 	initPoolSize := len(tp.pool)
 	expectedFinalSize := initPoolSize + len(diffToNext.In) - len(diffToNext.Out)
 
@@ -235,6 +481,13 @@ func (tp *TicketPool) advance() error {
 		return fmt.Errorf("pool size is %d, expected %d", len(tp.pool), expectedFinalSize)
 	}
 
+	if tp.cursor == tp.snapshotTip+PoolSnapshotInterval {
+		if err := tp.storeSnapshot(); err != nil {
+			log.Errorf("Failed to store pool snapshot at height %d: %v",
+				tp.cursor, err)
+		}
+	}
+
 	return nil
 }
 
@@ -254,7 +507,7 @@ func (tp *TicketPool) advanceTo(height int64) error {
 }
 
 // AdvanceToTip advances the pool map by applying all stored diffs. Note that
-// the cursor will stop just beyond the last element of the diffs slice. It will
+// the cursor will stop just beyond the last stored diff. It will
 // not be possible to advance further, only retreat.
 func (tp *TicketPool) AdvanceToTip() error {
 	tp.Lock()
@@ -270,7 +523,10 @@ func (tp *TicketPool) retreat() error {
 		return fmt.Errorf("cursor at genesis, unable to retreat")
 	}
 
-	diffFromPrev := tp.diffs[tp.cursor-1]
+	diffFromPrev, err := tp.diff(tp.cursor - 1)
+	if err != nil {
+		return err
+	}
 	initPoolSize := len(tp.pool)
 	expectedFinalSize := initPoolSize - len(diffFromPrev.In) + len(diffFromPrev.Out)
 
@@ -283,8 +539,8 @@ func (tp *TicketPool) retreat() error {
 	return nil
 }
 
-// maxCursor returns the largest valid index into the diffs slice, or 0 when the
-// slice is empty.
+// maxCursor returns the largest valid index of the stored diffs, or 0 when
+// there are none.
 func (tp *TicketPool) maxCursor() int64 {
 	if tp.tip == 0 {
 		return 0
@@ -309,19 +565,24 @@ func (tp *TicketPool) retreatTo(height int64) error {
 
 // applyDiff adds and removes tickets from the pool map.
 func (tp *TicketPool) applyDiff(in, out []chainhash.Hash) {
-	initsize := len(tp.pool)
+	applyDiff(tp.pool, in, out)
+}
+
+// applyDiff adds and removes tickets from the specified pool map.
+func applyDiff(pool map[chainhash.Hash]struct{}, in, out []chainhash.Hash) {
+	initsize := len(pool)
 	for i := range in {
-		tp.pool[in[i]] = struct{}{}
+		pool[in[i]] = struct{}{}
 	}
-	endsize := len(tp.pool)
+	endsize := len(pool)
 	if endsize != initsize+len(in) {
 		log.Warnf("pool grew by %d instead of %d", endsize-initsize, len(in))
 	}
 	initsize = endsize
 	for i := range out {
-		delete(tp.pool, out[i])
+		delete(pool, out[i])
 	}
-	endsize = len(tp.pool)
+	endsize = len(pool)
 	if endsize != initsize-len(out) {
 		log.Warnf("pool shrank by %d instead of %d", initsize-endsize, len(out))
 	}
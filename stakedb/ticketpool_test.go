@@ -2,6 +2,7 @@ package stakedb
 
 import (
 	"crypto/rand"
+	"encoding/binary"
 	"os"
 	"testing"
 
@@ -16,6 +17,18 @@ func randomHash() chainhash.Hash {
 	return hash
 }
 
+// migrateTestDB migrates a legacy storm ticket pool DB to the diff store in
+// dbFile, and opens it.
+func migrateTestDB(stormFile string) (*TicketPool, error) {
+	if err := os.RemoveAll(dbFile); err != nil {
+		return nil, err
+	}
+	if err := MigrateStormTicketPool(stormFile, dbFile); err != nil {
+		return nil, err
+	}
+	return NewTicketPool(dbFile)
+}
+
 func randomHashSlice(N int) []chainhash.Hash {
 	s := make([]chainhash.Hash, 0, N)
 	for i := 0; i < N; i++ {
@@ -25,7 +38,7 @@ func randomHashSlice(N int) []chainhash.Hash {
 }
 
 var (
-	dbFile             = "pooldiffs.db"
+	dbFile             = "pooldiffs"
 	dbFileRef          = "pooldiffs0.db"
 	dbFileFull         = "stakedb_ticket_pool.db"
 	fullHeight   int64 = 204578
@@ -61,8 +74,8 @@ func TestTicketPoolTraverseFull(t *testing.T) {
 		t.Skipf("%s not found, skipping TestTicketPoolTraverseFull", dbFileFull)
 	}
 
-	t.Logf("Loading entire ticket pool diffs from %s...", dbFileFull)
-	p, err := NewTicketPool(dbFileFull)
+	t.Logf("Migrating entire ticket pool diffs from %s...", dbFileFull)
+	p, err := migrateTestDB(dbFileFull)
 	if err != nil {
 		t.Fatalf("NewTicketPool failed: %v", err)
 	}
@@ -127,8 +140,8 @@ func TestTicketPoolTraverseFull(t *testing.T) {
 // tests correct use of AppendAndAdvancePool, verifying that tickets are removed
 // from the pool as expected.
 func TestTicketPoolAppendInvalid(t *testing.T) {
-	if err := os.Remove(dbFile); err != nil && !os.IsNotExist(err) {
-		t.Fatalf("Failed to delete db file: %v", err)
+	if err := os.RemoveAll(dbFile); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to delete diff store: %v", err)
 	}
 	p, err := NewTicketPool(dbFile)
 	if err != nil {
@@ -194,8 +207,8 @@ func TestTicketPoolAppendInvalid(t *testing.T) {
 
 // TestTicketPoolRetreat tests retreat.
 func TestTicketPoolRetreat(t *testing.T) {
-	if err := os.Remove(dbFile); err != nil && !os.IsNotExist(err) {
-		t.Fatalf("Failed to delete db file: %v", err)
+	if err := os.RemoveAll(dbFile); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to delete diff store: %v", err)
 	}
 	p, err := NewTicketPool(dbFile)
 	if err != nil {
@@ -267,8 +280,8 @@ func TestTicketPoolRetreat(t *testing.T) {
 
 // TestTicketPoolHeight tests Pool(height).
 func TestTicketPoolHeight(t *testing.T) {
-	if err := os.Remove(dbFile); err != nil && !os.IsNotExist(err) {
-		t.Fatalf("Failed to delete db file: %v", err)
+	if err := os.RemoveAll(dbFile); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to delete diff store: %v", err)
 	}
 	p, err := NewTicketPool(dbFile)
 	if err != nil {
@@ -349,7 +362,7 @@ func TestTicketPoolHeight(t *testing.T) {
 // TestTicketPoolPersistent tests the persistent DB by loading a referece .db
 // file, and verifies its state is as expected, and Pool works on it.
 func TestTicketPoolPersistent(t *testing.T) {
-	p, err := NewTicketPool(dbFileRef)
+	p, err := migrateTestDB(dbFileRef)
 	if err != nil {
 		t.Fatalf("NewTicketPool failed: %v", err)
 	}
@@ -380,3 +393,348 @@ func TestTicketPoolPersistent(t *testing.T) {
 		t.Fatalf("initial pool size incorrect. expected 4, got %d", tipPoolSize)
 	}
 }
+
+// TestTicketPoolSnapshots tests that pool snapshots are stored as the pool
+// advances, used by Pool and SortedPool, and removed by Trim. It also tests the
+// ticket history queries.
+func TestTicketPoolSnapshots(t *testing.T) {
+	if err := os.RemoveAll(dbFile); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to delete diff store: %v", err)
+	}
+	defer os.RemoveAll(dbFile)
+	p, err := NewTicketPool(dbFile)
+	if err != nil {
+		t.Fatalf("NewTicketPool failed: %v", err)
+	}
+
+	// Each block adds two tickets and removes the first ticket added by the
+	// previous block, so the pool size at height h > 0 is h+1.
+	tip := int64(2*PoolSnapshotInterval + 10)
+	diffs := make([]*PoolDiff, tip+1)
+	for h := int64(1); h <= tip; h++ {
+		diffs[h] = &PoolDiff{In: randomHashSlice(2)}
+		if h > 1 {
+			diffs[h].Out = diffs[h-1].In[:1]
+		}
+		if err = p.AppendAndAdvancePool(diffs[h], h); err != nil {
+			t.Fatalf("AppendAndAdvancePool failed: %v", err)
+		}
+	}
+	if p.snapshotTip != 2*PoolSnapshotInterval {
+		t.Errorf("snapshot tip incorrect. expected %d, got %d",
+			2*PoolSnapshotInterval, p.snapshotTip)
+	}
+	p.Close()
+
+	// Reopen, and get a pool near a snapshot without advancing to the tip.
+	p, err = NewTicketPool(dbFile)
+	if err != nil {
+		t.Fatalf("NewTicketPool failed: %v", err)
+	}
+	defer p.Close()
+	if p.snapshotTip != 2*PoolSnapshotInterval {
+		t.Errorf("loaded snapshot tip incorrect. expected %d, got %d",
+			2*PoolSnapshotInterval, p.snapshotTip)
+	}
+
+	height := int64(PoolSnapshotInterval + 5)
+	pool, err := p.Pool(height)
+	if err != nil {
+		t.Fatalf("Pool failed: %v", err)
+	}
+	if int64(len(pool)) != height+1 {
+		t.Errorf("incorrect pool size. expected %d, got %d", height+1, len(pool))
+	}
+	if p.Cursor() != height {
+		t.Errorf("cursor incorrect. expected %d, got %d", height, p.Cursor())
+	}
+
+	sorted, err := p.SortedPool(2*PoolSnapshotInterval - 3)
+	if err != nil {
+		t.Fatalf("SortedPool failed: %v", err)
+	}
+	if len(sorted) != 2*PoolSnapshotInterval-2 {
+		t.Errorf("incorrect sorted pool size. expected %d, got %d",
+			2*PoolSnapshotInterval-2, len(sorted))
+	}
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1].String() >= sorted[i].String() {
+			t.Fatalf("pool not sorted at index %d", i)
+		}
+	}
+	if p.Cursor() != height {
+		t.Errorf("SortedPool moved the cursor to %d", p.Cursor())
+	}
+
+	// Ticket history.
+	if in, out := p.TicketLiveHeights(diffs[3].In[0]); in != 3 || out != 4 {
+		t.Errorf("incorrect live heights (%d, %d), expected (3, 4)", in, out)
+	}
+	if in, out := p.TicketLiveHeights(diffs[3].In[1]); in != 3 || out != -1 {
+		t.Errorf("incorrect live heights (%d, %d), expected (3, -1)", in, out)
+	}
+	if in, out := p.TicketLiveHeights(randomHash()); in != -1 || out != -1 {
+		t.Errorf("incorrect live heights (%d, %d), expected (-1, -1)", in, out)
+	}
+
+	liveHeights, err := p.LiveTicketHeights(10)
+	if err != nil {
+		t.Fatalf("LiveTicketHeights failed: %v", err)
+	}
+	if len(liveHeights) != 11 {
+		t.Errorf("incorrect number of live tickets. expected 11, got %d",
+			len(liveHeights))
+	}
+	for h := int64(1); h <= 10; h++ {
+		if entered, ok := liveHeights[diffs[h].In[1]]; !ok || entered != h {
+			t.Errorf("ticket from height %d has height %d (live: %v)",
+				h, entered, ok)
+		}
+	}
+
+	// Trimming below a snapshot removes it.
+	for p.Tip() >= 2*PoolSnapshotInterval {
+		p.Trim()
+	}
+	if p.snapshotTip != PoolSnapshotInterval {
+		t.Errorf("snapshot tip incorrect after trim. expected %d, got %d",
+			PoolSnapshotInterval, p.snapshotTip)
+	}
+	pool, err = p.Pool(p.Tip())
+	if err != nil {
+		t.Fatalf("Pool failed: %v", err)
+	}
+	if int64(len(pool)) != p.Tip()+1 {
+		t.Errorf("incorrect pool size. expected %d, got %d", p.Tip()+1, len(pool))
+	}
+}
+
+// TestTicketPoolRecovery tests that an incompletely written diff is discarded
+// when the diff store is opened.
+func TestTicketPoolRecovery(t *testing.T) {
+	if err := os.RemoveAll(dbFile); err != nil {
+		t.Fatalf("Failed to delete diff store: %v", err)
+	}
+	defer os.RemoveAll(dbFile)
+	p, err := NewTicketPool(dbFile)
+	if err != nil {
+		t.Fatalf("NewTicketPool failed: %v", err)
+	}
+	for h := int64(1); h <= 3; h++ {
+		diff := &PoolDiff{In: randomHashSlice(2)}
+		if err = p.Append(diff, h); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	p.Close()
+
+	// Write a partial record and its index entry, as if interrupted.
+	store, err := openPoolDiffStore(dbFile)
+	if err != nil {
+		t.Fatalf("openPoolDiffStore failed: %v", err)
+	}
+	rec := encodeDiff(&PoolDiff{In: randomHashSlice(5)})
+	if _, err = store.data.WriteAt(rec[:len(rec)-10], store.dataEnd); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	store.offsets = append(store.offsets, store.dataEnd)
+	indexEntry := make([]byte, 8)
+	binary.LittleEndian.PutUint64(indexEntry, uint64(store.dataEnd))
+	if _, err = store.index.WriteAt(indexEntry, poolDiffFileHeaderLen+8*3); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	store.close()
+
+	p, err = NewTicketPool(dbFile)
+	if err != nil {
+		t.Fatalf("NewTicketPool failed: %v", err)
+	}
+	defer p.Close()
+	if p.Tip() != 3 {
+		t.Fatalf("tip incorrect. expected 3, got %d", p.Tip())
+	}
+	pool, err := p.Pool(3)
+	if err != nil {
+		t.Fatalf("Pool failed: %v", err)
+	}
+	if len(pool) != 6 {
+		t.Errorf("incorrect pool size. expected 6, got %d", len(pool))
+	}
+	if err = p.Append(&PoolDiff{In: randomHashSlice(1)}, 4); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if pool, err = p.Pool(4); err != nil || len(pool) != 7 {
+		t.Errorf("Pool(4) returned %d tickets, error %v", len(pool), err)
+	}
+}
+
+// TestTicketPoolRecoveryZeroFilled tests that diffs with index entries, or
+// ticket hashes, that were not written are discarded when the diff store is
+// opened.
+func TestTicketPoolRecoveryZeroFilled(t *testing.T) {
+	if err := os.RemoveAll(dbFile); err != nil {
+		t.Fatalf("Failed to delete diff store: %v", err)
+	}
+	defer os.RemoveAll(dbFile)
+	p, err := NewTicketPool(dbFile)
+	if err != nil {
+		t.Fatalf("NewTicketPool failed: %v", err)
+	}
+	for h := int64(1); h <= 3; h++ {
+		diff := &PoolDiff{In: randomHashSlice(2)}
+		if err = p.Append(diff, h); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	p.Close()
+
+	// Write a record with its ticket hashes zeroed, and its index entry,
+	// followed by a zeroed index entry.
+	store, err := openPoolDiffStore(dbFile)
+	if err != nil {
+		t.Fatalf("openPoolDiffStore failed: %v", err)
+	}
+	rec := encodeDiff(&PoolDiff{In: make([]chainhash.Hash, 2)})
+	if _, err = store.data.WriteAt(rec, store.dataEnd); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	indexEntries := make([]byte, 16)
+	binary.LittleEndian.PutUint64(indexEntries, uint64(store.dataEnd))
+	if _, err = store.index.WriteAt(indexEntries, poolDiffFileHeaderLen+8*3); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	store.close()
+
+	p, err = NewTicketPool(dbFile)
+	if err != nil {
+		t.Fatalf("NewTicketPool failed: %v", err)
+	}
+	if p.Tip() != 3 {
+		p.Close()
+		t.Fatalf("tip incorrect. expected 3, got %d", p.Tip())
+	}
+
+	// A zeroed index entry following complete records is discarded too.
+	if err = p.Append(&PoolDiff{In: randomHashSlice(1)}, 4); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	p.Close()
+	store, err = openPoolDiffStore(dbFile)
+	if err != nil {
+		t.Fatalf("openPoolDiffStore failed: %v", err)
+	}
+	if _, err = store.index.WriteAt(make([]byte, 8), poolDiffFileHeaderLen+8*4); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	store.close()
+	if p, err = NewTicketPool(dbFile); err != nil {
+		t.Fatalf("NewTicketPool failed: %v", err)
+	}
+	defer p.Close()
+	if p.Tip() != 4 {
+		t.Fatalf("tip incorrect. expected 4, got %d", p.Tip())
+	}
+}
+
+// TestTicketPoolLiveHeightsIndex tests that the heights at which tickets
+// entered and left the pool follow appended and trimmed diffs, and are
+// rebuilt from the diff store when it is reopened.
+func TestTicketPoolLiveHeightsIndex(t *testing.T) {
+	if err := os.RemoveAll(dbFile); err != nil {
+		t.Fatalf("Failed to delete diff store: %v", err)
+	}
+	defer os.RemoveAll(dbFile)
+	p, err := NewTicketPool(dbFile)
+	if err != nil {
+		t.Fatalf("NewTicketPool failed: %v", err)
+	}
+
+	a, b, c := randomHash(), randomHash(), randomHash()
+	diffs := []*PoolDiff{
+		{In: []chainhash.Hash{a, b}},
+		{In: []chainhash.Hash{c}, Out: []chainhash.Hash{a}},
+		{Out: []chainhash.Hash{b}},
+	}
+	for i, diff := range diffs {
+		if err = p.AppendAndAdvancePool(diff, int64(i+1)); err != nil {
+			t.Fatalf("AppendAndAdvancePool failed: %v", err)
+		}
+	}
+
+	check := func(ticket chainhash.Hash, entered, left int64) {
+		t.Helper()
+		if in, out := p.TicketLiveHeights(ticket); in != entered || out != left {
+			t.Errorf("incorrect live heights (%d, %d), expected (%d, %d)",
+				in, out, entered, left)
+		}
+	}
+	check(a, 1, 2)
+	check(b, 1, 3)
+	check(c, 2, -1)
+
+	p.Trim()
+	check(b, 1, -1)
+	p.Trim()
+	check(a, 1, -1)
+	check(c, -1, -1)
+	p.Close()
+
+	if p, err = NewTicketPool(dbFile); err != nil {
+		t.Fatalf("NewTicketPool failed: %v", err)
+	}
+	defer p.Close()
+	check(a, 1, -1)
+	check(b, 1, -1)
+	check(c, -1, -1)
+	if err = p.Append(diffs[1], 2); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	check(a, 1, 2)
+	check(c, 2, -1)
+}
+
+// TestPoolDiffStoreTrimSnapshots tests that trimming the diff store removes
+// every snapshot above the new tip, regardless of the order in which the
+// snapshot files are listed when the store is opened.
+func TestPoolDiffStoreTrimSnapshots(t *testing.T) {
+	if err := os.RemoveAll(dbFile); err != nil {
+		t.Fatalf("Failed to delete diff store: %v", err)
+	}
+	defer os.RemoveAll(dbFile)
+	store, err := openPoolDiffStore(dbFile)
+	if err != nil {
+		t.Fatalf("openPoolDiffStore failed: %v", err)
+	}
+	for h := int64(1); h <= 3*PoolSnapshotInterval; h++ {
+		if err = store.append(&PoolDiff{}); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+		if h%PoolSnapshotInterval == 0 {
+			if err = store.storeSnapshot(h, randomHashSlice(1)); err != nil {
+				t.Fatalf("storeSnapshot failed: %v", err)
+			}
+		}
+	}
+	store.close()
+
+	// The snapshot at 12288 is listed before 4096 and 8192 by name.
+	if store, err = openPoolDiffStore(dbFile); err != nil {
+		t.Fatalf("openPoolDiffStore failed: %v", err)
+	}
+	if len(store.snapshots) != 3 || store.lastSnapshot() != 3*PoolSnapshotInterval {
+		t.Fatalf("loaded snapshots %v", store.snapshots)
+	}
+	defer store.close()
+	if err = store.trim(5000); err != nil {
+		t.Fatalf("trim failed: %v", err)
+	}
+	if len(store.snapshots) != 1 || store.snapshots[0] != PoolSnapshotInterval {
+		t.Errorf("snapshots after trim %v, expected [%d]", store.snapshots,
+			PoolSnapshotInterval)
+	}
+	for _, h := range []int64{2 * PoolSnapshotInterval, 3 * PoolSnapshotInterval} {
+		if _, err = os.Stat(store.snapshotPath(h)); !os.IsNotExist(err) {
+			t.Errorf("snapshot at %d not removed: %v", h, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2018, The Decred developers
+// See LICENSE for details.
+
+package stakedb
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/asdine/storm"
+)
+
+// PoolDiffDBItem is the type in the legacy storm live ticket DB. The primary key
+// (id) is Height. It is only used to migrate the DB to the diff store.
+type PoolDiffDBItem struct {
+	Height   int64 `storm:"id"`
+	PoolDiff `storm:"inline"`
+}
+
+// MigrateStormTicketPool copies the pool diffs in the legacy storm DB file to a
+// new diff store in the directory dir. The store is built in a temporary
+// directory that is renamed to dir when complete, so an interrupted migration
+// may simply be repeated. The storm DB file is not modified.
+func MigrateStormTicketPool(stormFile, dir string) error {
+	db, err := storm.Open(stormFile)
+	if err != nil {
+		return fmt.Errorf("failed storm.Open: %v", err)
+	}
+	defer db.Close()
+
+	var poolDiffs []PoolDiffDBItem
+	if err = db.AllByIndex("Height", &poolDiffs); err != nil {
+		return fmt.Errorf("failed (*storm.DB).AllByIndex: %v", err)
+	}
+
+	tmpDir := dir + ".tmp"
+	if err = os.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+	store, err := openPoolDiffStore(tmpDir)
+	if err != nil {
+		return err
+	}
+	for i := range poolDiffs {
+		if poolDiffs[i].Height != int64(i)+1 {
+			store.close()
+			return fmt.Errorf("pool diff at height %d missing from %s",
+				int64(i)+1, stormFile)
+		}
+		if err = store.append(&poolDiffs[i].PoolDiff); err != nil {
+			store.close()
+			return err
+		}
+	}
+	if err = store.close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpDir, dir)
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2018, The dcrdata developers
+// See LICENSE for details.
+
+package txhelpers
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainec"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/txscript"
+	"github.com/decred/dcrd/wire"
+)
+
+// Redeem script types other than the txscript script classes.
+const (
+	RedeemScriptAtomicSwap = "atomicswap"
+)
+
+// RedeemScript describes a P2SH redeem script revealed by the signature script
+// of the input spending a pay-to-script-hash output.
+type RedeemScript struct {
+	Hex  string `json:"hex"`
+	Asm  string `json:"asm"`
+	Type string `json:"type"`
+	// ReqSigs and NumPubKeys are the m and n of an m-of-n multisig script.
+	ReqSigs    int `json:"reqSigs,omitempty"`
+	NumPubKeys int `json:"numPubKeys,omitempty"`
+	// Addresses are those of the public keys or hashes in the script.
+	Addresses  []string          `json:"addresses,omitempty"`
+	AtomicSwap *AtomicSwapScript `json:"atomicSwap,omitempty"`
+	// StakeSubmission indicates that the spent output is the stake submission
+	// of a ticket, spent by a vote or revocation.
+	StakeSubmission bool `json:"stakeSubmission,omitempty"`
+}
+
+// AtomicSwapScript describes the hashed timelock contract of an atomic swap.
+// The recipient may redeem the contract by revealing the secret, and the
+// initiator may refund it after the lock time.
+type AtomicSwapScript struct {
+	RecipientAddress string `json:"recipientAddress"`
+	RefundAddress    string `json:"refundAddress"`
+	SecretHash       string `json:"secretHash"`
+	SecretSize       int64  `json:"secretSize"`
+	LockTime         int64  `json:"lockTime"`
+}
+
+// ExtractRedeemScript returns the redeem script pushed last by a push-only
+// signature script, provided that it hashes to the given script hash. nil is
+// returned otherwise.
+func ExtractRedeemScript(sigScript, scriptHash []byte) []byte {
+	if !txscript.IsPushOnlyScript(sigScript) {
+		return nil
+	}
+	pushes, err := txscript.PushedData(sigScript)
+	if err != nil || len(pushes) == 0 {
+		return nil
+	}
+	script := pushes[len(pushes)-1]
+	if !bytes.Equal(dcrutil.Hash160(script), scriptHash) {
+		return nil
+	}
+	return script
+}
+
+// DecodeRedeemScript decodes a redeem script, identifying multisig and atomic
+// swap contracts. Other scripts are typed by their txscript class.
+func DecodeRedeemScript(script []byte, params *chaincfg.Params) *RedeemScript {
+	rs := &RedeemScript{
+		Hex: hex.EncodeToString(script),
+	}
+	// An unparsable script is disassembled up to the failure.
+	rs.Asm, _ = txscript.DisasmString(script)
+
+	swap, err := txscript.ExtractAtomicSwapDataPushes(0, script)
+	if err == nil && swap != nil {
+		rs.Type = RedeemScriptAtomicSwap
+		rs.AtomicSwap = &AtomicSwapScript{
+			RecipientAddress: pubKeyHashAddress(swap.RecipientHash160[:], params),
+			RefundAddress:    pubKeyHashAddress(swap.RefundHash160[:], params),
+			SecretHash:       hex.EncodeToString(swap.SecretHash[:]),
+			SecretSize:       swap.SecretSize,
+			LockTime:         swap.LockTime,
+		}
+		rs.Addresses = []string{rs.AtomicSwap.RecipientAddress,
+			rs.AtomicSwap.RefundAddress}
+		return rs
+	}
+
+	class, addrs, reqSigs, err := txscript.ExtractPkScriptAddrs(0, script, params)
+	rs.Type = class.String()
+	if err != nil {
+		return rs
+	}
+	for _, addr := range addrs {
+		rs.Addresses = append(rs.Addresses, addr.EncodeAddress())
+	}
+	if class == txscript.MultiSigTy {
+		rs.ReqSigs = reqSigs
+		rs.NumPubKeys, _, _ = txscript.CalcMultiSigStats(script)
+	}
+	return rs
+}
+
+// IsScriptHashAddress checks if the address is a P2SH address, as paid by both
+// regular and stake tagged P2SH outputs.
+func IsScriptHashAddress(address string) bool {
+	addr, err := dcrutil.DecodeAddress(address)
+	if err != nil {
+		return false
+	}
+	_, ok := addr.(*dcrutil.AddressScriptHash)
+	return ok
+}
+
+// SpentRedeemScript decodes the redeem script revealed by input vin of
+// spendingTx, which spends an output paying to the given P2SH address. nil is
+// returned if the address is not a script hash address, or if the input does
+// not reveal a matching redeem script.
+func SpentRedeemScript(spendingTx *wire.MsgTx, vin uint32, p2shAddress string,
+	params *chaincfg.Params) *RedeemScript {
+	if int(vin) >= len(spendingTx.TxIn) {
+		return nil
+	}
+	addr, err := dcrutil.DecodeAddress(p2shAddress)
+	if err != nil {
+		return nil
+	}
+	scriptHashAddr, ok := addr.(*dcrutil.AddressScriptHash)
+	if !ok {
+		return nil
+	}
+
+	script := ExtractRedeemScript(spendingTx.TxIn[vin].SignatureScript,
+		scriptHashAddr.Hash160()[:])
+	if script == nil {
+		return nil
+	}
+	rs := DecodeRedeemScript(script, params)
+	// Votes spend the ticket with their second input, after the stakebase.
+	switch stake.DetermineTxType(spendingTx) {
+	case stake.TxTypeSSGen:
+		rs.StakeSubmission = vin == 1
+	case stake.TxTypeSSRtx:
+		rs.StakeSubmission = vin == 0
+	}
+	return rs
+}
+
+// pubKeyHashAddress encodes a secp256k1 pubkey hash address, returning an
+// empty string if the hash is invalid.
+func pubKeyHashAddress(hash160 []byte, params *chaincfg.Params) string {
+	addr, err := dcrutil.NewAddressPubKeyHash(hash160, params,
+		chainec.ECTypeSecp256k1)
+	if err != nil {
+		return ""
+	}
+	return addr.EncodeAddress()
+}
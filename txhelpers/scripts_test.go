@@ -0,0 +1,91 @@
+package txhelpers
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/txscript"
+	"github.com/decred/dcrd/wire"
+)
+
+func testAtomicSwapContract(t *testing.T, secretHash []byte) []byte {
+	contract, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_IF).
+		AddOp(txscript.OP_SIZE).AddInt64(32).AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_SHA256).AddData(secretHash).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).
+		AddData(bytes.Repeat([]byte{4}, 20)).
+		AddOp(txscript.OP_ELSE).
+		AddInt64(1530000000).AddOp(txscript.OP_CHECKLOCKTIMEVERIFY).
+		AddOp(txscript.OP_DROP).
+		AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).
+		AddData(bytes.Repeat([]byte{5}, 20)).
+		AddOp(txscript.OP_ENDIF).
+		AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contract
+}
+
+func TestSpentRedeemScript(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	multisigSigScript := testMultisigSigScript(t)
+	pushes, err := txscript.PushedData(multisigSigScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	multisigScript := pushes[len(pushes)-1]
+
+	contract := testAtomicSwapContract(t, bytes.Repeat([]byte{3}, 32))
+	redeemSigScript, err := txscript.NewScriptBuilder().AddData([]byte{1}).
+		AddData([]byte{2}).AddData(bytes.Repeat([]byte{6}, 32)).
+		AddInt64(1).AddData(contract).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p2shAddress := func(script []byte) string {
+		addr, err := dcrutil.NewAddressScriptHash(script, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr.EncodeAddress()
+	}
+	msgTx := testMsgTx([][]byte{multisigSigScript, redeemSigScript},
+		[]int64{1e8})
+
+	rs := SpentRedeemScript(msgTx, 0, p2shAddress(multisigScript), params)
+	if rs == nil {
+		t.Fatal("multisig redeem script not found")
+	}
+	if rs.Type != "multisig" || rs.ReqSigs != 2 || rs.NumPubKeys != 2 ||
+		len(rs.Addresses) != 2 {
+		t.Errorf("multisig redeem script decoded as %+v", rs)
+	}
+
+	rs = SpentRedeemScript(msgTx, 1, p2shAddress(contract), params)
+	if rs == nil {
+		t.Fatal("atomic swap redeem script not found")
+	}
+	if rs.Type != RedeemScriptAtomicSwap || rs.AtomicSwap == nil {
+		t.Fatalf("atomic swap redeem script decoded as %+v", rs)
+	}
+	if rs.AtomicSwap.SecretSize != 32 || rs.AtomicSwap.LockTime != 1530000000 {
+		t.Errorf("atomic swap decoded as %+v", rs.AtomicSwap)
+	}
+
+	// The redeem script must hash to the address of the spent output.
+	if rs = SpentRedeemScript(msgTx, 1, p2shAddress(multisigScript), params); rs != nil {
+		t.Errorf("mismatched redeem script decoded as %+v", rs)
+	}
+	if rs = SpentRedeemScript(msgTx, 2, p2shAddress(contract), params); rs != nil {
+		t.Errorf("redeem script of missing input decoded as %+v", rs)
+	}
+	if rs = SpentRedeemScript(wire.NewMsgTx(), 0, p2shAddress(contract), params); rs != nil {
+		t.Errorf("redeem script of empty transaction decoded as %+v", rs)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2018, The dcrdata developers
+// See LICENSE for details.
+
+package txhelpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/decred/dcrd/blockchain"
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/txscript"
+	"github.com/decred/dcrd/wire"
+)
+
+// An atomic swap contract is a hashed timelock contract paid to by a P2SH
+// output, so it is only revealed when the output is spent. The contract is
+// redeemed by the recipient with a signature script revealing the secret, or
+// refunded to the initiator after the lock time without it.
+
+// AtomicSwapSpend describes an input spending an atomic swap contract output.
+type AtomicSwapSpend struct {
+	ContractTx   string
+	ContractVout uint32
+	SpendVin     uint32
+	// Value is the amount of the contract output.
+	Value    int64
+	Contract []byte
+	// ContractAddress is the P2SH address of the contract.
+	ContractAddress string
+	*AtomicSwapScript
+	// Secret is the preimage of the secret hash revealed by a redemption. It
+	// is nil for a refund.
+	Secret []byte
+}
+
+// IsRefund indicates if the contract was refunded rather than redeemed.
+func (s *AtomicSwapSpend) IsRefund() bool {
+	return s.Secret == nil
+}
+
+// IsAtomicSwapContract checks if the script is an atomic swap contract.
+func IsAtomicSwapContract(script []byte) bool {
+	swap, err := txscript.ExtractAtomicSwapDataPushes(0, script)
+	return err == nil && swap != nil
+}
+
+// AtomicSwapContractAddress returns the P2SH address paid to by the outputs
+// funding the atomic swap contract.
+func AtomicSwapContractAddress(contract []byte, params *chaincfg.Params) (string, error) {
+	addr, err := dcrutil.NewAddressScriptHash(contract, params)
+	if err != nil {
+		return "", err
+	}
+	return addr.EncodeAddress(), nil
+}
+
+// ExtractAtomicSwapSpends returns the inputs of a regular transaction spending
+// atomic swap contract outputs, identified by the contract pushed last by
+// their signature scripts.
+func ExtractAtomicSwapSpends(msgTx *wire.MsgTx, params *chaincfg.Params) []*AtomicSwapSpend {
+	if blockchain.IsCoinBaseTx(msgTx) {
+		return nil
+	}
+
+	var spends []*AtomicSwapSpend
+	for i, txIn := range msgTx.TxIn {
+		if !txscript.IsPushOnlyScript(txIn.SignatureScript) {
+			continue
+		}
+		pushes, err := txscript.PushedData(txIn.SignatureScript)
+		if err != nil || len(pushes) == 0 {
+			continue
+		}
+		contract := pushes[len(pushes)-1]
+		if !IsAtomicSwapContract(contract) {
+			continue
+		}
+		contractAddress, err := AtomicSwapContractAddress(contract, params)
+		if err != nil {
+			continue
+		}
+
+		rs := DecodeRedeemScript(contract, params)
+		spend := &AtomicSwapSpend{
+			ContractTx:       txIn.PreviousOutPoint.Hash.String(),
+			ContractVout:     txIn.PreviousOutPoint.Index,
+			SpendVin:         uint32(i),
+			Value:            txIn.ValueIn,
+			Contract:         contract,
+			ContractAddress:  contractAddress,
+			AtomicSwapScript: rs.AtomicSwap,
+		}
+		// A redemption pushes the secret before the contract.
+		for _, push := range pushes[:len(pushes)-1] {
+			if len(push) == 0 || int64(len(push)) != rs.AtomicSwap.SecretSize {
+				continue
+			}
+			secretHash := sha256.Sum256(push)
+			if hex.EncodeToString(secretHash[:]) == rs.AtomicSwap.SecretHash {
+				spend.Secret = push
+				break
+			}
+		}
+		spends = append(spends, spend)
+	}
+	return spends
+}
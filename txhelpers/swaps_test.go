@@ -0,0 +1,56 @@
+package txhelpers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/txscript"
+)
+
+func TestExtractAtomicSwapSpends(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	secret := bytes.Repeat([]byte{7}, 32)
+	secretHash := sha256.Sum256(secret)
+	contract := testAtomicSwapContract(t, secretHash[:])
+
+	redeem, err := txscript.NewScriptBuilder().AddData([]byte{1}).
+		AddData([]byte{2}).AddData(secret).AddInt64(1).AddData(contract).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refund, err := txscript.NewScriptBuilder().AddData([]byte{1}).
+		AddData([]byte{2}).AddInt64(0).AddData(contract).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgTx := testMsgTx([][]byte{{0x01, 0x01}, redeem, refund}, []int64{1e8})
+	msgTx.TxIn[1].ValueIn = 5e8
+
+	spends := ExtractAtomicSwapSpends(msgTx, params)
+	if len(spends) != 2 {
+		t.Fatalf("found %d atomic swap spends, expected 2", len(spends))
+	}
+	redemption := spends[0]
+	if redemption.SpendVin != 1 || redemption.IsRefund() ||
+		!bytes.Equal(redemption.Secret, secret) || redemption.Value != 5e8 {
+		t.Errorf("redemption decoded as %+v", redemption)
+	}
+	if redemption.ContractTx != msgTx.TxIn[1].PreviousOutPoint.Hash.String() ||
+		!bytes.Equal(redemption.Contract, contract) {
+		t.Errorf("redemption contract decoded as %s:%d %x", redemption.ContractTx,
+			redemption.ContractVout, redemption.Contract)
+	}
+	if redemption.AtomicSwapScript == nil || redemption.LockTime != 1530000000 {
+		t.Errorf("redemption contract decoded as %+v", redemption.AtomicSwapScript)
+	}
+	if spends[1].SpendVin != 2 || !spends[1].IsRefund() {
+		t.Errorf("refund decoded as %+v", spends[1])
+	}
+	addr, err := AtomicSwapContractAddress(contract, params)
+	if err != nil || redemption.ContractAddress != addr {
+		t.Errorf("contract address %s, expected %s (%v)",
+			redemption.ContractAddress, addr, err)
+	}
+}
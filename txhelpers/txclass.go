@@ -0,0 +1,158 @@
+// Copyright (c) 2018, The dcrdata developers
+// See LICENSE for details.
+
+package txhelpers
+
+import (
+	"strings"
+
+	"github.com/decred/dcrd/blockchain"
+	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/decred/dcrd/txscript"
+	"github.com/decred/dcrd/wire"
+)
+
+// TxClass is a set of labels describing the structure of a regular
+// transaction. A transaction may have any number of the labels.
+type TxClass uint32
+
+// Transaction class labels.
+const (
+	// TxClassSplitTicket labels a transaction funding the inputs of a ticket
+	// with multiple inputs. It can only be known once the ticket is mined, so
+	// it is not set by ClassifyTx.
+	TxClassSplitTicket TxClass = 1 << iota
+	// TxClassMultisig labels a transaction spending a P2SH multisig output.
+	TxClassMultisig
+	// TxClassMixed labels a transaction with several inputs and several
+	// outputs of equal value, as in a CoinJoin.
+	TxClassMixed
+	// TxClassConsolidation labels a transaction combining many inputs into a
+	// single output.
+	TxClassConsolidation
+	// TxClassFanOut labels a transaction paying many outputs from few inputs.
+	TxClassFanOut
+)
+
+// Thresholds of the transaction class heuristics.
+const (
+	mixMinEqualOutputs     = 3
+	mixMinInputs           = 2
+	consolidationMinInputs = 5
+	fanOutMinOutputs       = 10
+	fanOutMaxInputs        = 2
+)
+
+var txClassLabels = []struct {
+	class TxClass
+	label string
+}{
+	{TxClassSplitTicket, "split"},
+	{TxClassMultisig, "multisig"},
+	{TxClassMixed, "mixed"},
+	{TxClassConsolidation, "consolidation"},
+	{TxClassFanOut, "fanout"},
+}
+
+// Labels returns the labels of the classes in the set.
+func (c TxClass) Labels() []string {
+	var labels []string
+	for _, l := range txClassLabels {
+		if c&l.class != 0 {
+			labels = append(labels, l.label)
+		}
+	}
+	return labels
+}
+
+// String returns the comma-separated labels of the classes in the set.
+func (c TxClass) String() string {
+	return strings.Join(c.Labels(), ",")
+}
+
+// TxClassFromLabel returns the class with the given label, and a bool
+// indicating if the label is known.
+func TxClassFromLabel(label string) (TxClass, bool) {
+	for _, l := range txClassLabels {
+		if l.label == label {
+			return l.class, true
+		}
+	}
+	return 0, false
+}
+
+// ClassifyTx returns the classes of a regular transaction that are apparent
+// from the transaction itself. Stake and coinbase transactions are not
+// classified.
+func ClassifyTx(msgTx *wire.MsgTx) TxClass {
+	if stake.DetermineTxType(msgTx) != stake.TxTypeRegular ||
+		blockchain.IsCoinBaseTx(msgTx) {
+		return 0
+	}
+
+	sigScripts := make([][]byte, len(msgTx.TxIn))
+	for i, txIn := range msgTx.TxIn {
+		sigScripts[i] = txIn.SignatureScript
+	}
+	outValues := make([]int64, len(msgTx.TxOut))
+	for i, txOut := range msgTx.TxOut {
+		outValues[i] = txOut.Value
+	}
+	return ClassifyRegularTx(len(msgTx.TxIn), sigScripts, outValues)
+}
+
+// ClassifyRegularTx returns the classes of a regular, non-coinbase transaction
+// with numIn inputs and outputs of the given values. The inputs' signature
+// scripts identify multisig spends, and may be nil if they are not available.
+func ClassifyRegularTx(numIn int, sigScripts [][]byte, outValues []int64) TxClass {
+	var class TxClass
+	for _, sigScript := range sigScripts {
+		if txscript.IsMultisigSigScript(sigScript) {
+			class |= TxClassMultisig
+			break
+		}
+	}
+
+	numOut := len(outValues)
+	if numIn >= mixMinInputs && numOut >= mixMinEqualOutputs {
+		equalOutputs := make(map[int64]int, numOut)
+		for _, v := range outValues {
+			if v == 0 {
+				continue
+			}
+			equalOutputs[v]++
+			if equalOutputs[v] >= mixMinEqualOutputs {
+				class |= TxClassMixed
+				break
+			}
+		}
+	}
+	if numIn >= consolidationMinInputs && numOut == 1 {
+		class |= TxClassConsolidation
+	}
+	if numIn <= fanOutMaxInputs && numOut >= fanOutMinOutputs {
+		class |= TxClassFanOut
+	}
+	return class
+}
+
+// SplitTicketFundingTxns returns the hashes of the transactions funding the
+// inputs of the tickets with multiple inputs in a block.
+func SplitTicketFundingTxns(msgBlock *wire.MsgBlock) []string {
+	seen := make(map[string]struct{})
+	var txns []string
+	for _, stx := range msgBlock.STransactions {
+		if len(stx.TxIn) < 2 || stake.DetermineTxType(stx) != stake.TxTypeSStx {
+			continue
+		}
+		for _, txIn := range stx.TxIn {
+			hash := txIn.PreviousOutPoint.Hash.String()
+			if _, ok := seen[hash]; ok {
+				continue
+			}
+			seen[hash] = struct{}{}
+			txns = append(txns, hash)
+		}
+	}
+	return txns
+}
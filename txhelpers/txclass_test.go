@@ -0,0 +1,102 @@
+package txhelpers
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/txscript"
+	"github.com/decred/dcrd/wire"
+)
+
+// testMsgTx makes a regular transaction with the given input signature
+// scripts and output values.
+func testMsgTx(sigScripts [][]byte, values []int64) *wire.MsgTx {
+	msgTx := wire.NewMsgTx()
+	for i, sigScript := range sigScripts {
+		prevHash := chainhash.HashH([]byte{byte(i)})
+		prevOut := wire.NewOutPoint(&prevHash, 0, wire.TxTreeRegular)
+		msgTx.AddTxIn(wire.NewTxIn(prevOut, sigScript))
+	}
+	for _, v := range values {
+		msgTx.AddTxOut(wire.NewTxOut(v, []byte{txscript.OP_TRUE}))
+	}
+	return msgTx
+}
+
+func testMultisigSigScript(t *testing.T) []byte {
+	redeemScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_2).
+		AddData(append([]byte{0x02}, bytes.Repeat([]byte{1}, 32)...)).
+		AddData(append([]byte{0x03}, bytes.Repeat([]byte{2}, 32)...)).
+		AddOp(txscript.OP_2).AddOp(txscript.OP_CHECKMULTISIG).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigScript, err := txscript.NewScriptBuilder().AddData([]byte{1}).
+		AddData([]byte{2}).AddData(redeemScript).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sigScript
+}
+
+func TestClassifyTx(t *testing.T) {
+	sig := []byte{0x01, 0x01}
+	inputs := func(n int) [][]byte {
+		s := make([][]byte, n)
+		for i := range s {
+			s[i] = sig
+		}
+		return s
+	}
+	values := func(n int, v int64) []int64 {
+		s := make([]int64, n)
+		for i := range s {
+			s[i] = v + int64(i)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name   string
+		msgTx  *wire.MsgTx
+		labels []string
+	}{
+		{"plain", testMsgTx(inputs(1), []int64{5e8, 1e8}), nil},
+		{"multisig", testMsgTx([][]byte{sig, testMultisigSigScript(t)},
+			[]int64{5e8, 1e8}), []string{"multisig"}},
+		{"mixed", testMsgTx(inputs(3), []int64{2e8, 2e8, 2e8, 3e7, 4e7}),
+			[]string{"mixed"}},
+		{"equal outputs from one input", testMsgTx(inputs(1),
+			[]int64{2e8, 2e8, 2e8}), nil},
+		{"consolidation", testMsgTx(inputs(consolidationMinInputs),
+			[]int64{9e8}), []string{"consolidation"}},
+		{"fanout", testMsgTx(inputs(1), values(fanOutMinOutputs, 1e6)),
+			[]string{"fanout"}},
+	}
+	for _, tt := range tests {
+		got := ClassifyTx(tt.msgTx).Labels()
+		if !reflect.DeepEqual(got, tt.labels) {
+			t.Errorf("%s: got labels %v, expected %v", tt.name, got, tt.labels)
+		}
+	}
+}
+
+func TestTxClassFromLabel(t *testing.T) {
+	for _, l := range txClassLabels {
+		class, ok := TxClassFromLabel(l.label)
+		if !ok || class != l.class {
+			t.Errorf("TxClassFromLabel(%q) = %v, %v", l.label, class, ok)
+		}
+		if class.String() != l.label {
+			t.Errorf("String() = %q, expected %q", class.String(), l.label)
+		}
+	}
+	if _, ok := TxClassFromLabel("unknown"); ok {
+		t.Errorf("unknown label accepted")
+	}
+	if s := (TxClassMixed | TxClassFanOut).String(); s != "mixed,fanout" {
+		t.Errorf("String() = %q, expected %q", s, "mixed,fanout")
+	}
+}
@@ -266,6 +266,22 @@ func MedianCoin(s []float64) float64 {
 	return (s[middle] + s[middle-1]) / 2
 }
 
+// PercentileCoin gets the p-th percentile (0 <= p <= 1) of a slice of float64s
+// sorted in ascending order, by the nearest-rank method
+func PercentileCoin(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	} else if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
 // GetDifficultyRatio returns the proof-of-work difficulty as a multiple of the
 // minimum difficulty using the passed bits field from the header of a block.
 func GetDifficultyRatio(bits uint32, params *chaincfg.Params) float64 {
@@ -535,6 +551,22 @@ func FeeRateInfoBlock(block *dcrutil.Block) *dcrjson.FeeInfoBlock {
 	return feeInfo
 }
 
+// RegularTxFeeRates computes the fee rates in DCR/kB of the regular
+// transactions, excluding the coinbase, included in the specified block. The
+// fee rates are sorted in ascending order.
+func RegularTxFeeRates(msgBlock *wire.MsgBlock) []float64 {
+	feeRates := make([]float64, 0, len(msgBlock.Transactions))
+	for _, msgTx := range msgBlock.Transactions {
+		if blockchain.IsCoinBaseTx(msgTx) {
+			continue
+		}
+		_, feeRate := TxFeeRate(msgTx)
+		feeRates = append(feeRates, feeRate.ToCoin())
+	}
+	sort.Float64s(feeRates)
+	return feeRates
+}
+
 // MsgTxFromHex returns a wire.MsgTx struct built from the transaction hex string
 func MsgTxFromHex(txhex string) (*wire.MsgTx, error) {
 	txBytes, err := hex.DecodeString(txhex)
@@ -178,3 +178,39 @@ func ConnectNodeRPC(host, user, pass, cert string, disableTLS bool) (*rpcclient.
 
 	return dcrdClient, nodeVer, nil
 }
+
+func TestRegularTxFeeRates(t *testing.T) {
+	block, _ := LoadTestBlockAndSSTX(t)
+
+	feeRates := RegularTxFeeRates(block.MsgBlock())
+	t.Log(feeRates)
+
+	feeRatesExpected := []float64{0.00100396, 0.00100796, 0.01003968, 0.01005737,
+		0.01007343, 0.01008116, 0.01008116, 0.01175712}
+
+	if !reflect.DeepEqual(feeRatesExpected, feeRates) {
+		t.Errorf("Fee rates mismatch. Expected %v, got %v.", feeRatesExpected, feeRates)
+	}
+}
+
+func TestPercentileCoin(t *testing.T) {
+	sorted := []float64{0.001, 0.002, 0.003, 0.004, 0.01}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 0.001},
+		{0.1, 0.001},
+		{0.5, 0.003},
+		{0.9, 0.01},
+		{1, 0.01},
+	}
+	for _, tt := range tests {
+		if got := PercentileCoin(sorted, tt.p); got != tt.want {
+			t.Errorf("PercentileCoin(%v) = %v, expected %v", tt.p, got, tt.want)
+		}
+	}
+	if got := PercentileCoin(nil, 0.5); got != 0 {
+		t.Errorf("PercentileCoin of no values = %v, expected 0", got)
+	}
+}